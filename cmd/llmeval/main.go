@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/davegermiquet/kratos-chi-ollama/config"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/eval"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/langchain"
+)
+
+func main() {
+	suitePath := flag.String("suite", "", "path to the YAML evaluation suite (required)")
+	modelList := flag.String("models", "", "comma-separated provider:model list, overrides the suite's models")
+	judgeSpec := flag.String("judge", "", "provider:model used for rubric checks, overrides the suite's judge")
+	format := flag.String("format", "text", "report format: text, json or junit")
+	outPath := flag.String("out", "", "write the report to this file instead of stdout")
+	timeout := flag.Duration("timeout", 2*time.Minute, "timeout per model and judge call")
+	minScore := flag.Float64("min-score", 0, "exit with status 1 when the overall score is below this value (0-1)")
+	flag.Parse()
+
+	if *suitePath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	suite, err := eval.LoadSuite(*suitePath)
+	if err != nil {
+		log.Fatalf("Failed to load suite: %v", err)
+	}
+
+	targets := suite.Models
+	if *modelList != "" {
+		targets, err = eval.ParseModelTargets(*modelList)
+		if err != nil {
+			log.Fatalf("Invalid -models: %v", err)
+		}
+	}
+	if len(targets) == 0 {
+		targets = []eval.ModelTarget{{Provider: cfg.LLM.Provider, Model: cfg.LLM.Model}}
+	}
+
+	models := make([]eval.Model, 0, len(targets))
+	for _, target := range targets {
+		svc, err := newService(cfg.LLM, target)
+		if err != nil {
+			log.Fatalf("Failed to create client for %s: %v", target.Name(), err)
+		}
+		models = append(models, eval.Model{Target: target, Service: svc})
+	}
+
+	runner := &eval.Runner{Timeout: *timeout}
+
+	judge := suite.Judge
+	if *judgeSpec != "" {
+		parsed, err := eval.ParseModelTargets(*judgeSpec)
+		if err != nil || len(parsed) != 1 {
+			log.Fatalf("Invalid -judge: expected a single provider:model")
+		}
+		judge = &parsed[0]
+	}
+	if err := suite.ValidateJudge(judge); err != nil {
+		log.Fatalf("Invalid suite: %v", err)
+	}
+	if judge != nil {
+		runner.Judge, err = newService(cfg.LLM, *judge)
+		if err != nil {
+			log.Fatalf("Failed to create judge client for %s: %v", judge.Name(), err)
+		}
+	}
+
+	report := runner.Run(context.Background(), suite, models)
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatalf("Failed to create report file: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := report.Write(out, eval.Format(*format)); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+
+	if score := report.Score(); score < *minScore {
+		fmt.Fprintf(os.Stderr, "overall score %.2f is below -min-score %.2f\n", score, *minScore)
+		os.Exit(1)
+	}
+}
+
+// newService builds a client for target. Connection settings are inherited
+// from the LLM_* environment when the target uses the configured provider.
+func newService(defaults config.LLMConfig, target eval.ModelTarget) (langchain.LLMService, error) {
+	provider, err := langchain.ParseProvider(target.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	clientCfg := langchain.Config{
+		Provider: provider,
+		Model:    target.Model,
+		BaseURL:  target.BaseURL,
+	}
+
+	if target.Provider == defaults.Provider {
+		if clientCfg.BaseURL == "" {
+			clientCfg.BaseURL = defaults.BaseURL
+		}
+		clientCfg.APIKey = defaults.APIKey
+		clientCfg.Organization = defaults.Organization
+		clientCfg.CredentialsFile = defaults.CredentialsFile
+		clientCfg.FixtureFile = defaults.FixtureFile
+	}
+
+	if target.APIKeyEnv != "" {
+		clientCfg.APIKey = os.Getenv(target.APIKeyEnv)
+	}
+
+	return langchain.NewClient(clientCfg)
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/go-chi/chi/v5"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
@@ -11,10 +14,13 @@ import (
 
 	"github.com/davegermiquet/kratos-chi-ollama/config"
 	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/authz"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/challenge"
 	"github.com/davegermiquet/kratos-chi-ollama/internal/handlers"
 	"github.com/davegermiquet/kratos-chi-ollama/internal/langchain"
 	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
 	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
 )
 
 func main() {
@@ -27,37 +33,248 @@ func main() {
 	// Initialize dependencies
 	kratosClient := auth.NewKratosClient(cfg.Kratos.PublicURL, cfg.Kratos.AdminURL)
 
+	// Cache session validation and API key identity lookups unless
+	// SESSION_CACHE_TTL is 0
+	var kratosService auth.KratosService = kratosClient
+	var adminService auth.AdminService = kratosClient
+	var sessionCache *auth.SessionCache
+	apiKeyStore := auth.NewAPIKeyStore(kratosClient)
+	if cfg.Session.CacheTTL > 0 {
+		sessionCache = auth.NewSessionCache(kratosClient, auth.SessionCacheConfig{
+			TTL:         cfg.Session.CacheTTL,
+			NegativeTTL: cfg.Session.NegativeCacheTTL,
+			MaxEntries:  cfg.Session.CacheMaxEntries,
+		})
+		kratosService = sessionCache
+		apiKeyStore.WithIdentityCache(cfg.Session.CacheTTL, cfg.Session.CacheMaxEntries)
+		// Blocking, deleting or signing out an identity drops its cached
+		// sessions and API key lookups
+		adminService = apiKeyStore.WrapAdmin(sessionCache.WrapAdmin(kratosClient))
+	}
+
+	// Session JWTs, off unless JWT_ENABLED is set. Routes that accept JWTs
+	// verify them locally and fall back to Kratos for session tokens.
+	var jwtKeys *auth.JWTKeySet
+	var jwtValidator auth.SessionValidator = kratosService
+	if cfg.JWT.Enabled {
+		if cfg.JWT.KeysFile != "" {
+			jwtKeys, err = auth.LoadJWTKeySet(cfg.JWT.KeysFile)
+		} else {
+			jwtKeys, err = auth.GenerateJWTKeySet()
+			log.Println("JWT_KEYS_FILE not set, signing JWTs with a generated key")
+		}
+		if err != nil {
+			log.Fatalf("Failed to load JWT keys: %v", err)
+		}
+
+		verifier := auth.NewJWTVerifier(jwtKeys, cfg.JWT.Issuer, cfg.JWT.Audience)
+		jwtValidator = auth.NewJWTSessionValidator(verifier, kratosService)
+	}
+
+	// Relationship permissions from Keto, or local tuples when Keto is not configured
+	var permissionChecker authz.PermissionChecker
+	if cfg.Keto.ReadURL != "" {
+		permissionChecker = authz.NewKetoClient(cfg.Keto.ReadURL)
+	} else {
+		memoryStore := authz.NewMemoryStore()
+		if cfg.Keto.TuplesFile != "" {
+			memoryStore, err = authz.LoadMemoryStore(cfg.Keto.TuplesFile)
+			if err != nil {
+				log.Fatalf("Failed to load relation tuples: %v", err)
+			}
+		}
+		permissionChecker = memoryStore
+		log.Println("KETO_READ_URL not set, checking permissions against in-memory relation tuples")
+	}
+
+	llmProvider, err := langchain.ParseProvider(cfg.LLM.Provider)
+	if err != nil {
+		log.Fatalf("Invalid LLM provider: %v", err)
+	}
+
 	llmClient, err := langchain.NewClient(langchain.Config{
-		Provider: langchain.Provider(cfg.LLM.Provider),
-		Model:    cfg.LLM.Model,
-		BaseURL:  cfg.LLM.BaseURL,
-		APIKey:   cfg.LLM.APIKey,
+		Provider:        llmProvider,
+		Model:           cfg.LLM.Model,
+		BaseURL:         cfg.LLM.BaseURL,
+		APIKey:          cfg.LLM.APIKey,
+		Organization:    cfg.LLM.Organization,
+		CredentialsFile: cfg.LLM.CredentialsFile,
+		FixtureFile:     cfg.LLM.FixtureFile,
 	})
 	if err != nil {
 		log.Fatalf("Failed to create LLM client: %v", err)
 	}
 
+	llmService, err := langchain.WrapWithCassette(
+		llmClient,
+		langchain.CassetteMode(cfg.LLM.CassetteMode),
+		cfg.LLM.CassetteFile,
+		cfg.LLM.Model,
+	)
+	if err != nil {
+		log.Fatalf("Failed to set up LLM cassette: %v", err)
+	}
+
+	if cfg.Experiment.Mode != "" {
+		candidateProvider, err := langchain.ParseProvider(cfg.Experiment.Provider)
+		if err != nil {
+			log.Fatalf("Invalid candidate LLM provider: %v", err)
+		}
+
+		candidateClient, err := langchain.NewClient(langchain.Config{
+			Provider:        candidateProvider,
+			Model:           cfg.Experiment.Model,
+			BaseURL:         cfg.Experiment.BaseURL,
+			APIKey:          cfg.Experiment.APIKey,
+			Organization:    cfg.Experiment.Organization,
+			CredentialsFile: cfg.Experiment.CredentialsFile,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create candidate LLM client: %v", err)
+		}
+
+		llmService, err = langchain.NewExperimentRouter(llmService, candidateClient, langchain.ExperimentConfig{
+			Mode:           langchain.ExperimentMode(cfg.Experiment.Mode),
+			Percent:        cfg.Experiment.Percent,
+			MaxShadows:     cfg.Experiment.MaxShadows,
+			PrimaryModel:   cfg.LLM.Model,
+			CandidateModel: cfg.Experiment.Model,
+		})
+		if err != nil {
+			log.Fatalf("Failed to set up LLM experiment: %v", err)
+		}
+		log.Printf("LLM experiment: %s %d%% of users to %s", cfg.Experiment.Mode, cfg.Experiment.Percent, cfg.Experiment.Model)
+	}
+
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(kratosClient)
-	llmHandler := handlers.NewLLMHandler(llmClient)
+	authHandler := handlers.NewAuthHandler(kratosService)
+	browserHandler := handlers.NewBrowserHandler(kratosService, cfg.Browser.SecureCookies)
+
+	// Schema-driven registration and admin identity creation, off unless
+	// IDENTITY_SCHEMA_ID or _FILE is set
+	var schemas *validation.IdentitySchemaLoader
+	if cfg.IdentitySchema.ID != "" || cfg.IdentitySchema.File != "" {
+		loadSchema := func(ctx context.Context) ([]byte, error) {
+			return kratosClient.GetIdentitySchema(ctx, cfg.IdentitySchema.ID)
+		}
+		if cfg.IdentitySchema.File != "" {
+			loadSchema = func(ctx context.Context) ([]byte, error) {
+				return os.ReadFile(cfg.IdentitySchema.File)
+			}
+		}
+
+		schemas = validation.NewIdentitySchemaLoader(loadSchema, cfg.IdentitySchema.Refresh)
+		authHandler.WithIdentitySchema(schemas)
+		browserHandler.WithIdentitySchema(schemas)
+	}
+
+	// Failed login throttling unless LOGIN_MAX_FAILURES is 0
+	if cfg.Login.MaxFailures > 0 {
+		loginThrottle := auth.NewLoginThrottle(auth.LoginThrottleConfig{
+			MaxFailures:        cfg.Login.MaxFailures,
+			IPMaxFailures:      cfg.Login.IPMaxFailures,
+			BackoffBase:        cfg.Login.BackoffBase,
+			BackoffMax:         cfg.Login.BackoffMax,
+			LockoutDuration:    cfg.Login.LockoutDuration,
+			FailureWindow:      cfg.Login.FailureWindow,
+			BlockAfterLockouts: cfg.Login.BlockAfterLockouts,
+			MaxEntries:         cfg.Login.MaxEntries,
+		}, adminService)
+		authHandler.WithLoginThrottle(loginThrottle)
+		browserHandler.WithLoginThrottle(loginThrottle)
+	}
+
+	// Second factor on privileged routes, off unless MFA_REQUIRE_AAL2 is set
+	requireAAL2 := func(next http.Handler) http.Handler { return next }
+	if cfg.MFA.RequireAAL2 {
+		requireAAL2 = middleware.RequireAAL2
+	}
+
+	// CAPTCHA or proof-of-work challenges on registration, recovery and
+	// verification, off unless CHALLENGE_PROVIDER is set
+	requireChallenge := func(next http.Handler) http.Handler { return next }
+	var challengeHandler *handlers.ChallengeHandler
+	if cfg.Challenge.Provider != "" {
+		var verifier challenge.Verifier
+		if cfg.Challenge.Provider == challenge.TypeProofOfWork {
+			key := []byte(cfg.Challenge.Secret)
+			if len(key) == 0 {
+				key = make([]byte, 32)
+				if _, err := rand.Read(key); err != nil {
+					log.Fatalf("Failed to generate challenge key: %v", err)
+				}
+				log.Println("CHALLENGE_SECRET not set, signing proof-of-work puzzles with a generated key")
+			}
+			verifier = challenge.NewProofOfWork(key, cfg.Challenge.PoWDifficulty, cfg.Challenge.PoWTTL)
+		} else {
+			verifier = challenge.NewSiteVerifier(cfg.Challenge.Provider, cfg.Challenge.SiteKey, cfg.Challenge.Secret, cfg.Challenge.VerifyURL)
+		}
+
+		guard := challenge.NewGuard(challenge.GuardConfig{
+			Threshold:       cfg.Challenge.Threshold,
+			GlobalThreshold: cfg.Challenge.GlobalThreshold,
+			Window:          cfg.Challenge.Window,
+			MaxEntries:      cfg.Challenge.MaxEntries,
+		})
+		requireChallenge = middleware.RequireChallenge(guard, verifier)
+		challengeHandler = handlers.NewChallengeHandler(guard, verifier)
+	}
+
+	llmHandler := handlers.NewLLMHandler(llmService)
+	adminHandler := handlers.NewAdminHandler(adminService)
+	if schemas != nil {
+		adminHandler.WithIdentitySchema(cfg.IdentitySchema.ID, schemas)
+	}
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyStore)
+	permissionHandler := handlers.NewPermissionHandler(permissionChecker)
+
+	// Account export and deletion cover every store of per-user data
+	accountHandler := handlers.NewAccountHandler(kratosService, adminService, cfg.Account.ReauthWindow).
+		WithUserData("api_keys", apiKeyStore)
+	if sessionCache != nil {
+		accountHandler.WithUserData("session_cache", sessionCache)
+	}
+
+	var tokenHandler *handlers.TokenHandler
+	if cfg.JWT.Enabled {
+		var tokenizer auth.SessionTokenizer = auth.NewJWTSigner(jwtKeys, cfg.JWT.Issuer, cfg.JWT.Audience, cfg.JWT.TTL)
+		if cfg.JWT.KratosTemplate != "" {
+			tokenizer = auth.NewKratosTokenizer(kratosClient, cfg.JWT.KratosTemplate)
+		}
+		tokenHandler = handlers.NewTokenHandler(tokenizer, jwtKeys)
+	}
+
+	// Role checks from AUTHZ_* settings, applied to every protected group
+	authorizer := middleware.NewAuthorizer(cfg.Authz.RolesPath, cfg.Authz.RouteRoles, cfg.Authz.RolePermissions)
 
 	// Create router
 	r := chi.NewRouter()
 
 	// Global middleware
 	r.Use(chimiddleware.RequestID)
-	r.Use(chimiddleware.RealIP)
+	// Forwarded client IPs only from a proxy that sets them (TRUST_PROXY_HEADERS)
+	if cfg.Server.TrustProxyHeaders {
+		r.Use(chimiddleware.RealIP)
+	}
 	r.Use(chimiddleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   cfg.CORS.AllowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Session-Token"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Session-Token", "X-API-Key", "X-CSRF-Token", "X-Challenge-Response"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
+	// Cookie sessions must echo the CSRF cookie on state-changing requests.
+	// Without browser flows the session cookie is ignored instead.
+	if cfg.Browser.Enabled {
+		r.Use(middleware.CSRFProtection(cfg.Browser.SecureCookies))
+	} else {
+		r.Use(middleware.IgnoreSessionCookie)
+	}
+
 	// Health check endpoint
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		response.Success(w, response.HealthResponse{
@@ -66,6 +283,11 @@ func main() {
 		})
 	})
 
+	// Public keys for verifying session JWTs
+	if tokenHandler != nil {
+		r.Get("/.well-known/jwks.json", tokenHandler.JWKS)
+	}
+
 	// API v1 routesl
 	r.Route("/api/v1", func(r chi.Router) {
 		// Users routes
@@ -74,42 +296,157 @@ func main() {
 			r.Route("/auth", func(r chi.Router) {
 				r.Get("/login", authHandler.CreateLoginFlow)
 				r.Post("/login/flow", authHandler.SubmitLogin)
+				r.Get("/login/aal2", authHandler.CreateAAL2LoginFlow)
+				r.Post("/login/aal2/flow", authHandler.SubmitTOTPLogin)
+				r.Post("/login/aal2/lookup", authHandler.SubmitLookupSecretLogin)
+				r.With(requireChallenge).Post("/login/code", authHandler.SendLoginCode)
+				r.Post("/login/code/verify", authHandler.SubmitLoginCode)
+				r.Get("/login/passkey", authHandler.CreatePasskeyLoginFlow)
+				r.Post("/login/passkey", authHandler.SubmitPasskeyLogin)
 				r.Get("/registration", authHandler.CreateRegistrationFlow)
-				r.Post("/registration/flow", authHandler.SubmitRegistration)
+				r.With(requireChallenge).Post("/registration/flow", authHandler.SubmitRegistration)
+				r.With(requireChallenge).Post("/registration/code", authHandler.SendRegistrationCode)
+				r.Post("/registration/code/verify", authHandler.SubmitRegistrationCode)
+				r.Post("/registration/passkey", authHandler.StartPasskeyRegistration)
+				r.Post("/registration/passkey/verify", authHandler.SubmitPasskeyRegistration)
+				r.Get("/oidc", authHandler.CreateOIDCLoginFlow)
+				r.Post("/oidc/flow", authHandler.StartOIDCLogin)
+				r.Post("/oidc/exchange", authHandler.ExchangeOIDCSession)
+				if challengeHandler != nil {
+					r.Get("/challenge", challengeHandler.GetChallenge)
+				}
 			})
 
+			// Browser (cookie) flows, off unless BROWSER_FLOWS_ENABLED is set
+			if cfg.Browser.Enabled {
+				r.Route("/browser", func(r chi.Router) {
+					r.Get("/login", browserHandler.CreateLoginFlow)
+					r.Post("/login/flow", browserHandler.SubmitLogin)
+					r.Get("/registration", browserHandler.CreateRegistrationFlow)
+					r.With(requireChallenge).Post("/registration/flow", browserHandler.SubmitRegistration)
+					r.With(middleware.AuthMiddleware(kratosService)).Post("/logout", browserHandler.Logout)
+				})
+			}
+
 			// Public recovery routes
 			r.Route("/recovery", func(r chi.Router) {
 				r.Get("/", authHandler.CreateRecoveryFlow)
-				r.Post("/flow", authHandler.RequestRecoveryCode)
+				r.With(requireChallenge).Post("/flow", authHandler.RequestRecoveryCode)
 				r.Post("/code", authHandler.SubmitRecoveryCode)
 			})
 
 			// Protected verification routes
 			r.Route("/verification", func(r chi.Router) {
-				r.Use(middleware.AuthMiddleware(kratosClient))
+				r.Use(middleware.AuthMiddleware(kratosService))
+				r.Use(authorizer.RequireRouteRoles)
 				r.Get("/", authHandler.CreateVerificationFlow)
-				r.Post("/flow", authHandler.RequestVerificationEmail)
+				r.With(requireChallenge).Post("/flow", authHandler.RequestVerificationEmail)
 				r.Post("/code", authHandler.SubmitVerificationCode)
 			})
+
+			// Protected settings routes
+			r.Route("/settings", func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(kratosService))
+				r.Use(authorizer.RequireRouteRoles)
+				r.Get("/", authHandler.CreateSettingsFlow)
+				r.With(requireAAL2).Post("/password", authHandler.UpdatePassword)
+				r.Post("/profile", authHandler.UpdateProfile)
+				r.With(requireAAL2).Post("/email", authHandler.UpdateEmail)
+				r.Get("/reauth", authHandler.CreateReauthFlow)
+				r.Post("/reauth/flow", authHandler.SubmitReauth)
+				r.Get("/totp", authHandler.CreateTOTPSetup)
+				r.Post("/totp", authHandler.EnrollTOTP)
+				r.Post("/totp/unlink", authHandler.UnlinkTOTP)
+				r.Get("/lookup", authHandler.CreateLookupSecretsFlow)
+				r.Post("/lookup/regenerate", authHandler.RegenerateLookupSecrets)
+				r.Post("/lookup/reveal", authHandler.RevealLookupSecrets)
+				r.Post("/lookup/confirm", authHandler.ConfirmLookupSecrets)
+				r.Get("/passkey", authHandler.CreatePasskeySettings)
+				r.Post("/passkey", authHandler.LinkPasskey)
+				r.Post("/passkey/remove", authHandler.RemovePasskey)
+			})
+
+			// Protected session management routes
+			r.Route("/sessions", func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(kratosService))
+				r.Use(authorizer.RequireRouteRoles)
+				r.Get("/", authHandler.ListSessions)
+				r.Delete("/", authHandler.RevokeOtherSessions)
+				r.Delete("/{id}", authHandler.RevokeSession)
+			})
+
+			// Protected account export and deletion routes
+			r.Route("/account", func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(kratosService))
+				r.Use(authorizer.RequireRouteRoles)
+				r.Get("/export", accountHandler.ExportData)
+				r.With(requireAAL2).Delete("/", accountHandler.DeleteAccount)
+			})
+
+			// Protected API key management routes (session tokens only)
+			r.Route("/api-keys", func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(kratosService))
+				r.Use(requireAAL2)
+				r.Use(authorizer.RequireRouteRoles)
+				r.Get("/", apiKeyHandler.ListAPIKeys)
+				r.Post("/", apiKeyHandler.CreateAPIKey)
+				r.Delete("/{id}", apiKeyHandler.RevokeAPIKey)
+			})
+
+			// Protected session JWT exchange (session tokens only)
+			if tokenHandler != nil {
+				r.Route("/token", func(r chi.Router) {
+					r.Use(middleware.AuthMiddleware(kratosService))
+					r.Use(authorizer.RequireRouteRoles)
+					r.Post("/", tokenHandler.CreateToken)
+				})
+			}
 		})
 
 		// App routes
 		r.Route("/app", func(r chi.Router) {
-			// Protected LLM routes
+			// Protected LLM routes, also open to scoped API keys and session JWTs
 			r.Route("/llm", func(r chi.Router) {
-				r.Use(middleware.AuthMiddleware(kratosClient))
-				r.Post("/chat", llmHandler.Chat)
-				r.Post("/generate", llmHandler.Generate)
+				r.Use(middleware.AuthMiddlewareWithAPIKeys(jwtValidator, apiKeyStore))
+				r.Use(authorizer.RequireRouteRoles)
+				r.With(middleware.RequireScope(auth.ScopeLLMChat)).Post("/chat", llmHandler.Chat)
+				r.With(middleware.RequireScope(auth.ScopeLLMGenerate)).Post("/generate", llmHandler.Generate)
+			})
+
+			// Protected relationship permission routes, also open to session JWTs
+			r.Route("/permissions", func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(jwtValidator))
+				r.Use(authorizer.RequireRouteRoles)
+				r.Get("/check", permissionHandler.Check)
 			})
 
 			// Protected misc routes (session management, etc)
 			r.Route("/misc", func(r chi.Router) {
-				r.Use(middleware.AuthMiddleware(kratosClient))
+				r.Use(middleware.AuthMiddleware(kratosService))
+				r.Use(authorizer.RequireRouteRoles)
 				r.Get("/whoami", authHandler.WhoAmI)
 				r.Get("/logout", authHandler.Logout)
 			})
 		})
+
+		// Identity admin routes, off unless ADMIN_API_ENABLED is set
+		if cfg.Admin.Enabled {
+			r.Route("/admin/identities", func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(kratosService))
+				// Always admin-only, whatever AUTHZ_ROUTE_ROLES says
+				r.Use(authorizer.RequireRole("admin"))
+				r.Use(requireAAL2)
+				r.Use(authorizer.RequireRouteRoles)
+				r.Get("/", adminHandler.ListIdentities)
+				r.Post("/", adminHandler.CreateIdentity)
+				r.Get("/{id}", adminHandler.GetIdentity)
+				r.Patch("/{id}", adminHandler.UpdateIdentity)
+				r.Delete("/{id}", adminHandler.DeleteIdentity)
+				r.Post("/{id}/block", adminHandler.BlockIdentity)
+				r.Post("/{id}/unblock", adminHandler.UnblockIdentity)
+				r.Delete("/{id}/sessions", adminHandler.RevokeIdentitySessions)
+			})
+		}
 	})
 
 	// Start server
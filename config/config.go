@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,14 +15,31 @@ import (
 type Config struct {
 	Server  ServerConfig
 	Kratos  KratosConfig
+	Keto    KetoConfig
+	Session SessionConfig
+	JWT     JWTConfig
+	Browser BrowserConfig
+	Account AccountConfig
+	Login   LoginConfig
+	MFA     MFAConfig
 	LLM     LLMConfig
 	CORS    CORSConfig
+	Admin   AdminConfig
+	Authz   AuthzConfig
+
+	Experiment     ExperimentConfig
+	IdentitySchema IdentitySchemaConfig
+	Challenge      ChallengeConfig
 }
 
 // ServerConfig holds server-specific configuration
 type ServerConfig struct {
 	Port        int
 	Environment string
+	// TrustProxyHeaders takes the client IP from X-Forwarded-For or
+	// X-Real-IP. Enable it only behind a proxy that overwrites them, or
+	// clients can pick the IP that login throttling and challenges key on.
+	TrustProxyHeaders bool
 }
 
 // KratosConfig holds Kratos-specific configuration
@@ -30,12 +48,170 @@ type KratosConfig struct {
 	AdminURL  string
 }
 
+// KetoConfig holds Ory Keto configuration
+type KetoConfig struct {
+	// ReadURL is the Keto read API. When empty, permissions are checked
+	// against an in-memory store loaded from TuplesFile.
+	ReadURL    string
+	TuplesFile string
+}
+
+// SessionConfig holds session validation cache configuration
+type SessionConfig struct {
+	// CacheTTL caps how long a validated session is cached; 0 disables the cache
+	CacheTTL time.Duration
+	// NegativeCacheTTL is how long rejected tokens are cached
+	NegativeCacheTTL time.Duration
+	// CacheMaxEntries bounds the number of cached tokens
+	CacheMaxEntries int
+}
+
+// JWTConfig holds session tokenization configuration
+type JWTConfig struct {
+	// Enabled mounts the token and JWKS endpoints and accepts JWTs on the
+	// LLM and permission routes
+	Enabled  bool
+	Issuer   string
+	Audience string
+	TTL      time.Duration
+	// KeysFile is a JWKS with private RSA keys. When empty, a key is
+	// generated at startup.
+	KeysFile string
+	// KratosTemplate has Kratos sign tokens with this tokenize_as template
+	// instead of signing them locally. The template's jwks_url must hold the
+	// keys in KeysFile, and Issuer defaults to the Kratos public URL.
+	KratosTemplate string
+}
+
+// BrowserConfig holds browser (cookie) flow configuration
+type BrowserConfig struct {
+	// Enabled mounts the browser login, registration and logout routes
+	Enabled bool
+	// SecureCookies marks the CSRF cookie Secure; disable only for local HTTP
+	SecureCookies bool
+}
+
+// AccountConfig holds account self-service configuration
+type AccountConfig struct {
+	// ReauthWindow is how recently the caller must have authenticated to
+	// delete their account; 0 disables self-deletion
+	ReauthWindow time.Duration
+}
+
+// MFAConfig holds second factor enforcement configuration
+type MFAConfig struct {
+	// RequireAAL2 demands an aal2 session for password and email changes,
+	// API key management, account deletion and the admin API
+	RequireAAL2 bool
+}
+
+// LoginConfig holds failed login throttling configuration
+type LoginConfig struct {
+	// MaxFailures locks an identifier out after that many consecutive failed
+	// logins; 0 disables throttling
+	MaxFailures int
+	// IPMaxFailures locks a client IP out after that many failed logins
+	IPMaxFailures int
+	// BackoffBase is the wait after a first failure, doubling per failure up
+	// to BackoffMax
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// LockoutDuration is how long a lockout lasts
+	LockoutDuration time.Duration
+	// FailureWindow is how long failures are remembered
+	FailureWindow time.Duration
+	// BlockAfterLockouts sets the identity inactive after that many lockouts;
+	// 0 never does and is the default, since anyone who knows an identifier
+	// can trigger the lockouts and so deactivate the account
+	BlockAfterLockouts int
+	// MaxEntries bounds the identifiers and IPs tracked
+	MaxEntries int
+}
+
+// ChallengeConfig holds the CAPTCHA or proof-of-work challenge configuration
+// for registration, recovery and verification
+type ChallengeConfig struct {
+	// Provider is "pow", "hcaptcha" or "turnstile"; empty disables challenges
+	Provider string
+	// SiteKey is the CAPTCHA widget's public key
+	SiteKey string
+	// Secret is the CAPTCHA secret, or the proof-of-work signing key. An
+	// empty proof-of-work key is generated at startup, so puzzles do not
+	// survive restarts or work across replicas.
+	Secret string
+	// VerifyURL overrides the CAPTCHA provider's siteverify endpoint
+	VerifyURL string
+	// PoWDifficulty is the leading zero bits a proof-of-work solution needs
+	PoWDifficulty int
+	// PoWTTL is how long a proof-of-work puzzle may be solved for
+	PoWTTL time.Duration
+	// Threshold is how many guarded requests a client IP may make within
+	// Window before it must solve a challenge; 0 always requires one
+	Threshold int
+	// GlobalThreshold requires challenges from every client once all of them
+	// together made that many guarded requests within Window; 0 disables it
+	GlobalThreshold int
+	// Window is how long requests are counted for
+	Window time.Duration
+	// MaxEntries bounds the client IPs tracked
+	MaxEntries int
+}
+
+// IdentitySchemaConfig holds schema-driven registration configuration
+type IdentitySchemaConfig struct {
+	// ID is the Kratos identity schema registration and admin-created traits
+	// are validated against, and the schema admin-created identities use.
+	// When ID and File are empty, the built-in fields and Kratos' "default"
+	// schema are used.
+	ID string
+	// File reads the identity schema from a local file instead of Kratos
+	File string
+	// Refresh is how often the schema is reloaded; 0 loads it once
+	Refresh time.Duration
+}
+
 // LLMConfig holds LLM-specific configuration
 type LLMConfig struct {
-	Provider string
-	Model    string
-	BaseURL  string
-	APIKey   string
+	Provider        string
+	Model           string
+	BaseURL         string
+	APIKey          string
+	Organization    string
+	CredentialsFile string
+	FixtureFile     string
+	CassetteMode    string
+	CassetteFile    string
+}
+
+// ExperimentConfig holds A/B routing and shadow traffic configuration
+// for comparing a candidate model against LLM_MODEL
+type ExperimentConfig struct {
+	Mode            string
+	Percent         int
+	MaxShadows      int
+	Provider        string
+	Model           string
+	BaseURL         string
+	APIKey          string
+	Organization    string
+	CredentialsFile string
+}
+
+// AdminConfig holds admin API configuration
+type AdminConfig struct {
+	// Enabled mounts the identity admin routes under /api/v1/admin
+	Enabled bool
+}
+
+// AuthzConfig holds role-based authorization configuration
+type AuthzConfig struct {
+	// RolesPath is a dotted path to the roles in the identity, starting at
+	// metadata_public
+	RolesPath string
+	// RouteRoles maps path prefixes to the roles allowed under them
+	RouteRoles map[string][]string
+	// RolePermissions maps roles to the permissions they grant
+	RolePermissions map[string][]string
 }
 
 // CORSConfig holds CORS-specific configuration
@@ -55,24 +231,274 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid PORT value: %w", err)
 	}
 
+	trustProxyHeaders, err := strconv.ParseBool(getEnv("TRUST_PROXY_HEADERS", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUST_PROXY_HEADERS value: %w", err)
+	}
+
+	llmProvider := getEnv("LLM_PROVIDER", "ollama")
+	llmBaseURL := getEnv("LLM_BASE_URL", defaultLLMBaseURL(llmProvider))
+
+	experimentPercent, err := strconv.Atoi(getEnv("LLM_EXPERIMENT_PERCENT", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LLM_EXPERIMENT_PERCENT value: %w", err)
+	}
+
+	experimentMaxShadows, err := strconv.Atoi(getEnv("LLM_EXPERIMENT_MAX_SHADOWS", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LLM_EXPERIMENT_MAX_SHADOWS value: %w", err)
+	}
+
+	adminEnabled, err := strconv.ParseBool(getEnv("ADMIN_API_ENABLED", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ADMIN_API_ENABLED value: %w", err)
+	}
+
+	routeRoles, err := parseRoleMap(getEnv("AUTHZ_ROUTE_ROLES", "/api/v1/admin=admin"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUTHZ_ROUTE_ROLES value: %w", err)
+	}
+
+	rolePermissions, err := parseRoleMap(getEnv("AUTHZ_ROLE_PERMISSIONS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUTHZ_ROLE_PERMISSIONS value: %w", err)
+	}
+
+	sessionCacheTTL, err := time.ParseDuration(getEnv("SESSION_CACHE_TTL", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SESSION_CACHE_TTL value: %w", err)
+	}
+
+	sessionNegativeCacheTTL, err := time.ParseDuration(getEnv("SESSION_CACHE_NEGATIVE_TTL", "5s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SESSION_CACHE_NEGATIVE_TTL value: %w", err)
+	}
+
+	sessionCacheMaxEntries, err := strconv.Atoi(getEnv("SESSION_CACHE_MAX_ENTRIES", "10000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SESSION_CACHE_MAX_ENTRIES value: %w", err)
+	}
+
+	jwtEnabled, err := strconv.ParseBool(getEnv("JWT_ENABLED", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_ENABLED value: %w", err)
+	}
+
+	jwtTTL, err := time.ParseDuration(getEnv("JWT_TTL", "15m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT_TTL value: %w", err)
+	}
+
+	browserEnabled, err := strconv.ParseBool(getEnv("BROWSER_FLOWS_ENABLED", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BROWSER_FLOWS_ENABLED value: %w", err)
+	}
+
+	browserSecureCookies, err := strconv.ParseBool(getEnv("BROWSER_SECURE_COOKIES", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BROWSER_SECURE_COOKIES value: %w", err)
+	}
+
+	accountReauthWindow, err := time.ParseDuration(getEnv("ACCOUNT_REAUTH_WINDOW", "15m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ACCOUNT_REAUTH_WINDOW value: %w", err)
+	}
+
+	mfaRequireAAL2, err := strconv.ParseBool(getEnv("MFA_REQUIRE_AAL2", "false"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid MFA_REQUIRE_AAL2 value: %w", err)
+	}
+
+	loginMaxFailures, err := strconv.Atoi(getEnv("LOGIN_MAX_FAILURES", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_MAX_FAILURES value: %w", err)
+	}
+
+	loginIPMaxFailures, err := strconv.Atoi(getEnv("LOGIN_IP_MAX_FAILURES", "50"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_IP_MAX_FAILURES value: %w", err)
+	}
+
+	loginBackoffBase, err := time.ParseDuration(getEnv("LOGIN_BACKOFF_BASE", "1s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_BACKOFF_BASE value: %w", err)
+	}
+
+	loginBackoffMax, err := time.ParseDuration(getEnv("LOGIN_BACKOFF_MAX", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_BACKOFF_MAX value: %w", err)
+	}
+
+	loginLockoutDuration, err := time.ParseDuration(getEnv("LOGIN_LOCKOUT_DURATION", "15m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_LOCKOUT_DURATION value: %w", err)
+	}
+
+	loginFailureWindow, err := time.ParseDuration(getEnv("LOGIN_FAILURE_WINDOW", "1h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_FAILURE_WINDOW value: %w", err)
+	}
+
+	loginBlockAfterLockouts, err := strconv.Atoi(getEnv("LOGIN_BLOCK_AFTER_LOCKOUTS", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_BLOCK_AFTER_LOCKOUTS value: %w", err)
+	}
+
+	loginMaxEntries, err := strconv.Atoi(getEnv("LOGIN_THROTTLE_MAX_ENTRIES", "100000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LOGIN_THROTTLE_MAX_ENTRIES value: %w", err)
+	}
+
+	identitySchemaRefresh, err := time.ParseDuration(getEnv("IDENTITY_SCHEMA_REFRESH", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid IDENTITY_SCHEMA_REFRESH value: %w", err)
+	}
+
+	challengePoWDifficulty, err := strconv.Atoi(getEnv("CHALLENGE_POW_DIFFICULTY", "20"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CHALLENGE_POW_DIFFICULTY value: %w", err)
+	}
+
+	challengePoWTTL, err := time.ParseDuration(getEnv("CHALLENGE_POW_TTL", "5m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CHALLENGE_POW_TTL value: %w", err)
+	}
+
+	challengeThreshold, err := strconv.Atoi(getEnv("CHALLENGE_THRESHOLD", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CHALLENGE_THRESHOLD value: %w", err)
+	}
+
+	challengeGlobalThreshold, err := strconv.Atoi(getEnv("CHALLENGE_GLOBAL_THRESHOLD", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CHALLENGE_GLOBAL_THRESHOLD value: %w", err)
+	}
+
+	challengeWindow, err := time.ParseDuration(getEnv("CHALLENGE_WINDOW", "10m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CHALLENGE_WINDOW value: %w", err)
+	}
+
+	challengeMaxEntries, err := strconv.Atoi(getEnv("CHALLENGE_MAX_ENTRIES", "100000"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid CHALLENGE_MAX_ENTRIES value: %w", err)
+	}
+
+	// A candidate of the primary's provider inherits its connection settings
+	candidateProvider := getEnv("LLM_CANDIDATE_PROVIDER", llmProvider)
+	candidateBaseURL := defaultLLMBaseURL(candidateProvider)
+	var candidateOrganization, candidateCredentialsFile string
+	if candidateProvider == llmProvider {
+		candidateBaseURL = llmBaseURL
+		candidateOrganization = getEnv("LLM_ORGANIZATION", "")
+		candidateCredentialsFile = getEnv("LLM_CREDENTIALS_FILE", "")
+	}
+
+	// Kratos sets iss to its public URL on the tokens it signs
+	kratosPublicURL := getEnv("KRATOS_PUBLIC_URL", "http://localhost:4433")
+	jwtKratosTemplate := getEnv("JWT_KRATOS_TEMPLATE", "")
+	jwtIssuer := getEnv("JWT_ISSUER", "kratos-chi-ollama")
+	if jwtKratosTemplate != "" {
+		jwtIssuer = getEnv("JWT_ISSUER", kratosPublicURL)
+	}
+
 	cfg := &Config{
 		Server: ServerConfig{
-			Port:        port,
-			Environment: getEnv("ENVIRONMENT", "development"),
+			Port:              port,
+			Environment:       getEnv("ENVIRONMENT", "development"),
+			TrustProxyHeaders: trustProxyHeaders,
 		},
 		Kratos: KratosConfig{
-			PublicURL: getEnv("KRATOS_PUBLIC_URL", "http://localhost:4433"),
+			PublicURL: kratosPublicURL,
 			AdminURL:  getEnv("KRATOS_ADMIN_URL", "http://localhost:4434"),
 		},
+		Keto: KetoConfig{
+			ReadURL:    getEnv("KETO_READ_URL", ""),
+			TuplesFile: getEnv("KETO_TUPLES_FILE", ""),
+		},
+		Session: SessionConfig{
+			CacheTTL:         sessionCacheTTL,
+			NegativeCacheTTL: sessionNegativeCacheTTL,
+			CacheMaxEntries:  sessionCacheMaxEntries,
+		},
+		JWT: JWTConfig{
+			Enabled:        jwtEnabled,
+			Issuer:         jwtIssuer,
+			Audience:       getEnv("JWT_AUDIENCE", ""),
+			TTL:            jwtTTL,
+			KeysFile:       getEnv("JWT_KEYS_FILE", ""),
+			KratosTemplate: jwtKratosTemplate,
+		},
+		Browser: BrowserConfig{
+			Enabled:       browserEnabled,
+			SecureCookies: browserSecureCookies,
+		},
+		Account: AccountConfig{
+			ReauthWindow: accountReauthWindow,
+		},
+		MFA: MFAConfig{
+			RequireAAL2: mfaRequireAAL2,
+		},
+		Login: LoginConfig{
+			MaxFailures:        loginMaxFailures,
+			IPMaxFailures:      loginIPMaxFailures,
+			BackoffBase:        loginBackoffBase,
+			BackoffMax:         loginBackoffMax,
+			LockoutDuration:    loginLockoutDuration,
+			FailureWindow:      loginFailureWindow,
+			BlockAfterLockouts: loginBlockAfterLockouts,
+			MaxEntries:         loginMaxEntries,
+		},
 		LLM: LLMConfig{
-			Provider: getEnv("LLM_PROVIDER", "ollama"),
-			Model:    getEnv("LLM_MODEL", "llama2"),
-			BaseURL:  getEnv("LLM_BASE_URL", "http://localhost:11434"),
-			APIKey:   getEnv("LLM_API_KEY", ""),
+			Provider:        llmProvider,
+			Model:           getEnv("LLM_MODEL", "llama2"),
+			BaseURL:         llmBaseURL,
+			APIKey:          getEnv("LLM_API_KEY", ""),
+			Organization:    getEnv("LLM_ORGANIZATION", ""),
+			CredentialsFile: getEnv("LLM_CREDENTIALS_FILE", ""),
+			FixtureFile:     getEnv("LLM_FAKE_FIXTURE", ""),
+			CassetteMode:    getEnv("LLM_CASSETTE_MODE", ""),
+			CassetteFile:    getEnv("LLM_CASSETTE_FILE", ""),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: parseOrigins(getEnv("ALLOWED_ORIGINS", "http://localhost:3000")),
 		},
+		Admin: AdminConfig{
+			Enabled: adminEnabled,
+		},
+		Authz: AuthzConfig{
+			RolesPath:       getEnv("AUTHZ_ROLES_PATH", "metadata_public.roles"),
+			RouteRoles:      routeRoles,
+			RolePermissions: rolePermissions,
+		},
+		Experiment: ExperimentConfig{
+			Mode:            getEnv("LLM_EXPERIMENT_MODE", ""),
+			Percent:         experimentPercent,
+			MaxShadows:      experimentMaxShadows,
+			Provider:        candidateProvider,
+			Model:           getEnv("LLM_CANDIDATE_MODEL", ""),
+			BaseURL:         getEnv("LLM_CANDIDATE_BASE_URL", candidateBaseURL),
+			APIKey:          getEnv("LLM_CANDIDATE_API_KEY", ""),
+			Organization:    getEnv("LLM_CANDIDATE_ORGANIZATION", candidateOrganization),
+			CredentialsFile: getEnv("LLM_CANDIDATE_CREDENTIALS_FILE", candidateCredentialsFile),
+		},
+		IdentitySchema: IdentitySchemaConfig{
+			ID:      getEnv("IDENTITY_SCHEMA_ID", ""),
+			File:    getEnv("IDENTITY_SCHEMA_FILE", ""),
+			Refresh: identitySchemaRefresh,
+		},
+		Challenge: ChallengeConfig{
+			Provider:        getEnv("CHALLENGE_PROVIDER", ""),
+			SiteKey:         getEnv("CHALLENGE_SITE_KEY", ""),
+			Secret:          getEnv("CHALLENGE_SECRET", ""),
+			VerifyURL:       getEnv("CHALLENGE_VERIFY_URL", ""),
+			PoWDifficulty:   challengePoWDifficulty,
+			PoWTTL:          challengePoWTTL,
+			Threshold:       challengeThreshold,
+			GlobalThreshold: challengeGlobalThreshold,
+			Window:          challengeWindow,
+			MaxEntries:      challengeMaxEntries,
+		},
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -96,6 +522,125 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("LLM_MODEL is required")
 	}
 
+	// Identities write their own traits through registration and settings, so
+	// roles read from them could be self-granted
+	if c.Authz.RolesPath != "" && !strings.HasPrefix(c.Authz.RolesPath, "metadata_public.") {
+		return fmt.Errorf("invalid AUTHZ_ROLES_PATH: %q (must start with metadata_public.)", c.Authz.RolesPath)
+	}
+
+	if c.Session.CacheTTL < 0 || c.Session.NegativeCacheTTL < 0 {
+		return fmt.Errorf("SESSION_CACHE_TTL and SESSION_CACHE_NEGATIVE_TTL must not be negative")
+	}
+
+	if c.Session.CacheTTL > 0 && c.Session.CacheMaxEntries < 1 {
+		return fmt.Errorf("SESSION_CACHE_MAX_ENTRIES must be at least 1 when the session cache is enabled, got %d", c.Session.CacheMaxEntries)
+	}
+
+	if c.JWT.Enabled {
+		if c.JWT.TTL <= 0 {
+			return fmt.Errorf("JWT_TTL must be positive, got %s", c.JWT.TTL)
+		}
+		if c.IsProduction() && c.JWT.KeysFile == "" {
+			return fmt.Errorf("JWT_KEYS_FILE is required in production when JWT_ENABLED is set")
+		}
+		// Kratos signs with the template's jwks_url; tokens only verify here
+		// when JWT_KEYS_FILE holds the same keys
+		if c.JWT.KratosTemplate != "" && c.JWT.KeysFile == "" {
+			return fmt.Errorf("JWT_KEYS_FILE is required when JWT_KRATOS_TEMPLATE is set")
+		}
+	}
+
+	if c.Browser.Enabled && c.IsProduction() && !c.Browser.SecureCookies {
+		return fmt.Errorf("BROWSER_SECURE_COOKIES must be true in production")
+	}
+
+	if c.Account.ReauthWindow < 0 {
+		return fmt.Errorf("ACCOUNT_REAUTH_WINDOW must not be negative, got %s", c.Account.ReauthWindow)
+	}
+
+	if c.Login.MaxFailures < 0 || c.Login.IPMaxFailures < 0 || c.Login.BlockAfterLockouts < 0 {
+		return fmt.Errorf("LOGIN_MAX_FAILURES, LOGIN_IP_MAX_FAILURES and LOGIN_BLOCK_AFTER_LOCKOUTS must not be negative")
+	}
+
+	if c.Login.BackoffBase < 0 || c.Login.BackoffMax < 0 || c.Login.LockoutDuration < 0 || c.Login.FailureWindow < 0 {
+		return fmt.Errorf("LOGIN_BACKOFF_BASE, LOGIN_BACKOFF_MAX, LOGIN_LOCKOUT_DURATION and LOGIN_FAILURE_WINDOW must not be negative")
+	}
+
+	if c.Login.MaxFailures > 0 && c.Login.MaxEntries < 1 {
+		return fmt.Errorf("LOGIN_THROTTLE_MAX_ENTRIES must be at least 1 when login throttling is enabled, got %d", c.Login.MaxEntries)
+	}
+
+	if c.IdentitySchema.ID != "" && c.IdentitySchema.File != "" {
+		return fmt.Errorf("IDENTITY_SCHEMA_ID and IDENTITY_SCHEMA_FILE are mutually exclusive")
+	}
+
+	if c.IdentitySchema.Refresh < 0 {
+		return fmt.Errorf("IDENTITY_SCHEMA_REFRESH must not be negative, got %s", c.IdentitySchema.Refresh)
+	}
+
+	switch c.LLM.CassetteMode {
+	case "":
+	case "record", "replay":
+		if c.LLM.CassetteFile == "" {
+			return fmt.Errorf("LLM_CASSETTE_FILE is required when LLM_CASSETTE_MODE is %q", c.LLM.CassetteMode)
+		}
+		// Recorded prompts are not tied to an identity, so account export
+		// and deletion cannot cover them; recording is for development only
+		if c.LLM.CassetteMode == "record" && c.IsProduction() {
+			return fmt.Errorf("LLM_CASSETTE_MODE=record is not allowed in production")
+		}
+		if c.LLM.CassetteMode == "record" && c.Account.ReauthWindow > 0 {
+			return fmt.Errorf("LLM_CASSETTE_MODE=record requires ACCOUNT_REAUTH_WINDOW=0: deleted accounts' prompts would stay in the cassette")
+		}
+	default:
+		return fmt.Errorf("invalid LLM_CASSETTE_MODE: %q (expected record or replay)", c.LLM.CassetteMode)
+	}
+
+	switch c.Experiment.Mode {
+	case "":
+	case "split", "shadow":
+		if c.Experiment.Model == "" {
+			return fmt.Errorf("LLM_CANDIDATE_MODEL is required when LLM_EXPERIMENT_MODE is %q", c.Experiment.Mode)
+		}
+		if c.Experiment.Percent < 0 || c.Experiment.Percent > 100 {
+			return fmt.Errorf("LLM_EXPERIMENT_PERCENT must be between 0 and 100, got %d", c.Experiment.Percent)
+		}
+		if c.Experiment.MaxShadows < 1 {
+			return fmt.Errorf("LLM_EXPERIMENT_MAX_SHADOWS must be at least 1, got %d", c.Experiment.MaxShadows)
+		}
+	default:
+		return fmt.Errorf("invalid LLM_EXPERIMENT_MODE: %q (expected split or shadow)", c.Experiment.Mode)
+	}
+
+	switch c.Challenge.Provider {
+	case "":
+	case "pow":
+		if c.Challenge.PoWDifficulty < 1 || c.Challenge.PoWDifficulty > 32 {
+			return fmt.Errorf("CHALLENGE_POW_DIFFICULTY must be between 1 and 32, got %d", c.Challenge.PoWDifficulty)
+		}
+		if c.Challenge.PoWTTL <= 0 {
+			return fmt.Errorf("CHALLENGE_POW_TTL must be positive, got %s", c.Challenge.PoWTTL)
+		}
+	case "hcaptcha", "turnstile":
+		if c.Challenge.SiteKey == "" || c.Challenge.Secret == "" {
+			return fmt.Errorf("CHALLENGE_SITE_KEY and CHALLENGE_SECRET are required when CHALLENGE_PROVIDER is %q", c.Challenge.Provider)
+		}
+	default:
+		return fmt.Errorf("invalid CHALLENGE_PROVIDER: %q (expected pow, hcaptcha or turnstile)", c.Challenge.Provider)
+	}
+
+	if c.Challenge.Provider != "" {
+		if c.Challenge.Threshold < 0 || c.Challenge.GlobalThreshold < 0 {
+			return fmt.Errorf("CHALLENGE_THRESHOLD and CHALLENGE_GLOBAL_THRESHOLD must not be negative")
+		}
+		if c.Challenge.Window <= 0 {
+			return fmt.Errorf("CHALLENGE_WINDOW must be positive, got %s", c.Challenge.Window)
+		}
+		if c.Challenge.MaxEntries < 1 {
+			return fmt.Errorf("CHALLENGE_MAX_ENTRIES must be at least 1 when challenges are enabled, got %d", c.Challenge.MaxEntries)
+		}
+	}
+
 	return nil
 }
 
@@ -116,6 +661,15 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// defaultLLMBaseURL returns the default server URL for a provider.
+// Hosted providers use their client library's default endpoint.
+func defaultLLMBaseURL(provider string) string {
+	if provider == "ollama" {
+		return "http://localhost:11434"
+	}
+	return ""
+}
+
 func parseOrigins(origins string) []string {
 	parts := strings.Split(origins, ",")
 	result := make([]string, 0, len(parts))
@@ -127,3 +681,27 @@ func parseOrigins(origins string) []string {
 	}
 	return result
 }
+
+// parseRoleMap parses "key=a,b;key2=c" into a map of keys to values
+func parseRoleMap(value string) (map[string][]string, error) {
+	result := make(map[string][]string)
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, values, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", entry)
+		}
+
+		list := parseOrigins(values)
+		if len(list) == 0 {
+			return nil, fmt.Errorf("no values for %q", key)
+		}
+		result[key] = list
+	}
+	return result, nil
+}
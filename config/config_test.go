@@ -3,20 +3,75 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
 	// Save current env and restore after test
 	originalEnv := map[string]string{
-		"PORT":              os.Getenv("PORT"),
-		"ENVIRONMENT":       os.Getenv("ENVIRONMENT"),
-		"KRATOS_PUBLIC_URL": os.Getenv("KRATOS_PUBLIC_URL"),
-		"KRATOS_ADMIN_URL":  os.Getenv("KRATOS_ADMIN_URL"),
-		"LLM_PROVIDER":      os.Getenv("LLM_PROVIDER"),
-		"LLM_MODEL":         os.Getenv("LLM_MODEL"),
-		"LLM_BASE_URL":      os.Getenv("LLM_BASE_URL"),
-		"LLM_API_KEY":       os.Getenv("LLM_API_KEY"),
-		"ALLOWED_ORIGINS":   os.Getenv("ALLOWED_ORIGINS"),
+		"PORT":                           os.Getenv("PORT"),
+		"ENVIRONMENT":                    os.Getenv("ENVIRONMENT"),
+		"TRUST_PROXY_HEADERS":            os.Getenv("TRUST_PROXY_HEADERS"),
+		"KRATOS_PUBLIC_URL":              os.Getenv("KRATOS_PUBLIC_URL"),
+		"KRATOS_ADMIN_URL":               os.Getenv("KRATOS_ADMIN_URL"),
+		"LLM_PROVIDER":                   os.Getenv("LLM_PROVIDER"),
+		"LLM_MODEL":                      os.Getenv("LLM_MODEL"),
+		"LLM_BASE_URL":                   os.Getenv("LLM_BASE_URL"),
+		"LLM_API_KEY":                    os.Getenv("LLM_API_KEY"),
+		"LLM_ORGANIZATION":               os.Getenv("LLM_ORGANIZATION"),
+		"LLM_CREDENTIALS_FILE":           os.Getenv("LLM_CREDENTIALS_FILE"),
+		"LLM_CASSETTE_MODE":              os.Getenv("LLM_CASSETTE_MODE"),
+		"LLM_CASSETTE_FILE":              os.Getenv("LLM_CASSETTE_FILE"),
+		"LLM_EXPERIMENT_MODE":            os.Getenv("LLM_EXPERIMENT_MODE"),
+		"LLM_EXPERIMENT_PERCENT":         os.Getenv("LLM_EXPERIMENT_PERCENT"),
+		"LLM_CANDIDATE_PROVIDER":         os.Getenv("LLM_CANDIDATE_PROVIDER"),
+		"LLM_CANDIDATE_MODEL":            os.Getenv("LLM_CANDIDATE_MODEL"),
+		"LLM_CANDIDATE_BASE_URL":         os.Getenv("LLM_CANDIDATE_BASE_URL"),
+		"LLM_CANDIDATE_API_KEY":          os.Getenv("LLM_CANDIDATE_API_KEY"),
+		"LLM_CANDIDATE_ORGANIZATION":     os.Getenv("LLM_CANDIDATE_ORGANIZATION"),
+		"LLM_CANDIDATE_CREDENTIALS_FILE": os.Getenv("LLM_CANDIDATE_CREDENTIALS_FILE"),
+		"LLM_EXPERIMENT_MAX_SHADOWS":     os.Getenv("LLM_EXPERIMENT_MAX_SHADOWS"),
+		"ALLOWED_ORIGINS":                os.Getenv("ALLOWED_ORIGINS"),
+		"ADMIN_API_ENABLED":              os.Getenv("ADMIN_API_ENABLED"),
+		"AUTHZ_ROLES_PATH":               os.Getenv("AUTHZ_ROLES_PATH"),
+		"AUTHZ_ROUTE_ROLES":              os.Getenv("AUTHZ_ROUTE_ROLES"),
+		"AUTHZ_ROLE_PERMISSIONS":         os.Getenv("AUTHZ_ROLE_PERMISSIONS"),
+		"KETO_READ_URL":                  os.Getenv("KETO_READ_URL"),
+		"KETO_TUPLES_FILE":               os.Getenv("KETO_TUPLES_FILE"),
+		"SESSION_CACHE_TTL":              os.Getenv("SESSION_CACHE_TTL"),
+		"MFA_REQUIRE_AAL2":               os.Getenv("MFA_REQUIRE_AAL2"),
+		"SESSION_CACHE_NEGATIVE_TTL":     os.Getenv("SESSION_CACHE_NEGATIVE_TTL"),
+		"SESSION_CACHE_MAX_ENTRIES":      os.Getenv("SESSION_CACHE_MAX_ENTRIES"),
+		"JWT_ENABLED":                    os.Getenv("JWT_ENABLED"),
+		"JWT_ISSUER":                     os.Getenv("JWT_ISSUER"),
+		"JWT_AUDIENCE":                   os.Getenv("JWT_AUDIENCE"),
+		"JWT_TTL":                        os.Getenv("JWT_TTL"),
+		"JWT_KEYS_FILE":                  os.Getenv("JWT_KEYS_FILE"),
+		"JWT_KRATOS_TEMPLATE":            os.Getenv("JWT_KRATOS_TEMPLATE"),
+		"BROWSER_FLOWS_ENABLED":          os.Getenv("BROWSER_FLOWS_ENABLED"),
+		"BROWSER_SECURE_COOKIES":         os.Getenv("BROWSER_SECURE_COOKIES"),
+		"ACCOUNT_REAUTH_WINDOW":          os.Getenv("ACCOUNT_REAUTH_WINDOW"),
+		"LOGIN_MAX_FAILURES":             os.Getenv("LOGIN_MAX_FAILURES"),
+		"LOGIN_IP_MAX_FAILURES":          os.Getenv("LOGIN_IP_MAX_FAILURES"),
+		"LOGIN_BACKOFF_BASE":             os.Getenv("LOGIN_BACKOFF_BASE"),
+		"LOGIN_BACKOFF_MAX":              os.Getenv("LOGIN_BACKOFF_MAX"),
+		"LOGIN_LOCKOUT_DURATION":         os.Getenv("LOGIN_LOCKOUT_DURATION"),
+		"LOGIN_FAILURE_WINDOW":           os.Getenv("LOGIN_FAILURE_WINDOW"),
+		"LOGIN_BLOCK_AFTER_LOCKOUTS":     os.Getenv("LOGIN_BLOCK_AFTER_LOCKOUTS"),
+		"LOGIN_THROTTLE_MAX_ENTRIES":     os.Getenv("LOGIN_THROTTLE_MAX_ENTRIES"),
+		"IDENTITY_SCHEMA_ID":             os.Getenv("IDENTITY_SCHEMA_ID"),
+		"IDENTITY_SCHEMA_FILE":           os.Getenv("IDENTITY_SCHEMA_FILE"),
+		"IDENTITY_SCHEMA_REFRESH":        os.Getenv("IDENTITY_SCHEMA_REFRESH"),
+		"CHALLENGE_PROVIDER":             os.Getenv("CHALLENGE_PROVIDER"),
+		"CHALLENGE_SITE_KEY":             os.Getenv("CHALLENGE_SITE_KEY"),
+		"CHALLENGE_SECRET":               os.Getenv("CHALLENGE_SECRET"),
+		"CHALLENGE_VERIFY_URL":           os.Getenv("CHALLENGE_VERIFY_URL"),
+		"CHALLENGE_POW_DIFFICULTY":       os.Getenv("CHALLENGE_POW_DIFFICULTY"),
+		"CHALLENGE_POW_TTL":              os.Getenv("CHALLENGE_POW_TTL"),
+		"CHALLENGE_THRESHOLD":            os.Getenv("CHALLENGE_THRESHOLD"),
+		"CHALLENGE_GLOBAL_THRESHOLD":     os.Getenv("CHALLENGE_GLOBAL_THRESHOLD"),
+		"CHALLENGE_WINDOW":               os.Getenv("CHALLENGE_WINDOW"),
+		"CHALLENGE_MAX_ENTRIES":          os.Getenv("CHALLENGE_MAX_ENTRIES"),
 	}
 
 	defer func() {
@@ -44,6 +99,9 @@ func TestLoad(t *testing.T) {
 			check: func(c *Config) bool {
 				return c.Server.Port == 8080 &&
 					c.Server.Environment == "development" &&
+					!c.Server.TrustProxyHeaders &&
+					!c.MFA.RequireAAL2 &&
+					c.Login.BlockAfterLockouts == 0 &&
 					c.LLM.Model == "llama2"
 			},
 		},
@@ -58,6 +116,25 @@ func TestLoad(t *testing.T) {
 				return c.Server.Port == 3000
 			},
 		},
+		{
+			name: "trust proxy headers",
+			envVars: map[string]string{
+				"TRUST_PROXY_HEADERS": "true",
+				"LLM_MODEL":           "llama2",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Server.TrustProxyHeaders
+			},
+		},
+		{
+			name: "invalid trust proxy headers",
+			envVars: map[string]string{
+				"TRUST_PROXY_HEADERS": "maybe",
+				"LLM_MODEL":           "llama2",
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid port",
 			envVars: map[string]string{
@@ -117,6 +194,515 @@ func TestLoad(t *testing.T) {
 					c.LLM.APIKey == "sk-test123"
 			},
 		},
+		{
+			name: "ollama default base URL",
+			envVars: map[string]string{
+				"LLM_MODEL": "llama2",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.LLM.Provider == "ollama" &&
+					c.LLM.BaseURL == "http://localhost:11434"
+			},
+		},
+		{
+			name: "hosted provider has no default base URL",
+			envVars: map[string]string{
+				"LLM_PROVIDER":     "anthropic",
+				"LLM_MODEL":        "claude-3-5-sonnet-latest",
+				"LLM_API_KEY":      "sk-ant-test",
+				"LLM_ORGANIZATION": "org-ignored",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.LLM.Provider == "anthropic" &&
+					c.LLM.BaseURL == "" &&
+					c.LLM.Organization == "org-ignored"
+			},
+		},
+		{
+			name: "split experiment inherits primary connection",
+			envVars: map[string]string{
+				"LLM_MODEL":              "llama2",
+				"LLM_BASE_URL":           "http://ollama:11434",
+				"LLM_EXPERIMENT_MODE":    "split",
+				"LLM_EXPERIMENT_PERCENT": "10",
+				"LLM_CANDIDATE_MODEL":    "llama3",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Experiment.Mode == "split" &&
+					c.Experiment.Percent == 10 &&
+					c.Experiment.MaxShadows == 10 &&
+					c.Experiment.Provider == "ollama" &&
+					c.Experiment.BaseURL == "http://ollama:11434"
+			},
+		},
+		{
+			name: "candidate inherits primary organization and credentials",
+			envVars: map[string]string{
+				"LLM_PROVIDER":         "google",
+				"LLM_MODEL":            "gemini-1.5-pro",
+				"LLM_ORGANIZATION":     "org-123",
+				"LLM_CREDENTIALS_FILE": "/etc/gcp.json",
+				"LLM_EXPERIMENT_MODE":  "shadow",
+				"LLM_CANDIDATE_MODEL":  "gemini-1.5-flash",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Experiment.Organization == "org-123" &&
+					c.Experiment.CredentialsFile == "/etc/gcp.json"
+			},
+		},
+		{
+			name: "candidate organization and credentials for another provider",
+			envVars: map[string]string{
+				"LLM_MODEL":                  "llama2",
+				"LLM_ORGANIZATION":           "org-123",
+				"LLM_EXPERIMENT_MODE":        "split",
+				"LLM_CANDIDATE_PROVIDER":     "openai",
+				"LLM_CANDIDATE_MODEL":        "gpt-4o",
+				"LLM_CANDIDATE_ORGANIZATION": "org-456",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Experiment.Organization == "org-456" &&
+					c.Experiment.CredentialsFile == ""
+			},
+		},
+		{
+			name: "invalid experiment max shadows",
+			envVars: map[string]string{
+				"LLM_MODEL":                  "llama2",
+				"LLM_EXPERIMENT_MODE":        "shadow",
+				"LLM_CANDIDATE_MODEL":        "llama3",
+				"LLM_EXPERIMENT_MAX_SHADOWS": "0",
+			},
+			wantErr: true,
+		},
+		{
+			name: "experiment without candidate model",
+			envVars: map[string]string{
+				"LLM_MODEL":           "llama2",
+				"LLM_EXPERIMENT_MODE": "shadow",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid experiment percent",
+			envVars: map[string]string{
+				"LLM_MODEL":              "llama2",
+				"LLM_EXPERIMENT_PERCENT": "half",
+			},
+			wantErr: true,
+		},
+		{
+			name: "admin API enabled",
+			envVars: map[string]string{
+				"LLM_MODEL":         "llama2",
+				"ADMIN_API_ENABLED": "true",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Admin.Enabled
+			},
+		},
+		{
+			name: "invalid admin API flag",
+			envVars: map[string]string{
+				"LLM_MODEL":         "llama2",
+				"ADMIN_API_ENABLED": "maybe",
+			},
+			wantErr: true,
+		},
+		{
+			name: "admin routes require admin role by default",
+			envVars: map[string]string{
+				"LLM_MODEL": "llama2",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Authz.RolesPath == "metadata_public.roles" &&
+					len(c.Authz.RouteRoles["/api/v1/admin"]) == 1 &&
+					c.Authz.RouteRoles["/api/v1/admin"][0] == "admin"
+			},
+		},
+		{
+			name: "custom role configuration",
+			envVars: map[string]string{
+				"LLM_MODEL":              "llama2",
+				"AUTHZ_ROLES_PATH":       "metadata_public.access.roles",
+				"AUTHZ_ROUTE_ROLES":      "/api/v1/admin=admin; /api/v1/app/llm=user, admin",
+				"AUTHZ_ROLE_PERMISSIONS": "admin=identities:write,llm:chat;user=llm:chat",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Authz.RolesPath == "metadata_public.access.roles" &&
+					len(c.Authz.RouteRoles) == 2 &&
+					len(c.Authz.RouteRoles["/api/v1/app/llm"]) == 2 &&
+					len(c.Authz.RolePermissions["admin"]) == 2
+			},
+		},
+		{
+			name: "keto settings",
+			envVars: map[string]string{
+				"LLM_MODEL":        "llama2",
+				"KETO_READ_URL":    "http://keto:4466",
+				"KETO_TUPLES_FILE": "tuples.txt",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Keto.ReadURL == "http://keto:4466" && c.Keto.TuplesFile == "tuples.txt"
+			},
+		},
+		{
+			name: "session cache defaults",
+			envVars: map[string]string{
+				"LLM_MODEL": "llama2",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Session.CacheTTL == 30*time.Second &&
+					c.Session.NegativeCacheTTL == 5*time.Second &&
+					c.Session.CacheMaxEntries == 10000
+			},
+		},
+		{
+			name: "session cache disabled",
+			envVars: map[string]string{
+				"LLM_MODEL":                 "llama2",
+				"SESSION_CACHE_TTL":         "0",
+				"SESSION_CACHE_MAX_ENTRIES": "0",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Session.CacheTTL == 0
+			},
+		},
+		{
+			name: "aal2 required",
+			envVars: map[string]string{
+				"LLM_MODEL":        "llama2",
+				"MFA_REQUIRE_AAL2": "true",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.MFA.RequireAAL2
+			},
+		},
+		{
+			name: "invalid aal2 requirement",
+			envVars: map[string]string{
+				"LLM_MODEL":        "llama2",
+				"MFA_REQUIRE_AAL2": "sometimes",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid session cache TTL",
+			envVars: map[string]string{
+				"LLM_MODEL":         "llama2",
+				"SESSION_CACHE_TTL": "thirty",
+			},
+			wantErr: true,
+		},
+		{
+			name: "session cache without entries",
+			envVars: map[string]string{
+				"LLM_MODEL":                 "llama2",
+				"SESSION_CACHE_MAX_ENTRIES": "0",
+			},
+			wantErr: true,
+		},
+		{
+			name: "jwt defaults",
+			envVars: map[string]string{
+				"LLM_MODEL": "llama2",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return !c.JWT.Enabled &&
+					c.JWT.Issuer == "kratos-chi-ollama" &&
+					c.JWT.TTL == 15*time.Minute
+			},
+		},
+		{
+			name: "jwt custom values",
+			envVars: map[string]string{
+				"LLM_MODEL":           "llama2",
+				"JWT_ENABLED":         "true",
+				"JWT_AUDIENCE":        "reports",
+				"JWT_TTL":             "5m",
+				"JWT_KEYS_FILE":       "jwks.json",
+				"JWT_KRATOS_TEMPLATE": "downstream",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.JWT.Enabled &&
+					c.JWT.Audience == "reports" &&
+					c.JWT.TTL == 5*time.Minute &&
+					c.JWT.KeysFile == "jwks.json" &&
+					c.JWT.KratosTemplate == "downstream" &&
+					c.JWT.Issuer == "http://localhost:4433"
+			},
+		},
+		{
+			name: "jwt kratos template with explicit issuer",
+			envVars: map[string]string{
+				"LLM_MODEL":           "llama2",
+				"JWT_ENABLED":         "true",
+				"JWT_ISSUER":          "https://auth.example.com/",
+				"JWT_KEYS_FILE":       "jwks.json",
+				"JWT_KRATOS_TEMPLATE": "downstream",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.JWT.Issuer == "https://auth.example.com/"
+			},
+		},
+		{
+			name: "jwt kratos template without keys",
+			envVars: map[string]string{
+				"LLM_MODEL":           "llama2",
+				"JWT_ENABLED":         "true",
+				"JWT_KRATOS_TEMPLATE": "downstream",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid JWT TTL",
+			envVars: map[string]string{
+				"LLM_MODEL": "llama2",
+				"JWT_TTL":   "soon",
+			},
+			wantErr: true,
+		},
+		{
+			name: "jwt in production without keys",
+			envVars: map[string]string{
+				"LLM_MODEL":   "llama2",
+				"ENVIRONMENT": "production",
+				"JWT_ENABLED": "true",
+			},
+			wantErr: true,
+		},
+		{
+			name: "browser flows defaults",
+			envVars: map[string]string{
+				"LLM_MODEL": "llama2",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return !c.Browser.Enabled && c.Browser.SecureCookies
+			},
+		},
+		{
+			name: "browser flows over plain HTTP",
+			envVars: map[string]string{
+				"LLM_MODEL":              "llama2",
+				"BROWSER_FLOWS_ENABLED":  "true",
+				"BROWSER_SECURE_COOKIES": "false",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Browser.Enabled && !c.Browser.SecureCookies
+			},
+		},
+		{
+			name: "insecure browser cookies in production",
+			envVars: map[string]string{
+				"LLM_MODEL":              "llama2",
+				"ENVIRONMENT":            "production",
+				"BROWSER_FLOWS_ENABLED":  "true",
+				"BROWSER_SECURE_COOKIES": "false",
+			},
+			wantErr: true,
+		},
+		{
+			name: "account reauth window",
+			envVars: map[string]string{
+				"LLM_MODEL":             "llama2",
+				"ACCOUNT_REAUTH_WINDOW": "5m",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Account.ReauthWindow == 5*time.Minute
+			},
+		},
+		{
+			name: "invalid account reauth window",
+			envVars: map[string]string{
+				"LLM_MODEL":             "llama2",
+				"ACCOUNT_REAUTH_WINDOW": "recently",
+			},
+			wantErr: true,
+		},
+		{
+			name: "login throttle defaults",
+			envVars: map[string]string{
+				"LLM_MODEL": "llama2",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Login.MaxFailures == 5 &&
+					c.Login.IPMaxFailures == 50 &&
+					c.Login.BackoffBase == time.Second &&
+					c.Login.BackoffMax == 30*time.Second &&
+					c.Login.LockoutDuration == 15*time.Minute &&
+					c.Login.FailureWindow == time.Hour &&
+					c.Login.BlockAfterLockouts == 0 &&
+					c.Login.MaxEntries == 100000
+			},
+		},
+		{
+			name: "login block after lockouts",
+			envVars: map[string]string{
+				"LLM_MODEL":                  "llama2",
+				"LOGIN_BLOCK_AFTER_LOCKOUTS": "3",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Login.BlockAfterLockouts == 3
+			},
+		},
+		{
+			name: "invalid login max failures",
+			envVars: map[string]string{
+				"LLM_MODEL":          "llama2",
+				"LOGIN_MAX_FAILURES": "-1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid login lockout duration",
+			envVars: map[string]string{
+				"LLM_MODEL":              "llama2",
+				"LOGIN_LOCKOUT_DURATION": "forever",
+			},
+			wantErr: true,
+		},
+		{
+			name: "challenge defaults",
+			envVars: map[string]string{
+				"LLM_MODEL": "llama2",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Challenge.Provider == "" &&
+					c.Challenge.PoWDifficulty == 20 &&
+					c.Challenge.PoWTTL == 5*time.Minute &&
+					c.Challenge.Threshold == 5 &&
+					c.Challenge.GlobalThreshold == 0 &&
+					c.Challenge.Window == 10*time.Minute &&
+					c.Challenge.MaxEntries == 100000
+			},
+		},
+		{
+			name: "turnstile challenge",
+			envVars: map[string]string{
+				"LLM_MODEL":          "llama2",
+				"CHALLENGE_PROVIDER": "turnstile",
+				"CHALLENGE_SITE_KEY": "site-key",
+				"CHALLENGE_SECRET":   "secret",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.Challenge.Provider == "turnstile" &&
+					c.Challenge.SiteKey == "site-key" &&
+					c.Challenge.Secret == "secret"
+			},
+		},
+		{
+			name: "captcha challenge without secret",
+			envVars: map[string]string{
+				"LLM_MODEL":          "llama2",
+				"CHALLENGE_PROVIDER": "hcaptcha",
+				"CHALLENGE_SITE_KEY": "site-key",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown challenge provider",
+			envVars: map[string]string{
+				"LLM_MODEL":          "llama2",
+				"CHALLENGE_PROVIDER": "recaptcha",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid proof of work difficulty",
+			envVars: map[string]string{
+				"LLM_MODEL":                "llama2",
+				"CHALLENGE_PROVIDER":       "pow",
+				"CHALLENGE_POW_DIFFICULTY": "64",
+			},
+			wantErr: true,
+		},
+		{
+			name: "identity schema defaults",
+			envVars: map[string]string{
+				"LLM_MODEL": "llama2",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.IdentitySchema.ID == "" &&
+					c.IdentitySchema.File == "" &&
+					c.IdentitySchema.Refresh == 5*time.Minute
+			},
+		},
+		{
+			name: "identity schema from kratos",
+			envVars: map[string]string{
+				"LLM_MODEL":               "llama2",
+				"IDENTITY_SCHEMA_ID":      "default",
+				"IDENTITY_SCHEMA_REFRESH": "0s",
+			},
+			wantErr: false,
+			check: func(c *Config) bool {
+				return c.IdentitySchema.ID == "default" && c.IdentitySchema.Refresh == 0
+			},
+		},
+		{
+			name: "identity schema ID and file",
+			envVars: map[string]string{
+				"LLM_MODEL":            "llama2",
+				"IDENTITY_SCHEMA_ID":   "default",
+				"IDENTITY_SCHEMA_FILE": "identity.schema.json",
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid identity schema refresh",
+			envVars: map[string]string{
+				"LLM_MODEL":               "llama2",
+				"IDENTITY_SCHEMA_REFRESH": "often",
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed route roles",
+			envVars: map[string]string{
+				"LLM_MODEL":         "llama2",
+				"AUTHZ_ROUTE_ROLES": "/api/v1/admin",
+			},
+			wantErr: true,
+		},
+		{
+			name: "roles path outside identity",
+			envVars: map[string]string{
+				"LLM_MODEL":        "llama2",
+				"AUTHZ_ROLES_PATH": "session.roles",
+			},
+			wantErr: true,
+		},
+		{
+			name: "roles path in self-editable traits",
+			envVars: map[string]string{
+				"LLM_MODEL":        "llama2",
+				"AUTHZ_ROLES_PATH": "traits.roles",
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -203,6 +789,91 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "cassette replay with file",
+			config: &Config{
+				Server: ServerConfig{Port: 8080, Environment: "development"},
+				Kratos: KratosConfig{PublicURL: "http://localhost:4433"},
+				LLM:    LLMConfig{Model: "llama2", CassetteMode: "replay", CassetteFile: "testdata/chat.json"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "cassette record with account deletion off",
+			config: &Config{
+				Server: ServerConfig{Port: 8080, Environment: "development"},
+				Kratos: KratosConfig{PublicURL: "http://localhost:4433"},
+				LLM:    LLMConfig{Model: "llama2", CassetteMode: "record", CassetteFile: "testdata/chat.json"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "cassette record with account deletion",
+			config: &Config{
+				Server:  ServerConfig{Port: 8080, Environment: "development"},
+				Kratos:  KratosConfig{PublicURL: "http://localhost:4433"},
+				LLM:     LLMConfig{Model: "llama2", CassetteMode: "record", CassetteFile: "testdata/chat.json"},
+				Account: AccountConfig{ReauthWindow: 15 * time.Minute},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cassette record in production",
+			config: &Config{
+				Server: ServerConfig{Port: 8080, Environment: "production"},
+				Kratos: KratosConfig{PublicURL: "http://localhost:4433"},
+				LLM:    LLMConfig{Model: "llama2", CassetteMode: "record", CassetteFile: "testdata/chat.json"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cassette record without file",
+			config: &Config{
+				Server: ServerConfig{Port: 8080, Environment: "development"},
+				Kratos: KratosConfig{PublicURL: "http://localhost:4433"},
+				LLM:    LLMConfig{Model: "llama2", CassetteMode: "record"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "jwt enabled without TTL",
+			config: &Config{
+				Server: ServerConfig{Port: 8080, Environment: "development"},
+				Kratos: KratosConfig{PublicURL: "http://localhost:4433"},
+				JWT:    JWTConfig{Enabled: true},
+				LLM:    LLMConfig{Model: "llama2"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative identity schema refresh",
+			config: &Config{
+				Server:         ServerConfig{Port: 8080, Environment: "development"},
+				Kratos:         KratosConfig{PublicURL: "http://localhost:4433"},
+				LLM:            LLMConfig{Model: "llama2"},
+				IdentitySchema: IdentitySchemaConfig{ID: "default", Refresh: -time.Minute},
+			},
+			wantErr: true,
+		},
+		{
+			name: "proof of work challenge without window",
+			config: &Config{
+				Server:    ServerConfig{Port: 8080, Environment: "development"},
+				Kratos:    KratosConfig{PublicURL: "http://localhost:4433"},
+				LLM:       LLMConfig{Model: "llama2"},
+				Challenge: ChallengeConfig{Provider: "pow", PoWDifficulty: 20, PoWTTL: time.Minute, MaxEntries: 10},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid cassette mode",
+			config: &Config{
+				Server: ServerConfig{Port: 8080, Environment: "development"},
+				Kratos: KratosConfig{PublicURL: "http://localhost:4433"},
+				LLM:    LLMConfig{Model: "llama2", CassetteMode: "rewind", CassetteFile: "testdata/chat.json"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
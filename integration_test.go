@@ -15,6 +15,8 @@ import (
 
 	"github.com/davegermiquet/kratos-chi-ollama/config"
 	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/authz"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/challenge"
 	"github.com/davegermiquet/kratos-chi-ollama/internal/handlers"
 	"github.com/davegermiquet/kratos-chi-ollama/internal/langchain"
 	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
@@ -45,11 +47,19 @@ func TestUserAuthenticationFlow(t *testing.T) {
 	// Initialize dependencies
 	kratosClient := auth.NewKratosClient(cfg.Kratos.PublicURL, cfg.Kratos.AdminURL)
 
+	llmProvider, err := langchain.ParseProvider(cfg.LLM.Provider)
+	if err != nil {
+		t.Fatalf("Invalid LLM provider: %v", err)
+	}
+
 	llmClient, err := langchain.NewClient(langchain.Config{
-		Provider: langchain.Provider(cfg.LLM.Provider),
-		Model:    cfg.LLM.Model,
-		BaseURL:  cfg.LLM.BaseURL,
-		APIKey:   cfg.LLM.APIKey,
+		Provider:        llmProvider,
+		Model:           cfg.LLM.Model,
+		BaseURL:         cfg.LLM.BaseURL,
+		APIKey:          cfg.LLM.APIKey,
+		Organization:    cfg.LLM.Organization,
+		CredentialsFile: cfg.LLM.CredentialsFile,
+		FixtureFile:     cfg.LLM.FixtureFile,
 	})
 	if err != nil {
 		t.Fatalf("Failed to create LLM client: %v", err)
@@ -316,22 +326,77 @@ func TestUserAuthenticationFlow(t *testing.T) {
 
 // setupRouter creates and configures the Chi router for testing
 func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, llmHandler *handlers.LLMHandler, kratosClient *auth.KratosClient) *chi.Mux {
+	adminHandler := handlers.NewAdminHandler(kratosClient)
+	browserHandler := handlers.NewBrowserHandler(kratosClient, cfg.Browser.SecureCookies)
+	apiKeyStore := auth.NewAPIKeyStore(kratosClient)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyStore)
+	accountHandler := handlers.NewAccountHandler(kratosClient, kratosClient, cfg.Account.ReauthWindow).
+		WithUserData("api_keys", apiKeyStore)
+	permissionHandler := handlers.NewPermissionHandler(authz.NewMemoryStore())
+	authorizer := middleware.NewAuthorizer(cfg.Authz.RolesPath, cfg.Authz.RouteRoles, cfg.Authz.RolePermissions)
+
+	var tokenHandler *handlers.TokenHandler
+	var jwtValidator auth.SessionValidator = kratosClient
+	if cfg.JWT.Enabled {
+		jwtKeys, err := auth.GenerateJWTKeySet()
+		if err != nil {
+			panic(err)
+		}
+		verifier := auth.NewJWTVerifier(jwtKeys, cfg.JWT.Issuer, cfg.JWT.Audience)
+		jwtValidator = auth.NewJWTSessionValidator(verifier, kratosClient)
+		tokenHandler = handlers.NewTokenHandler(auth.NewJWTSigner(jwtKeys, cfg.JWT.Issuer, cfg.JWT.Audience, cfg.JWT.TTL), jwtKeys)
+	}
+
+	// Second factor on privileged routes, off unless MFA_REQUIRE_AAL2 is set
+	requireAAL2 := func(next http.Handler) http.Handler { return next }
+	if cfg.MFA.RequireAAL2 {
+		requireAAL2 = middleware.RequireAAL2
+	}
+
+	requireChallenge := func(next http.Handler) http.Handler { return next }
+	var challengeHandler *handlers.ChallengeHandler
+	if cfg.Challenge.Provider != "" {
+		var verifier challenge.Verifier = challenge.NewProofOfWork([]byte(cfg.Challenge.Secret), cfg.Challenge.PoWDifficulty, cfg.Challenge.PoWTTL)
+		if cfg.Challenge.Provider != challenge.TypeProofOfWork {
+			verifier = challenge.NewSiteVerifier(cfg.Challenge.Provider, cfg.Challenge.SiteKey, cfg.Challenge.Secret, cfg.Challenge.VerifyURL)
+		}
+		guard := challenge.NewGuard(challenge.GuardConfig{
+			Threshold:       cfg.Challenge.Threshold,
+			GlobalThreshold: cfg.Challenge.GlobalThreshold,
+			Window:          cfg.Challenge.Window,
+			MaxEntries:      cfg.Challenge.MaxEntries,
+		})
+		requireChallenge = middleware.RequireChallenge(guard, verifier)
+		challengeHandler = handlers.NewChallengeHandler(guard, verifier)
+	}
+
 	r := chi.NewRouter()
 
 	// Global middleware
 	r.Use(chimiddleware.RequestID)
-	r.Use(chimiddleware.RealIP)
+	// Forwarded client IPs only from a proxy that sets them (TRUST_PROXY_HEADERS)
+	if cfg.Server.TrustProxyHeaders {
+		r.Use(chimiddleware.RealIP)
+	}
 	r.Use(chimiddleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   cfg.CORS.AllowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Session-Token"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Session-Token", "X-API-Key", "X-CSRF-Token", "X-Challenge-Response"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
+	// Cookie sessions must echo the CSRF cookie on state-changing requests.
+	// Without browser flows the session cookie is ignored instead.
+	if cfg.Browser.Enabled {
+		r.Use(middleware.CSRFProtection(cfg.Browser.SecureCookies))
+	} else {
+		r.Use(middleware.IgnoreSessionCookie)
+	}
+
 	// Health check endpoint
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		response.Success(w, response.HealthResponse{
@@ -340,6 +405,11 @@ func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, llmHandl
 		})
 	})
 
+	// Public keys for verifying session JWTs
+	if tokenHandler != nil {
+		r.Get("/.well-known/jwks.json", tokenHandler.JWKS)
+	}
+
 	// API v1 routes
 	r.Route("/api/v1", func(r chi.Router) {
 		// Users routes
@@ -348,35 +418,150 @@ func setupRouter(cfg *config.Config, authHandler *handlers.AuthHandler, llmHandl
 			r.Route("/auth", func(r chi.Router) {
 				r.Get("/login", authHandler.CreateLoginFlow)
 				r.Post("/login/flow", authHandler.SubmitLogin)
+				r.Get("/login/aal2", authHandler.CreateAAL2LoginFlow)
+				r.Post("/login/aal2/flow", authHandler.SubmitTOTPLogin)
+				r.Post("/login/aal2/lookup", authHandler.SubmitLookupSecretLogin)
+				r.With(requireChallenge).Post("/login/code", authHandler.SendLoginCode)
+				r.Post("/login/code/verify", authHandler.SubmitLoginCode)
+				r.Get("/login/passkey", authHandler.CreatePasskeyLoginFlow)
+				r.Post("/login/passkey", authHandler.SubmitPasskeyLogin)
 				r.Get("/registration", authHandler.CreateRegistrationFlow)
-				r.Post("/registration/flow", authHandler.SubmitRegistration)
+				r.With(requireChallenge).Post("/registration/flow", authHandler.SubmitRegistration)
+				r.With(requireChallenge).Post("/registration/code", authHandler.SendRegistrationCode)
+				r.Post("/registration/code/verify", authHandler.SubmitRegistrationCode)
+				r.Post("/registration/passkey", authHandler.StartPasskeyRegistration)
+				r.Post("/registration/passkey/verify", authHandler.SubmitPasskeyRegistration)
+				r.Get("/oidc", authHandler.CreateOIDCLoginFlow)
+				r.Post("/oidc/flow", authHandler.StartOIDCLogin)
+				r.Post("/oidc/exchange", authHandler.ExchangeOIDCSession)
+				if challengeHandler != nil {
+					r.Get("/challenge", challengeHandler.GetChallenge)
+				}
 			})
 
+			// Browser (cookie) flows, off unless BROWSER_FLOWS_ENABLED is set
+			if cfg.Browser.Enabled {
+				r.Route("/browser", func(r chi.Router) {
+					r.Get("/login", browserHandler.CreateLoginFlow)
+					r.Post("/login/flow", browserHandler.SubmitLogin)
+					r.Get("/registration", browserHandler.CreateRegistrationFlow)
+					r.With(requireChallenge).Post("/registration/flow", browserHandler.SubmitRegistration)
+					r.With(middleware.AuthMiddleware(kratosClient)).Post("/logout", browserHandler.Logout)
+				})
+			}
+
 			// Protected verification routes
 			r.Route("/verification", func(r chi.Router) {
 				r.Use(middleware.AuthMiddleware(kratosClient))
+				r.Use(authorizer.RequireRouteRoles)
 				r.Get("/", authHandler.CreateVerificationFlow)
-				r.Post("/flow", authHandler.RequestVerificationEmail)
+				r.With(requireChallenge).Post("/flow", authHandler.RequestVerificationEmail)
 				r.Post("/code", authHandler.SubmitVerificationCode)
 			})
+
+			// Protected settings routes
+			r.Route("/settings", func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(kratosClient))
+				r.Use(authorizer.RequireRouteRoles)
+				r.Get("/", authHandler.CreateSettingsFlow)
+				r.With(requireAAL2).Post("/password", authHandler.UpdatePassword)
+				r.Post("/profile", authHandler.UpdateProfile)
+				r.With(requireAAL2).Post("/email", authHandler.UpdateEmail)
+				r.Get("/reauth", authHandler.CreateReauthFlow)
+				r.Post("/reauth/flow", authHandler.SubmitReauth)
+				r.Get("/totp", authHandler.CreateTOTPSetup)
+				r.Post("/totp", authHandler.EnrollTOTP)
+				r.Post("/totp/unlink", authHandler.UnlinkTOTP)
+				r.Get("/lookup", authHandler.CreateLookupSecretsFlow)
+				r.Post("/lookup/regenerate", authHandler.RegenerateLookupSecrets)
+				r.Post("/lookup/reveal", authHandler.RevealLookupSecrets)
+				r.Post("/lookup/confirm", authHandler.ConfirmLookupSecrets)
+				r.Get("/passkey", authHandler.CreatePasskeySettings)
+				r.Post("/passkey", authHandler.LinkPasskey)
+				r.Post("/passkey/remove", authHandler.RemovePasskey)
+			})
+
+			// Protected session management routes
+			r.Route("/sessions", func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(kratosClient))
+				r.Use(authorizer.RequireRouteRoles)
+				r.Get("/", authHandler.ListSessions)
+				r.Delete("/", authHandler.RevokeOtherSessions)
+				r.Delete("/{id}", authHandler.RevokeSession)
+			})
+
+			// Protected account export and deletion routes
+			r.Route("/account", func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(kratosClient))
+				r.Use(authorizer.RequireRouteRoles)
+				r.Get("/export", accountHandler.ExportData)
+				r.With(requireAAL2).Delete("/", accountHandler.DeleteAccount)
+			})
+
+			// Protected API key management routes (session tokens only)
+			r.Route("/api-keys", func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(kratosClient))
+				r.Use(requireAAL2)
+				r.Use(authorizer.RequireRouteRoles)
+				r.Get("/", apiKeyHandler.ListAPIKeys)
+				r.Post("/", apiKeyHandler.CreateAPIKey)
+				r.Delete("/{id}", apiKeyHandler.RevokeAPIKey)
+			})
+
+			// Protected session JWT exchange (session tokens only)
+			if tokenHandler != nil {
+				r.Route("/token", func(r chi.Router) {
+					r.Use(middleware.AuthMiddleware(kratosClient))
+					r.Use(authorizer.RequireRouteRoles)
+					r.Post("/", tokenHandler.CreateToken)
+				})
+			}
 		})
 
 		// App routes
 		r.Route("/app", func(r chi.Router) {
-			// Protected LLM routes
+			// Protected LLM routes, also open to scoped API keys and session JWTs
 			r.Route("/llm", func(r chi.Router) {
-				r.Use(middleware.AuthMiddleware(kratosClient))
-				r.Post("/chat", llmHandler.Chat)
-				r.Post("/generate", llmHandler.Generate)
+				r.Use(middleware.AuthMiddlewareWithAPIKeys(jwtValidator, apiKeyStore))
+				r.Use(authorizer.RequireRouteRoles)
+				r.With(middleware.RequireScope(auth.ScopeLLMChat)).Post("/chat", llmHandler.Chat)
+				r.With(middleware.RequireScope(auth.ScopeLLMGenerate)).Post("/generate", llmHandler.Generate)
+			})
+
+			// Protected relationship permission routes, also open to session JWTs
+			r.Route("/permissions", func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(jwtValidator))
+				r.Use(authorizer.RequireRouteRoles)
+				r.Get("/check", permissionHandler.Check)
 			})
 
 			// Protected misc routes (session management, etc)
 			r.Route("/misc", func(r chi.Router) {
 				r.Use(middleware.AuthMiddleware(kratosClient))
+				r.Use(authorizer.RequireRouteRoles)
 				r.Get("/whoami", authHandler.WhoAmI)
 				r.Get("/logout", authHandler.Logout)
 			})
 		})
+
+		// Identity admin routes, off unless ADMIN_API_ENABLED is set
+		if cfg.Admin.Enabled {
+			r.Route("/admin/identities", func(r chi.Router) {
+				r.Use(middleware.AuthMiddleware(kratosClient))
+				// Always admin-only, whatever AUTHZ_ROUTE_ROLES says
+				r.Use(authorizer.RequireRole("admin"))
+				r.Use(requireAAL2)
+				r.Use(authorizer.RequireRouteRoles)
+				r.Get("/", adminHandler.ListIdentities)
+				r.Post("/", adminHandler.CreateIdentity)
+				r.Get("/{id}", adminHandler.GetIdentity)
+				r.Patch("/{id}", adminHandler.UpdateIdentity)
+				r.Delete("/{id}", adminHandler.DeleteIdentity)
+				r.Post("/{id}/block", adminHandler.BlockIdentity)
+				r.Post("/{id}/unblock", adminHandler.UnblockIdentity)
+				r.Delete("/{id}/sessions", adminHandler.RevokeIdentitySessions)
+			})
+		}
 	})
 
 	return r
@@ -0,0 +1,446 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	ory "github.com/ory/client-go"
+)
+
+// APIKeyPrefix marks a token as an API key rather than a Kratos session token
+const APIKeyPrefix = "kco_"
+
+// API key scopes
+const (
+	ScopeLLMChat     = "llm:chat"
+	ScopeLLMGenerate = "llm:generate"
+)
+
+// APIKeyScopes lists the scopes an API key can be granted
+var APIKeyScopes = []string{ScopeLLMChat, ScopeLLMGenerate}
+
+// AuthMethodAPIKey is the authentication method of sessions resolved from API keys
+const AuthMethodAPIKey = "api_key"
+
+// apiKeysMetadataKey is the metadata_admin field holding an identity's API keys
+const apiKeysMetadataKey = "api_keys"
+
+// Ensure APIKeyStore implements APIKeyManager, APIKeyValidator and UserDataStore
+var (
+	_ APIKeyManager   = (*APIKeyStore)(nil)
+	_ APIKeyValidator = (*APIKeyStore)(nil)
+	_ UserDataStore   = (*APIKeyStore)(nil)
+)
+
+// APIKey is a stored API key. Only the SHA-256 hash of the key is kept.
+type APIKey struct {
+	ID         string     `json:"id"`
+	IdentityID string     `json:"-"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	Hash       string     `json:"hash,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the key has expired at now
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// APIKeyOptions describes a new API key. A nil ExpiresAt never expires.
+type APIKeyOptions struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+// IsAPIKey reports whether token looks like an API key
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, APIKeyPrefix)
+}
+
+// APIKeyStore keeps hashed API keys in the owning identity's metadata_admin,
+// which users cannot read or change through the frontend API
+type APIKeyStore struct {
+	admin AdminService
+	now   func() time.Time
+	// mu serializes read-modify-write updates of metadata_admin
+	mu sync.Mutex
+
+	// cacheTTL and cacheMaxEntries bound the identity lookups cached for
+	// ValidateAPIKey; a zero TTL disables the cache
+	cacheTTL        time.Duration
+	cacheMaxEntries int
+	cacheMu         sync.Mutex
+	identities      map[string]cachedIdentity
+	// generation counts purges; lookups that started before the latest one
+	// are not cached
+	generation uint64
+}
+
+// cachedIdentity is an identity lookup; a nil identity was not found
+type cachedIdentity struct {
+	identity *ory.Identity
+	expires  time.Time
+}
+
+// NewAPIKeyStore creates an API key store backed by the Kratos admin API
+func NewAPIKeyStore(admin AdminService) *APIKeyStore {
+	return &APIKeyStore{admin: admin, now: time.Now}
+}
+
+// WithIdentityCache caches the identity lookups behind ValidateAPIKey for up
+// to ttl, so each request does not call the admin API. Creating and revoking
+// keys through the store, and admin changes made through WrapAdmin, drop the
+// identity at once.
+func (s *APIKeyStore) WithIdentityCache(ttl time.Duration, maxEntries int) *APIKeyStore {
+	s.cacheTTL = ttl
+	s.cacheMaxEntries = maxEntries
+	s.identities = make(map[string]cachedIdentity)
+	return s
+}
+
+// WrapAdmin returns admin with the identity cache kept in step: updating,
+// deleting or signing out an identity through it drops the cached identity,
+// so a blocked identity's keys stop working at once
+func (s *APIKeyStore) WrapAdmin(admin AdminService) AdminService {
+	return &purgingAdmin{AdminService: admin, purge: s.PurgeIdentity}
+}
+
+// PurgeIdentity drops the identity's cached lookup
+func (s *APIKeyStore) PurgeIdentity(identityID string) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+
+	s.generation++
+	delete(s.identities, identityID)
+}
+
+// CreateAPIKey creates a key for the identity. The plaintext key is returned
+// once and cannot be recovered later.
+func (s *APIKeyStore) CreateAPIKey(ctx context.Context, identityID string, opts APIKeyOptions) (*APIKey, string, error) {
+	keyID, err := randomHex(8)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	plaintext := APIKeyPrefix + identityID + "_" + keyID + "_" + secret
+	key := APIKey{
+		ID:         keyID,
+		IdentityID: identityID,
+		Name:       opts.Name,
+		Scopes:     opts.Scopes,
+		Hash:       hashAPIKey(plaintext),
+		CreatedAt:  s.now().UTC(),
+		ExpiresAt:  opts.ExpiresAt,
+	}
+
+	err = s.update(ctx, identityID, func(keys []APIKey) ([]APIKey, error) {
+		return append(keys, key), nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return &key, plaintext, nil
+}
+
+// ListAPIKeys lists the identity's keys, including expired ones
+func (s *APIKeyStore) ListAPIKeys(ctx context.Context, identityID string) ([]APIKey, error) {
+	identity, err := s.admin.GetIdentity(ctx, identityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	keys, err := apiKeysFromIdentity(identity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey deletes one of the identity's keys
+func (s *APIKeyStore) RevokeAPIKey(ctx context.Context, identityID, keyID string) error {
+	err := s.update(ctx, identityID, func(keys []APIKey) ([]APIKey, error) {
+		for i, key := range keys {
+			if key.ID == keyID {
+				return append(keys[:i], keys[i+1:]...), nil
+			}
+		}
+		return nil, ErrAPIKeyNotFound
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return nil
+}
+
+// ValidateAPIKey resolves a key to the key record and a synthetic session for
+// the identity that owns it. Unknown, expired and malformed keys, and keys of
+// inactive identities, return ErrInvalidAPIKey.
+func (s *APIKeyStore) ValidateAPIKey(ctx context.Context, plaintext string) (*APIKey, *ory.Session, error) {
+	identityID, keyID, ok := parseAPIKey(plaintext)
+	if !ok {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	identity, err := s.lookupIdentity(ctx, identityID)
+	if errors.Is(err, ErrIdentityNotFound) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to validate API key: %w", err)
+	}
+
+	if identity.GetState() != IdentityStateActive {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	keys, err := apiKeysFromIdentity(identity)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to validate API key: %w", err)
+	}
+
+	now := s.now()
+	hash := hashAPIKey(plaintext)
+	for i := range keys {
+		key := &keys[i]
+		if key.ID != keyID {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash)) != 1 || key.Expired(now) {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return key, apiKeySession(key, identity), nil
+	}
+
+	return nil, nil, ErrInvalidAPIKey
+}
+
+// ExportUserData returns the identity's keys without their hashes
+func (s *APIKeyStore) ExportUserData(ctx context.Context, identityID string) (interface{}, error) {
+	keys, err := s.ListAPIKeys(ctx, identityID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range keys {
+		keys[i].Hash = ""
+	}
+	return keys, nil
+}
+
+// PurgeUserData drops the identity's cached lookup. The keys themselves live
+// in its metadata_admin and are deleted with it.
+func (s *APIKeyStore) PurgeUserData(ctx context.Context, identityID string) error {
+	s.PurgeIdentity(identityID)
+	return nil
+}
+
+// lookupIdentity returns the identity from the cache, or from the admin API,
+// caching the result. Identities that do not exist are cached too, so keys
+// naming random identities do not reach the admin API either.
+func (s *APIKeyStore) lookupIdentity(ctx context.Context, identityID string) (*ory.Identity, error) {
+	if s.cacheTTL <= 0 || s.cacheMaxEntries <= 0 {
+		return s.admin.GetIdentity(ctx, identityID)
+	}
+
+	now := s.now()
+	s.cacheMu.Lock()
+	cached, ok := s.identities[identityID]
+	generation := s.generation
+	s.cacheMu.Unlock()
+
+	if ok && now.Before(cached.expires) {
+		if cached.identity == nil {
+			return nil, ErrIdentityNotFound
+		}
+		return cached.identity, nil
+	}
+
+	identity, err := s.admin.GetIdentity(ctx, identityID)
+	if err != nil && !errors.Is(err, ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	s.cacheMu.Lock()
+	if s.generation == generation {
+		if _, ok := s.identities[identityID]; !ok && len(s.identities) >= s.cacheMaxEntries {
+			s.evictIdentities(now)
+		}
+		s.identities[identityID] = cachedIdentity{identity: identity, expires: now.Add(s.cacheTTL)}
+	}
+	s.cacheMu.Unlock()
+
+	return identity, err
+}
+
+// evictIdentities drops expired lookups, then arbitrary ones until there is
+// room for another; the caller must hold cacheMu
+func (s *APIKeyStore) evictIdentities(now time.Time) {
+	for id, cached := range s.identities {
+		if !now.Before(cached.expires) {
+			delete(s.identities, id)
+		}
+	}
+	for id := range s.identities {
+		if len(s.identities) < s.cacheMaxEntries {
+			return
+		}
+		delete(s.identities, id)
+	}
+}
+
+// update applies fn to the identity's keys and writes them back to metadata_admin
+func (s *APIKeyStore) update(ctx context.Context, identityID string, fn func([]APIKey) ([]APIKey, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.PurgeIdentity(identityID)
+
+	identity, err := s.admin.GetIdentity(ctx, identityID)
+	if err != nil {
+		return err
+	}
+
+	keys, err := apiKeysFromIdentity(identity)
+	if err != nil {
+		return err
+	}
+
+	keys, err = fn(keys)
+	if err != nil {
+		return err
+	}
+
+	updated := make(map[string]interface{}, len(identity.MetadataAdmin)+1)
+	for k, v := range identity.MetadataAdmin {
+		updated[k] = v
+	}
+	updated[apiKeysMetadataKey] = keys
+
+	_, err = s.admin.PatchIdentity(ctx, identityID, []ory.JsonPatch{
+		{Op: "add", Path: "/metadata_admin", Value: updated},
+	})
+	return err
+}
+
+// apiKeysFromIdentity decodes the API keys from metadata_admin
+func apiKeysFromIdentity(identity *ory.Identity) ([]APIKey, error) {
+	stored, ok := identity.MetadataAdmin[apiKeysMetadataKey]
+	if !ok || stored == nil {
+		return []APIKey{}, nil
+	}
+
+	raw, err := json.Marshal(stored)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []APIKey
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return nil, fmt.Errorf("invalid %s metadata: %w", apiKeysMetadataKey, err)
+	}
+
+	for i := range keys {
+		keys[i].IdentityID = identity.Id
+	}
+	return keys, nil
+}
+
+// WithoutAPIKeys returns a copy of identity without the API keys in its
+// metadata_admin, for responses that would otherwise carry their hashes
+func WithoutAPIKeys(identity *ory.Identity) *ory.Identity {
+	if identity == nil {
+		return nil
+	}
+	if _, ok := identity.MetadataAdmin[apiKeysMetadataKey]; !ok {
+		return identity
+	}
+
+	redacted := *identity
+	redacted.MetadataAdmin = make(map[string]interface{}, len(identity.MetadataAdmin))
+	for k, v := range identity.MetadataAdmin {
+		if k != apiKeysMetadataKey {
+			redacted.MetadataAdmin[k] = v
+		}
+	}
+	return &redacted
+}
+
+// apiKeySession builds the synthetic session an API key authenticates as.
+// The identity is copied without metadata_admin, which holds the key hashes
+// and is never visible to the identity itself.
+func apiKeySession(key *APIKey, identity *ory.Identity) *ory.Session {
+	visible := *identity
+	visible.MetadataAdmin = nil
+
+	active := true
+	aal := ory.AUTHENTICATORASSURANCELEVEL_AAL1
+	createdAt := key.CreatedAt
+
+	return &ory.Session{
+		Id:                          "api_key:" + key.ID,
+		Active:                      &active,
+		AuthenticatorAssuranceLevel: &aal,
+		AuthenticatedAt:             &createdAt,
+		ExpiresAt:                   key.ExpiresAt,
+		AuthenticationMethods: []ory.SessionAuthenticationMethod{
+			{Method: ory.PtrString(AuthMethodAPIKey)},
+		},
+		Identity: &visible,
+	}
+}
+
+// parseAPIKey splits kco_<identity ID>_<key ID>_<secret> into its IDs
+func parseAPIKey(plaintext string) (identityID, keyID string, ok bool) {
+	if !IsAPIKey(plaintext) {
+		return "", "", false
+	}
+
+	parts := strings.Split(strings.TrimPrefix(plaintext, APIKeyPrefix), "_")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// hashAPIKey returns the hex SHA-256 of a key. Keys carry 256 bits of
+// randomness, so a fast unsalted hash is enough.
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	ory "github.com/ory/client-go"
+)
+
+// fakeAdminService keeps identities in memory and applies metadata_admin patches
+type fakeAdminService struct {
+	AdminService
+	identities map[string]*ory.Identity
+	gets       int
+}
+
+func (f *fakeAdminService) GetIdentity(ctx context.Context, id string) (*ory.Identity, error) {
+	f.gets++
+	identity, ok := f.identities[id]
+	if !ok {
+		return nil, ErrIdentityNotFound
+	}
+	copied := *identity
+	return &copied, nil
+}
+
+func (f *fakeAdminService) PatchIdentity(ctx context.Context, id string, patches []ory.JsonPatch) (*ory.Identity, error) {
+	identity, ok := f.identities[id]
+	if !ok {
+		return nil, ErrIdentityNotFound
+	}
+	for _, patch := range patches {
+		if patch.Path == "/metadata_admin" {
+			identity.MetadataAdmin = patch.Value.(map[string]interface{})
+		}
+	}
+	return identity, nil
+}
+
+func newTestAPIKeyStore(state string) (*APIKeyStore, *fakeAdminService) {
+	admin := &fakeAdminService{identities: map[string]*ory.Identity{
+		"identity-123": {
+			Id:            "identity-123",
+			State:         &state,
+			MetadataAdmin: map[string]interface{}{"note": "keep me"},
+		},
+	}}
+	return NewAPIKeyStore(admin), admin
+}
+
+func TestAPIKeyStore_Lifecycle(t *testing.T) {
+	store, admin := newTestAPIKeyStore(IdentityStateActive)
+	ctx := context.Background()
+
+	key, plaintext, err := store.CreateAPIKey(ctx, "identity-123", APIKeyOptions{
+		Name:   "batch",
+		Scopes: []string{ScopeLLMChat},
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+
+	if !strings.HasPrefix(plaintext, APIKeyPrefix+"identity-123_"+key.ID+"_") {
+		t.Errorf("CreateAPIKey() key = %q, want kco_<identity>_<id>_<secret>", plaintext)
+	}
+
+	if strings.Contains(key.Hash, plaintext) || key.Hash != hashAPIKey(plaintext) {
+		t.Errorf("CreateAPIKey() stored hash %q", key.Hash)
+	}
+
+	if admin.identities["identity-123"].MetadataAdmin["note"] != "keep me" {
+		t.Error("CreateAPIKey() dropped existing metadata_admin fields")
+	}
+
+	validated, session, err := store.ValidateAPIKey(ctx, plaintext)
+	if err != nil {
+		t.Fatalf("ValidateAPIKey() error = %v", err)
+	}
+
+	if validated.ID != key.ID || !validated.HasScope(ScopeLLMChat) || validated.HasScope(ScopeLLMGenerate) {
+		t.Errorf("ValidateAPIKey() key = %+v", validated)
+	}
+
+	if session.Identity.Id != "identity-123" || session.Id != "api_key:"+key.ID {
+		t.Errorf("ValidateAPIKey() session = %s for identity %s", session.Id, session.Identity.Id)
+	}
+
+	if len(session.AuthenticationMethods) != 1 || session.AuthenticationMethods[0].GetMethod() != AuthMethodAPIKey {
+		t.Errorf("ValidateAPIKey() methods = %+v", session.AuthenticationMethods)
+	}
+
+	keys, err := store.ListAPIKeys(ctx, "identity-123")
+	if err != nil || len(keys) != 1 || keys[0].Name != "batch" {
+		t.Fatalf("ListAPIKeys() = %+v, %v", keys, err)
+	}
+
+	exported, err := store.ExportUserData(ctx, "identity-123")
+	if exportedKeys, ok := exported.([]APIKey); err != nil || !ok || len(exportedKeys) != 1 || exportedKeys[0].Hash != "" {
+		t.Errorf("ExportUserData() = %+v, %v, want the key without its hash", exported, err)
+	}
+
+	if err := store.RevokeAPIKey(ctx, "identity-123", key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey() error = %v", err)
+	}
+
+	if _, _, err := store.ValidateAPIKey(ctx, plaintext); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("ValidateAPIKey() after revoke error = %v, want ErrInvalidAPIKey", err)
+	}
+
+	if err := store.RevokeAPIKey(ctx, "identity-123", key.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("RevokeAPIKey() twice error = %v, want ErrAPIKeyNotFound", err)
+	}
+}
+
+func TestAPIKeyStore_ValidateAPIKey(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		state   string
+		opts    APIKeyOptions
+		mangle  func(key string) string
+		wantErr error
+	}{
+		{
+			name:  "valid",
+			state: IdentityStateActive,
+			opts:  APIKeyOptions{Name: "ok", Scopes: []string{ScopeLLMChat}},
+		},
+		{
+			name:    "expired",
+			state:   IdentityStateActive,
+			opts:    APIKeyOptions{Name: "old", Scopes: []string{ScopeLLMChat}, ExpiresAt: &past},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name:    "inactive identity",
+			state:   IdentityStateInactive,
+			opts:    APIKeyOptions{Name: "blocked", Scopes: []string{ScopeLLMChat}},
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name:    "wrong secret",
+			state:   IdentityStateActive,
+			opts:    APIKeyOptions{Name: "ok", Scopes: []string{ScopeLLMChat}},
+			mangle:  func(key string) string { return key[:len(key)-4] + "0000" },
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name:    "unknown identity",
+			state:   IdentityStateActive,
+			opts:    APIKeyOptions{Name: "ok", Scopes: []string{ScopeLLMChat}},
+			mangle:  func(key string) string { return strings.Replace(key, "identity-123", "identity-999", 1) },
+			wantErr: ErrInvalidAPIKey,
+		},
+		{
+			name:    "malformed",
+			state:   IdentityStateActive,
+			opts:    APIKeyOptions{Name: "ok", Scopes: []string{ScopeLLMChat}},
+			mangle:  func(key string) string { return APIKeyPrefix + "garbage" },
+			wantErr: ErrInvalidAPIKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _ := newTestAPIKeyStore(tt.state)
+
+			_, plaintext, err := store.CreateAPIKey(context.Background(), "identity-123", tt.opts)
+			if err != nil {
+				t.Fatalf("CreateAPIKey() error = %v", err)
+			}
+			if tt.mangle != nil {
+				plaintext = tt.mangle(plaintext)
+			}
+
+			_, _, err = store.ValidateAPIKey(context.Background(), plaintext)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ValidateAPIKey() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAPIKeyStore_IdentityCache(t *testing.T) {
+	store, admin := newTestAPIKeyStore(IdentityStateActive)
+	store.WithIdentityCache(time.Minute, 10)
+	ctx := context.Background()
+
+	key, plaintext, err := store.CreateAPIKey(ctx, "identity-123", APIKeyOptions{Name: "batch", Scopes: []string{ScopeLLMChat}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+
+	admin.gets = 0
+	for i := 0; i < 3; i++ {
+		if _, _, err := store.ValidateAPIKey(ctx, plaintext); err != nil {
+			t.Fatalf("ValidateAPIKey() error = %v", err)
+		}
+	}
+	if admin.gets != 1 {
+		t.Errorf("ValidateAPIKey() x3 made %d admin lookups, want 1", admin.gets)
+	}
+
+	unknown := strings.Replace(plaintext, "identity-123", "identity-999", 1)
+	for i := 0; i < 2; i++ {
+		if _, _, err := store.ValidateAPIKey(ctx, unknown); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Fatalf("ValidateAPIKey() unknown identity error = %v, want ErrInvalidAPIKey", err)
+		}
+	}
+	if admin.gets != 2 {
+		t.Errorf("unknown identity made %d admin lookups in total, want 2", admin.gets)
+	}
+
+	// Revoking through the store takes effect at once
+	if err := store.RevokeAPIKey(ctx, "identity-123", key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey() error = %v", err)
+	}
+	if _, _, err := store.ValidateAPIKey(ctx, plaintext); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("ValidateAPIKey() after revoke error = %v, want ErrInvalidAPIKey", err)
+	}
+
+	// So does blocking the identity through the wrapped admin API
+	_, plaintext, err = store.CreateAPIKey(ctx, "identity-123", APIKeyOptions{Name: "batch", Scopes: []string{ScopeLLMChat}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+	if _, _, err := store.ValidateAPIKey(ctx, plaintext); err != nil {
+		t.Fatalf("ValidateAPIKey() error = %v", err)
+	}
+	inactive := IdentityStateInactive
+	admin.identities["identity-123"].State = &inactive
+	if _, err := store.WrapAdmin(admin).PatchIdentity(ctx, "identity-123", BuildIdentityPatch(nil, IdentityStateInactive)); err != nil {
+		t.Fatalf("PatchIdentity() error = %v", err)
+	}
+	if _, _, err := store.ValidateAPIKey(ctx, plaintext); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("ValidateAPIKey() after block error = %v, want ErrInvalidAPIKey", err)
+	}
+}
+
+func TestAPIKeyStore_SessionHidesMetadataAdmin(t *testing.T) {
+	store, admin := newTestAPIKeyStore(IdentityStateActive)
+	ctx := context.Background()
+
+	_, plaintext, err := store.CreateAPIKey(ctx, "identity-123", APIKeyOptions{Name: "batch", Scopes: []string{ScopeLLMChat}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey() error = %v", err)
+	}
+
+	_, session, err := store.ValidateAPIKey(ctx, plaintext)
+	if err != nil {
+		t.Fatalf("ValidateAPIKey() error = %v", err)
+	}
+	if session.Identity.MetadataAdmin != nil {
+		t.Errorf("ValidateAPIKey() session identity metadata_admin = %v, want none", session.Identity.MetadataAdmin)
+	}
+
+	redacted := WithoutAPIKeys(admin.identities["identity-123"])
+	if _, ok := redacted.MetadataAdmin[apiKeysMetadataKey]; ok || redacted.MetadataAdmin["note"] != "keep me" {
+		t.Errorf("WithoutAPIKeys() metadata_admin = %v, want only the other fields", redacted.MetadataAdmin)
+	}
+	if _, ok := admin.identities["identity-123"].MetadataAdmin[apiKeysMetadataKey]; !ok {
+		t.Error("WithoutAPIKeys() modified the identity it was given")
+	}
+}
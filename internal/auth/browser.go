@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	ory "github.com/ory/client-go"
+)
+
+// SessionCookieName is the cookie Kratos keeps browser sessions in
+const SessionCookieName = "ory_kratos_session"
+
+// IsSessionCookie reports whether a session credential is a Kratos session
+// cookie ("ory_kratos_session=...") rather than a session token
+func IsSessionCookie(credential string) bool {
+	return strings.HasPrefix(credential, SessionCookieName+"=")
+}
+
+// SessionCookieCredential formats a session cookie value as a credential the
+// KratosService methods accept in place of a session token
+func SessionCookieCredential(value string) string {
+	return SessionCookieName + "=" + value
+}
+
+// CreateBrowserLoginFlow creates a browser login flow. cookies is the
+// client's Cookie header; the returned Set-Cookie headers, including Kratos'
+// CSRF cookie, must be relayed to the client.
+func (k *KratosClient) CreateBrowserLoginFlow(ctx context.Context, cookies string) (*ory.LoginFlow, []string, error) {
+	req := k.frontend.FrontendAPI.CreateBrowserLoginFlow(ctx)
+	if cookies != "" {
+		req = req.Cookie(cookies)
+	}
+
+	flow, resp, err := req.Execute()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create browser login flow: %w (status: %d)", err, getStatusCode(resp))
+	}
+	return flow, setCookies(resp), nil
+}
+
+// UpdateBrowserLoginFlow submits a browser login flow. The body must carry
+// the flow's csrf_token and cookies the matching CSRF cookie. On success the
+// Set-Cookie headers hold the new session cookie.
+func (k *KratosClient) UpdateBrowserLoginFlow(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, cookies string) (*ory.SuccessfulNativeLogin, []string, error) {
+	result, resp, err := k.frontend.FrontendAPI.UpdateLoginFlow(ctx).
+		Flow(flowID).
+		UpdateLoginFlowBody(body).
+		Cookie(cookies).
+		Execute()
+
+	if err != nil {
+		return nil, nil, flowUpdateError("failed to update browser login flow", err, resp)
+	}
+	return result, setCookies(resp), nil
+}
+
+// CreateBrowserRegistrationFlow creates a browser registration flow. cookies
+// is the client's Cookie header; the returned Set-Cookie headers, including
+// Kratos' CSRF cookie, must be relayed to the client.
+func (k *KratosClient) CreateBrowserRegistrationFlow(ctx context.Context, cookies string) (*ory.RegistrationFlow, []string, error) {
+	// the generated request has no Cookie parameter; cookieTransport adds it
+	flow, resp, err := k.frontend.FrontendAPI.CreateBrowserRegistrationFlow(withRequestCookies(ctx, cookies)).Execute()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create browser registration flow: %w (status: %d)", err, getStatusCode(resp))
+	}
+	return flow, setCookies(resp), nil
+}
+
+// UpdateBrowserRegistrationFlow submits a browser registration flow. When
+// Kratos signs the new identity in, the Set-Cookie headers hold its session.
+func (k *KratosClient) UpdateBrowserRegistrationFlow(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody, cookies string) (*ory.SuccessfulNativeRegistration, []string, error) {
+	result, resp, err := k.frontend.FrontendAPI.UpdateRegistrationFlow(ctx).
+		Flow(flowID).
+		UpdateRegistrationFlowBody(body).
+		Cookie(cookies).
+		Execute()
+
+	if err != nil {
+		return nil, nil, flowUpdateError("failed to update browser registration flow", err, resp)
+	}
+	return result, setCookies(resp), nil
+}
+
+// PerformBrowserLogout completes a logout flow from CreateLogoutFlow. The
+// returned Set-Cookie headers clear the session cookie.
+func (k *KratosClient) PerformBrowserLogout(ctx context.Context, logoutToken, cookie string) ([]string, error) {
+	resp, err := k.frontend.FrontendAPI.UpdateLogoutFlow(ctx).
+		Token(logoutToken).
+		Cookie(cookie).
+		Execute()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to logout: %w (status: %d)", err, getStatusCode(resp))
+	}
+	return setCookies(resp), nil
+}
+
+// BuildBrowserPasswordLoginBody is BuildPasswordLoginBody with the flow's
+// CSRF token, which browser flows require
+func BuildBrowserPasswordLoginBody(email, password, csrfToken string) ory.UpdateLoginFlowBody {
+	body := BuildPasswordLoginBody(email, password)
+	body.UpdateLoginFlowWithPasswordMethod.CsrfToken = &csrfToken
+	return body
+}
+
+// ExtractLoginCSRFToken extracts the CSRF token from a login flow
+func ExtractLoginCSRFToken(flow *ory.LoginFlow) string {
+	if flow == nil {
+		return ""
+	}
+	return findNodeValue(flow.Ui.Nodes, "csrf_token")
+}
+
+// ExtractLoginFormFields extracts form fields from a login flow
+func ExtractLoginFormFields(flow *ory.LoginFlow) []map[string]interface{} {
+	return extractFormFields(flow.Ui.Nodes)
+}
+
+// requestCookiesKey is the context key for withRequestCookies
+type requestCookiesKey struct{}
+
+// withRequestCookies returns ctx carrying a Cookie header for cookieTransport
+func withRequestCookies(ctx context.Context, cookies string) context.Context {
+	if cookies == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestCookiesKey{}, cookies)
+}
+
+// cookieTransport sends the Cookie header from withRequestCookies, for
+// Kratos calls whose generated request cannot set one
+type cookieTransport struct {
+	base http.RoundTripper
+}
+
+func (t cookieTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if cookies, ok := req.Context().Value(requestCookiesKey{}).(string); ok && req.Header.Get("Cookie") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Cookie", cookies)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// setCookies returns a response's Set-Cookie headers
+func setCookies(resp *http.Response) []string {
+	if resp == nil {
+		return nil
+	}
+	return resp.Header.Values("Set-Cookie")
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKratosClient_CreateBrowserRegistrationFlow_ForwardsCookies(t *testing.T) {
+	var gotCookie string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotCookie = r.Header.Get("Cookie")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Add("Set-Cookie", "csrf_token_abc=xyz; Path=/; HttpOnly")
+		w.Write([]byte(`{"id": "flow-123", "type": "browser", "expires_at": "2030-01-01T00:00:00Z", "issued_at": "2020-01-01T00:00:00Z", "request_url": "", "state": "choose_method",
+			"ui": {"action": "", "method": "POST", "nodes": []}}`))
+	}))
+	defer server.Close()
+
+	client := NewKratosClient(server.URL, server.URL)
+
+	flow, cookies, err := client.CreateBrowserRegistrationFlow(context.Background(), "csrf_token_abc=existing")
+	if err != nil {
+		t.Fatalf("CreateBrowserRegistrationFlow() error = %v", err)
+	}
+	if flow.Id != "flow-123" {
+		t.Errorf("CreateBrowserRegistrationFlow() flow ID = %q, want flow-123", flow.Id)
+	}
+	if gotCookie != "csrf_token_abc=existing" {
+		t.Errorf("Kratos received Cookie %q, want the client's cookies", gotCookie)
+	}
+	if len(cookies) != 1 {
+		t.Errorf("CreateBrowserRegistrationFlow() cookies = %v, want Kratos' Set-Cookie", cookies)
+	}
+
+	if _, _, err := client.CreateBrowserRegistrationFlow(context.Background(), ""); err != nil {
+		t.Fatalf("CreateBrowserRegistrationFlow() error = %v", err)
+	}
+	if gotCookie != "" {
+		t.Errorf("Kratos received Cookie %q without client cookies, want none", gotCookie)
+	}
+}
@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	ory "github.com/ory/client-go"
+)
+
+// Ensure SessionCache implements KratosService and UserDataStore
+var (
+	_ KratosService = (*SessionCache)(nil)
+	_ UserDataStore = (*SessionCache)(nil)
+)
+
+// SessionCacheConfig configures a SessionCache
+type SessionCacheConfig struct {
+	// TTL caps how long a valid session is cached. Entries never outlive the
+	// session's ExpiresAt.
+	TTL time.Duration
+	// NegativeTTL is how long tokens Kratos rejected stay rejected. Zero
+	// disables negative caching.
+	NegativeTTL time.Duration
+	// MaxEntries bounds the cache; the least recently used entry is evicted
+	MaxEntries int
+}
+
+// SessionCache is a KratosService that caches ValidateSession results, keyed
+// by the SHA-256 of the token. Logging out or revoking sessions through it
+// drops the affected entries; sessions revoked elsewhere stay valid here for
+// at most TTL. A validation still in flight when entries are dropped is not
+// cached, so it cannot bring a revoked session back.
+type SessionCache struct {
+	KratosService
+
+	cfg SessionCacheConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru holds *sessionCacheEntry, most recently used first
+	lru *list.List
+	// generation counts invalidations; results of validations that started
+	// before the latest one are not cached
+	generation uint64
+}
+
+type sessionCacheEntry struct {
+	key     string
+	session *ory.Session
+	err     error
+	expires time.Time
+}
+
+// NewSessionCache wraps kratos with a session validation cache
+func NewSessionCache(kratos KratosService, cfg SessionCacheConfig) *SessionCache {
+	return &SessionCache{
+		KratosService: kratos,
+		cfg:           cfg,
+		now:           time.Now,
+		entries:       make(map[string]*list.Element),
+		lru:           list.New(),
+	}
+}
+
+// ValidateSession returns the cached result for the token, or validates it
+// with Kratos and caches the outcome. Errors other than ErrInvalidSession,
+// e.g. Kratos being unreachable, are not cached.
+func (c *SessionCache) ValidateSession(ctx context.Context, sessionToken string) (*ory.Session, error) {
+	key := sessionCacheKey(sessionToken)
+
+	if entry, ok := c.get(key); ok {
+		return entry.session, entry.err
+	}
+
+	generation := c.currentGeneration()
+	session, err := c.KratosService.ValidateSession(ctx, sessionToken)
+	switch {
+	case err == nil:
+		c.put(key, generation, session, nil, c.sessionExpiry(session))
+	case errors.Is(err, ErrInvalidSession) && c.cfg.NegativeTTL > 0:
+		c.put(key, generation, nil, err, c.now().Add(c.cfg.NegativeTTL))
+	}
+
+	return session, err
+}
+
+// PerformNativeLogout logs out with Kratos and drops the token from the
+// cache, again once Kratos has answered so a validation racing the logout
+// cannot re-cache it
+func (c *SessionCache) PerformNativeLogout(ctx context.Context, body ory.PerformNativeLogoutBody) error {
+	c.Invalidate(body.SessionToken)
+	defer c.Invalidate(body.SessionToken)
+	return c.KratosService.PerformNativeLogout(ctx, body)
+}
+
+// PerformBrowserLogout logs out a browser session with Kratos and drops the
+// session cookie from the cache
+func (c *SessionCache) PerformBrowserLogout(ctx context.Context, logoutToken, cookie string) ([]string, error) {
+	c.Invalidate(cookie)
+	defer c.Invalidate(cookie)
+	return c.KratosService.PerformBrowserLogout(ctx, logoutToken, cookie)
+}
+
+// RevokeMySession revokes a session with Kratos and drops it from the cache
+func (c *SessionCache) RevokeMySession(ctx context.Context, sessionToken, sessionID string) error {
+	revoked := func(entry *sessionCacheEntry) bool {
+		return entry.session != nil && entry.session.Id == sessionID
+	}
+	c.invalidateWhere(revoked)
+	defer c.invalidateWhere(revoked)
+	return c.KratosService.RevokeMySession(ctx, sessionToken, sessionID)
+}
+
+// RevokeMyOtherSessions revokes the caller's other sessions with Kratos and
+// drops them from the cache
+func (c *SessionCache) RevokeMyOtherSessions(ctx context.Context, sessionToken string) (int64, error) {
+	count, err := c.KratosService.RevokeMyOtherSessions(ctx, sessionToken)
+
+	currentKey := sessionCacheKey(sessionToken)
+	current, ok := c.get(currentKey)
+	if ok && current.session != nil && current.session.Identity != nil {
+		identityID := current.session.Identity.Id
+		c.invalidateWhere(func(entry *sessionCacheEntry) bool {
+			return entry.key != currentKey &&
+				entry.session != nil &&
+				entry.session.Identity != nil &&
+				entry.session.Identity.Id == identityID
+		})
+	} else {
+		c.discardInFlight()
+	}
+
+	return count, err
+}
+
+// UpdateSettingsFlow updates settings with Kratos and, once it succeeds,
+// drops the identity's cached sessions so they pick up the changed traits and
+// verifiable addresses
+func (c *SessionCache) UpdateSettingsFlow(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error) {
+	flow, err := c.KratosService.UpdateSettingsFlow(ctx, flowID, body, sessionToken)
+	if err != nil {
+		return flow, err
+	}
+
+	currentKey := sessionCacheKey(sessionToken)
+	current, ok := c.get(currentKey)
+	if ok && current.session != nil && current.session.Identity != nil {
+		identityID := current.session.Identity.Id
+		c.invalidateWhere(func(entry *sessionCacheEntry) bool {
+			return entry.session != nil &&
+				entry.session.Identity != nil &&
+				entry.session.Identity.Id == identityID
+		})
+	} else {
+		c.Invalidate(sessionToken)
+	}
+
+	return flow, nil
+}
+
+// UpdateLoginFlow submits a login with Kratos. A refresh or aal2 step-up
+// made with a session token changes that session's authentication time and
+// assurance level, so once it succeeds the token is dropped from the cache.
+func (c *SessionCache) UpdateLoginFlow(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error) {
+	login, err := c.KratosService.UpdateLoginFlow(ctx, flowID, body, sessionToken)
+	if err == nil && sessionToken != "" {
+		c.Invalidate(sessionToken)
+	}
+	return login, err
+}
+
+// ExportUserData returns nil: the cache only holds copies of Kratos sessions,
+// which are exported from Kratos
+func (c *SessionCache) ExportUserData(ctx context.Context, identityID string) (interface{}, error) {
+	return nil, nil
+}
+
+// PurgeUserData drops the identity's cached sessions, so a deleted account's
+// tokens stop working immediately rather than after TTL
+func (c *SessionCache) PurgeUserData(ctx context.Context, identityID string) error {
+	c.PurgeIdentity(identityID)
+	return nil
+}
+
+// PurgeIdentity drops every cached session of an identity
+func (c *SessionCache) PurgeIdentity(identityID string) {
+	c.invalidateWhere(func(entry *sessionCacheEntry) bool {
+		return entry.session != nil &&
+			entry.session.Identity != nil &&
+			entry.session.Identity.Id == identityID
+	})
+}
+
+// WrapAdmin returns admin with the cache kept in step: updating, deleting or
+// signing out an identity through it also drops the identity's cached
+// sessions, so a blocked or deleted identity is rejected at once
+func (c *SessionCache) WrapAdmin(admin AdminService) AdminService {
+	return &purgingAdmin{AdminService: admin, purge: c.PurgeIdentity}
+}
+
+// purgingAdmin calls purge with the identity after admin changes to it, so
+// caches of identity data stay in step
+type purgingAdmin struct {
+	AdminService
+	purge func(identityID string)
+}
+
+func (a *purgingAdmin) PatchIdentity(ctx context.Context, id string, patches []ory.JsonPatch) (*ory.Identity, error) {
+	identity, err := a.AdminService.PatchIdentity(ctx, id, patches)
+	if err == nil {
+		a.purge(id)
+	}
+	return identity, err
+}
+
+func (a *purgingAdmin) DeleteIdentity(ctx context.Context, id string) error {
+	err := a.AdminService.DeleteIdentity(ctx, id)
+	if err == nil || errors.Is(err, ErrIdentityNotFound) {
+		a.purge(id)
+	}
+	return err
+}
+
+func (a *purgingAdmin) RevokeIdentitySessions(ctx context.Context, id string) error {
+	err := a.AdminService.RevokeIdentitySessions(ctx, id)
+	if err == nil {
+		a.purge(id)
+	}
+	return err
+}
+
+// Invalidate drops a token from the cache
+func (c *SessionCache) Invalidate(sessionToken string) {
+	key := sessionCacheKey(sessionToken)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// discardInFlight stops validations already in flight from caching their
+// results, for revocations whose entries cannot be identified
+func (c *SessionCache) discardInFlight() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+}
+
+// currentGeneration returns the invalidation count to pass to put
+func (c *SessionCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// sessionExpiry is now plus TTL, capped at the session's ExpiresAt
+func (c *SessionCache) sessionExpiry(session *ory.Session) time.Time {
+	expires := c.now().Add(c.cfg.TTL)
+	if session != nil && session.ExpiresAt != nil && session.ExpiresAt.Before(expires) {
+		return *session.ExpiresAt
+	}
+	return expires
+}
+
+// get returns the unexpired entry for key, marking it recently used
+func (c *SessionCache) get(key string) (*sessionCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*sessionCacheEntry)
+	if !c.now().Before(entry.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return entry, true
+}
+
+// put caches a validation result unless entries were invalidated since
+// generation was read
+func (c *SessionCache) put(key string, generation uint64, session *ory.Session, err error, expires time.Time) {
+	if c.cfg.MaxEntries <= 0 || !c.now().Before(expires) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+
+	for c.lru.Len() >= c.cfg.MaxEntries {
+		c.remove(c.lru.Back())
+	}
+
+	c.entries[key] = c.lru.PushFront(&sessionCacheEntry{
+		key:     key,
+		session: session,
+		err:     err,
+		expires: expires,
+	})
+}
+
+func (c *SessionCache) invalidateWhere(match func(*sessionCacheEntry) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+
+	for elem := c.lru.Front(); elem != nil; {
+		next := elem.Next()
+		if match(elem.Value.(*sessionCacheEntry)) {
+			c.remove(elem)
+		}
+		elem = next
+	}
+}
+
+// remove deletes an entry; the caller must hold mu
+func (c *SessionCache) remove(elem *list.Element) {
+	entry := c.lru.Remove(elem).(*sessionCacheEntry)
+	delete(c.entries, entry.key)
+}
+
+// sessionCacheKey hashes the token so raw tokens are never held in the cache
+func sessionCacheKey(sessionToken string) string {
+	sum := sha256.Sum256([]byte(sessionToken))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,386 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	ory "github.com/ory/client-go"
+)
+
+// fakeKratosService counts ValidateSession calls and returns a session per token
+type fakeKratosService struct {
+	KratosService
+	calls     map[string]int
+	sessions  map[string]*ory.Session
+	validErr  error
+	loggedOut []string
+}
+
+func newFakeKratosService() *fakeKratosService {
+	return &fakeKratosService{calls: make(map[string]int), sessions: make(map[string]*ory.Session)}
+}
+
+func (f *fakeKratosService) ValidateSession(ctx context.Context, sessionToken string) (*ory.Session, error) {
+	f.calls[sessionToken]++
+	if f.validErr != nil {
+		return nil, f.validErr
+	}
+	session, ok := f.sessions[sessionToken]
+	if !ok {
+		return nil, fmt.Errorf("unauthorized: %w", ErrInvalidSession)
+	}
+	return session, nil
+}
+
+func (f *fakeKratosService) PerformNativeLogout(ctx context.Context, body ory.PerformNativeLogoutBody) error {
+	f.loggedOut = append(f.loggedOut, body.SessionToken)
+	return nil
+}
+
+func (f *fakeKratosService) PerformBrowserLogout(ctx context.Context, logoutToken, cookie string) ([]string, error) {
+	f.loggedOut = append(f.loggedOut, cookie)
+	return nil, nil
+}
+
+func (f *fakeKratosService) RevokeMySession(ctx context.Context, sessionToken, sessionID string) error {
+	return nil
+}
+
+func (f *fakeKratosService) RevokeMyOtherSessions(ctx context.Context, sessionToken string) (int64, error) {
+	return 1, nil
+}
+
+func (f *fakeKratosService) UpdateLoginFlow(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error) {
+	return &ory.SuccessfulNativeLogin{}, nil
+}
+
+func (f *fakeKratosService) UpdateSettingsFlow(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error) {
+	return &ory.SettingsFlow{Id: flowID}, nil
+}
+
+func testSession(id, identityID string) *ory.Session {
+	return &ory.Session{Id: id, Identity: &ory.Identity{Id: identityID}}
+}
+
+func newTestSessionCache(kratos KratosService, now *time.Time) *SessionCache {
+	cache := NewSessionCache(kratos, SessionCacheConfig{
+		TTL:         time.Minute,
+		NegativeTTL: 5 * time.Second,
+		MaxEntries:  2,
+	})
+	cache.now = func() time.Time { return *now }
+	return cache
+}
+
+func TestSessionCache_ValidateSession(t *testing.T) {
+	now := time.Now()
+	kratos := newFakeKratosService()
+	kratos.sessions["token-a"] = testSession("session-a", "identity-1")
+	cache := newTestSessionCache(kratos, &now)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.ValidateSession(ctx, "token-a"); err != nil {
+			t.Fatalf("ValidateSession() error = %v", err)
+		}
+	}
+	if kratos.calls["token-a"] != 1 {
+		t.Errorf("ValidateSession() called Kratos %d times, want 1", kratos.calls["token-a"])
+	}
+
+	if _, ok := cache.entries["token-a"]; ok {
+		t.Error("SessionCache keyed an entry by the raw token")
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := cache.ValidateSession(ctx, "token-a"); err != nil {
+		t.Fatalf("ValidateSession() error = %v", err)
+	}
+	if kratos.calls["token-a"] != 2 {
+		t.Errorf("ValidateSession() after TTL called Kratos %d times, want 2", kratos.calls["token-a"])
+	}
+}
+
+func TestSessionCache_SessionExpiry(t *testing.T) {
+	now := time.Now()
+	expires := now.Add(10 * time.Second)
+	kratos := newFakeKratosService()
+	kratos.sessions["token-a"] = &ory.Session{Id: "session-a", ExpiresAt: &expires}
+	cache := newTestSessionCache(kratos, &now)
+	ctx := context.Background()
+
+	cache.ValidateSession(ctx, "token-a")
+
+	now = now.Add(10 * time.Second)
+	cache.ValidateSession(ctx, "token-a")
+
+	if kratos.calls["token-a"] != 2 {
+		t.Errorf("ValidateSession() served a session past its ExpiresAt (Kratos calls = %d, want 2)", kratos.calls["token-a"])
+	}
+}
+
+func TestSessionCache_NegativeCaching(t *testing.T) {
+	now := time.Now()
+	kratos := newFakeKratosService()
+	cache := newTestSessionCache(kratos, &now)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.ValidateSession(ctx, "bad-token"); !errors.Is(err, ErrInvalidSession) {
+			t.Fatalf("ValidateSession() error = %v, want ErrInvalidSession", err)
+		}
+	}
+	if kratos.calls["bad-token"] != 1 {
+		t.Errorf("ValidateSession() called Kratos %d times for a rejected token, want 1", kratos.calls["bad-token"])
+	}
+
+	now = now.Add(5 * time.Second)
+	cache.ValidateSession(ctx, "bad-token")
+	if kratos.calls["bad-token"] != 2 {
+		t.Errorf("ValidateSession() after negative TTL called Kratos %d times, want 2", kratos.calls["bad-token"])
+	}
+
+	// Transient failures are retried
+	kratos.validErr = errors.New("connection refused")
+	cache.ValidateSession(ctx, "other-token")
+	cache.ValidateSession(ctx, "other-token")
+	if kratos.calls["other-token"] != 2 {
+		t.Errorf("ValidateSession() cached a transient error (Kratos calls = %d, want 2)", kratos.calls["other-token"])
+	}
+}
+
+func TestSessionCache_Eviction(t *testing.T) {
+	now := time.Now()
+	kratos := newFakeKratosService()
+	kratos.sessions["token-a"] = testSession("session-a", "identity-1")
+	kratos.sessions["token-b"] = testSession("session-b", "identity-1")
+	kratos.sessions["token-c"] = testSession("session-c", "identity-2")
+	cache := newTestSessionCache(kratos, &now)
+	ctx := context.Background()
+
+	cache.ValidateSession(ctx, "token-a")
+	cache.ValidateSession(ctx, "token-b")
+	cache.ValidateSession(ctx, "token-a") // a is now most recently used
+	cache.ValidateSession(ctx, "token-c") // evicts b
+
+	if cache.lru.Len() != 2 {
+		t.Fatalf("SessionCache holds %d entries, want 2", cache.lru.Len())
+	}
+
+	cache.ValidateSession(ctx, "token-a")
+	cache.ValidateSession(ctx, "token-b")
+
+	if kratos.calls["token-a"] != 1 || kratos.calls["token-b"] != 2 {
+		t.Errorf("Kratos calls a=%d b=%d, want a=1 b=2", kratos.calls["token-a"], kratos.calls["token-b"])
+	}
+}
+
+func TestSessionCache_Invalidation(t *testing.T) {
+	now := time.Now()
+	ctx := context.Background()
+
+	setup := func() (*fakeKratosService, *SessionCache) {
+		kratos := newFakeKratosService()
+		kratos.sessions["token-a"] = testSession("session-a", "identity-1")
+		kratos.sessions["token-b"] = testSession("session-b", "identity-1")
+		cache := newTestSessionCache(kratos, &now)
+		cache.ValidateSession(ctx, "token-a")
+		cache.ValidateSession(ctx, "token-b")
+		return kratos, cache
+	}
+
+	t.Run("logout", func(t *testing.T) {
+		kratos, cache := setup()
+
+		if err := cache.PerformNativeLogout(ctx, *BuildNewPerformNativeLogoutBody("token-a")); err != nil {
+			t.Fatalf("PerformNativeLogout() error = %v", err)
+		}
+		if len(kratos.loggedOut) != 1 {
+			t.Fatal("PerformNativeLogout() did not reach Kratos")
+		}
+
+		cache.ValidateSession(ctx, "token-a")
+		cache.ValidateSession(ctx, "token-b")
+		if kratos.calls["token-a"] != 2 || kratos.calls["token-b"] != 1 {
+			t.Errorf("Kratos calls a=%d b=%d, want a=2 b=1", kratos.calls["token-a"], kratos.calls["token-b"])
+		}
+	})
+
+	t.Run("browser logout", func(t *testing.T) {
+		kratos := newFakeKratosService()
+		cookie := SessionCookieCredential("cookie-a")
+		kratos.sessions[cookie] = testSession("session-a", "identity-1")
+		cache := newTestSessionCache(kratos, &now)
+		cache.ValidateSession(ctx, cookie)
+
+		if _, err := cache.PerformBrowserLogout(ctx, "logout-token", cookie); err != nil {
+			t.Fatalf("PerformBrowserLogout() error = %v", err)
+		}
+
+		cache.ValidateSession(ctx, cookie)
+		if kratos.calls[cookie] != 2 {
+			t.Errorf("Kratos calls = %d, want 2", kratos.calls[cookie])
+		}
+	})
+
+	t.Run("revoke session", func(t *testing.T) {
+		kratos, cache := setup()
+
+		cache.RevokeMySession(ctx, "token-a", "session-b")
+
+		cache.ValidateSession(ctx, "token-a")
+		cache.ValidateSession(ctx, "token-b")
+		if kratos.calls["token-a"] != 1 || kratos.calls["token-b"] != 2 {
+			t.Errorf("Kratos calls a=%d b=%d, want a=1 b=2", kratos.calls["token-a"], kratos.calls["token-b"])
+		}
+	})
+
+	t.Run("revoke other sessions", func(t *testing.T) {
+		kratos, cache := setup()
+
+		cache.RevokeMyOtherSessions(ctx, "token-a")
+
+		cache.ValidateSession(ctx, "token-a")
+		cache.ValidateSession(ctx, "token-b")
+		if kratos.calls["token-a"] != 1 || kratos.calls["token-b"] != 2 {
+			t.Errorf("Kratos calls a=%d b=%d, want a=1 b=2", kratos.calls["token-a"], kratos.calls["token-b"])
+		}
+	})
+
+	t.Run("purge user data", func(t *testing.T) {
+		kratos, cache := setup()
+
+		if err := cache.PurgeUserData(ctx, "identity-1"); err != nil {
+			t.Fatalf("PurgeUserData() error = %v", err)
+		}
+
+		cache.ValidateSession(ctx, "token-a")
+		cache.ValidateSession(ctx, "token-b")
+		if kratos.calls["token-a"] != 2 || kratos.calls["token-b"] != 2 {
+			t.Errorf("Kratos calls a=%d b=%d, want a=2 b=2", kratos.calls["token-a"], kratos.calls["token-b"])
+		}
+	})
+
+	t.Run("settings update", func(t *testing.T) {
+		kratos, cache := setup()
+
+		if _, err := cache.UpdateSettingsFlow(ctx, "flow-1", BuildProfileSettingsBody(nil), "token-a"); err != nil {
+			t.Fatalf("UpdateSettingsFlow() error = %v", err)
+		}
+
+		cache.ValidateSession(ctx, "token-a")
+		cache.ValidateSession(ctx, "token-b")
+		if kratos.calls["token-a"] != 2 || kratos.calls["token-b"] != 2 {
+			t.Errorf("Kratos calls a=%d b=%d, want a=2 b=2", kratos.calls["token-a"], kratos.calls["token-b"])
+		}
+	})
+
+	t.Run("step-up login", func(t *testing.T) {
+		kratos, cache := setup()
+
+		// A first-factor login carries no session token and changes nothing cached
+		if _, err := cache.UpdateLoginFlow(ctx, "flow-1", ory.UpdateLoginFlowBody{}, ""); err != nil {
+			t.Fatalf("UpdateLoginFlow() error = %v", err)
+		}
+		if _, err := cache.UpdateLoginFlow(ctx, "flow-2", ory.UpdateLoginFlowBody{}, "token-a"); err != nil {
+			t.Fatalf("UpdateLoginFlow() error = %v", err)
+		}
+
+		cache.ValidateSession(ctx, "token-a")
+		cache.ValidateSession(ctx, "token-b")
+		if kratos.calls["token-a"] != 2 || kratos.calls["token-b"] != 1 {
+			t.Errorf("Kratos calls a=%d b=%d, want a=2 b=1", kratos.calls["token-a"], kratos.calls["token-b"])
+		}
+	})
+
+	adminTests := []struct {
+		name   string
+		change func(admin AdminService) error
+	}{
+		{
+			name: "admin block",
+			change: func(admin AdminService) error {
+				_, err := admin.PatchIdentity(ctx, "identity-1", BuildIdentityPatch(nil, IdentityStateInactive))
+				return err
+			},
+		},
+		{
+			name:   "admin delete",
+			change: func(admin AdminService) error { return admin.DeleteIdentity(ctx, "identity-1") },
+		},
+		{
+			name:   "admin revoke sessions",
+			change: func(admin AdminService) error { return admin.RevokeIdentitySessions(ctx, "identity-1") },
+		},
+	}
+
+	for _, tt := range adminTests {
+		t.Run(tt.name, func(t *testing.T) {
+			kratos, cache := setup()
+			admin := cache.WrapAdmin(&blockingAdminService{})
+
+			if err := tt.change(admin); err != nil {
+				t.Fatalf("admin change error = %v", err)
+			}
+
+			cache.ValidateSession(ctx, "token-a")
+			cache.ValidateSession(ctx, "token-b")
+			if kratos.calls["token-a"] != 2 || kratos.calls["token-b"] != 2 {
+				t.Errorf("Kratos calls a=%d b=%d, want a=2 b=2", kratos.calls["token-a"], kratos.calls["token-b"])
+			}
+		})
+	}
+}
+
+// slowKratosService signals started and holds ValidateSession until release
+// is closed
+type slowKratosService struct {
+	*fakeKratosService
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *slowKratosService) ValidateSession(ctx context.Context, sessionToken string) (*ory.Session, error) {
+	select {
+	case s.started <- struct{}{}:
+	default:
+	}
+	<-s.release
+	return s.fakeKratosService.ValidateSession(ctx, sessionToken)
+}
+
+func TestSessionCache_LogoutDuringValidation(t *testing.T) {
+	now := time.Now()
+	fake := newFakeKratosService()
+	fake.sessions["token-a"] = testSession("session-a", "identity-1")
+	kratos := &slowKratosService{fakeKratosService: fake, started: make(chan struct{}, 1), release: make(chan struct{})}
+	cache := newTestSessionCache(kratos, &now)
+
+	done := make(chan error)
+	go func() {
+		_, err := cache.ValidateSession(context.Background(), "token-a")
+		done <- err
+	}()
+
+	// Log out while Kratos is still answering the validation with the
+	// session it saw before the logout
+	<-kratos.started
+	if err := cache.PerformNativeLogout(context.Background(), ory.PerformNativeLogoutBody{SessionToken: "token-a"}); err != nil {
+		t.Fatalf("PerformNativeLogout() error = %v", err)
+	}
+	close(kratos.release)
+	if err := <-done; err != nil {
+		t.Fatalf("ValidateSession() in flight error = %v", err)
+	}
+	delete(fake.sessions, "token-a")
+
+	if _, err := cache.ValidateSession(context.Background(), "token-a"); !errors.Is(err, ErrInvalidSession) {
+		t.Errorf("ValidateSession() after logout error = %v, want ErrInvalidSession", err)
+	}
+	if fake.calls["token-a"] != 2 {
+		t.Errorf("Kratos calls = %d, want 2: the in-flight result must not be cached", fake.calls["token-a"])
+	}
+}
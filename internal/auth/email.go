@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"strings"
+
+	ory "github.com/ory/client-go"
+)
+
+// BuildEmailTraits returns a copy of traits with the email replaced
+func BuildEmailTraits(traits map[string]interface{}, email string) map[string]interface{} {
+	updated := make(map[string]interface{}, len(traits)+1)
+	for k, v := range traits {
+		updated[k] = v
+	}
+
+	updated["email"] = email
+	return updated
+}
+
+// IdentityEmail returns the identity's email trait and whether Kratos has
+// verified it. An address changed through settings stays unverified until
+// its verification code is submitted.
+func IdentityEmail(identity *ory.Identity) (string, bool) {
+	if identity == nil {
+		return "", false
+	}
+
+	traits, _ := identity.Traits.(map[string]interface{})
+	email, _ := traits["email"].(string)
+	if email == "" {
+		return "", false
+	}
+
+	for _, address := range identity.VerifiableAddresses {
+		if address.Via == "email" && strings.EqualFold(address.Value, email) {
+			return email, address.Verified
+		}
+	}
+	return email, false
+}
+
+// PendingVerification reports whether Kratos already sent a verification
+// message for email after a settings change, which it does when the address
+// is added, and returns the verification flow it started if the settings
+// flow names one
+func PendingVerification(flow *ory.SettingsFlow, email string) (string, bool) {
+	if flow == nil {
+		return "", false
+	}
+
+	for _, action := range flow.ContinueWith {
+		if ui := action.ContinueWithVerificationUi; ui != nil && strings.EqualFold(ui.Flow.VerifiableAddress, email) {
+			return ui.Flow.Id, true
+		}
+	}
+
+	for _, address := range flow.Identity.VerifiableAddresses {
+		if address.Via == "email" && strings.EqualFold(address.Value, email) && address.Status == "sent" {
+			return "", true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"testing"
+
+	ory "github.com/ory/client-go"
+)
+
+func TestIdentityEmail(t *testing.T) {
+	tests := []struct {
+		name         string
+		identity     *ory.Identity
+		wantEmail    string
+		wantVerified bool
+	}{
+		{name: "no identity"},
+		{
+			name: "verified",
+			identity: &ory.Identity{
+				Traits: map[string]interface{}{"email": "User@example.com"},
+				VerifiableAddresses: []ory.VerifiableIdentityAddress{
+					{Value: "user@example.com", Via: "email", Verified: true},
+				},
+			},
+			wantEmail:    "User@example.com",
+			wantVerified: true,
+		},
+		{
+			name: "changed and not yet verified",
+			identity: &ory.Identity{
+				Traits: map[string]interface{}{"email": "new@example.com"},
+				VerifiableAddresses: []ory.VerifiableIdentityAddress{
+					{Value: "old@example.com", Via: "email", Verified: true},
+					{Value: "new@example.com", Via: "email", Status: "sent"},
+				},
+			},
+			wantEmail: "new@example.com",
+		},
+		{
+			name:      "no verifiable address",
+			identity:  &ory.Identity{Traits: map[string]interface{}{"email": "user@example.com"}},
+			wantEmail: "user@example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, verified := IdentityEmail(tt.identity)
+			if email != tt.wantEmail || verified != tt.wantVerified {
+				t.Errorf("IdentityEmail() = %q, %v, want %q, %v", email, verified, tt.wantEmail, tt.wantVerified)
+			}
+		})
+	}
+}
+
+func TestBuildEmailTraits(t *testing.T) {
+	traits := map[string]interface{}{"email": "old@example.com", "name": "kept"}
+
+	updated := BuildEmailTraits(traits, "new@example.com")
+
+	if updated["email"] != "new@example.com" || updated["name"] != "kept" {
+		t.Errorf("BuildEmailTraits() = %v", updated)
+	}
+	if traits["email"] != "old@example.com" {
+		t.Error("BuildEmailTraits() modified the original traits")
+	}
+}
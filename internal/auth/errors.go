@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	ory "github.com/ory/client-go"
+)
+
+// ErrPrivilegedSessionRequired is returned when Kratos requires the user to
+// re-authenticate before changing sensitive settings (session_refresh_required)
+var ErrPrivilegedSessionRequired = errors.New("privileged session required: re-authenticate and retry")
+
+// ErrInvalidSession is returned when Kratos rejects a session token as invalid or expired
+var ErrInvalidSession = errors.New("invalid or expired session")
+
+// ErrSessionNotFound is returned when a session does not exist or belongs to someone else
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrSessionTokenRequired is returned when a cookie session starts a native
+// flow, which Kratos only starts for session tokens
+var ErrSessionTokenRequired = errors.New("session token required: cookie sessions cannot start native flows")
+
+// ErrIdentityNotFound is returned by the admin API when an identity does not exist
+var ErrIdentityNotFound = errors.New("identity not found")
+
+// ErrIdentityRejected is returned when Kratos rejects an identity, e.g. because
+// its traits fail schema validation or its identifier is already taken
+var ErrIdentityRejected = errors.New("identity rejected")
+
+// ErrAPIKeyNotFound is returned when revoking a key the identity does not have
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// ErrInvalidAPIKey is returned when an API key is unknown, expired or revoked
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// Kratos error IDs returned in GenericError.id
+const (
+	kratosErrorSessionRefreshRequired = "session_refresh_required"
+)
+
+// Kratos flow state after a one-time code has been emailed
+const flowStateSentEmail = "sent_email"
+
+// kratosErrorID extracts the GenericError id from a Kratos API error
+func kratosErrorID(err error) string {
+	var apiErr *ory.GenericOpenAPIError
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+
+	var body struct {
+		Error struct {
+			ID string `json:"id"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(apiErr.Body(), &body) != nil {
+		return ""
+	}
+	return body.Error.ID
+}
+
+// kratosErrorModel returns the decoded response body of a Kratos API error.
+// For flow updates rejected with 400 this is the flow itself.
+func kratosErrorModel(err error) interface{} {
+	var apiErr *ory.GenericOpenAPIError
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+	return apiErr.Model()
+}
+
+// Kratos UI message IDs that FlowError classifies
+const (
+	kratosMessageInvalidCredentials            = 4000006
+	kratosMessageDuplicateCredentials          = 4000007
+	kratosMessageInvalidTOTPCode               = 4000008
+	kratosMessageLookupSecretUsed              = 4000012
+	kratosMessageInvalidLookupSecret           = 4000016
+	kratosMessageInvalidLoginCode              = 4010008
+	kratosMessageDuplicateCredentialsWithHints = 4000027
+)
+
+// kratosErrorFlowExpired is the GenericError id of an expired self-service flow
+const kratosErrorFlowExpired = "self_service_flow_expired"
+
+// FlowMessage is a message Kratos rendered for a flow. Field is the node the
+// message belongs to, e.g. "traits.email", or empty for flow-level messages.
+type FlowMessage struct {
+	ID    int64
+	Field string
+	Text  string
+	Type  string
+}
+
+// FlowError is a flow submission Kratos rejected, with the messages it
+// rendered into the flow or its GenericError
+type FlowError struct {
+	Status int
+	// ID is the GenericError id, e.g. self_service_flow_expired
+	ID       string
+	Messages []FlowMessage
+	err      error
+}
+
+func (e *FlowError) Error() string {
+	if msg := e.Message(); msg != "" {
+		return fmt.Sprintf("%s (status: %d)", msg, e.Status)
+	}
+	return fmt.Sprintf("%v (status: %d)", e.err, e.Status)
+}
+
+func (e *FlowError) Unwrap() error {
+	return e.err
+}
+
+// Message returns the first flow-level message, or the first message at all
+func (e *FlowError) Message() string {
+	for _, msg := range e.Messages {
+		if msg.Field == "" {
+			return msg.Text
+		}
+	}
+	if len(e.Messages) > 0 {
+		return e.Messages[0].Text
+	}
+	return ""
+}
+
+// FieldMessages returns the messages attached to form fields
+func (e *FlowError) FieldMessages() []FlowMessage {
+	var fields []FlowMessage
+	for _, msg := range e.Messages {
+		if msg.Field != "" {
+			fields = append(fields, msg)
+		}
+	}
+	return fields
+}
+
+// Expired reports whether the flow expired and a new one must be started
+func (e *FlowError) Expired() bool {
+	return e.Status == http.StatusGone || e.ID == kratosErrorFlowExpired
+}
+
+// Conflict reports whether an identity with the same identifier exists
+func (e *FlowError) Conflict() bool {
+	return e.hasMessage(kratosMessageDuplicateCredentials, kratosMessageDuplicateCredentialsWithHints)
+}
+
+// InvalidCredentials reports whether Kratos rejected the submitted
+// credentials: a wrong password, login code, TOTP code or backup code
+func (e *FlowError) InvalidCredentials() bool {
+	return e.hasMessage(kratosMessageInvalidCredentials, kratosMessageInvalidTOTPCode,
+		kratosMessageLookupSecretUsed, kratosMessageInvalidLookupSecret, kratosMessageInvalidLoginCode)
+}
+
+func (e *FlowError) hasMessage(ids ...int64) bool {
+	for _, msg := range e.Messages {
+		for _, id := range ids {
+			if msg.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// newFlowError parses a rejected flow submission into a FlowError. It returns
+// nil when err carries neither a GenericError nor a flow with messages.
+func newFlowError(err error, resp *http.Response) *FlowError {
+	var apiErr *ory.GenericOpenAPIError
+	if !errors.As(err, &apiErr) {
+		return nil
+	}
+
+	type uiText struct {
+		ID   int64  `json:"id"`
+		Text string `json:"text"`
+		Type string `json:"type"`
+	}
+	var body struct {
+		Error *struct {
+			ID      string `json:"id"`
+			Message string `json:"message"`
+			Reason  string `json:"reason"`
+		} `json:"error"`
+		UI *struct {
+			Messages []uiText `json:"messages"`
+			Nodes    []struct {
+				Attributes struct {
+					Name string `json:"name"`
+				} `json:"attributes"`
+				Messages []uiText `json:"messages"`
+			} `json:"nodes"`
+		} `json:"ui"`
+	}
+	if json.Unmarshal(apiErr.Body(), &body) != nil {
+		return nil
+	}
+
+	flowErr := &FlowError{Status: getStatusCode(resp), err: err}
+
+	if body.Error != nil {
+		flowErr.ID = body.Error.ID
+		text := body.Error.Reason
+		if text == "" {
+			text = body.Error.Message
+		}
+		if text != "" {
+			flowErr.Messages = append(flowErr.Messages, FlowMessage{Text: text, Type: "error"})
+		}
+	}
+
+	if body.UI != nil {
+		for _, msg := range body.UI.Messages {
+			flowErr.Messages = append(flowErr.Messages, FlowMessage{ID: msg.ID, Text: msg.Text, Type: msg.Type})
+		}
+		for _, node := range body.UI.Nodes {
+			for _, msg := range node.Messages {
+				flowErr.Messages = append(flowErr.Messages, FlowMessage{ID: msg.ID, Field: node.Attributes.Name, Text: msg.Text, Type: msg.Type})
+			}
+		}
+	}
+
+	if flowErr.ID == "" && len(flowErr.Messages) == 0 {
+		return nil
+	}
+	return flowErr
+}
+
+// flowUpdateError wraps a failed flow submission, as a FlowError when Kratos
+// explained the rejection
+func flowUpdateError(action string, err error, resp *http.Response) error {
+	if flowErr := newFlowError(err, resp); flowErr != nil {
+		return fmt.Errorf("%s: %w", action, flowErr)
+	}
+	return fmt.Errorf("%s: %w (status: %d)", action, err, getStatusCode(resp))
+}
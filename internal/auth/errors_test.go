@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newFlowErrorTestClient returns a client whose Kratos answers every request
+// with status and body
+func newFlowErrorTestClient(t *testing.T, status int, body string) *KratosClient {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return NewKratosClient(server.URL, server.URL)
+}
+
+func TestFlowError(t *testing.T) {
+	tests := []struct {
+		name              string
+		status            int
+		body              string
+		wantFlowErr       bool
+		wantExpired       bool
+		wantConflict      bool
+		wantInvalidCreds  bool
+		wantMessage       string
+		wantFieldMessages int
+	}{
+		{
+			name:   "duplicate identifier",
+			status: http.StatusBadRequest,
+			body: `{"id": "flow-123", "type": "api", "state": "choose_method", "ui": {"action": "", "method": "POST", "nodes": [],
+				"messages": [{"id": 4000007, "text": "An account with the same identifier (email, phone, username, ...) exists already.", "type": "error"}]}}`,
+			wantFlowErr:  true,
+			wantConflict: true,
+			wantMessage:  "An account with the same identifier (email, phone, username, ...) exists already.",
+		},
+		{
+			name:   "password policy",
+			status: http.StatusBadRequest,
+			body: `{"id": "flow-123", "type": "api", "state": "choose_method", "ui": {"action": "", "method": "POST", "nodes": [
+				{"type": "input", "group": "password", "attributes": {"node_type": "input", "name": "password", "type": "password"},
+				 "messages": [{"id": 4000005, "text": "The password can not be used because the password is too similar to the identifier.", "type": "error"}], "meta": {}}]}}`,
+			wantFlowErr:       true,
+			wantMessage:       "The password can not be used because the password is too similar to the identifier.",
+			wantFieldMessages: 1,
+		},
+		{
+			name:   "invalid credentials",
+			status: http.StatusBadRequest,
+			body: `{"id": "flow-123", "type": "api", "state": "choose_method", "ui": {"action": "", "method": "POST", "nodes": [],
+				"messages": [{"id": 4000006, "text": "The provided credentials are invalid, check for spelling mistakes in your password or username, email address, or phone number.", "type": "error"}]}}`,
+			wantFlowErr:      true,
+			wantInvalidCreds: true,
+			wantMessage:      "The provided credentials are invalid, check for spelling mistakes in your password or username, email address, or phone number.",
+		},
+		{
+			name:        "flow expired",
+			status:      http.StatusGone,
+			body:        `{"error": {"id": "self_service_flow_expired", "code": 410, "status": "Gone", "message": "self-service flow expired", "reason": "The self-service flow expired 5.00 minutes ago, initialize a new one."}}`,
+			wantFlowErr: true,
+			wantExpired: true,
+			wantMessage: "The self-service flow expired 5.00 minutes ago, initialize a new one.",
+		},
+		{
+			name:   "unexplained failure",
+			status: http.StatusInternalServerError,
+			body:   `not json`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFlowErrorTestClient(t, tt.status, tt.body)
+
+			_, err := client.UpdateRegistrationFlow(context.Background(), "flow-123", BuildPasswordRegistrationBody("a@example.com", "password123", "A", "B"))
+			if err == nil {
+				t.Fatal("UpdateRegistrationFlow() expected error, got nil")
+			}
+
+			var flowErr *FlowError
+			if !errors.As(err, &flowErr) {
+				if tt.wantFlowErr {
+					t.Fatalf("UpdateRegistrationFlow() error = %v, want a FlowError", err)
+				}
+				return
+			}
+			if !tt.wantFlowErr {
+				t.Fatalf("UpdateRegistrationFlow() error = %v, want no FlowError", err)
+			}
+
+			if flowErr.Status != tt.status {
+				t.Errorf("Status = %d, want %d", flowErr.Status, tt.status)
+			}
+			if flowErr.Expired() != tt.wantExpired || flowErr.Conflict() != tt.wantConflict || flowErr.InvalidCredentials() != tt.wantInvalidCreds {
+				t.Errorf("Expired() = %v, Conflict() = %v, InvalidCredentials() = %v", flowErr.Expired(), flowErr.Conflict(), flowErr.InvalidCredentials())
+			}
+			if flowErr.Message() != tt.wantMessage {
+				t.Errorf("Message() = %q, want %q", flowErr.Message(), tt.wantMessage)
+			}
+			if got := flowErr.FieldMessages(); len(got) != tt.wantFieldMessages {
+				t.Errorf("FieldMessages() = %+v, want %d", got, tt.wantFieldMessages)
+			} else if len(got) > 0 && got[0].Field != "password" {
+				t.Errorf("FieldMessages()[0].Field = %q, want %q", got[0].Field, "password")
+			}
+		})
+	}
+}
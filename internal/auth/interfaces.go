@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"time"
 
 	ory "github.com/ory/client-go"
 )
@@ -14,13 +15,18 @@ type SessionValidator interface {
 // LoginFlowManager manages login flows
 type LoginFlowManager interface {
 	CreateLoginFlow(ctx context.Context) (*ory.LoginFlow, error)
-	UpdateLoginFlow(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (*ory.SuccessfulNativeLogin, error)
+	CreateRefreshLoginFlow(ctx context.Context, sessionToken string) (*ory.LoginFlow, error)
+	CreateAAL2LoginFlow(ctx context.Context, sessionToken string) (*ory.LoginFlow, error)
+	UpdateLoginFlow(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error)
+	SendLoginCode(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (*ory.LoginFlow, error)
 }
 
 // RegistrationFlowManager manages registration flows
 type RegistrationFlowManager interface {
 	CreateRegistrationFlow(ctx context.Context) (*ory.RegistrationFlow, error)
 	UpdateRegistrationFlow(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.SuccessfulNativeRegistration, error)
+	SendRegistrationCode(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.RegistrationFlow, error)
+	PreparePasskeyRegistration(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.RegistrationFlow, error)
 }
 
 // LogoutFlowManager manages logout flows
@@ -29,6 +35,18 @@ type LogoutFlowManager interface {
 	PerformNativeLogout(ctx context.Context, sessionToken ory.PerformNativeLogoutBody) error
 }
 
+// BrowserFlowManager manages browser flows, which keep the session in the
+// ory_kratos_session cookie instead of returning a session token. Cookies
+// passed in are the client's Cookie header; cookies returned are Set-Cookie
+// headers to relay to the client.
+type BrowserFlowManager interface {
+	CreateBrowserLoginFlow(ctx context.Context, cookies string) (*ory.LoginFlow, []string, error)
+	UpdateBrowserLoginFlow(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, cookies string) (*ory.SuccessfulNativeLogin, []string, error)
+	CreateBrowserRegistrationFlow(ctx context.Context, cookies string) (*ory.RegistrationFlow, []string, error)
+	UpdateBrowserRegistrationFlow(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody, cookies string) (*ory.SuccessfulNativeRegistration, []string, error)
+	PerformBrowserLogout(ctx context.Context, logoutToken, cookie string) ([]string, error)
+}
+
 // VerificationFlowManager manages email verification flows
 type VerificationFlowManager interface {
 	CreateVerificationFlow(ctx context.Context) (*ory.VerificationFlow, error)
@@ -43,17 +61,71 @@ type RecoveryFlowManager interface {
 
 // SettingsFlowManager manages user settings flows
 type SettingsFlowManager interface {
-	CreateSettingsFlow(ctx context.Context) (*ory.SettingsFlow, error)
+	CreateSettingsFlow(ctx context.Context, sessionToken string) (*ory.SettingsFlow, error)
 	UpdateSettingsFlow(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error)
 }
 
+// OIDCFlowManager manages social sign-in through native flows
+type OIDCFlowManager interface {
+	CreateOIDCLoginFlow(ctx context.Context, returnTo string) (*ory.LoginFlow, error)
+	StartOIDCLogin(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (string, error)
+	ExchangeSessionToken(ctx context.Context, initCode, returnToCode string) (*ory.SuccessfulNativeLogin, error)
+}
+
+// SessionManager lists and revokes the caller's sessions
+type SessionManager interface {
+	ListMySessions(ctx context.Context, sessionToken string) ([]ory.Session, error)
+	RevokeMySession(ctx context.Context, sessionToken, sessionID string) error
+	RevokeMyOtherSessions(ctx context.Context, sessionToken string) (int64, error)
+}
+
 // KratosService combines all auth operations
 type KratosService interface {
 	SessionValidator
 	LoginFlowManager
 	RegistrationFlowManager
 	LogoutFlowManager
+	BrowserFlowManager
 	VerificationFlowManager
 	RecoveryFlowManager
 	SettingsFlowManager
+	OIDCFlowManager
+	SessionManager
+}
+
+// AdminService manages identities through the Kratos admin API
+type AdminService interface {
+	ListIdentities(ctx context.Context, opts IdentityListOptions) (*IdentityPage, error)
+	GetIdentity(ctx context.Context, id string) (*ory.Identity, error)
+	CreateIdentity(ctx context.Context, body ory.CreateIdentityBody) (*ory.Identity, error)
+	PatchIdentity(ctx context.Context, id string, patches []ory.JsonPatch) (*ory.Identity, error)
+	DeleteIdentity(ctx context.Context, id string) error
+	RevokeIdentitySessions(ctx context.Context, id string) error
+}
+
+// APIKeyManager manages an identity's API keys
+type APIKeyManager interface {
+	CreateAPIKey(ctx context.Context, identityID string, opts APIKeyOptions) (*APIKey, string, error)
+	ListAPIKeys(ctx context.Context, identityID string) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, identityID, keyID string) error
+}
+
+// APIKeyValidator resolves API keys to a session for the identity that owns them
+type APIKeyValidator interface {
+	ValidateAPIKey(ctx context.Context, key string) (*APIKey, *ory.Session, error)
+}
+
+// SessionTokenizer exchanges a validated session for a signed JWT
+type SessionTokenizer interface {
+	TokenizeSession(ctx context.Context, sessionToken string, session *ory.Session) (string, time.Time, error)
+}
+
+// UserDataStore holds application data about identities outside the Kratos
+// identity itself. Account export includes it and account deletion purges it.
+type UserDataStore interface {
+	// ExportUserData returns the identity's data, or nil if there is none
+	ExportUserData(ctx context.Context, identityID string) (interface{}, error)
+	// PurgeUserData deletes the identity's data. It runs after the identity
+	// has been deleted.
+	PurgeUserData(ctx context.Context, identityID string) error
 }
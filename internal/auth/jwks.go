@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// jwtKeyBits is the size of generated RSA signing keys
+const jwtKeyBits = 2048
+
+// JWK is a JSON Web Key. Only RSA keys are supported.
+type JWK struct {
+	KeyID     string `json:"kid"`
+	KeyType   string `json:"kty"`
+	Algorithm string `json:"alg,omitempty"`
+	Use       string `json:"use,omitempty"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+
+	// Private key members, never published
+	D  string `json:"d,omitempty"`
+	P  string `json:"p,omitempty"`
+	Q  string `json:"q,omitempty"`
+	DP string `json:"dp,omitempty"`
+	DQ string `json:"dq,omitempty"`
+	QI string `json:"qi,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWTKeySet holds the RSA keys used to sign and verify JWTs. The first key
+// signs; every key verifies, so retired keys can stay in the set while
+// tokens signed with them expire.
+type JWTKeySet struct {
+	keys []jwtKey
+}
+
+type jwtKey struct {
+	id      string
+	private *rsa.PrivateKey
+}
+
+// LoadJWTKeySet reads a JWKS file with private RSA keys, the same format
+// Kratos' session tokenizer takes as jwks_url
+func LoadJWTKeySet(path string) (*JWTKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT keys: %w", err)
+	}
+
+	var jwks JWKS
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to parse JWT keys: %w", err)
+	}
+
+	if len(jwks.Keys) == 0 {
+		return nil, errors.New("failed to parse JWT keys: no keys in set")
+	}
+
+	set := &JWTKeySet{}
+	for i, jwk := range jwks.Keys {
+		private, err := jwk.rsaPrivateKey()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JWT key %d: %w", i, err)
+		}
+		set.keys = append(set.keys, jwtKey{id: jwk.KeyID, private: private})
+	}
+
+	return set, nil
+}
+
+// GenerateJWTKeySet creates a set with one random key. Tokens signed with it
+// stop verifying when the process restarts, so it suits development only.
+func GenerateJWTKeySet() (*JWTKeySet, error) {
+	private, err := rsa.GenerateKey(rand.Reader, jwtKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JWT key: %w", err)
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate JWT key: %w", err)
+	}
+
+	return &JWTKeySet{keys: []jwtKey{{id: id, private: private}}}, nil
+}
+
+// PublicJWKS returns the public keys for the JWKS endpoint
+func (s *JWTKeySet) PublicJWKS() JWKS {
+	jwks := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		jwks.Keys = append(jwks.Keys, JWK{
+			KeyID:     key.id,
+			KeyType:   "RSA",
+			Algorithm: jwtAlgorithm,
+			Use:       "sig",
+			N:         encodeBigInt(key.private.N),
+			E:         encodeBigInt(big.NewInt(int64(key.private.E))),
+		})
+	}
+	return jwks
+}
+
+// signingKey returns the key new tokens are signed with
+func (s *JWTKeySet) signingKey() jwtKey {
+	return s.keys[0]
+}
+
+// publicKey finds the key for kid. An empty kid matches only a single-key set.
+func (s *JWTKeySet) publicKey(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		return &s.keys[0].private.PublicKey, true
+	}
+	for _, key := range s.keys {
+		if key.id == kid {
+			return &key.private.PublicKey, true
+		}
+	}
+	return nil, false
+}
+
+// rsaPrivateKey decodes an RSA private JWK
+func (k JWK) rsaPrivateKey() (*rsa.PrivateKey, error) {
+	if k.KeyType != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+	if k.Algorithm != "" && k.Algorithm != jwtAlgorithm {
+		return nil, fmt.Errorf("unsupported algorithm %q", k.Algorithm)
+	}
+	if k.D == "" || k.P == "" || k.Q == "" {
+		return nil, errors.New("private key members d, p and q are required")
+	}
+
+	values := make(map[string]*big.Int, 5)
+	for name, encoded := range map[string]string{"n": k.N, "e": k.E, "d": k.D, "p": k.P, "q": k.Q} {
+		value, err := decodeBigInt(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		values[name] = value
+	}
+
+	if !values["e"].IsInt64() {
+		return nil, errors.New("invalid e: too large")
+	}
+
+	private := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: values["n"], E: int(values["e"].Int64())},
+		D:         values["d"],
+		Primes:    []*big.Int{values["p"], values["q"]},
+	}
+	if err := private.Validate(); err != nil {
+		return nil, err
+	}
+	private.Precompute()
+
+	return private, nil
+}
+
+func encodeBigInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func decodeBigInt(encoded string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// privateJWKS encodes a key set with its private members, as LoadJWTKeySet reads it
+func privateJWKS(t *testing.T, set *JWTKeySet) []byte {
+	t.Helper()
+
+	jwks := set.PublicJWKS()
+	for i, key := range set.keys {
+		jwks.Keys[i].D = encodeBigInt(key.private.D)
+		jwks.Keys[i].P = encodeBigInt(key.private.Primes[0])
+		jwks.Keys[i].Q = encodeBigInt(key.private.Primes[1])
+	}
+
+	data, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatalf("failed to encode JWKS: %v", err)
+	}
+	return data
+}
+
+func TestLoadJWTKeySet(t *testing.T) {
+	generated, err := GenerateJWTKeySet()
+	if err != nil {
+		t.Fatalf("GenerateJWTKeySet() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jwks.json")
+	if err := os.WriteFile(path, privateJWKS(t, generated), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadJWTKeySet(path)
+	if err != nil {
+		t.Fatalf("LoadJWTKeySet() error = %v", err)
+	}
+
+	if loaded.signingKey().id != generated.signingKey().id {
+		t.Errorf("LoadJWTKeySet() kid = %q, want %q", loaded.signingKey().id, generated.signingKey().id)
+	}
+	if loaded.signingKey().private.N.Cmp(generated.signingKey().private.N) != 0 {
+		t.Error("LoadJWTKeySet() loaded a different key")
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "not json", data: "keys"},
+		{name: "empty set", data: `{"keys": []}`},
+		{name: "public key only", data: `{"keys": [{"kid": "a", "kty": "RSA", "n": "AQAB", "e": "AQAB"}]}`},
+		{name: "unsupported key type", data: `{"keys": [{"kid": "a", "kty": "EC"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := LoadJWTKeySet(path); err == nil {
+				t.Error("LoadJWTKeySet() expected error, got nil")
+			}
+		})
+	}
+
+	if _, err := LoadJWTKeySet(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadJWTKeySet() expected error for a missing file, got nil")
+	}
+}
+
+func TestJWTKeySet_PublicJWKS(t *testing.T) {
+	set, err := GenerateJWTKeySet()
+	if err != nil {
+		t.Fatalf("GenerateJWTKeySet() error = %v", err)
+	}
+
+	data, err := json.Marshal(set.PublicJWKS())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, member := range []string{`"d"`, `"p"`, `"q"`, `"dp"`, `"dq"`, `"qi"`} {
+		if strings.Contains(string(data), member) {
+			t.Errorf("PublicJWKS() publishes private member %s", member)
+		}
+	}
+
+	jwks := set.PublicJWKS()
+	if len(jwks.Keys) != 1 {
+		t.Fatalf("PublicJWKS() has %d keys, want 1", len(jwks.Keys))
+	}
+
+	key := jwks.Keys[0]
+	if key.KeyType != "RSA" || key.Algorithm != jwtAlgorithm || key.Use != "sig" || key.KeyID == "" {
+		t.Errorf("PublicJWKS() key = %+v", key)
+	}
+
+	n, err := decodeBigInt(key.N)
+	if err != nil || n.Cmp(set.signingKey().private.N) != 0 {
+		t.Error("PublicJWKS() modulus does not match the signing key")
+	}
+	if e, _ := decodeBigInt(key.E); e.Cmp(big.NewInt(65537)) != 0 {
+		t.Errorf("PublicJWKS() exponent = %v, want 65537", e)
+	}
+}
@@ -0,0 +1,375 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	ory "github.com/ory/client-go"
+)
+
+// jwtAlgorithm is the only signing algorithm issued and accepted
+const jwtAlgorithm = "RS256"
+
+// jwtClockSkew is the leeway allowed on nbf and iat
+const jwtClockSkew = 30 * time.Second
+
+// AuthMethodJWT is the authentication method of sessions resolved from JWTs
+const AuthMethodJWT = "jwt"
+
+// ErrInvalidJWT is returned when a JWT is malformed, expired, or fails
+// signature, issuer or audience checks
+var ErrInvalidJWT = errors.New("invalid JWT")
+
+// Ensure the tokenizers implement SessionTokenizer and JWTSessionValidator
+// implements SessionValidator
+var (
+	_ SessionTokenizer = (*JWTSigner)(nil)
+	_ SessionTokenizer = (*KratosTokenizer)(nil)
+	_ SessionValidator = (*JWTSessionValidator)(nil)
+)
+
+// JWTClaims are the claims of a session JWT
+type JWTClaims struct {
+	Issuer    string   `json:"iss,omitempty"`
+	Subject   string   `json:"sub"`
+	Audience  Audience `json:"aud,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+	ID        string   `json:"jti,omitempty"`
+
+	// SessionID is the Kratos session the token was issued for
+	SessionID      string                 `json:"sid,omitempty"`
+	AAL            string                 `json:"aal,omitempty"`
+	Email          string                 `json:"email,omitempty"`
+	EmailVerified  bool                   `json:"email_verified,omitempty"`
+	MetadataPublic map[string]interface{} `json:"metadata_public,omitempty"`
+}
+
+// Audience is the aud claim, which may be a string or a list of strings
+type Audience []string
+
+// UnmarshalJSON accepts a single string or a list
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = Audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// Contains reports whether aud lists audience
+func (a Audience) Contains(audience string) bool {
+	for _, aud := range a {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// JWTSigner issues JWTs for sessions with a local key set
+type JWTSigner struct {
+	keys     *JWTKeySet
+	issuer   string
+	audience string
+	ttl      time.Duration
+	now      func() time.Time
+}
+
+// NewJWTSigner creates a signer. Tokens live for ttl, but never longer than
+// the session they were issued for. An empty audience omits the aud claim.
+func NewJWTSigner(keys *JWTKeySet, issuer, audience string, ttl time.Duration) *JWTSigner {
+	return &JWTSigner{keys: keys, issuer: issuer, audience: audience, ttl: ttl, now: time.Now}
+}
+
+// TokenizeSession signs a JWT for a validated session
+func (s *JWTSigner) TokenizeSession(ctx context.Context, sessionToken string, session *ory.Session) (string, time.Time, error) {
+	if session == nil || session.Identity == nil {
+		return "", time.Time{}, errors.New("failed to tokenize session: session has no identity")
+	}
+
+	now := s.now()
+	expires := now.Add(s.ttl)
+	if session.ExpiresAt != nil && session.ExpiresAt.Before(expires) {
+		expires = *session.ExpiresAt
+	}
+
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to tokenize session: %w", err)
+	}
+
+	claims := JWTClaims{
+		Issuer:         s.issuer,
+		Subject:        session.Identity.Id,
+		IssuedAt:       now.Unix(),
+		NotBefore:      now.Unix(),
+		ExpiresAt:      expires.Unix(),
+		ID:             jti,
+		SessionID:      session.Id,
+		MetadataPublic: session.Identity.MetadataPublic,
+	}
+	if s.audience != "" {
+		claims.Audience = Audience{s.audience}
+	}
+	if session.AuthenticatorAssuranceLevel != nil {
+		claims.AAL = string(*session.AuthenticatorAssuranceLevel)
+	}
+	claims.Email, claims.EmailVerified = IdentityEmail(session.Identity)
+
+	token, err := signJWT(s.keys.signingKey(), claims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to tokenize session: %w", err)
+	}
+
+	return token, time.Unix(claims.ExpiresAt, 0), nil
+}
+
+// KratosTokenizer has Kratos issue session JWTs through the tokenize_as
+// parameter of /sessions/whoami. The template and its signing keys are
+// configured in Kratos under session.whoami.tokenizer.templates.
+type KratosTokenizer struct {
+	kratos   *KratosClient
+	template string
+}
+
+// NewKratosTokenizer creates a tokenizer for a Kratos tokenizer template
+func NewKratosTokenizer(kratos *KratosClient, template string) *KratosTokenizer {
+	return &KratosTokenizer{kratos: kratos, template: template}
+}
+
+// TokenizeSession asks Kratos to tokenize the session behind sessionToken
+func (t *KratosTokenizer) TokenizeSession(ctx context.Context, sessionToken string, session *ory.Session) (string, time.Time, error) {
+	req := t.kratos.frontend.FrontendAPI.ToSession(ctx).TokenizeAs(t.template)
+	if IsSessionCookie(sessionToken) {
+		req = req.Cookie(sessionToken)
+	} else {
+		req = req.XSessionToken(sessionToken)
+	}
+
+	tokenized, resp, err := req.Execute()
+
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return "", time.Time{}, fmt.Errorf("failed to tokenize session: %w", ErrInvalidSession)
+		}
+		return "", time.Time{}, fmt.Errorf("failed to tokenize session: %w (status: %d)", err, getStatusCode(resp))
+	}
+
+	token := tokenized.GetTokenized()
+	if token == "" {
+		return "", time.Time{}, errors.New("failed to tokenize session: Kratos returned no token")
+	}
+
+	claims, err := decodeJWTClaims(token)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to tokenize session: %w", err)
+	}
+
+	return token, time.Unix(claims.ExpiresAt, 0), nil
+}
+
+// JWTVerifier validates session JWTs against a key set
+type JWTVerifier struct {
+	keys     *JWTKeySet
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// NewJWTVerifier creates a verifier. Empty issuer or audience skip that check.
+func NewJWTVerifier(keys *JWTKeySet, issuer, audience string) *JWTVerifier {
+	return &JWTVerifier{keys: keys, issuer: issuer, audience: audience, now: time.Now}
+}
+
+// Verify checks the token's signature and claims
+func (v *JWTVerifier) Verify(token string) (*JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidJWT)
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidJWT)
+	}
+
+	if header.Algorithm != jwtAlgorithm {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidJWT, header.Algorithm)
+	}
+
+	key, ok := v.keys.publicKey(header.KeyID)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidJWT, header.KeyID)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidJWT)
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidJWT)
+	}
+
+	var claims JWTClaims
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidJWT)
+	}
+
+	now := v.now()
+	switch {
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidJWT)
+	case claims.ExpiresAt == 0 || !now.Before(time.Unix(claims.ExpiresAt, 0)):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidJWT)
+	case claims.NotBefore != 0 && now.Add(jwtClockSkew).Before(time.Unix(claims.NotBefore, 0)):
+		return nil, fmt.Errorf("%w: not valid yet", ErrInvalidJWT)
+	case v.issuer != "" && claims.Issuer != v.issuer:
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidJWT, claims.Issuer)
+	case v.audience != "" && !claims.Audience.Contains(v.audience):
+		return nil, fmt.Errorf("%w: audience does not include %q", ErrInvalidJWT, v.audience)
+	}
+
+	return &claims, nil
+}
+
+// JWTSessionValidator validates JWTs locally and hands every other token to
+// the fallback validator. With a nil fallback only JWTs are accepted.
+type JWTSessionValidator struct {
+	verifier *JWTVerifier
+	fallback SessionValidator
+}
+
+// NewJWTSessionValidator creates a validator for AuthMiddleware that accepts JWTs
+func NewJWTSessionValidator(verifier *JWTVerifier, fallback SessionValidator) *JWTSessionValidator {
+	return &JWTSessionValidator{verifier: verifier, fallback: fallback}
+}
+
+// ValidateSession verifies a JWT and returns the session it describes, or
+// delegates opaque session tokens to the fallback
+func (v *JWTSessionValidator) ValidateSession(ctx context.Context, sessionToken string) (*ory.Session, error) {
+	if !IsJWT(sessionToken) {
+		if v.fallback == nil {
+			return nil, fmt.Errorf("%w: malformed token", ErrInvalidJWT)
+		}
+		return v.fallback.ValidateSession(ctx, sessionToken)
+	}
+
+	claims, err := v.verifier.Verify(sessionToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims.Session(), nil
+}
+
+// Session builds the synthetic session a JWT authenticates as
+func (c *JWTClaims) Session() *ory.Session {
+	active := true
+	expires := time.Unix(c.ExpiresAt, 0)
+
+	session := &ory.Session{
+		Id:        c.SessionID,
+		Active:    &active,
+		ExpiresAt: &expires,
+		AuthenticationMethods: []ory.SessionAuthenticationMethod{
+			{Method: ory.PtrString(AuthMethodJWT)},
+		},
+		Identity: &ory.Identity{
+			Id:             c.Subject,
+			MetadataPublic: c.MetadataPublic,
+		},
+	}
+
+	if c.IssuedAt != 0 {
+		issued := time.Unix(c.IssuedAt, 0)
+		session.AuthenticatedAt = &issued
+	}
+	if c.AAL != "" {
+		aal := ory.AuthenticatorAssuranceLevel(c.AAL)
+		session.AuthenticatorAssuranceLevel = &aal
+	}
+	if c.Email != "" {
+		session.Identity.Traits = map[string]interface{}{"email": c.Email}
+		if c.EmailVerified {
+			session.Identity.VerifiableAddresses = []ory.VerifiableIdentityAddress{
+				{Value: c.Email, Via: "email", Status: "completed", Verified: true},
+			}
+		}
+	}
+
+	return session
+}
+
+// IsJWT reports whether token has the three segments of a compact JWS
+func IsJWT(token string) bool {
+	return !IsSessionCookie(token) && strings.Count(token, ".") == 2
+}
+
+// signJWT signs claims with key as a compact RS256 JWS
+func signJWT(key jwtKey, claims JWTClaims) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": jwtAlgorithm, "typ": "JWT", "kid": key.id})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key.private, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// decodeJWTClaims reads the claims of a token without verifying it
+func decodeJWTClaims(token string) (*JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidJWT)
+	}
+
+	var claims JWTClaims
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidJWT)
+	}
+	return &claims, nil
+}
+
+func decodeJWTSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
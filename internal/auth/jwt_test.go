@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ory "github.com/ory/client-go"
+)
+
+// testJWTKeys is shared so the suite generates one RSA key per set
+var testJWTKeys, otherJWTKeys = mustGenerateJWTKeySet(), mustGenerateJWTKeySet()
+
+func mustGenerateJWTKeySet() *JWTKeySet {
+	set, err := GenerateJWTKeySet()
+	if err != nil {
+		panic(err)
+	}
+	return set
+}
+
+func jwtTestSession(expiresAt time.Time) *ory.Session {
+	aal := ory.AUTHENTICATORASSURANCELEVEL_AAL2
+	return &ory.Session{
+		Id:                          "session-123",
+		ExpiresAt:                   &expiresAt,
+		AuthenticatorAssuranceLevel: &aal,
+		Identity: &ory.Identity{
+			Id:             "identity-123",
+			Traits:         map[string]interface{}{"email": "user@example.com"},
+			MetadataPublic: map[string]interface{}{"roles": []interface{}{"admin"}},
+			VerifiableAddresses: []ory.VerifiableIdentityAddress{
+				{Value: "user@example.com", Via: "email", Status: "completed", Verified: true},
+			},
+		},
+	}
+}
+
+func TestJWTSigner_RoundTrip(t *testing.T) {
+	now := time.Now()
+	signer := NewJWTSigner(testJWTKeys, "issuer", "reports", 15*time.Minute)
+	signer.now = func() time.Time { return now }
+
+	token, expiresAt, err := signer.TokenizeSession(context.Background(), "token123", jwtTestSession(now.Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("TokenizeSession() error = %v", err)
+	}
+
+	if want := now.Add(15 * time.Minute).Unix(); expiresAt.Unix() != want {
+		t.Errorf("TokenizeSession() expires at %d, want %d", expiresAt.Unix(), want)
+	}
+
+	claims, err := NewJWTVerifier(testJWTKeys, "issuer", "reports").Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if claims.Subject != "identity-123" || claims.SessionID != "session-123" ||
+		claims.Email != "user@example.com" || !claims.EmailVerified || claims.AAL != "aal2" || claims.ID == "" {
+		t.Errorf("Verify() claims = %+v", claims)
+	}
+
+	session := claims.Session()
+	if session.Identity.Id != "identity-123" || session.GetAuthenticatorAssuranceLevel() != ory.AUTHENTICATORASSURANCELEVEL_AAL2 {
+		t.Errorf("Session() = %+v", session)
+	}
+	if email, verified := IdentityEmail(session.Identity); email != "user@example.com" || !verified {
+		t.Errorf("Session() email = %q, verified = %v", email, verified)
+	}
+	if roles, _ := session.Identity.MetadataPublic["roles"].([]interface{}); len(roles) != 1 {
+		t.Errorf("Session() metadata_public = %v", session.Identity.MetadataPublic)
+	}
+}
+
+func TestJWTSigner_CapsExpiryAtSession(t *testing.T) {
+	now := time.Now()
+	signer := NewJWTSigner(testJWTKeys, "issuer", "", 15*time.Minute)
+	signer.now = func() time.Time { return now }
+
+	sessionExpiry := now.Add(time.Minute)
+	_, expiresAt, err := signer.TokenizeSession(context.Background(), "token123", jwtTestSession(sessionExpiry))
+	if err != nil {
+		t.Fatalf("TokenizeSession() error = %v", err)
+	}
+
+	if expiresAt.Unix() != sessionExpiry.Unix() {
+		t.Errorf("TokenizeSession() expires at %v, want the session expiry %v", expiresAt, sessionExpiry)
+	}
+}
+
+func TestJWTVerifier_Verify(t *testing.T) {
+	now := time.Now()
+	signer := NewJWTSigner(testJWTKeys, "issuer", "reports", 15*time.Minute)
+	signer.now = func() time.Time { return now }
+	token, _, err := signer.TokenizeSession(context.Background(), "token123", jwtTestSession(now.Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("TokenizeSession() error = %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + parts[1] + "."
+
+	tests := []struct {
+		name     string
+		token    string
+		keys     *JWTKeySet
+		issuer   string
+		audience string
+		at       time.Time
+		wantErr  bool
+	}{
+		{name: "valid", token: token, keys: testJWTKeys, issuer: "issuer", audience: "reports", at: now},
+		{name: "no issuer or audience checks", token: token, keys: testJWTKeys, at: now},
+		{name: "expired", token: token, keys: testJWTKeys, at: now.Add(15 * time.Minute), wantErr: true},
+		{name: "not valid yet", token: token, keys: testJWTKeys, at: now.Add(-time.Minute), wantErr: true},
+		{name: "wrong key", token: token, keys: otherJWTKeys, at: now, wantErr: true},
+		{name: "wrong issuer", token: token, keys: testJWTKeys, issuer: "other", at: now, wantErr: true},
+		{name: "wrong audience", token: token, keys: testJWTKeys, audience: "billing", at: now, wantErr: true},
+		{name: "alg none", token: unsigned, keys: testJWTKeys, at: now, wantErr: true},
+		{name: "tampered claims", token: parts[0] + "." + parts[1] + "e30." + parts[2], keys: testJWTKeys, at: now, wantErr: true},
+		{name: "malformed", token: "not-a-jwt", keys: testJWTKeys, at: now, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewJWTVerifier(tt.keys, tt.issuer, tt.audience)
+			verifier.now = func() time.Time { return tt.at }
+
+			_, err := verifier.Verify(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidJWT) {
+					t.Errorf("Verify() error = %v, want ErrInvalidJWT", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Verify() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestKratosTokenizer_VerifiesLocally(t *testing.T) {
+	now := time.Now()
+
+	// Kratos signs with the template's jwks_url, which holds the same keys as
+	// JWT_KEYS_FILE, and sets iss to its public URL
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sessions/whoami" || r.URL.Query().Get("tokenize_as") != "downstream" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Session-Token") != "token123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		token, err := signJWT(testJWTKeys.signingKey(), JWTClaims{
+			Issuer:    server.URL,
+			Subject:   "identity-123",
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			ExpiresAt: now.Add(10 * time.Minute).Unix(),
+			ID:        "jti-123",
+			SessionID: "session-123",
+		})
+		if err != nil {
+			t.Errorf("signJWT() error = %v", err)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "session-123", "tokenized": token})
+	}))
+	defer server.Close()
+
+	tokenizer := NewKratosTokenizer(NewKratosClient(server.URL, server.URL), "downstream")
+	token, expiresAt, err := tokenizer.TokenizeSession(context.Background(), "token123", jwtTestSession(now.Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("TokenizeSession() error = %v", err)
+	}
+	if want := now.Add(10 * time.Minute).Unix(); expiresAt.Unix() != want {
+		t.Errorf("TokenizeSession() expires at %d, want %d", expiresAt.Unix(), want)
+	}
+
+	claims, err := NewJWTVerifier(testJWTKeys, server.URL, "").Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() of a Kratos-signed token error = %v", err)
+	}
+	if claims.Subject != "identity-123" || claims.SessionID != "session-123" {
+		t.Errorf("Verify() claims = %+v", claims)
+	}
+
+	if _, err := NewJWTVerifier(otherJWTKeys, server.URL, "").Verify(token); !errors.Is(err, ErrInvalidJWT) {
+		t.Errorf("Verify() with keys Kratos does not use error = %v, want ErrInvalidJWT", err)
+	}
+}
+
+func TestJWTSessionValidator_ValidateSession(t *testing.T) {
+	signer := NewJWTSigner(testJWTKeys, "issuer", "", 15*time.Minute)
+	token, _, err := signer.TokenizeSession(context.Background(), "token123", jwtTestSession(time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("TokenizeSession() error = %v", err)
+	}
+
+	kratos := newFakeKratosService()
+	kratos.sessions["ory_st_opaque"] = testSession("session-kratos", "identity-kratos")
+	verifier := NewJWTVerifier(testJWTKeys, "issuer", "")
+	validator := NewJWTSessionValidator(verifier, kratos)
+	ctx := context.Background()
+
+	session, err := validator.ValidateSession(ctx, token)
+	if err != nil {
+		t.Fatalf("ValidateSession(jwt) error = %v", err)
+	}
+	if session.Identity.Id != "identity-123" || len(kratos.calls) != 0 {
+		t.Errorf("ValidateSession(jwt) = %+v, Kratos calls = %v", session, kratos.calls)
+	}
+
+	session, err = validator.ValidateSession(ctx, "ory_st_opaque")
+	if err != nil {
+		t.Fatalf("ValidateSession(session token) error = %v", err)
+	}
+	if session.Id != "session-kratos" {
+		t.Errorf("ValidateSession(session token) = %+v, want the Kratos session", session)
+	}
+
+	if _, err := NewJWTSessionValidator(verifier, nil).ValidateSession(ctx, "ory_st_opaque"); !errors.Is(err, ErrInvalidJWT) {
+		t.Errorf("ValidateSession() without fallback error = %v, want ErrInvalidJWT", err)
+	}
+}
+
+func TestAudience_UnmarshalJSON(t *testing.T) {
+	var single, list Audience
+	if err := single.UnmarshalJSON([]byte(`"reports"`)); err != nil || !single.Contains("reports") {
+		t.Errorf("UnmarshalJSON(string) = %v, %v", single, err)
+	}
+	if err := list.UnmarshalJSON([]byte(`["billing", "reports"]`)); err != nil || !list.Contains("reports") {
+		t.Errorf("UnmarshalJSON(list) = %v, %v", list, err)
+	}
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -23,6 +24,7 @@ func NewKratosClient(publicURL, adminURL string) *KratosClient {
 	frontendConfig.Servers = ory.ServerConfigurations{
 		{URL: publicURL},
 	}
+	frontendConfig.HTTPClient = &http.Client{Transport: cookieTransport{base: http.DefaultTransport}}
 
 	adminConfig := ory.NewConfiguration()
 	adminConfig.Servers = ory.ServerConfigurations{
@@ -35,19 +37,25 @@ func NewKratosClient(publicURL, adminURL string) *KratosClient {
 	}
 }
 
-// ValidateSession validates a session token and returns the session
+// ValidateSession validates a session token, or a session cookie credential
+// from SessionCookieCredential, and returns the session
 func (k *KratosClient) ValidateSession(ctx context.Context, sessionToken string) (*ory.Session, error) {
 	if sessionToken == "" {
 		return nil, fmt.Errorf("session token is required")
 	}
 
-	session, resp, err := k.frontend.FrontendAPI.ToSession(ctx).
-		XSessionToken(sessionToken).
-		Execute()
+	req := k.frontend.FrontendAPI.ToSession(ctx)
+	if IsSessionCookie(sessionToken) {
+		req = req.Cookie(sessionToken)
+	} else {
+		req = req.XSessionToken(sessionToken)
+	}
+
+	session, resp, err := req.Execute()
 
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
-			return nil, fmt.Errorf("unauthorized: invalid or expired session")
+			return nil, fmt.Errorf("unauthorized: %w", ErrInvalidSession)
 		}
 		return nil, fmt.Errorf("failed to validate session: %w", err)
 	}
@@ -64,15 +72,123 @@ func (k *KratosClient) CreateLoginFlow(ctx context.Context) (*ory.LoginFlow, err
 	return flow, nil
 }
 
-// UpdateLoginFlow submits login credentials
-func (k *KratosClient) UpdateLoginFlow(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (*ory.SuccessfulNativeLogin, error) {
-	result, resp, err := k.frontend.FrontendAPI.UpdateLoginFlow(ctx).
+// CreateRefreshLoginFlow creates a native login flow that re-authenticates an
+// existing session, granting it a fresh privileged session window. Only
+// session tokens can start it; cookie sessions get ErrSessionTokenRequired.
+func (k *KratosClient) CreateRefreshLoginFlow(ctx context.Context, sessionToken string) (*ory.LoginFlow, error) {
+	if IsSessionCookie(sessionToken) {
+		return nil, fmt.Errorf("failed to create refresh login flow: %w", ErrSessionTokenRequired)
+	}
+
+	flow, resp, err := k.frontend.FrontendAPI.CreateNativeLoginFlow(ctx).
+		Refresh(true).
+		XSessionToken(sessionToken).
+		Execute()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh login flow: %w (status: %d)", err, getStatusCode(resp))
+	}
+	return flow, nil
+}
+
+// CreateAAL2LoginFlow creates a native login flow that upgrades an existing
+// aal1 session to aal2 with a second factor. Only session tokens can start
+// it; cookie sessions get ErrSessionTokenRequired.
+func (k *KratosClient) CreateAAL2LoginFlow(ctx context.Context, sessionToken string) (*ory.LoginFlow, error) {
+	if IsSessionCookie(sessionToken) {
+		return nil, fmt.Errorf("failed to create aal2 login flow: %w", ErrSessionTokenRequired)
+	}
+
+	flow, resp, err := k.frontend.FrontendAPI.CreateNativeLoginFlow(ctx).
+		Aal(string(ory.AUTHENTICATORASSURANCELEVEL_AAL2)).
+		XSessionToken(sessionToken).
+		Execute()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aal2 login flow: %w (status: %d)", err, getStatusCode(resp))
+	}
+	return flow, nil
+}
+
+// UpdateLoginFlow submits login credentials with optional session token (needed for refresh logins)
+func (k *KratosClient) UpdateLoginFlow(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error) {
+	req := k.frontend.FrontendAPI.UpdateLoginFlow(ctx).
+		Flow(flowID).
+		UpdateLoginFlowBody(body)
+
+	if IsSessionCookie(sessionToken) {
+		req = req.Cookie(sessionToken)
+	} else if sessionToken != "" {
+		req = req.XSessionToken(sessionToken)
+	}
+
+	result, resp, err := req.Execute()
+
+	if err != nil {
+		return nil, flowUpdateError("failed to update login flow", err, resp)
+	}
+	return result, nil
+}
+
+// SendLoginCode emails a one-time login code for a passwordless login.
+// Kratos answers this step with 400 and the flow in the sent_email state.
+func (k *KratosClient) SendLoginCode(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (*ory.LoginFlow, error) {
+	_, resp, err := k.frontend.FrontendAPI.UpdateLoginFlow(ctx).
+		Flow(flowID).
+		UpdateLoginFlowBody(body).
+		Execute()
+
+	if flow, ok := kratosErrorModel(err).(ory.LoginFlow); ok && flow.State == flowStateSentEmail {
+		return &flow, nil
+	}
+	if err == nil {
+		return nil, fmt.Errorf("failed to send login code: flow completed without a code")
+	}
+	return nil, fmt.Errorf("failed to send login code: %w (status: %d)", err, getStatusCode(resp))
+}
+
+// CreateOIDCLoginFlow creates a native login flow for social sign-in. The flow
+// carries a session token exchange code; the provider sends the browser back to
+// returnTo with the matching return_to_code once the user has signed in.
+func (k *KratosClient) CreateOIDCLoginFlow(ctx context.Context, returnTo string) (*ory.LoginFlow, error) {
+	flow, resp, err := k.frontend.FrontendAPI.CreateNativeLoginFlow(ctx).
+		ReturnSessionTokenExchangeCode(true).
+		ReturnTo(returnTo).
+		Execute()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create oidc login flow: %w (status: %d)", err, getStatusCode(resp))
+	}
+	return flow, nil
+}
+
+// StartOIDCLogin submits the chosen provider and returns the provider URL the
+// browser must open. Kratos answers with 422 and redirect_browser_to.
+func (k *KratosClient) StartOIDCLogin(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (string, error) {
+	_, resp, err := k.frontend.FrontendAPI.UpdateLoginFlow(ctx).
 		Flow(flowID).
 		UpdateLoginFlowBody(body).
 		Execute()
 
+	if redirect, ok := kratosErrorModel(err).(ory.ErrorBrowserLocationChangeRequired); ok && redirect.GetRedirectBrowserTo() != "" {
+		return redirect.GetRedirectBrowserTo(), nil
+	}
+	if err == nil {
+		return "", fmt.Errorf("failed to start oidc login: no redirect returned")
+	}
+	return "", flowUpdateError("failed to start oidc login", err, resp)
+}
+
+// ExchangeSessionToken trades the flow's exchange code and the return_to_code
+// from the provider redirect for a session token
+func (k *KratosClient) ExchangeSessionToken(ctx context.Context, initCode, returnToCode string) (*ory.SuccessfulNativeLogin, error) {
+	result, resp, err := k.frontend.FrontendAPI.ExchangeSessionToken(ctx).
+		InitCode(initCode).
+		ReturnToCode(returnToCode).
+		Execute()
+
 	if err != nil {
-		return nil, fmt.Errorf("failed to update login flow: %w (status: %d)", err, getStatusCode(resp))
+		return nil, flowUpdateError("failed to exchange session token", err, resp)
 	}
 	return result, nil
 }
@@ -94,11 +210,46 @@ func (k *KratosClient) UpdateRegistrationFlow(ctx context.Context, flowID string
 		Execute()
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to update registration flow: %w (status: %d)", err, getStatusCode(resp))
+		return nil, flowUpdateError("failed to update registration flow", err, resp)
 	}
 	return result, nil
 }
 
+// PreparePasskeyRegistration submits the traits of a passkey registration.
+// Kratos answers with 400 and the flow re-rendered with the WebAuthn creation
+// options the authenticator needs.
+func (k *KratosClient) PreparePasskeyRegistration(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.RegistrationFlow, error) {
+	_, resp, err := k.frontend.FrontendAPI.UpdateRegistrationFlow(ctx).
+		Flow(flowID).
+		UpdateRegistrationFlowBody(body).
+		Execute()
+
+	if flow, ok := kratosErrorModel(err).(ory.RegistrationFlow); ok && findNodeValue(flow.Ui.Nodes, passkeyCreateDataNode) != "" {
+		return &flow, nil
+	}
+	if err == nil {
+		return nil, fmt.Errorf("failed to prepare passkey registration: flow completed without a passkey")
+	}
+	return nil, fmt.Errorf("failed to prepare passkey registration: %w (status: %d)", err, getStatusCode(resp))
+}
+
+// SendRegistrationCode emails a one-time code for a passwordless registration.
+// Kratos answers this step with 400 and the flow in the sent_email state.
+func (k *KratosClient) SendRegistrationCode(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.RegistrationFlow, error) {
+	_, resp, err := k.frontend.FrontendAPI.UpdateRegistrationFlow(ctx).
+		Flow(flowID).
+		UpdateRegistrationFlowBody(body).
+		Execute()
+
+	if flow, ok := kratosErrorModel(err).(ory.RegistrationFlow); ok && flow.State == flowStateSentEmail {
+		return &flow, nil
+	}
+	if err == nil {
+		return nil, fmt.Errorf("failed to send registration code: flow completed without a code")
+	}
+	return nil, fmt.Errorf("failed to send registration code: %w (status: %d)", err, getStatusCode(resp))
+}
+
 // CreateLogoutFlow creates a browser logout flow
 func (k *KratosClient) CreateLogoutFlow(ctx context.Context, cookie string) (*ory.LogoutFlow, error) {
 	flow, resp, err := k.frontend.FrontendAPI.CreateBrowserLogoutFlow(ctx).
@@ -111,7 +262,9 @@ func (k *KratosClient) CreateLogoutFlow(ctx context.Context, cookie string) (*or
 	return flow, nil
 }
 
-// PerformNativeLogout performs a native logout by disabling the session
+// PerformNativeLogout performs a native logout by disabling the session.
+// Kratos takes only session tokens here; cookie sessions log out through
+// CreateLogoutFlow and PerformBrowserLogout.
 func (k *KratosClient) PerformNativeLogout(ctx context.Context, sessionToken ory.PerformNativeLogoutBody) error {
 	
 	resp, err := k.frontend.FrontendAPI.PerformNativeLogout(ctx).PerformNativeLogoutBody(sessionToken).Execute()
@@ -123,6 +276,62 @@ func (k *KratosClient) PerformNativeLogout(ctx context.Context, sessionToken ory
 	return nil
 }
 
+// ListMySessions lists the caller's active sessions other than the current one
+func (k *KratosClient) ListMySessions(ctx context.Context, sessionToken string) ([]ory.Session, error) {
+	req := k.frontend.FrontendAPI.ListMySessions(ctx)
+	if IsSessionCookie(sessionToken) {
+		req = req.Cookie(sessionToken)
+	} else {
+		req = req.XSessionToken(sessionToken)
+	}
+
+	sessions, resp, err := req.Execute()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w (status: %d)", err, getStatusCode(resp))
+	}
+	return sessions, nil
+}
+
+// RevokeMySession revokes one of the caller's other sessions. Kratos refuses
+// to revoke the current session this way; use logout for that.
+func (k *KratosClient) RevokeMySession(ctx context.Context, sessionToken, sessionID string) error {
+	req := k.frontend.FrontendAPI.DisableMySession(ctx, sessionID)
+	if IsSessionCookie(sessionToken) {
+		req = req.Cookie(sessionToken)
+	} else {
+		req = req.XSessionToken(sessionToken)
+	}
+
+	resp, err := req.Execute()
+
+	if err != nil {
+		if getStatusCode(resp) == http.StatusNotFound {
+			return fmt.Errorf("failed to revoke session: %w", ErrSessionNotFound)
+		}
+		return fmt.Errorf("failed to revoke session: %w (status: %d)", err, getStatusCode(resp))
+	}
+	return nil
+}
+
+// RevokeMyOtherSessions revokes every session of the caller except the current
+// one and returns how many were revoked
+func (k *KratosClient) RevokeMyOtherSessions(ctx context.Context, sessionToken string) (int64, error) {
+	req := k.frontend.FrontendAPI.DisableMyOtherSessions(ctx)
+	if IsSessionCookie(sessionToken) {
+		req = req.Cookie(sessionToken)
+	} else {
+		req = req.XSessionToken(sessionToken)
+	}
+
+	result, resp, err := req.Execute()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w (status: %d)", err, getStatusCode(resp))
+	}
+	return result.GetCount(), nil
+}
+
 // CreateVerificationFlow creates a new native verification flow
 func (k *KratosClient) CreateVerificationFlow(ctx context.Context) (*ory.VerificationFlow, error) {
 	flow, resp, err := k.frontend.FrontendAPI.CreateNativeVerificationFlow(ctx).Execute()
@@ -175,9 +384,13 @@ func ExtractCSRFToken(flow *ory.RegistrationFlow) string {
 
 // ExtractFormFields extracts form fields from a registration flow
 func ExtractFormFields(flow *ory.RegistrationFlow) []map[string]interface{} {
+	return extractFormFields(flow.Ui.Nodes)
+}
+
+func extractFormFields(nodes []ory.UiNode) []map[string]interface{} {
 	fields := make([]map[string]interface{}, 0)
 
-	for _, node := range flow.Ui.Nodes {
+	for _, node := range nodes {
 		if node.Attributes.UiNodeInputAttributes != nil {
 			attrs := node.Attributes.UiNodeInputAttributes
 
@@ -213,6 +426,115 @@ func BuildPasswordLoginBody(email, password string) ory.UpdateLoginFlowBody {
 	}
 }
 
+// BuildTOTPLoginBody creates a login body for completing an aal2 login with a TOTP code
+func BuildTOTPLoginBody(code string) ory.UpdateLoginFlowBody {
+	return ory.UpdateLoginFlowBody{
+		UpdateLoginFlowWithTotpMethod: &ory.UpdateLoginFlowWithTotpMethod{
+			Method:   "totp",
+			TotpCode: code,
+		},
+	}
+}
+
+// BuildLookupSecretLoginBody creates a login body for completing an aal2 login with a backup code
+func BuildLookupSecretLoginBody(code string) ory.UpdateLoginFlowBody {
+	return ory.UpdateLoginFlowBody{
+		UpdateLoginFlowWithLookupSecretMethod: &ory.UpdateLoginFlowWithLookupSecretMethod{
+			Method:       "lookup_secret",
+			LookupSecret: code,
+		},
+	}
+}
+
+// BuildOIDCLoginBody creates a login body that starts sign-in with an OIDC provider
+func BuildOIDCLoginBody(provider string) ory.UpdateLoginFlowBody {
+	return ory.UpdateLoginFlowBody{
+		UpdateLoginFlowWithOidcMethod: &ory.UpdateLoginFlowWithOidcMethod{
+			Method:   "oidc",
+			Provider: provider,
+		},
+	}
+}
+
+// ExtractOIDCProviders lists the OIDC providers offered by a login flow
+func ExtractOIDCProviders(flow *ory.LoginFlow) []string {
+	providers := make([]string, 0)
+	if flow == nil {
+		return providers
+	}
+
+	for _, node := range flow.Ui.Nodes {
+		if node.Group != "oidc" {
+			continue
+		}
+
+		attrs := node.Attributes.UiNodeInputAttributes
+		if attrs == nil || attrs.Name != "provider" {
+			continue
+		}
+
+		if provider, ok := attrs.Value.(string); ok && provider != "" {
+			providers = append(providers, provider)
+		}
+	}
+
+	return providers
+}
+
+// BuildCodeLoginBody creates a login body for the passwordless code method.
+// An empty code requests that a code be emailed to the identifier.
+func BuildCodeLoginBody(email, code string) ory.UpdateLoginFlowBody {
+	body := &ory.UpdateLoginFlowWithCodeMethod{
+		Method:     "code",
+		Identifier: &email,
+	}
+	if code != "" {
+		body.Code = &code
+	}
+
+	return ory.UpdateLoginFlowBody{UpdateLoginFlowWithCodeMethod: body}
+}
+
+// BuildCodeRegistrationBody creates a registration body for the passwordless code method.
+// An empty code requests that a code be emailed; the same traits must be sent with the code.
+func BuildCodeRegistrationBody(traits map[string]interface{}, code string) ory.UpdateRegistrationFlowBody {
+	body := &ory.UpdateRegistrationFlowWithCodeMethod{
+		Method: "code",
+		Traits: traits,
+	}
+	if code != "" {
+		body.Code = &code
+	}
+
+	return ory.UpdateRegistrationFlowBody{UpdateRegistrationFlowWithCodeMethod: body}
+}
+
+// BuildPasskeyLoginBody creates a login body carrying the authenticator's
+// assertion response (the JSON-encoded PublicKeyCredential)
+func BuildPasskeyLoginBody(credential string) ory.UpdateLoginFlowBody {
+	return ory.UpdateLoginFlowBody{
+		UpdateLoginFlowWithPasskeyMethod: &ory.UpdateLoginFlowWithPasskeyMethod{
+			Method:       "passkey",
+			PasskeyLogin: &credential,
+		},
+	}
+}
+
+// BuildPasskeyRegistrationBody creates a registration body for the passkey method.
+// An empty credential requests the WebAuthn creation options; the same traits
+// must be sent again with the authenticator's attestation response.
+func BuildPasskeyRegistrationBody(traits map[string]interface{}, credential string) ory.UpdateRegistrationFlowBody {
+	body := &ory.UpdateRegistrationFlowWithPasskeyMethod{
+		Method: "passkey",
+		Traits: traits,
+	}
+	if credential != "" {
+		body.PasskeyRegister = &credential
+	}
+
+	return ory.UpdateRegistrationFlowBody{UpdateRegistrationFlowWithPasskeyMethod: body}
+}
+
 // BuildPasswordRegistrationBody creates a registration body for password authentication
 func BuildPasswordRegistrationBody(email, password, firstName, lastName string) ory.UpdateRegistrationFlowBody {
 	traits := map[string]interface{}{
@@ -223,13 +545,7 @@ func BuildPasswordRegistrationBody(email, password, firstName, lastName string)
 		},
 	}
 
-	return ory.UpdateRegistrationFlowBody{
-		UpdateRegistrationFlowWithPasswordMethod: &ory.UpdateRegistrationFlowWithPasswordMethod{
-			Method:   "password",
-			Password: password,
-			Traits:   traits,
-		},
-	}
+	return BuildTraitsRegistrationBody(password, traits)
 }
 
 // BuildCodeVerificationBody creates a verification body for code method
@@ -296,9 +612,17 @@ func BuildCodeRecoveryBodySubmit(code, password string) ory.UpdateRecoveryFlowBo
 	}
 }
 
-// CreateSettingsFlow creates a new native settings flow
-func (k *KratosClient) CreateSettingsFlow(ctx context.Context) (*ory.SettingsFlow, error) {
-	flow, resp, err := k.frontend.FrontendAPI.CreateNativeSettingsFlow(ctx).Execute()
+// CreateSettingsFlow creates a new native settings flow for the session. Only
+// session tokens can start it; cookie sessions get ErrSessionTokenRequired.
+func (k *KratosClient) CreateSettingsFlow(ctx context.Context, sessionToken string) (*ory.SettingsFlow, error) {
+	if IsSessionCookie(sessionToken) {
+		return nil, fmt.Errorf("failed to create settings flow: %w", ErrSessionTokenRequired)
+	}
+
+	flow, resp, err := k.frontend.FrontendAPI.CreateNativeSettingsFlow(ctx).
+		XSessionToken(sessionToken).
+		Execute()
+
 	if err != nil {
 		return nil, fmt.Errorf("failed to create settings flow: %w (status: %d)", err, getStatusCode(resp))
 	}
@@ -312,14 +636,19 @@ func (k *KratosClient) UpdateSettingsFlow(ctx context.Context, flowID string, bo
 		UpdateSettingsFlowBody(body)
 
 	// Add session token if provided (needed for privileged flows like password recovery)
-	if sessionToken != "" {
+	if IsSessionCookie(sessionToken) {
+		req = req.Cookie(sessionToken)
+	} else if sessionToken != "" {
 		req = req.XSessionToken(sessionToken)
 	}
 
 	flow, resp, err := req.Execute()
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to update settings flow: %w (status: %d)", err, getStatusCode(resp))
+		if getStatusCode(resp) == http.StatusForbidden && kratosErrorID(err) == kratosErrorSessionRefreshRequired {
+			return nil, fmt.Errorf("failed to update settings flow: %w", ErrPrivilegedSessionRequired)
+		}
+		return nil, flowUpdateError("failed to update settings flow", err, resp)
 	}
 	return flow, nil
 }
@@ -345,6 +674,249 @@ func BuildPasswordSettingsBodyWithCSRF(password, csrfToken string) ory.UpdateSet
 	}
 }
 
+// BuildProfileSettingsBody creates a settings body for updating identity traits.
+// Kratos replaces traits wholesale, so traits must contain every trait.
+func BuildProfileSettingsBody(traits map[string]interface{}) ory.UpdateSettingsFlowBody {
+	return ory.UpdateSettingsFlowBody{
+		UpdateSettingsFlowWithProfileMethod: &ory.UpdateSettingsFlowWithProfileMethod{
+			Method: "profile",
+			Traits: traits,
+		},
+	}
+}
+
+// BuildNameTraits returns a copy of traits with name.first and name.last replaced
+func BuildNameTraits(traits map[string]interface{}, firstName, lastName string) map[string]interface{} {
+	updated := make(map[string]interface{}, len(traits)+1)
+	for k, v := range traits {
+		updated[k] = v
+	}
+
+	updated["name"] = map[string]interface{}{
+		"first": firstName,
+		"last":  lastName,
+	}
+	return updated
+}
+
+// BuildTOTPSettingsBody creates a settings body for enrolling a TOTP authenticator
+func BuildTOTPSettingsBody(code string) ory.UpdateSettingsFlowBody {
+	return ory.UpdateSettingsFlowBody{
+		UpdateSettingsFlowWithTotpMethod: &ory.UpdateSettingsFlowWithTotpMethod{
+			Method:   "totp",
+			TotpCode: &code,
+		},
+	}
+}
+
+// BuildTOTPUnlinkBody creates a settings body for removing the TOTP authenticator
+func BuildTOTPUnlinkBody() ory.UpdateSettingsFlowBody {
+	unlink := true
+	return ory.UpdateSettingsFlowBody{
+		UpdateSettingsFlowWithTotpMethod: &ory.UpdateSettingsFlowWithTotpMethod{
+			Method:     "totp",
+			TotpUnlink: &unlink,
+		},
+	}
+}
+
+// BuildPasskeySettingsBody creates a settings body that links a new passkey
+func BuildPasskeySettingsBody(credential string) ory.UpdateSettingsFlowBody {
+	return ory.UpdateSettingsFlowBody{
+		UpdateSettingsFlowWithPasskeyMethod: &ory.UpdateSettingsFlowWithPasskeyMethod{
+			Method:                  "passkey",
+			PasskeySettingsRegister: &credential,
+		},
+	}
+}
+
+// BuildPasskeyRemoveBody creates a settings body that unlinks the passkey with the given ID
+func BuildPasskeyRemoveBody(credentialID string) ory.UpdateSettingsFlowBody {
+	return ory.UpdateSettingsFlowBody{
+		UpdateSettingsFlowWithPasskeyMethod: &ory.UpdateSettingsFlowWithPasskeyMethod{
+			Method:        "passkey",
+			PasskeyRemove: &credentialID,
+		},
+	}
+}
+
+// UI node names carrying WebAuthn options for the passkey method
+const (
+	passkeyCreateDataNode = "passkey_create_data"
+	passkeyChallengeNode  = "passkey_challenge"
+	passkeyRemoveNode     = "passkey_remove"
+)
+
+// ExtractPasskeyCreationOptions extracts the WebAuthn credential creation
+// options (for navigator.credentials.create) from registration or settings flow nodes
+func ExtractPasskeyCreationOptions(nodes []ory.UiNode) json.RawMessage {
+	return rawJSON(findNodeValue(nodes, passkeyCreateDataNode))
+}
+
+// ExtractPasskeyRequestOptions extracts the WebAuthn credential request
+// options (for navigator.credentials.get) from login flow nodes
+func ExtractPasskeyRequestOptions(nodes []ory.UiNode) json.RawMessage {
+	return rawJSON(findNodeValue(nodes, passkeyChallengeNode))
+}
+
+// ExtractPasskeyCredentialIDs lists the IDs of passkeys that can be removed in a settings flow
+func ExtractPasskeyCredentialIDs(flow *ory.SettingsFlow) []string {
+	ids := make([]string, 0)
+	if flow == nil {
+		return ids
+	}
+
+	for _, node := range flow.Ui.Nodes {
+		attrs := node.Attributes.UiNodeInputAttributes
+		if attrs == nil || attrs.Name != passkeyRemoveNode {
+			continue
+		}
+		if id, ok := attrs.Value.(string); ok && id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// findNodeValue returns the string value of the named input node
+func findNodeValue(nodes []ory.UiNode, name string) string {
+	for _, node := range nodes {
+		if attrs := node.Attributes.UiNodeInputAttributes; attrs != nil && attrs.Name == name {
+			value, _ := attrs.Value.(string)
+			return value
+		}
+	}
+	return ""
+}
+
+// rawJSON returns value as raw JSON, or nil if it is not valid JSON
+func rawJSON(value string) json.RawMessage {
+	if value == "" || !json.Valid([]byte(value)) {
+		return nil
+	}
+	return json.RawMessage(value)
+}
+
+// BuildLookupRegenerateBody creates a settings body that generates a new set of
+// backup codes. They replace the old set only once confirmed.
+func BuildLookupRegenerateBody() ory.UpdateSettingsFlowBody {
+	regenerate := true
+	return ory.UpdateSettingsFlowBody{
+		UpdateSettingsFlowWithLookupMethod: &ory.UpdateSettingsFlowWithLookupMethod{
+			Method:                 "lookup_secret",
+			LookupSecretRegenerate: &regenerate,
+		},
+	}
+}
+
+// BuildLookupConfirmBody creates a settings body that saves regenerated backup codes
+func BuildLookupConfirmBody() ory.UpdateSettingsFlowBody {
+	confirm := true
+	return ory.UpdateSettingsFlowBody{
+		UpdateSettingsFlowWithLookupMethod: &ory.UpdateSettingsFlowWithLookupMethod{
+			Method:              "lookup_secret",
+			LookupSecretConfirm: &confirm,
+		},
+	}
+}
+
+// BuildLookupRevealBody creates a settings body that shows the saved backup codes
+func BuildLookupRevealBody() ory.UpdateSettingsFlowBody {
+	reveal := true
+	return ory.UpdateSettingsFlowBody{
+		UpdateSettingsFlowWithLookupMethod: &ory.UpdateSettingsFlowWithLookupMethod{
+			Method:             "lookup_secret",
+			LookupSecretReveal: &reveal,
+		},
+	}
+}
+
+// uiTextLookupSecretUsed is the Kratos UI text ID of a backup code that was used
+const uiTextLookupSecretUsed = 1050014
+
+// ExtractLookupSecrets extracts the backup codes shown by a regenerate or
+// reveal settings flow, skipping codes that have already been used
+func ExtractLookupSecrets(flow *ory.SettingsFlow) []string {
+	codes := make([]string, 0)
+	if flow == nil {
+		return codes
+	}
+
+	for _, node := range flow.Ui.Nodes {
+		attrs := node.Attributes.UiNodeTextAttributes
+		if attrs == nil || attrs.Id != "lookup_secret_codes" {
+			continue
+		}
+
+		secrets, _ := attrs.Text.Context["secrets"].([]interface{})
+		for _, secret := range secrets {
+			entry, ok := secret.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			id, _ := entry["id"].(float64)
+			text, _ := entry["text"].(string)
+			if int(id) == uiTextLookupSecretUsed || text == "" {
+				continue
+			}
+			codes = append(codes, text)
+		}
+	}
+
+	return codes
+}
+
+// HasLookupSecrets reports whether the identity has confirmed backup codes,
+// which Kratos signals by offering to reveal them
+func HasLookupSecrets(flow *ory.SettingsFlow) bool {
+	if flow == nil {
+		return false
+	}
+
+	for _, node := range flow.Ui.Nodes {
+		if attrs := node.Attributes.UiNodeInputAttributes; attrs != nil && attrs.Name == "lookup_secret_reveal" {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractTOTPSetup extracts the enrollment QR code (a data URI) and secret key
+// from a settings flow. When TOTP is already linked Kratos offers an unlink
+// button instead, and linked is true.
+func ExtractTOTPSetup(flow *ory.SettingsFlow) (qrCode, secret string, linked bool) {
+	if flow == nil {
+		return "", "", false
+	}
+
+	for _, node := range flow.Ui.Nodes {
+		if node.Group != "totp" {
+			continue
+		}
+
+		if attrs := node.Attributes.UiNodeImageAttributes; attrs != nil && attrs.Id == "totp_qr" {
+			qrCode = attrs.Src
+		}
+
+		if attrs := node.Attributes.UiNodeTextAttributes; attrs != nil && attrs.Id == "totp_secret_key" {
+			secret = attrs.Text.Text
+		}
+
+		if attrs := node.Attributes.UiNodeInputAttributes; attrs != nil && attrs.Name == "totp_unlink" {
+			linked = true
+		}
+	}
+
+	return qrCode, secret, linked
+}
+
+// ExtractSettingsFormFields extracts form fields from a settings flow
+func ExtractSettingsFormFields(flow *ory.SettingsFlow) []map[string]interface{} {
+	return extractFormFields(flow.Ui.Nodes)
+}
+
 // ExtractCSRFTokenFromSettings extracts CSRF token from a settings flow
 func ExtractCSRFTokenFromSettings(flow *ory.SettingsFlow) string {
 	if flow == nil || flow.Ui.Nodes == nil {
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	ory "github.com/ory/client-go"
+)
+
+// GetIdentitySchema fetches the identity JSON schema with the given ID from
+// the public API (/schemas/{id})
+func (k *KratosClient) GetIdentitySchema(ctx context.Context, id string) ([]byte, error) {
+	schema, resp, err := k.frontend.IdentityAPI.GetIdentitySchema(ctx, id).Execute()
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("identity schema %q not found", id)
+		}
+		return nil, fmt.Errorf("failed to get identity schema: %w (status: %d)", err, getStatusCode(resp))
+	}
+
+	raw, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode identity schema: %w", err)
+	}
+	return raw, nil
+}
+
+// BuildTraitsRegistrationBody creates a password registration body carrying
+// traits as given, so their shape follows the identity schema
+func BuildTraitsRegistrationBody(password string, traits map[string]interface{}) ory.UpdateRegistrationFlowBody {
+	return ory.UpdateRegistrationFlowBody{
+		UpdateRegistrationFlowWithPasswordMethod: &ory.UpdateRegistrationFlowWithPasswordMethod{
+			Method:   "password",
+			Password: password,
+			Traits:   traits,
+		},
+	}
+}
+
+// BuildBrowserTraitsRegistrationBody is BuildTraitsRegistrationBody with the
+// flow's CSRF token, which browser flows require
+func BuildBrowserTraitsRegistrationBody(password string, traits map[string]interface{}, csrfToken string) ory.UpdateRegistrationFlowBody {
+	body := BuildTraitsRegistrationBody(password, traits)
+	body.UpdateRegistrationFlowWithPasswordMethod.CsrfToken = &csrfToken
+	return body
+}
@@ -0,0 +1,320 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginThrottleConfig configures a LoginThrottle
+type LoginThrottleConfig struct {
+	// MaxFailures locks an identifier out for LockoutDuration after that many
+	// consecutive failed logins
+	MaxFailures int
+	// IPMaxFailures does the same for a client IP. Users behind one address
+	// share it, so it should be well above MaxFailures.
+	IPMaxFailures int
+	// BackoffBase is how long to wait after the first failure; each further
+	// failure doubles it, up to BackoffMax
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// LockoutDuration is how long a lockout lasts
+	LockoutDuration time.Duration
+	// FailureWindow forgets an identifier or IP once it has gone this long
+	// without a failure
+	FailureWindow time.Duration
+	// BlockAfterLockouts deactivates the identity after that many lockouts
+	// within FailureWindow of each other; 0 never does. Anyone who knows an
+	// identifier can cause those lockouts, so this lets them deactivate the
+	// account until an admin unblocks it.
+	BlockAfterLockouts int
+	// MaxEntries bounds the identifiers and IPs tracked; the least recently
+	// failed are forgotten first
+	MaxEntries int
+}
+
+// loginInFlightWait is the wait reported for a login refused because another
+// attempt for the same identifier or IP is still in flight
+const loginInFlightWait = time.Second
+
+// LoginThrottle tracks failed password logins per identifier and per client
+// IP. Each failure makes the next attempt wait an exponentially growing
+// backoff, and too many consecutive failures lock the identifier or IP out.
+// Check reserves the attempt, so concurrent guesses cannot all slip past it:
+// an identifier has one attempt in flight at a time, and an IP no more than
+// it has failures left before lockout. State is held in process, so each
+// replica throttles separately.
+type LoginThrottle struct {
+	cfg   LoginThrottleConfig
+	admin AdminService
+	now   func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*loginFailures
+}
+
+type loginFailures struct {
+	failures    int
+	lockouts    int
+	inFlight    int
+	lastFailure time.Time
+	retryAt     time.Time
+}
+
+// NewLoginThrottle creates a login throttle. With admin set, identities that
+// reach cfg.BlockAfterLockouts are set inactive through the admin API.
+func NewLoginThrottle(cfg LoginThrottleConfig, admin AdminService) *LoginThrottle {
+	return &LoginThrottle{
+		cfg:     cfg,
+		admin:   admin,
+		now:     time.Now,
+		entries: make(map[string]*loginFailures),
+	}
+}
+
+// Check returns how long a login for identifier from ip must wait, or 0 if
+// it may go ahead. A login that may go ahead holds a reservation until its
+// outcome is passed to RecordFailure, RecordSuccess or Release.
+func (t *LoginThrottle) Check(identifier, ip string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	keys := loginThrottleKeys(identifier, ip)
+
+	var wait time.Duration
+	for _, key := range keys {
+		entry := t.entry(key, now)
+		if entry == nil {
+			continue
+		}
+		if entry.retryAt.After(now) && entry.retryAt.Sub(now) > wait {
+			wait = entry.retryAt.Sub(now)
+		}
+		if !t.admits(key, entry) && loginInFlightWait > wait {
+			wait = loginInFlightWait
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+
+	for _, key := range keys {
+		entry := t.entry(key, now)
+		if entry == nil {
+			t.evict()
+			entry = &loginFailures{}
+			t.entries[key] = entry
+		}
+		entry.inFlight++
+	}
+	return 0
+}
+
+// Release ends a reservation from Check without counting it, for logins
+// that neither succeeded nor had their credentials rejected
+func (t *LoginThrottle) Release(identifier, ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, key := range loginThrottleKeys(identifier, ip) {
+		t.release(key)
+	}
+}
+
+// RecordFailure counts a rejected login against identifier and ip, ending
+// its reservation. When the identifier is locked out often enough, its
+// identity is blocked.
+func (t *LoginThrottle) RecordFailure(ctx context.Context, identifier, ip string) {
+	block := t.recordFailure(identifier, ip)
+	if !block {
+		return
+	}
+	if t.admin == nil {
+		log.Printf("login throttle: cannot block %q after repeated lockouts: no admin API", identifier)
+		return
+	}
+
+	if err := BlockIdentifier(ctx, t.admin, identifier); err != nil {
+		log.Printf("login throttle: failed to block %q after repeated lockouts: %v", identifier, err)
+		return
+	}
+	log.Printf("login throttle: blocked %q after repeated lockouts", identifier)
+}
+
+// RecordSuccess ends the login's reservation and clears identifier's
+// failures. The IP's are kept, so logging into one account does not reset
+// guessing against others.
+func (t *LoginThrottle) RecordSuccess(identifier, ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, key := range loginThrottleKeys(identifier, ip) {
+		t.release(key)
+	}
+	delete(t.entries, identifierThrottleKey(identifier))
+}
+
+// recordFailure updates both keys and reports whether the identity should be
+// blocked
+func (t *LoginThrottle) recordFailure(identifier, ip string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	block := false
+	for _, key := range loginThrottleKeys(identifier, ip) {
+		entry := t.entry(key, now)
+		if entry == nil {
+			t.evict()
+			entry = &loginFailures{}
+			t.entries[key] = entry
+		}
+
+		if entry.inFlight > 0 {
+			entry.inFlight--
+		}
+		entry.failures++
+		entry.lastFailure = now
+
+		maxFailures := t.cfg.MaxFailures
+		if strings.HasPrefix(key, "ip:") {
+			maxFailures = t.cfg.IPMaxFailures
+		}
+
+		if maxFailures > 0 && entry.failures >= maxFailures {
+			entry.failures = 0
+			entry.lockouts++
+			entry.retryAt = now.Add(t.cfg.LockoutDuration)
+
+			if !strings.HasPrefix(key, "ip:") && t.cfg.BlockAfterLockouts > 0 && entry.lockouts >= t.cfg.BlockAfterLockouts {
+				block = true
+			}
+			continue
+		}
+
+		entry.retryAt = now.Add(t.backoff(entry.failures))
+	}
+	return block
+}
+
+// admits reports whether key may take another attempt alongside those in
+// flight; the caller must hold mu
+func (t *LoginThrottle) admits(key string, entry *loginFailures) bool {
+	if !strings.HasPrefix(key, "ip:") {
+		return entry.inFlight == 0
+	}
+	return t.cfg.IPMaxFailures <= 0 || entry.failures+entry.inFlight < t.cfg.IPMaxFailures
+}
+
+// release ends one reservation on key, forgetting entries left with nothing
+// recorded; the caller must hold mu
+func (t *LoginThrottle) release(key string) {
+	entry, ok := t.entries[key]
+	if !ok || entry.inFlight == 0 {
+		return
+	}
+
+	entry.inFlight--
+	if entry.inFlight == 0 && entry.failures == 0 && entry.lockouts == 0 && entry.lastFailure.IsZero() {
+		delete(t.entries, key)
+	}
+}
+
+// entry returns the failures recorded for key, dropping them once they are
+// older than the failure window; the caller must hold mu
+func (t *LoginThrottle) entry(key string, now time.Time) *loginFailures {
+	entry, ok := t.entries[key]
+	if !ok {
+		return nil
+	}
+	if t.stale(entry, now) {
+		delete(t.entries, key)
+		return nil
+	}
+	return entry
+}
+
+func (t *LoginThrottle) stale(entry *loginFailures, now time.Time) bool {
+	return entry.inFlight == 0 && now.Sub(entry.lastFailure) > t.cfg.FailureWindow && !entry.retryAt.After(now)
+}
+
+// evict makes room for a new entry, forgetting stale entries and then the
+// least recently failed, keeping attempts in flight where it can; the caller
+// must hold mu
+func (t *LoginThrottle) evict() {
+	if t.cfg.MaxEntries <= 0 || len(t.entries) < t.cfg.MaxEntries {
+		return
+	}
+
+	now := t.now()
+	for key, entry := range t.entries {
+		if t.stale(entry, now) {
+			delete(t.entries, key)
+		}
+	}
+
+	for len(t.entries) >= t.cfg.MaxEntries {
+		var oldestKey string
+		var oldest time.Time
+		oldestInFlight := false
+		for key, entry := range t.entries {
+			inFlight := entry.inFlight > 0
+			if oldestKey == "" || (oldestInFlight && !inFlight) ||
+				(inFlight == oldestInFlight && entry.lastFailure.Before(oldest)) {
+				oldestKey, oldest, oldestInFlight = key, entry.lastFailure, inFlight
+			}
+		}
+		delete(t.entries, oldestKey)
+	}
+}
+
+// backoff is BackoffBase doubled for each failure after the first, capped at
+// BackoffMax
+func (t *LoginThrottle) backoff(failures int) time.Duration {
+	delay := t.cfg.BackoffBase
+	for i := 1; i < failures && delay < t.cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+	if t.cfg.BackoffMax > 0 && delay > t.cfg.BackoffMax {
+		delay = t.cfg.BackoffMax
+	}
+	return delay
+}
+
+// BlockIdentifier sets every identity with the credential identifier
+// inactive and revokes its sessions. Admins lift the block by setting the
+// identity active again.
+func BlockIdentifier(ctx context.Context, admin AdminService, identifier string) error {
+	page, err := admin.ListIdentities(ctx, IdentityListOptions{Identifier: identifier})
+	if err != nil {
+		return fmt.Errorf("failed to look up identity to block: %w", err)
+	}
+
+	for _, identity := range page.Identities {
+		if _, err := admin.PatchIdentity(ctx, identity.Id, BuildIdentityPatch(nil, IdentityStateInactive)); err != nil {
+			return fmt.Errorf("failed to block identity %s: %w", identity.Id, err)
+		}
+		if err := admin.RevokeIdentitySessions(ctx, identity.Id); err != nil {
+			return fmt.Errorf("failed to revoke sessions of blocked identity %s: %w", identity.Id, err)
+		}
+	}
+	return nil
+}
+
+func loginThrottleKeys(identifier, ip string) []string {
+	keys := []string{identifierThrottleKey(identifier)}
+	if ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	return keys
+}
+
+// identifierThrottleKey normalises identifiers so case variants of an email
+// share their failures
+func identifierThrottleKey(identifier string) string {
+	return "id:" + strings.ToLower(strings.TrimSpace(identifier))
+}
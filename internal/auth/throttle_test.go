@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ory "github.com/ory/client-go"
+)
+
+// blockingAdminService records the identities a throttle blocks
+type blockingAdminService struct {
+	AdminService
+	identities map[string]string // identifier -> identity ID
+	patched    []string
+	revoked    []string
+	deleted    []string
+}
+
+func (b *blockingAdminService) ListIdentities(ctx context.Context, opts IdentityListOptions) (*IdentityPage, error) {
+	page := &IdentityPage{}
+	if id, ok := b.identities[opts.Identifier]; ok {
+		page.Identities = append(page.Identities, ory.Identity{Id: id})
+	}
+	return page, nil
+}
+
+func (b *blockingAdminService) PatchIdentity(ctx context.Context, id string, patches []ory.JsonPatch) (*ory.Identity, error) {
+	for _, patch := range patches {
+		if patch.Path == "/state" && patch.Value == IdentityStateInactive {
+			b.patched = append(b.patched, id)
+		}
+	}
+	return &ory.Identity{Id: id}, nil
+}
+
+func (b *blockingAdminService) RevokeIdentitySessions(ctx context.Context, id string) error {
+	b.revoked = append(b.revoked, id)
+	return nil
+}
+
+func (b *blockingAdminService) DeleteIdentity(ctx context.Context, id string) error {
+	b.deleted = append(b.deleted, id)
+	return nil
+}
+
+func newTestLoginThrottle(admin AdminService, now *time.Time) *LoginThrottle {
+	throttle := NewLoginThrottle(LoginThrottleConfig{
+		MaxFailures:     3,
+		IPMaxFailures:   5,
+		BackoffBase:     time.Second,
+		BackoffMax:      4 * time.Second,
+		LockoutDuration: time.Minute,
+		FailureWindow:   time.Hour,
+		MaxEntries:      10,
+	}, admin)
+	throttle.now = func() time.Time { return *now }
+	return throttle
+}
+
+func TestLoginThrottle_Backoff(t *testing.T) {
+	now := time.Now()
+	ctx := context.Background()
+	throttle := newTestLoginThrottle(nil, &now)
+
+	if wait := throttle.Check("user@example.com", "203.0.113.7"); wait != 0 {
+		t.Fatalf("Check() before any failure = %v, want 0", wait)
+	}
+
+	throttle.RecordFailure(ctx, "user@example.com", "203.0.113.7")
+	if wait := throttle.Check("user@example.com", "203.0.113.7"); wait != time.Second {
+		t.Errorf("Check() after 1 failure = %v, want 1s", wait)
+	}
+
+	now = now.Add(time.Second)
+	throttle.RecordFailure(ctx, "USER@example.com", "203.0.113.7")
+	if wait := throttle.Check("user@example.com", "198.51.100.1"); wait != 2*time.Second {
+		t.Errorf("Check() after 2 failures = %v, want 2s for the identifier from any IP", wait)
+	}
+	if wait := throttle.Check("other@example.com", "203.0.113.7"); wait != 2*time.Second {
+		t.Errorf("Check() after 2 failures = %v, want 2s for any identifier from the IP", wait)
+	}
+
+	now = now.Add(2 * time.Second)
+	if wait := throttle.Check("user@example.com", "203.0.113.7"); wait != 0 {
+		t.Errorf("Check() after the backoff = %v, want 0", wait)
+	}
+}
+
+func TestLoginThrottle_Lockout(t *testing.T) {
+	now := time.Now()
+	ctx := context.Background()
+	throttle := newTestLoginThrottle(nil, &now)
+
+	for i := 0; i < 3; i++ {
+		throttle.RecordFailure(ctx, "user@example.com", "")
+		now = now.Add(5 * time.Second)
+	}
+
+	if wait := throttle.Check("user@example.com", ""); wait <= 50*time.Second {
+		t.Errorf("Check() after 3 failures = %v, want the remaining lockout", wait)
+	}
+
+	now = now.Add(time.Minute)
+	if wait := throttle.Check("user@example.com", ""); wait != 0 {
+		t.Errorf("Check() after the lockout = %v, want 0", wait)
+	}
+}
+
+func TestLoginThrottle_IPLockout(t *testing.T) {
+	now := time.Now()
+	ctx := context.Background()
+	throttle := newTestLoginThrottle(nil, &now)
+
+	// Spraying one password over many accounts locks out the IP
+	for _, identifier := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+		throttle.RecordFailure(ctx, identifier, "203.0.113.7")
+		now = now.Add(5 * time.Second)
+	}
+
+	if wait := throttle.Check("f@example.com", "203.0.113.7"); wait <= 30*time.Second {
+		t.Errorf("Check() from a locked out IP = %v, want the remaining lockout", wait)
+	}
+	if wait := throttle.Check("f@example.com", "198.51.100.1"); wait != 0 {
+		t.Errorf("Check() from another IP = %v, want 0", wait)
+	}
+}
+
+func TestLoginThrottle_Success(t *testing.T) {
+	now := time.Now()
+	ctx := context.Background()
+	throttle := newTestLoginThrottle(nil, &now)
+
+	throttle.RecordFailure(ctx, "user@example.com", "203.0.113.7")
+	throttle.RecordSuccess("user@example.com", "")
+
+	if wait := throttle.Check("user@example.com", ""); wait != 0 {
+		t.Errorf("Check() after a success = %v, want the identifier cleared", wait)
+	}
+	if wait := throttle.Check("user@example.com", "203.0.113.7"); wait == 0 {
+		t.Error("Check() after a success cleared the IP's failures")
+	}
+}
+
+func TestLoginThrottle_ConcurrentAttempts(t *testing.T) {
+	now := time.Now()
+	ctx := context.Background()
+	throttle := newTestLoginThrottle(nil, &now)
+
+	// A second guess at the identifier waits for the first to finish
+	if wait := throttle.Check("user@example.com", "203.0.113.7"); wait != 0 {
+		t.Fatalf("Check() first attempt = %v, want 0", wait)
+	}
+	if wait := throttle.Check("user@example.com", "198.51.100.1"); wait != loginInFlightWait {
+		t.Errorf("Check() while an attempt is in flight = %v, want %v", wait, loginInFlightWait)
+	}
+
+	throttle.Release("user@example.com", "203.0.113.7")
+	if wait := throttle.Check("user@example.com", "203.0.113.7"); wait != 0 {
+		t.Errorf("Check() after Release() = %v, want 0", wait)
+	}
+
+	// The failure it reserved for is counted once, and backs off the next guess
+	throttle.RecordFailure(ctx, "user@example.com", "203.0.113.7")
+	if wait := throttle.Check("user@example.com", "203.0.113.7"); wait != time.Second {
+		t.Errorf("Check() after the failure = %v, want 1s", wait)
+	}
+
+	// An IP has no more attempts in flight than failures left before lockout
+	for i, identifier := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+		if wait := throttle.Check(identifier, "192.0.2.1"); wait != 0 {
+			t.Fatalf("Check() attempt %d from the IP = %v, want 0", i+1, wait)
+		}
+	}
+	if wait := throttle.Check("f@example.com", "192.0.2.1"); wait != loginInFlightWait {
+		t.Errorf("Check() past the IP's remaining failures = %v, want %v", wait, loginInFlightWait)
+	}
+
+	throttle.RecordSuccess("b@example.com", "192.0.2.1")
+	if wait := throttle.Check("f@example.com", "192.0.2.1"); wait != 0 {
+		t.Errorf("Check() after an attempt finished = %v, want 0", wait)
+	}
+}
+
+func TestLoginThrottle_FailureWindow(t *testing.T) {
+	now := time.Now()
+	ctx := context.Background()
+	throttle := newTestLoginThrottle(nil, &now)
+
+	throttle.RecordFailure(ctx, "user@example.com", "")
+	throttle.RecordFailure(ctx, "user@example.com", "")
+
+	// Once forgotten, the next failure starts from the first backoff
+	now = now.Add(2 * time.Hour)
+	throttle.RecordFailure(ctx, "user@example.com", "")
+	if wait := throttle.Check("user@example.com", ""); wait != time.Second {
+		t.Errorf("Check() after the failure window = %v, want 1s", wait)
+	}
+}
+
+func TestLoginThrottle_MaxEntries(t *testing.T) {
+	now := time.Now()
+	ctx := context.Background()
+	throttle := newTestLoginThrottle(nil, &now)
+
+	for i := 0; i < 20; i++ {
+		throttle.RecordFailure(ctx, string(rune('a'+i))+"@example.com", "")
+		now = now.Add(time.Millisecond)
+	}
+
+	if len(throttle.entries) > 10 {
+		t.Errorf("tracked %d entries, want at most 10", len(throttle.entries))
+	}
+	if wait := throttle.Check("t@example.com", ""); wait == 0 {
+		t.Error("Check() forgot the most recent failure")
+	}
+}
+
+func TestLoginThrottle_BlockAfterLockouts(t *testing.T) {
+	now := time.Now()
+	ctx := context.Background()
+	admin := &blockingAdminService{identities: map[string]string{"user@example.com": "identity-123"}}
+	throttle := newTestLoginThrottle(admin, &now)
+	throttle.cfg.BlockAfterLockouts = 2
+
+	for lockout := 1; lockout <= 2; lockout++ {
+		for i := 0; i < 3; i++ {
+			throttle.RecordFailure(ctx, "user@example.com", "")
+		}
+		now = now.Add(2 * time.Minute)
+
+		blocked := len(admin.patched) == 1
+		if blocked != (lockout == 2) {
+			t.Fatalf("after lockout %d blocked = %v, want %v", lockout, blocked, lockout == 2)
+		}
+	}
+
+	if admin.patched[0] != "identity-123" || len(admin.revoked) != 1 {
+		t.Errorf("blocked %v, revoked sessions of %v, want identity-123", admin.patched, admin.revoked)
+	}
+}
@@ -0,0 +1,131 @@
+// Package authz checks relationship-based permissions of the form
+// namespace:object#relation@subject, backed by Ory Keto or an in-memory store.
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidTuple is returned when a relation tuple cannot be parsed
+var ErrInvalidTuple = errors.New("invalid relation tuple")
+
+// PermissionChecker checks whether a relation tuple holds
+type PermissionChecker interface {
+	Check(ctx context.Context, tuple RelationTuple) (bool, error)
+}
+
+// SubjectSet is a subject that refers to everyone with a relation on an
+// object, e.g. groups:admins#member
+type SubjectSet struct {
+	Namespace string
+	Object    string
+	Relation  string
+}
+
+// String formats the subject set as namespace:object#relation
+func (s SubjectSet) String() string {
+	return s.Namespace + ":" + s.Object + "#" + s.Relation
+}
+
+// RelationTuple states that a subject has a relation on an object.
+// Exactly one of SubjectID and SubjectSet is set.
+type RelationTuple struct {
+	Namespace  string
+	Object     string
+	Relation   string
+	SubjectID  string
+	SubjectSet *SubjectSet
+}
+
+// NewRelationTuple creates a tuple for a subject ID, typically an identity ID
+func NewRelationTuple(namespace, object, relation, subjectID string) RelationTuple {
+	return RelationTuple{
+		Namespace: namespace,
+		Object:    object,
+		Relation:  relation,
+		SubjectID: subjectID,
+	}
+}
+
+// String formats the tuple as namespace:object#relation@subject
+func (t RelationTuple) String() string {
+	subject := t.SubjectID
+	if t.SubjectSet != nil {
+		subject = t.SubjectSet.String()
+	}
+	return t.Namespace + ":" + t.Object + "#" + t.Relation + "@" + subject
+}
+
+// Validate checks that every part of the tuple is set
+func (t RelationTuple) Validate() error {
+	if t.Namespace == "" || t.Object == "" || t.Relation == "" {
+		return fmt.Errorf("%w: namespace, object and relation are required", ErrInvalidTuple)
+	}
+
+	if (t.SubjectID == "") == (t.SubjectSet == nil) {
+		return fmt.Errorf("%w: exactly one of subject ID and subject set is required", ErrInvalidTuple)
+	}
+
+	if t.SubjectSet != nil && (t.SubjectSet.Namespace == "" || t.SubjectSet.Object == "" || t.SubjectSet.Relation == "") {
+		return fmt.Errorf("%w: subject set needs namespace, object and relation", ErrInvalidTuple)
+	}
+
+	return nil
+}
+
+// ParseRelationTuple parses namespace:object#relation@subject, where subject
+// is a subject ID or a subject set namespace:object#relation (optionally
+// wrapped in parentheses)
+func ParseRelationTuple(s string) (RelationTuple, error) {
+	s = strings.TrimSpace(s)
+
+	object, subject, ok := strings.Cut(s, "@")
+	if !ok {
+		return RelationTuple{}, fmt.Errorf("%w: %q is missing @subject", ErrInvalidTuple, s)
+	}
+
+	namespace, objectID, relation, ok := splitObjectRelation(object)
+	if !ok {
+		return RelationTuple{}, fmt.Errorf("%w: %q is not namespace:object#relation@subject", ErrInvalidTuple, s)
+	}
+
+	tuple := RelationTuple{
+		Namespace: namespace,
+		Object:    objectID,
+		Relation:  relation,
+	}
+
+	subject = strings.TrimSuffix(strings.TrimPrefix(subject, "("), ")")
+	if strings.Contains(subject, ":") {
+		setNamespace, setObject, setRelation, ok := splitObjectRelation(subject)
+		if !ok {
+			return RelationTuple{}, fmt.Errorf("%w: subject set %q is not namespace:object#relation", ErrInvalidTuple, subject)
+		}
+		tuple.SubjectSet = &SubjectSet{Namespace: setNamespace, Object: setObject, Relation: setRelation}
+	} else {
+		tuple.SubjectID = subject
+	}
+
+	if err := tuple.Validate(); err != nil {
+		return RelationTuple{}, err
+	}
+	return tuple, nil
+}
+
+// splitObjectRelation splits namespace:object#relation into its parts
+func splitObjectRelation(s string) (namespace, object, relation string, ok bool) {
+	namespace, rest, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", "", "", false
+	}
+
+	object, relation, ok = strings.Cut(rest, "#")
+	if !ok {
+		return "", "", "", false
+	}
+
+	return namespace, object, relation, true
+}
@@ -0,0 +1,50 @@
+package authz
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseRelationTuple(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantSet bool
+		wantErr bool
+	}{
+		{name: "subject ID", input: "conversations:conv-1#viewer@identity-123", want: "conversations:conv-1#viewer@identity-123"},
+		{name: "subject set", input: "conversations:conv-1#viewer@groups:team#member", want: "conversations:conv-1#viewer@groups:team#member", wantSet: true},
+		{name: "parenthesized subject set", input: " conversations:conv-1#viewer@(groups:team#member) ", want: "conversations:conv-1#viewer@groups:team#member", wantSet: true},
+		{name: "missing subject", input: "conversations:conv-1#viewer", wantErr: true},
+		{name: "missing relation", input: "conversations:conv-1@identity-123", wantErr: true},
+		{name: "missing namespace", input: "conv-1#viewer@identity-123", wantErr: true},
+		{name: "empty subject", input: "conversations:conv-1#viewer@", wantErr: true},
+		{name: "incomplete subject set", input: "conversations:conv-1#viewer@groups:team", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRelationTuple(tt.input)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRelationTuple() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTuple) {
+					t.Errorf("ParseRelationTuple() error = %v, want ErrInvalidTuple", err)
+				}
+				return
+			}
+
+			if got.String() != tt.want {
+				t.Errorf("ParseRelationTuple() = %q, want %q", got.String(), tt.want)
+			}
+
+			if (got.SubjectSet != nil) != tt.wantSet {
+				t.Errorf("ParseRelationTuple() subject set = %v, want set %v", got.SubjectSet, tt.wantSet)
+			}
+		})
+	}
+}
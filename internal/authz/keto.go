@@ -0,0 +1,100 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ketoCheckPath is the Keto read API endpoint that always answers 200 with
+// {"allowed": bool}, unlike /relation-tuples/check which answers 403 on deny
+const ketoCheckPath = "/relation-tuples/check/openapi"
+
+// KetoClient checks permissions against the Ory Keto read API
+type KetoClient struct {
+	readURL    string
+	httpClient *http.Client
+}
+
+// NewKetoClient creates a Keto client for the read API at readURL,
+// e.g. http://localhost:4466
+func NewKetoClient(readURL string) *KetoClient {
+	return &KetoClient{
+		readURL:    strings.TrimSuffix(readURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type ketoSubjectSet struct {
+	Namespace string `json:"namespace"`
+	Object    string `json:"object"`
+	Relation  string `json:"relation"`
+}
+
+type ketoCheckRequest struct {
+	Namespace  string          `json:"namespace"`
+	Object     string          `json:"object"`
+	Relation   string          `json:"relation"`
+	SubjectID  string          `json:"subject_id,omitempty"`
+	SubjectSet *ketoSubjectSet `json:"subject_set,omitempty"`
+}
+
+type ketoCheckResponse struct {
+	Allowed bool `json:"allowed"`
+}
+
+// Check asks Keto whether the tuple holds, directly or through subject sets
+func (k *KetoClient) Check(ctx context.Context, tuple RelationTuple) (bool, error) {
+	if err := tuple.Validate(); err != nil {
+		return false, err
+	}
+
+	body := ketoCheckRequest{
+		Namespace: tuple.Namespace,
+		Object:    tuple.Object,
+		Relation:  tuple.Relation,
+		SubjectID: tuple.SubjectID,
+	}
+	if tuple.SubjectSet != nil {
+		body.SubjectSet = &ketoSubjectSet{
+			Namespace: tuple.SubjectSet.Namespace,
+			Object:    tuple.SubjectSet.Object,
+			Relation:  tuple.SubjectSet.Relation,
+		}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode permission check: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.readURL+ketoCheckPath, bytes.NewReader(payload))
+	if err != nil {
+		return false, fmt.Errorf("failed to create permission check: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to check permission: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, fmt.Errorf("failed to check permission: keto returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	var result ketoCheckResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("failed to decode permission check: %w", err)
+	}
+
+	return result.Allowed, nil
+}
@@ -0,0 +1,99 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKetoClient_Check(t *testing.T) {
+	tests := []struct {
+		name        string
+		tuple       RelationTuple
+		status      int
+		body        string
+		wantAllowed bool
+		wantErr     bool
+	}{
+		{
+			name:        "allowed",
+			tuple:       NewRelationTuple("conversations", "conv-1", "viewer", "identity-123"),
+			status:      http.StatusOK,
+			body:        `{"allowed": true}`,
+			wantAllowed: true,
+		},
+		{
+			name:   "denied",
+			tuple:  NewRelationTuple("conversations", "conv-1", "viewer", "identity-123"),
+			status: http.StatusOK,
+			body:   `{"allowed": false}`,
+		},
+		{
+			name: "subject set",
+			tuple: RelationTuple{
+				Namespace:  "conversations",
+				Object:     "conv-1",
+				Relation:   "viewer",
+				SubjectSet: &SubjectSet{Namespace: "groups", Object: "team", Relation: "member"},
+			},
+			status:      http.StatusOK,
+			body:        `{"allowed": true}`,
+			wantAllowed: true,
+		},
+		{
+			name:    "keto error",
+			tuple:   NewRelationTuple("conversations", "conv-1", "viewer", "identity-123"),
+			status:  http.StatusInternalServerError,
+			body:    `{"error": {"message": "boom"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid tuple",
+			tuple:   NewRelationTuple("conversations", "", "viewer", "identity-123"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got ketoCheckRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != ketoCheckPath {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("failed to decode request: %v", err)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := NewKetoClient(server.URL + "/")
+
+			allowed, err := client.Check(context.Background(), tt.tuple)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if allowed != tt.wantAllowed {
+				t.Errorf("Check() = %v, want %v", allowed, tt.wantAllowed)
+			}
+
+			if tt.status == 0 {
+				return
+			}
+
+			if got.Namespace != tt.tuple.Namespace || got.Object != tt.tuple.Object || got.Relation != tt.tuple.Relation || got.SubjectID != tt.tuple.SubjectID {
+				t.Errorf("Check() sent %+v for %s", got, tt.tuple)
+			}
+
+			if (got.SubjectSet != nil) != (tt.tuple.SubjectSet != nil) {
+				t.Errorf("Check() sent subject set %+v, want %+v", got.SubjectSet, tt.tuple.SubjectSet)
+			}
+		})
+	}
+}
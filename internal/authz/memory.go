@@ -0,0 +1,126 @@
+package authz
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// maxCheckDepth bounds how many subject sets a check follows, like Keto's
+// max-depth, so cyclic tuples cannot recurse forever
+const maxCheckDepth = 5
+
+// MemoryStore is an in-memory PermissionChecker for tests and local runs
+// without Keto. It resolves subject sets but not Keto namespace rewrites.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	tuples map[string]RelationTuple
+}
+
+// NewMemoryStore creates a store holding the given tuples
+func NewMemoryStore(tuples ...RelationTuple) *MemoryStore {
+	s := &MemoryStore{tuples: make(map[string]RelationTuple)}
+	for _, tuple := range tuples {
+		s.tuples[tuple.String()] = tuple
+	}
+	return s
+}
+
+// LoadMemoryStore creates a store from a file with one tuple per line.
+// Blank lines and lines starting with # are ignored.
+func LoadMemoryStore(path string) (*MemoryStore, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open relation tuples: %w", err)
+	}
+	defer file.Close()
+
+	store := NewMemoryStore()
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		tuple, err := ParseRelationTuple(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+		store.tuples[tuple.String()] = tuple
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read relation tuples: %w", err)
+	}
+
+	return store, nil
+}
+
+// Add stores relation tuples
+func (s *MemoryStore) Add(tuples ...RelationTuple) error {
+	for _, tuple := range tuples {
+		if err := tuple.Validate(); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tuple := range tuples {
+		s.tuples[tuple.String()] = tuple
+	}
+	return nil
+}
+
+// Delete removes relation tuples; missing tuples are ignored
+func (s *MemoryStore) Delete(tuples ...RelationTuple) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tuple := range tuples {
+		delete(s.tuples, tuple.String())
+	}
+}
+
+// Check reports whether the tuple is stored, or whether its subject holds
+// the relation through a stored subject set
+func (s *MemoryStore) Check(ctx context.Context, tuple RelationTuple) (bool, error) {
+	if err := tuple.Validate(); err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.check(tuple, maxCheckDepth), nil
+}
+
+func (s *MemoryStore) check(tuple RelationTuple, depth int) bool {
+	if depth <= 0 {
+		return false
+	}
+
+	if _, ok := s.tuples[tuple.String()]; ok {
+		return true
+	}
+
+	for _, stored := range s.tuples {
+		if stored.SubjectSet == nil ||
+			stored.Namespace != tuple.Namespace ||
+			stored.Object != tuple.Object ||
+			stored.Relation != tuple.Relation {
+			continue
+		}
+
+		member := tuple
+		member.Namespace = stored.SubjectSet.Namespace
+		member.Object = stored.SubjectSet.Object
+		member.Relation = stored.SubjectSet.Relation
+		if s.check(member, depth-1) {
+			return true
+		}
+	}
+
+	return false
+}
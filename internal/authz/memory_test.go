@@ -0,0 +1,111 @@
+package authz
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func mustParse(t *testing.T, s string) RelationTuple {
+	t.Helper()
+	tuple, err := ParseRelationTuple(s)
+	if err != nil {
+		t.Fatalf("ParseRelationTuple(%q) error = %v", s, err)
+	}
+	return tuple
+}
+
+func TestMemoryStore_Check(t *testing.T) {
+	store := NewMemoryStore(
+		mustParse(t, "conversations:conv-1#owner@alice"),
+		mustParse(t, "conversations:conv-1#viewer@groups:team#member"),
+		mustParse(t, "groups:team#member@bob"),
+		mustParse(t, "groups:team#member@groups:contractors#member"),
+		mustParse(t, "groups:contractors#member@carol"),
+		// A cycle must not recurse forever
+		mustParse(t, "groups:a#member@groups:b#member"),
+		mustParse(t, "groups:b#member@groups:a#member"),
+	)
+
+	tests := []struct {
+		tuple string
+		want  bool
+	}{
+		{tuple: "conversations:conv-1#owner@alice", want: true},
+		{tuple: "conversations:conv-1#viewer@alice", want: false},
+		{tuple: "conversations:conv-1#viewer@bob", want: true},
+		{tuple: "conversations:conv-1#viewer@carol", want: true},
+		{tuple: "conversations:conv-2#viewer@bob", want: false},
+		{tuple: "conversations:conv-1#viewer@groups:team#member", want: true},
+		{tuple: "groups:a#member@dave", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tuple, func(t *testing.T) {
+			got, err := store.Check(context.Background(), mustParse(t, tt.tuple))
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Check(%s) = %v, want %v", tt.tuple, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_AddDelete(t *testing.T) {
+	store := NewMemoryStore()
+	tuple := NewRelationTuple("conversations", "conv-1", "viewer", "bob")
+
+	if err := store.Add(tuple); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	if allowed, _ := store.Check(context.Background(), tuple); !allowed {
+		t.Fatal("Check() = false after Add")
+	}
+
+	store.Delete(tuple)
+
+	if allowed, _ := store.Check(context.Background(), tuple); allowed {
+		t.Error("Check() = true after Delete")
+	}
+
+	if err := store.Add(RelationTuple{Namespace: "conversations"}); err == nil {
+		t.Error("Add() accepted an invalid tuple")
+	}
+}
+
+func TestLoadMemoryStore(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "tuples.txt")
+	content := "# shared conversations\n\nconversations:conv-1#viewer@bob\n"
+	if err := os.WriteFile(valid, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := LoadMemoryStore(valid)
+	if err != nil {
+		t.Fatalf("LoadMemoryStore() error = %v", err)
+	}
+
+	if allowed, _ := store.Check(context.Background(), NewRelationTuple("conversations", "conv-1", "viewer", "bob")); !allowed {
+		t.Error("LoadMemoryStore() did not load the tuple")
+	}
+
+	invalid := filepath.Join(dir, "invalid.txt")
+	if err := os.WriteFile(invalid, []byte("conversations:conv-1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadMemoryStore(invalid); err == nil {
+		t.Error("LoadMemoryStore() accepted an invalid tuple")
+	}
+
+	if _, err := LoadMemoryStore(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("LoadMemoryStore() accepted a missing file")
+	}
+}
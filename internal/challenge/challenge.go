@@ -0,0 +1,169 @@
+// Package challenge protects public endpoints from automated abuse with
+// CAPTCHA or proof-of-work challenges, demanded once a client looks risky.
+package challenge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrChallengeFailed is returned when a challenge response is missing,
+// wrong, expired or already used
+var ErrChallengeFailed = errors.New("challenge failed")
+
+// Challenge tells a client how to produce a challenge response
+type Challenge struct {
+	// Type is the verifier, e.g. "pow", "hcaptcha" or "turnstile"
+	Type string
+	// SiteKey is the CAPTCHA widget's public key
+	SiteKey string
+	// Token and Difficulty describe a proof-of-work puzzle
+	Token      string
+	Difficulty int
+	ExpiresAt  time.Time
+}
+
+// Verifier checks challenge responses
+type Verifier interface {
+	// Challenge returns what a client needs to produce a response
+	Challenge(ctx context.Context) (*Challenge, error)
+	// Verify checks a response from the client at remoteIP. Rejected
+	// responses return ErrChallengeFailed; other errors mean the verifier
+	// could not decide.
+	Verify(ctx context.Context, response, remoteIP string) error
+}
+
+// GuardConfig configures a Guard
+type GuardConfig struct {
+	// Threshold is how many guarded requests a client IP may make within
+	// Window before it must solve a challenge; 0 always requires one
+	Threshold int
+	// GlobalThreshold requires a challenge from every client once all of
+	// them together made that many requests within Window; 0 disables it
+	GlobalThreshold int
+	// Window is how long requests are counted for
+	Window time.Duration
+	// MaxEntries bounds the client IPs tracked; when full, IPs whose window
+	// has ended are forgotten and then the oldest
+	MaxEntries int
+}
+
+// Guard counts requests to guarded endpoints per client IP and overall, and
+// decides when a challenge is required. Counts are held in process, so each
+// replica counts separately.
+type Guard struct {
+	cfg GuardConfig
+	now func() time.Time
+
+	mu     sync.Mutex
+	ips    map[string]*requestCount
+	global requestCount
+}
+
+// requestCount counts requests in a fixed window starting at start
+type requestCount struct {
+	start time.Time
+	count int
+}
+
+// NewGuard creates a guard
+func NewGuard(cfg GuardConfig) *Guard {
+	return &Guard{
+		cfg: cfg,
+		now: time.Now,
+		ips: make(map[string]*requestCount),
+	}
+}
+
+// Hit counts a request from ip and reports whether it must carry a solved
+// challenge
+func (g *Guard) Hit(ip string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	entry := g.entry(ip, now)
+	if entry == nil {
+		g.evict(now)
+		entry = &requestCount{start: now}
+		g.ips[ip] = entry
+	}
+	entry.count++
+
+	g.resetIfEnded(&g.global, now)
+	g.global.count++
+
+	return g.required(entry.count, g.global.count)
+}
+
+// Required reports whether the next request from ip must carry a solved
+// challenge, without counting it
+func (g *Guard) Required(ip string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	g.resetIfEnded(&g.global, now)
+
+	count := 0
+	if entry := g.entry(ip, now); entry != nil {
+		count = entry.count
+	}
+
+	return g.required(count+1, g.global.count+1)
+}
+
+// required applies the thresholds to the request counts including the
+// request in question
+func (g *Guard) required(ipCount, globalCount int) bool {
+	if g.cfg.Threshold <= 0 || ipCount > g.cfg.Threshold {
+		return true
+	}
+	return g.cfg.GlobalThreshold > 0 && globalCount > g.cfg.GlobalThreshold
+}
+
+// entry returns ip's count, dropping it once its window has ended; the
+// caller must hold mu
+func (g *Guard) entry(ip string, now time.Time) *requestCount {
+	entry, ok := g.ips[ip]
+	if !ok {
+		return nil
+	}
+	if now.Sub(entry.start) >= g.cfg.Window {
+		delete(g.ips, ip)
+		return nil
+	}
+	return entry
+}
+
+func (g *Guard) resetIfEnded(count *requestCount, now time.Time) {
+	if now.Sub(count.start) >= g.cfg.Window {
+		count.start, count.count = now, 0
+	}
+}
+
+// evict makes room for a new IP; the caller must hold mu
+func (g *Guard) evict(now time.Time) {
+	if g.cfg.MaxEntries <= 0 || len(g.ips) < g.cfg.MaxEntries {
+		return
+	}
+
+	for ip, entry := range g.ips {
+		if now.Sub(entry.start) >= g.cfg.Window {
+			delete(g.ips, ip)
+		}
+	}
+
+	for len(g.ips) >= g.cfg.MaxEntries {
+		var oldestIP string
+		var oldest time.Time
+		for ip, entry := range g.ips {
+			if oldestIP == "" || entry.start.Before(oldest) {
+				oldestIP, oldest = ip, entry.start
+			}
+		}
+		delete(g.ips, oldestIP)
+	}
+}
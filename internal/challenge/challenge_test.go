@@ -0,0 +1,97 @@
+package challenge
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestGuard(cfg GuardConfig, now *time.Time) *Guard {
+	guard := NewGuard(cfg)
+	guard.now = func() time.Time { return *now }
+	return guard
+}
+
+func TestGuard_Threshold(t *testing.T) {
+	now := time.Now()
+	guard := newTestGuard(GuardConfig{Threshold: 2, Window: time.Minute, MaxEntries: 10}, &now)
+
+	for i := 1; i <= 2; i++ {
+		if guard.Hit("203.0.113.7") {
+			t.Fatalf("Hit() %d within the threshold required a challenge", i)
+		}
+	}
+	if !guard.Required("203.0.113.7") {
+		t.Error("Required() after the threshold = false, want true")
+	}
+	if !guard.Hit("203.0.113.7") {
+		t.Error("Hit() after the threshold = false, want true")
+	}
+	if guard.Hit("198.51.100.1") {
+		t.Error("Hit() from another IP required a challenge")
+	}
+
+	now = now.Add(time.Minute)
+	if guard.Hit("203.0.113.7") {
+		t.Error("Hit() after the window required a challenge")
+	}
+}
+
+func TestGuard_ZeroThreshold(t *testing.T) {
+	now := time.Now()
+	guard := newTestGuard(GuardConfig{Window: time.Minute}, &now)
+
+	if !guard.Required("203.0.113.7") || !guard.Hit("203.0.113.7") {
+		t.Error("a zero threshold must always require a challenge")
+	}
+}
+
+func TestGuard_Required_DoesNotCount(t *testing.T) {
+	now := time.Now()
+	guard := newTestGuard(GuardConfig{Threshold: 1, GlobalThreshold: 1, Window: time.Minute, MaxEntries: 10}, &now)
+
+	for i := 0; i < 3; i++ {
+		if guard.Required("203.0.113.7") {
+			t.Fatal("Required() counted the request")
+		}
+	}
+	if guard.Hit("203.0.113.7") {
+		t.Error("Hit() after Required() calls required a challenge")
+	}
+}
+
+func TestGuard_GlobalThreshold(t *testing.T) {
+	now := time.Now()
+	guard := newTestGuard(GuardConfig{Threshold: 5, GlobalThreshold: 3, Window: time.Minute, MaxEntries: 10}, &now)
+
+	for i := 0; i < 3; i++ {
+		if guard.Hit(fmt.Sprintf("203.0.113.%d", i)) {
+			t.Fatalf("Hit() %d within the global threshold required a challenge", i)
+		}
+	}
+	if !guard.Hit("198.51.100.1") {
+		t.Error("Hit() past the global threshold = false, want true for a new IP")
+	}
+
+	now = now.Add(time.Minute)
+	if guard.Hit("198.51.100.1") {
+		t.Error("Hit() after the window required a challenge")
+	}
+}
+
+func TestGuard_MaxEntries(t *testing.T) {
+	now := time.Now()
+	guard := newTestGuard(GuardConfig{Threshold: 1, Window: time.Hour, MaxEntries: 10}, &now)
+
+	for i := 0; i < 20; i++ {
+		guard.Hit(fmt.Sprintf("203.0.113.%d", i))
+		now = now.Add(time.Millisecond)
+	}
+
+	if len(guard.ips) > 10 {
+		t.Errorf("tracked %d IPs, want at most 10", len(guard.ips))
+	}
+	if !guard.Required("203.0.113.19") {
+		t.Error("Required() forgot the most recent IP")
+	}
+}
@@ -0,0 +1,188 @@
+package challenge
+
+import (
+	"container/heap"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TypeProofOfWork is the Challenge type of ProofOfWork puzzles
+const TypeProofOfWork = "pow"
+
+// defaultMaxSpentTokens bounds how many unexpired tokens ProofOfWork
+// remembers as used
+const defaultMaxSpentTokens = 100000
+
+// ProofOfWork is a self-hosted Verifier. Its puzzles are signed tokens; a
+// client solves one by finding a solution whose SHA-256 of
+// "<token>:<solution>" starts with Difficulty zero bits, and responds with
+// "<token>:<solution>". Each token is accepted once, until it expires. Used
+// tokens are remembered in memory, so another replica accepts a token once
+// more. Once maxSpent unexpired tokens are held, solutions are refused until
+// some expire.
+type ProofOfWork struct {
+	key        []byte
+	difficulty int
+	ttl        time.Duration
+	now        func() time.Time
+	maxSpent   int
+
+	mu    sync.Mutex
+	spent map[string]struct{}
+	// expiries holds the spent tokens, soonest expiring first
+	expiries spentQueue
+}
+
+// NewProofOfWork creates a proof-of-work verifier signing puzzles with key.
+// Each puzzle takes about 2^difficulty hashes to solve and expires after ttl.
+func NewProofOfWork(key []byte, difficulty int, ttl time.Duration) *ProofOfWork {
+	return &ProofOfWork{
+		key:        key,
+		difficulty: difficulty,
+		ttl:        ttl,
+		now:        time.Now,
+		maxSpent:   defaultMaxSpentTokens,
+		spent:      make(map[string]struct{}),
+	}
+}
+
+// Challenge issues a new puzzle
+func (p *ProofOfWork) Challenge(ctx context.Context) (*Challenge, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+
+	expiresAt := p.now().Add(p.ttl).Truncate(time.Second)
+	payload := fmt.Sprintf("%s.%d.%d", hex.EncodeToString(nonce), expiresAt.Unix(), p.difficulty)
+
+	return &Challenge{
+		Type:       TypeProofOfWork,
+		Token:      payload + "." + p.sign(payload),
+		Difficulty: p.difficulty,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// Verify checks a "<token>:<solution>" response
+func (p *ProofOfWork) Verify(ctx context.Context, response, remoteIP string) error {
+	token, solution, ok := strings.Cut(response, ":")
+	if !ok || solution == "" {
+		return fmt.Errorf("%w: response must be <token>:<solution>", ErrChallengeFailed)
+	}
+
+	payload, signature, ok := cutLast(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(p.sign(payload))) {
+		return fmt.Errorf("%w: invalid token", ErrChallengeFailed)
+	}
+
+	// payload is <nonce>.<expires>.<difficulty>, as signed by Challenge
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("%w: invalid token", ErrChallengeFailed)
+	}
+	expiresUnix, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid token", ErrChallengeFailed)
+	}
+	difficulty, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return fmt.Errorf("%w: invalid token", ErrChallengeFailed)
+	}
+
+	now := p.now()
+	expiresAt := time.Unix(expiresUnix, 0)
+	if !now.Before(expiresAt) {
+		return fmt.Errorf("%w: token expired", ErrChallengeFailed)
+	}
+
+	if leadingZeroBits(sha256.Sum256([]byte(response))) < difficulty {
+		return fmt.Errorf("%w: wrong solution", ErrChallengeFailed)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.expiries) > 0 && !now.Before(p.expiries[0].expires) {
+		expired := heap.Pop(&p.expiries).(spentToken)
+		delete(p.spent, expired.token)
+	}
+	if _, used := p.spent[token]; used {
+		return fmt.Errorf("%w: token already used", ErrChallengeFailed)
+	}
+	if len(p.spent) >= p.maxSpent {
+		return fmt.Errorf("%w: too many challenges in use, try again later", ErrChallengeFailed)
+	}
+	p.spent[token] = struct{}{}
+	heap.Push(&p.expiries, spentToken{token: token, expires: expiresAt})
+
+	return nil
+}
+
+// spentToken is a used token and when it expires
+type spentToken struct {
+	token   string
+	expires time.Time
+}
+
+// spentQueue is a min-heap of spent tokens by expiry
+type spentQueue []spentToken
+
+func (q spentQueue) Len() int           { return len(q) }
+func (q spentQueue) Less(i, j int) bool { return q[i].expires.Before(q[j].expires) }
+func (q spentQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *spentQueue) Push(x any) { *q = append(*q, x.(spentToken)) }
+
+func (q *spentQueue) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+func (p *ProofOfWork) sign(payload string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SolveProofOfWork finds a solution to a puzzle token, returning the full
+// "<token>:<solution>" response. Clients do the same work in their own
+// language; this is for Go clients and tests.
+func SolveProofOfWork(token string, difficulty int) string {
+	for i := 0; ; i++ {
+		response := token + ":" + strconv.Itoa(i)
+		if leadingZeroBits(sha256.Sum256([]byte(response))) >= difficulty {
+			return response
+		}
+	}
+}
+
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			return zeros + bits.LeadingZeros8(b)
+		}
+		zeros += 8
+	}
+	return zeros
+}
+
+// cutLast slices s around the last sep
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,127 @@
+package challenge
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProofOfWork_Verify(t *testing.T) {
+	ctx := context.Background()
+	pow := NewProofOfWork([]byte("test-key"), 8, time.Minute)
+
+	c, err := pow.Challenge(ctx)
+	if err != nil {
+		t.Fatalf("Challenge() error = %v", err)
+	}
+	if c.Type != TypeProofOfWork || c.Difficulty != 8 || c.Token == "" {
+		t.Fatalf("Challenge() = %+v, want a pow puzzle of difficulty 8", c)
+	}
+
+	response := SolveProofOfWork(c.Token, c.Difficulty)
+	if err := pow.Verify(ctx, response, "203.0.113.7"); err != nil {
+		t.Fatalf("Verify() of a solution error = %v", err)
+	}
+
+	if err := pow.Verify(ctx, response, "203.0.113.7"); !errors.Is(err, ErrChallengeFailed) {
+		t.Errorf("Verify() of a used token error = %v, want ErrChallengeFailed", err)
+	}
+}
+
+func TestProofOfWork_Verify_Rejected(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	pow := NewProofOfWork([]byte("test-key"), 8, time.Minute)
+	pow.now = func() time.Time { return now }
+
+	c, err := pow.Challenge(ctx)
+	if err != nil {
+		t.Fatalf("Challenge() error = %v", err)
+	}
+	solved := SolveProofOfWork(c.Token, c.Difficulty)
+
+	// A wrong solution falls short of the difficulty
+	wrong := c.Token + ":0"
+	for i := 1; leadingZeroBits(sha256.Sum256([]byte(wrong))) >= c.Difficulty; i++ {
+		wrong = c.Token + ":" + strconv.Itoa(i)
+	}
+
+	other := NewProofOfWork([]byte("other-key"), 8, time.Minute)
+	forged, err := other.Challenge(ctx)
+	if err != nil {
+		t.Fatalf("Challenge() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		response string
+	}{
+		{name: "empty", response: ""},
+		{name: "no solution", response: c.Token},
+		{name: "wrong solution", response: wrong},
+		{name: "tampered difficulty", response: strings.Replace(solved, ".8.", ".1.", 1)},
+		{name: "other key", response: SolveProofOfWork(forged.Token, forged.Difficulty)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := pow.Verify(ctx, tt.response, ""); !errors.Is(err, ErrChallengeFailed) {
+				t.Errorf("Verify() error = %v, want ErrChallengeFailed", err)
+			}
+		})
+	}
+
+	t.Run("expired", func(t *testing.T) {
+		now = now.Add(2 * time.Minute)
+		if err := pow.Verify(ctx, solved, ""); !errors.Is(err, ErrChallengeFailed) {
+			t.Errorf("Verify() error = %v, want ErrChallengeFailed", err)
+		}
+	})
+}
+
+func TestProofOfWork_SpentTokens(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	pow := NewProofOfWork([]byte("test-key"), 4, time.Minute)
+	pow.now = func() time.Time { return now }
+	pow.maxSpent = 2
+
+	solve := func() string {
+		t.Helper()
+		c, err := pow.Challenge(ctx)
+		if err != nil {
+			t.Fatalf("Challenge() error = %v", err)
+		}
+		return SolveProofOfWork(c.Token, c.Difficulty)
+	}
+
+	first := solve()
+	now = now.Add(30 * time.Second)
+	second, third := solve(), solve()
+
+	if err := pow.Verify(ctx, first, ""); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if err := pow.Verify(ctx, second, ""); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if err := pow.Verify(ctx, third, ""); !errors.Is(err, ErrChallengeFailed) {
+		t.Fatalf("Verify() with the spent set full error = %v, want ErrChallengeFailed", err)
+	}
+
+	// Once the first token expires it is pruned, making room for the third
+	now = now.Add(31 * time.Second)
+	if err := pow.Verify(ctx, third, ""); err != nil {
+		t.Fatalf("Verify() after a spent token expired error = %v", err)
+	}
+	if len(pow.spent) != 2 || len(pow.expiries) != 2 {
+		t.Errorf("spent tokens = %d (queued %d), want 2", len(pow.spent), len(pow.expiries))
+	}
+	if err := pow.Verify(ctx, second, ""); !errors.Is(err, ErrChallengeFailed) {
+		t.Errorf("Verify() of a used token error = %v, want ErrChallengeFailed", err)
+	}
+}
@@ -0,0 +1,73 @@
+package challenge
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSiteVerifier_Verify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("failed to parse form: %v", err)
+		}
+		if r.PostForm.Get("secret") != "test-secret" {
+			t.Errorf("secret = %q, want %q", r.PostForm.Get("secret"), "test-secret")
+		}
+
+		switch r.PostForm.Get("response") {
+		case "valid":
+			if r.PostForm.Get("remoteip") != "203.0.113.7" {
+				t.Errorf("remoteip = %q, want %q", r.PostForm.Get("remoteip"), "203.0.113.7")
+			}
+			w.Write([]byte(`{"success": true}`))
+		case "unavailable":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"success": false, "error-codes": ["invalid-input-response"]}`))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	verifier := NewSiteVerifier(TypeTurnstile, "site-key", "test-secret", server.URL)
+
+	c, err := verifier.Challenge(ctx)
+	if err != nil {
+		t.Fatalf("Challenge() error = %v", err)
+	}
+	if c.Type != TypeTurnstile || c.SiteKey != "site-key" {
+		t.Errorf("Challenge() = %+v, want the turnstile site key", c)
+	}
+
+	if err := verifier.Verify(ctx, "valid", "203.0.113.7"); err != nil {
+		t.Errorf("Verify() of a valid response error = %v", err)
+	}
+
+	if err := verifier.Verify(ctx, "invalid", "203.0.113.7"); !errors.Is(err, ErrChallengeFailed) {
+		t.Errorf("Verify() of an invalid response error = %v, want ErrChallengeFailed", err)
+	}
+
+	err = verifier.Verify(ctx, "unavailable", "203.0.113.7")
+	if err == nil || errors.Is(err, ErrChallengeFailed) {
+		t.Errorf("Verify() with the provider down error = %v, want a non-challenge error", err)
+	}
+}
+
+func TestNewSiteVerifier_DefaultURL(t *testing.T) {
+	tests := []struct {
+		kind string
+		want string
+	}{
+		{kind: TypeHCaptcha, want: HCaptchaVerifyURL},
+		{kind: TypeTurnstile, want: TurnstileVerifyURL},
+	}
+
+	for _, tt := range tests {
+		if got := NewSiteVerifier(tt.kind, "site-key", "secret", "").verifyURL; got != tt.want {
+			t.Errorf("NewSiteVerifier(%q) URL = %q, want %q", tt.kind, got, tt.want)
+		}
+	}
+}
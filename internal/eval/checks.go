@@ -0,0 +1,200 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/langchain"
+)
+
+// maxJudgeScore is the top of the 1-5 scale used by rubric checks
+const maxJudgeScore = 5
+
+// defaultMinScore is the passing judge score when a rubric check sets none
+const defaultMinScore = 3
+
+// CheckResult is the outcome of one check against a response
+type CheckResult struct {
+	Kind    string  `json:"kind"`
+	Passed  bool    `json:"passed"`
+	Score   float64 `json:"score"`
+	Message string  `json:"message,omitempty"`
+}
+
+var judgeScorePattern = regexp.MustCompile(`(?i)"?score"?\s*[:=]\s*([0-9]+)`)
+
+// evaluate runs a single check. judge may be nil when the suite has no rubric checks.
+func evaluate(ctx context.Context, check Check, output string, judge langchain.LLMService) CheckResult {
+	result := CheckResult{Kind: check.Kind()}
+
+	switch result.Kind {
+	case "contains":
+		result.Passed = containsText(output, check.Contains, check.IgnoreCase)
+		if !result.Passed {
+			result.Message = fmt.Sprintf("expected output to contain %q", check.Contains)
+		}
+	case "not_contains":
+		result.Passed = !containsText(output, check.NotContains, check.IgnoreCase)
+		if !result.Passed {
+			result.Message = fmt.Sprintf("expected output not to contain %q", check.NotContains)
+		}
+	case "regex":
+		re, err := regexp.Compile(check.Regex)
+		if err != nil {
+			result.Message = fmt.Sprintf("invalid regex: %v", err)
+			break
+		}
+		result.Passed = re.MatchString(output)
+		if !result.Passed {
+			result.Message = fmt.Sprintf("expected output to match %q", check.Regex)
+		}
+	case "json_schema":
+		if err := validateJSONSchema(check.JSONSchema, output); err != nil {
+			result.Message = err.Error()
+			break
+		}
+		result.Passed = true
+	case "rubric":
+		return evaluateRubric(ctx, check, output, judge)
+	default:
+		result.Message = "unknown check"
+	}
+
+	if result.Passed {
+		result.Score = 1
+	}
+	return result
+}
+
+func containsText(output, substr string, ignoreCase bool) bool {
+	if ignoreCase {
+		return strings.Contains(strings.ToLower(output), strings.ToLower(substr))
+	}
+	return strings.Contains(output, substr)
+}
+
+// validateJSONSchema checks that the output is JSON matching the schema.
+// Markdown code fences around the JSON are ignored.
+func validateJSONSchema(schema, output string) error {
+	compiled, err := compileJSONSchema(schema)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(stripCodeFence(output)))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("output is not valid JSON: %v", err)
+	}
+
+	if err := compiled.Validate(value); err != nil {
+		return fmt.Errorf("output does not match schema: %v", err)
+	}
+
+	return nil
+}
+
+// compileJSONSchema compiles a json_schema check's schema
+func compileJSONSchema(schema string) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", strings.NewReader(schema)); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	compiled, err := compiler.Compile("schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return compiled, nil
+}
+
+func stripCodeFence(output string) string {
+	trimmed := strings.TrimSpace(output)
+	if !strings.HasPrefix(trimmed, "```") {
+		return trimmed
+	}
+
+	trimmed = strings.TrimPrefix(trimmed, "```")
+	if i := strings.Index(trimmed, "\n"); i >= 0 {
+		trimmed = trimmed[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(trimmed, "```"))
+}
+
+// evaluateRubric asks the judge model to score the output from 1 to 5
+func evaluateRubric(ctx context.Context, check Check, output string, judge langchain.LLMService) CheckResult {
+	result := CheckResult{Kind: "rubric"}
+
+	if judge == nil {
+		result.Message = "no judge model configured"
+		return result
+	}
+
+	minScore := check.MinScore
+	if minScore == 0 {
+		minScore = defaultMinScore
+	}
+
+	messages := langchain.ConvertMessages([]langchain.ChatMessage{
+		{
+			Role: langchain.RoleSystem,
+			Content: fmt.Sprintf("You are a strict evaluator. Score the response against the rubric from 1 (fails) to %d (fully satisfies). "+
+				`Reply only with JSON: {"score": <integer>, "reason": "<one sentence>"}`, maxJudgeScore),
+		},
+		{
+			Role:    langchain.RoleUser,
+			Content: fmt.Sprintf("Rubric:\n%s\n\nResponse:\n%s", check.Rubric, output),
+		},
+	})
+
+	verdict, err := judge.Chat(ctx, messages, llms.WithTemperature(0))
+	if err != nil {
+		result.Message = fmt.Sprintf("judge failed: %v", err)
+		return result
+	}
+
+	score, reason, err := parseJudgeVerdict(verdict)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	result.Score = float64(score) / maxJudgeScore
+	result.Passed = score >= minScore
+	result.Message = fmt.Sprintf("judge score %d/%d (min %d)", score, maxJudgeScore, minScore)
+	if reason != "" {
+		result.Message += ": " + reason
+	}
+	return result
+}
+
+// parseJudgeVerdict extracts the score from the judge's reply, accepting
+// either the requested JSON or a loose "score: N" form
+func parseJudgeVerdict(verdict string) (int, string, error) {
+	var parsed struct {
+		Score  int    `json:"score"`
+		Reason string `json:"reason"`
+	}
+
+	score := 0
+	if err := json.Unmarshal([]byte(stripCodeFence(verdict)), &parsed); err == nil {
+		score = parsed.Score
+	} else if m := judgeScorePattern.FindStringSubmatch(verdict); m != nil {
+		score, _ = strconv.Atoi(m[1])
+	}
+
+	if score < 1 || score > maxJudgeScore {
+		return 0, "", fmt.Errorf("judge returned an unusable verdict: %q", verdict)
+	}
+
+	return score, parsed.Reason, nil
+}
@@ -0,0 +1,107 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/langchain"
+)
+
+// stubJudge returns a fixed verdict
+type stubJudge struct {
+	verdict string
+	err     error
+}
+
+func (s *stubJudge) GenerateContent(ctx context.Context, prompt string, opts ...llms.CallOption) (string, error) {
+	return s.verdict, s.err
+}
+
+func (s *stubJudge) Chat(ctx context.Context, messages []llms.MessageContent, opts ...llms.CallOption) (string, error) {
+	return s.verdict, s.err
+}
+
+func TestEvaluate(t *testing.T) {
+	schema := `{"type": "object", "required": ["n"], "properties": {"n": {"type": "integer"}}}`
+
+	tests := []struct {
+		name       string
+		check      Check
+		output     string
+		judge      *stubJudge
+		wantPassed bool
+		wantScore  float64
+	}{
+		{name: "contains", check: Check{Contains: "world"}, output: "hello world", wantPassed: true, wantScore: 1},
+		{name: "contains case sensitive", check: Check{Contains: "World"}, output: "hello world", wantPassed: false},
+		{name: "contains ignore case", check: Check{Contains: "World", IgnoreCase: true}, output: "hello world", wantPassed: true, wantScore: 1},
+		{name: "not contains", check: Check{NotContains: "sorry"}, output: "hello", wantPassed: true, wantScore: 1},
+		{name: "regex", check: Check{Regex: `\d{3}`}, output: "answer: 144", wantPassed: true, wantScore: 1},
+		{name: "invalid regex", check: Check{Regex: "("}, output: "x", wantPassed: false},
+		{name: "json schema", check: Check{JSONSchema: schema}, output: `{"n": 3}`, wantPassed: true, wantScore: 1},
+		{name: "json schema in code fence", check: Check{JSONSchema: schema}, output: "```json\n{\"n\": 3}\n```", wantPassed: true, wantScore: 1},
+		{name: "json schema mismatch", check: Check{JSONSchema: schema}, output: `{"n": "three"}`, wantPassed: false},
+		{name: "not json", check: Check{JSONSchema: schema}, output: "three", wantPassed: false},
+		{
+			name:       "rubric pass",
+			check:      Check{Rubric: "polite", MinScore: 4},
+			output:     "thanks!",
+			judge:      &stubJudge{verdict: `{"score": 5, "reason": "very polite"}`},
+			wantPassed: true,
+			wantScore:  1,
+		},
+		{
+			name:       "rubric below threshold",
+			check:      Check{Rubric: "polite", MinScore: 4},
+			output:     "whatever",
+			judge:      &stubJudge{verdict: "Score: 2"},
+			wantPassed: false,
+			wantScore:  0.4,
+		},
+		{
+			name:       "rubric default threshold",
+			check:      Check{Rubric: "polite"},
+			output:     "ok",
+			judge:      &stubJudge{verdict: `{"score": 3}`},
+			wantPassed: true,
+			wantScore:  0.6,
+		},
+		{
+			name:       "rubric judge error",
+			check:      Check{Rubric: "polite"},
+			output:     "ok",
+			judge:      &stubJudge{err: errors.New("judge down")},
+			wantPassed: false,
+		},
+		{
+			name:       "rubric unusable verdict",
+			check:      Check{Rubric: "polite"},
+			output:     "ok",
+			judge:      &stubJudge{verdict: "I think it is fine"},
+			wantPassed: false,
+		},
+		{name: "rubric without judge", check: Check{Rubric: "polite"}, output: "ok", wantPassed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var judge langchain.LLMService
+			if tt.judge != nil {
+				judge = tt.judge
+			}
+
+			got := evaluate(context.Background(), tt.check, tt.output, judge)
+
+			if got.Passed != tt.wantPassed {
+				t.Errorf("evaluate() passed = %v, want %v (%s)", got.Passed, tt.wantPassed, got.Message)
+			}
+
+			if got.Score != tt.wantScore {
+				t.Errorf("evaluate() score = %v, want %v", got.Score, tt.wantScore)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package eval
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Format is a report output format
+type Format string
+
+const (
+	FormatText  Format = "text"
+	FormatJSON  Format = "json"
+	FormatJUnit Format = "junit"
+)
+
+// Write renders the report in the given format
+func (r *Report) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatText:
+		return r.writeText(w)
+	case FormatJSON:
+		return r.writeJSON(w)
+	case FormatJUnit:
+		return r.writeJUnit(w)
+	default:
+		return fmt.Errorf("unsupported report format %q", format)
+	}
+}
+
+func (r *Report) writeText(w io.Writer) error {
+	fmt.Fprintf(w, "Suite: %s\n\n", r.Suite)
+
+	for _, m := range r.Models {
+		fmt.Fprintf(w, "Model: %s  score %.2f  passed %d/%d  time %s\n",
+			m.Model, m.Score, m.Passed, m.Passed+m.Failed, m.Duration.Round(1e6))
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, c := range m.Cases {
+			status := "PASS"
+			if !c.Passed {
+				status = "FAIL"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%.2f\t%s\n", status, c.Case, c.Score, c.Duration.Round(1e6))
+
+			if c.Error != "" {
+				fmt.Fprintf(tw, "  \t  error: %s\t\t\n", c.Error)
+			}
+			for _, check := range c.Checks {
+				if !check.Passed {
+					fmt.Fprintf(tw, "  \t  %s: %s\t\t\n", check.Kind, check.Message)
+				}
+			}
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(r.Models) > 1 {
+		fmt.Fprintln(w, "Comparison:")
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  MODEL\tSCORE\tPASSED")
+		for _, m := range r.Models {
+			fmt.Fprintf(tw, "  %s\t%.2f\t%d/%d\n", m.Model, m.Score, m.Passed, m.Passed+m.Failed)
+		}
+		return tw.Flush()
+	}
+
+	return nil
+}
+
+func (r *Report) writeJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Name    string           `xml:"name,attr"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+func (r *Report) writeJUnit(w io.Writer) error {
+	doc := junitTestSuites{Name: r.Suite}
+
+	for _, m := range r.Models {
+		suite := junitTestSuite{
+			Name:     m.Model,
+			Tests:    len(m.Cases),
+			Failures: m.Failed,
+			Time:     m.Duration.Seconds(),
+		}
+
+		for _, c := range m.Cases {
+			tc := junitTestCase{
+				Name:      c.Case,
+				ClassName: m.Model,
+				Time:      c.Duration.Seconds(),
+				SystemOut: c.Output,
+			}
+
+			if !c.Passed {
+				var details []string
+				if c.Error != "" {
+					details = append(details, "error: "+c.Error)
+				}
+				for _, check := range c.Checks {
+					if !check.Passed {
+						details = append(details, check.Kind+": "+check.Message)
+					}
+				}
+				tc.Failure = &junitFailure{
+					Message: fmt.Sprintf("score %.2f", c.Score),
+					Body:    strings.Join(details, "\n"),
+				}
+			}
+
+			suite.Cases = append(suite.Cases, tc)
+		}
+
+		doc.Suites = append(doc.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
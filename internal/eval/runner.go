@@ -0,0 +1,137 @@
+package eval
+
+import (
+	"context"
+	"time"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/langchain"
+)
+
+// Model pairs a target with the service used to query it
+type Model struct {
+	Target  ModelTarget
+	Service langchain.LLMService
+}
+
+// CaseResult is the outcome of one case against one model
+type CaseResult struct {
+	Case     string        `json:"case"`
+	Output   string        `json:"output"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+	Checks   []CheckResult `json:"checks"`
+	Score    float64       `json:"score"`
+	Passed   bool          `json:"passed"`
+}
+
+// ModelReport aggregates the results of a suite for one model
+type ModelReport struct {
+	Model    string        `json:"model"`
+	Cases    []CaseResult  `json:"cases"`
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Score    float64       `json:"score"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Report is the scored outcome of a suite run
+type Report struct {
+	Suite  string        `json:"suite"`
+	Models []ModelReport `json:"models"`
+}
+
+// Runner executes a suite against models
+type Runner struct {
+	// Judge scores rubric checks; may be nil when the suite has none
+	Judge langchain.LLMService
+	// Timeout bounds each model and judge call; zero means no limit
+	Timeout time.Duration
+}
+
+// Run evaluates every case of the suite against every model
+func (r *Runner) Run(ctx context.Context, suite *Suite, models []Model) *Report {
+	report := &Report{Suite: suite.Name}
+
+	for _, model := range models {
+		mr := ModelReport{Model: model.Target.Name()}
+
+		for _, c := range suite.Cases {
+			result := r.runCase(ctx, c, model.Service)
+
+			mr.Cases = append(mr.Cases, result)
+			mr.Score += result.Score
+			mr.Duration += result.Duration
+			if result.Passed {
+				mr.Passed++
+			} else {
+				mr.Failed++
+			}
+		}
+
+		if len(mr.Cases) > 0 {
+			mr.Score /= float64(len(mr.Cases))
+		}
+
+		report.Models = append(report.Models, mr)
+	}
+
+	return report
+}
+
+func (r *Runner) runCase(ctx context.Context, c Case, svc langchain.LLMService) CaseResult {
+	result := CaseResult{Case: c.Name}
+
+	messages := make([]langchain.ChatMessage, 0, 2)
+	if c.System != "" {
+		messages = append(messages, langchain.ChatMessage{Role: langchain.RoleSystem, Content: c.System})
+	}
+	messages = append(messages, langchain.ChatMessage{Role: langchain.RoleUser, Content: c.Prompt})
+
+	callCtx, cancel := r.callContext(ctx)
+	start := time.Now()
+	output, err := svc.Chat(callCtx, langchain.ConvertMessages(messages))
+	result.Duration = time.Since(start)
+	cancel()
+
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Output = output
+
+	result.Passed = true
+	for _, check := range c.Checks {
+		checkCtx, cancel := r.callContext(ctx)
+		cr := evaluate(checkCtx, check, output, r.Judge)
+		cancel()
+		result.Checks = append(result.Checks, cr)
+		result.Score += cr.Score
+		if !cr.Passed {
+			result.Passed = false
+		}
+	}
+	result.Score /= float64(len(c.Checks))
+
+	return result
+}
+
+// callContext bounds a single model or judge call by Timeout
+func (r *Runner) callContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.Timeout > 0 {
+		return context.WithTimeout(ctx, r.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Score returns the mean score across all models
+func (r *Report) Score() float64 {
+	if len(r.Models) == 0 {
+		return 0
+	}
+
+	total := 0.0
+	for _, m := range r.Models {
+		total += m.Score
+	}
+	return total / float64(len(r.Models))
+}
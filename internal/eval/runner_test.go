@@ -0,0 +1,191 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/langchain"
+)
+
+func newFakeService(t *testing.T, fixture *langchain.FakeFixture) langchain.LLMService {
+	t.Helper()
+
+	client, err := langchain.NewClient(langchain.Config{Provider: langchain.ProviderFake, Model: "fake"})
+	if err != nil {
+		t.Fatalf("NewClient() unexpected error: %v", err)
+	}
+	if fixture == nil {
+		return client
+	}
+
+	model, err := langchain.NewFakeModel(fixture)
+	if err != nil {
+		t.Fatalf("NewFakeModel() unexpected error: %v", err)
+	}
+	return &fakeService{model: model}
+}
+
+// fakeService adapts a FakeModel to LLMService for scripted replies
+type fakeService struct {
+	model *langchain.FakeModel
+}
+
+func (f *fakeService) GenerateContent(ctx context.Context, prompt string, opts ...llms.CallOption) (string, error) {
+	return f.model.Call(ctx, prompt, opts...)
+}
+
+func (f *fakeService) Chat(ctx context.Context, messages []llms.MessageContent, opts ...llms.CallOption) (string, error) {
+	resp, err := f.model.GenerateContent(ctx, messages, opts...)
+	if err != nil {
+		return "", err
+	}
+	return resp.Choices[0].Content, nil
+}
+
+func runTestSuite(t *testing.T) *Report {
+	t.Helper()
+
+	suite, err := LoadSuite("testdata/suite.yaml")
+	if err != nil {
+		t.Fatalf("LoadSuite() unexpected error: %v", err)
+	}
+
+	echo := newFakeService(t, nil)
+	broken := newFakeService(t, &langchain.FakeFixture{
+		Default: &langchain.FakeResponse{Content: "no idea"},
+	})
+	judge := newFakeService(t, &langchain.FakeFixture{
+		Responses: []langchain.FakeResponse{{Match: "thank you", Content: `{"score": 5, "reason": "polite"}`}},
+		Default:   &langchain.FakeResponse{Content: `{"score": 1}`},
+	})
+
+	runner := &Runner{Judge: judge}
+	return runner.Run(context.Background(), suite, []Model{
+		{Target: ModelTarget{Provider: "fake", Model: "echo"}, Service: echo},
+		{Target: ModelTarget{Provider: "fake", Model: "broken"}, Service: broken},
+	})
+}
+
+func TestRunner_Run(t *testing.T) {
+	report := runTestSuite(t)
+
+	if len(report.Models) != 2 {
+		t.Fatalf("Models count = %d, want 2", len(report.Models))
+	}
+
+	echo := report.Models[0]
+	if echo.Passed != 3 || echo.Failed != 0 || echo.Score != 1 {
+		t.Errorf("echo model = passed %d failed %d score %.2f, want 3/0/1.00", echo.Passed, echo.Failed, echo.Score)
+	}
+
+	broken := report.Models[1]
+	if broken.Passed != 0 || broken.Failed != 3 {
+		t.Errorf("broken model = passed %d failed %d, want 0/3", broken.Passed, broken.Failed)
+	}
+
+	if score := report.Score(); score <= 0 || score >= 1 {
+		t.Errorf("Score() = %.2f, want between 0 and 1", score)
+	}
+}
+
+// hangingJudge blocks until its call is cancelled
+type hangingJudge struct{}
+
+func (hangingJudge) GenerateContent(ctx context.Context, prompt string, opts ...llms.CallOption) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func (hangingJudge) Chat(ctx context.Context, messages []llms.MessageContent, opts ...llms.CallOption) (string, error) {
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestRunner_JudgeTimeout(t *testing.T) {
+	suite, err := ParseSuite([]byte(`
+cases:
+  - name: polite
+    prompt: thank you
+    checks:
+      - rubric: The reply is polite.`))
+	if err != nil {
+		t.Fatalf("ParseSuite() unexpected error: %v", err)
+	}
+
+	runner := &Runner{Judge: hangingJudge{}, Timeout: 50 * time.Millisecond}
+
+	done := make(chan *Report, 1)
+	go func() {
+		done <- runner.Run(context.Background(), suite, []Model{
+			{Target: ModelTarget{Provider: "fake", Model: "echo"}, Service: newFakeService(t, nil)},
+		})
+	}()
+
+	select {
+	case report := <-done:
+		check := report.Models[0].Cases[0].Checks[0]
+		if check.Passed || !strings.Contains(check.Message, "judge failed") {
+			t.Errorf("rubric check = %+v, want a judge failure", check)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run() did not bound the judge call by Timeout")
+	}
+}
+
+func TestReport_Write(t *testing.T) {
+	report := runTestSuite(t)
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.Write(&buf, FormatText); err != nil {
+			t.Fatalf("Write() unexpected error: %v", err)
+		}
+		out := buf.String()
+		for _, want := range []string{"Suite: test", "fake/echo", "FAIL", "Comparison:"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("text report missing %q:\n%s", want, out)
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.Write(&buf, FormatJSON); err != nil {
+			t.Fatalf("Write() unexpected error: %v", err)
+		}
+		var decoded Report
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("json report is invalid: %v", err)
+		}
+		if len(decoded.Models) != 2 {
+			t.Errorf("json report models = %d, want 2", len(decoded.Models))
+		}
+	})
+
+	t.Run("junit", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := report.Write(&buf, FormatJUnit); err != nil {
+			t.Fatalf("Write() unexpected error: %v", err)
+		}
+		var decoded junitTestSuites
+		if err := xml.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("junit report is invalid: %v", err)
+		}
+		if len(decoded.Suites) != 2 || decoded.Suites[1].Failures != 3 {
+			t.Errorf("junit report = %+v", decoded)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if err := report.Write(&bytes.Buffer{}, Format("pdf")); err == nil {
+			t.Error("Write() expected error for unknown format, got nil")
+		}
+	})
+}
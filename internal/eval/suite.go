@@ -0,0 +1,204 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a set of prompts with expected properties
+type Suite struct {
+	Name   string        `yaml:"name"`
+	Models []ModelTarget `yaml:"models"`
+	Judge  *ModelTarget  `yaml:"judge,omitempty"`
+	Cases  []Case        `yaml:"cases"`
+}
+
+// ModelTarget identifies a model to evaluate (or to judge with)
+type ModelTarget struct {
+	Provider string `yaml:"provider"`
+	Model    string `yaml:"model"`
+	BaseURL  string `yaml:"base_url,omitempty"`
+	// APIKeyEnv names the environment variable holding the API key
+	APIKeyEnv string `yaml:"api_key_env,omitempty"`
+}
+
+// Name returns a display name such as "ollama/llama2"
+func (m ModelTarget) Name() string {
+	return m.Provider + "/" + m.Model
+}
+
+// Case is a single prompt and the checks applied to the response
+type Case struct {
+	Name   string  `yaml:"name"`
+	System string  `yaml:"system,omitempty"`
+	Prompt string  `yaml:"prompt"`
+	Checks []Check `yaml:"checks"`
+}
+
+// Check is one expected property of a response. Exactly one of the
+// property fields must be set.
+type Check struct {
+	Contains    string `yaml:"contains,omitempty"`
+	NotContains string `yaml:"not_contains,omitempty"`
+	Regex       string `yaml:"regex,omitempty"`
+	JSONSchema  string `yaml:"json_schema,omitempty"`
+	Rubric      string `yaml:"rubric,omitempty"`
+
+	// MinScore is the lowest judge score (1-5) that passes a rubric check
+	MinScore int `yaml:"min_score,omitempty"`
+	// IgnoreCase makes contains/not_contains case-insensitive
+	IgnoreCase bool `yaml:"ignore_case,omitempty"`
+}
+
+// Kind returns the type of check
+func (c Check) Kind() string {
+	switch {
+	case c.Contains != "":
+		return "contains"
+	case c.NotContains != "":
+		return "not_contains"
+	case c.Regex != "":
+		return "regex"
+	case c.JSONSchema != "":
+		return "json_schema"
+	case c.Rubric != "":
+		return "rubric"
+	default:
+		return ""
+	}
+}
+
+// LoadSuite reads and validates a YAML suite file
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suite: %w", err)
+	}
+
+	return ParseSuite(data)
+}
+
+// ParseSuite parses and validates a YAML suite
+func ParseSuite(data []byte) (*Suite, error) {
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse suite: %w", err)
+	}
+
+	if err := suite.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &suite, nil
+}
+
+// Validate checks the suite for structural errors. Whether rubric checks
+// have a judge is left to ValidateJudge, as the judge may be overridden.
+func (s *Suite) Validate() error {
+	if len(s.Cases) == 0 {
+		return fmt.Errorf("suite has no cases")
+	}
+
+	seen := make(map[string]bool, len(s.Cases))
+	for i, c := range s.Cases {
+		if strings.TrimSpace(c.Name) == "" {
+			return fmt.Errorf("case %d: name is required", i)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("case %q: duplicate name", c.Name)
+		}
+		seen[c.Name] = true
+
+		if strings.TrimSpace(c.Prompt) == "" {
+			return fmt.Errorf("case %q: prompt is required", c.Name)
+		}
+		if len(c.Checks) == 0 {
+			return fmt.Errorf("case %q: at least one check is required", c.Name)
+		}
+
+		for j, check := range c.Checks {
+			if err := check.validate(); err != nil {
+				return fmt.Errorf("case %q check %d: %w", c.Name, j, err)
+			}
+		}
+	}
+
+	for i, m := range s.Models {
+		if m.Provider == "" || m.Model == "" {
+			return fmt.Errorf("model %d: provider and model are required", i)
+		}
+	}
+
+	return nil
+}
+
+// ValidateJudge checks that the suite can run with judge, the effective
+// judge model after any override; nil means none
+func (s *Suite) ValidateJudge(judge *ModelTarget) error {
+	if judge != nil {
+		return nil
+	}
+
+	for _, c := range s.Cases {
+		for j, check := range c.Checks {
+			if check.Kind() == "rubric" {
+				return fmt.Errorf("case %q check %d: rubric checks require a judge model", c.Name, j)
+			}
+		}
+	}
+	return nil
+}
+
+func (c Check) validate() error {
+	set := 0
+	for _, v := range []string{c.Contains, c.NotContains, c.Regex, c.JSONSchema, c.Rubric} {
+		if v != "" {
+			set++
+		}
+	}
+
+	if set != 1 {
+		return fmt.Errorf("exactly one of contains, not_contains, regex, json_schema or rubric must be set")
+	}
+
+	if c.MinScore < 0 || c.MinScore > maxJudgeScore {
+		return fmt.Errorf("min_score must be between 1 and %d", maxJudgeScore)
+	}
+
+	// compile patterns now so a typo fails the suite, not every response
+	if c.Regex != "" {
+		if _, err := regexp.Compile(c.Regex); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	if c.JSONSchema != "" {
+		if _, err := compileJSONSchema(c.JSONSchema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseModelTargets parses a comma-separated list of provider:model pairs
+func ParseModelTargets(list string) ([]ModelTarget, error) {
+	var targets []ModelTarget
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		provider, model, ok := strings.Cut(part, ":")
+		if !ok || provider == "" || model == "" {
+			return nil, fmt.Errorf("invalid model %q (expected provider:model)", part)
+		}
+
+		targets = append(targets, ModelTarget{Provider: provider, Model: model})
+	}
+	return targets, nil
+}
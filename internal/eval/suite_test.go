@@ -0,0 +1,149 @@
+package eval
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadSuite(t *testing.T) {
+	suite, err := LoadSuite("testdata/suite.yaml")
+	if err != nil {
+		t.Fatalf("LoadSuite() unexpected error: %v", err)
+	}
+
+	if suite.Name != "test" {
+		t.Errorf("Name = %q, want %q", suite.Name, "test")
+	}
+
+	if len(suite.Cases) != 3 {
+		t.Fatalf("Cases count = %d, want 3", len(suite.Cases))
+	}
+
+	if kind := suite.Cases[2].Checks[0].Kind(); kind != "rubric" {
+		t.Errorf("Kind() = %q, want %q", kind, "rubric")
+	}
+}
+
+func TestParseSuite_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{
+			name:    "no cases",
+			yaml:    "name: empty",
+			wantErr: "no cases",
+		},
+		{
+			name: "missing prompt",
+			yaml: `
+cases:
+  - name: a
+    checks:
+      - contains: x`,
+			wantErr: "prompt is required",
+		},
+		{
+			name: "two properties in one check",
+			yaml: `
+cases:
+  - name: a
+    prompt: p
+    checks:
+      - contains: x
+        regex: y`,
+			wantErr: "exactly one",
+		},
+		{
+			name: "duplicate case",
+			yaml: `
+cases:
+  - name: a
+    prompt: p
+    checks: [{contains: x}]
+  - name: a
+    prompt: p
+    checks: [{contains: x}]`,
+			wantErr: "duplicate",
+		},
+		{
+			name: "invalid regex",
+			yaml: `
+cases:
+  - name: a
+    prompt: p
+    checks:
+      - regex: "(unclosed"`,
+			wantErr: "invalid regex",
+		},
+		{
+			name: "invalid json schema",
+			yaml: `
+cases:
+  - name: a
+    prompt: p
+    checks:
+      - json_schema: '{"type": 5}'`,
+			wantErr: "invalid schema",
+		},
+		{
+			name: "json schema not json",
+			yaml: `
+cases:
+  - name: a
+    prompt: p
+    checks:
+      - json_schema: "not json"`,
+			wantErr: "invalid schema",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSuite([]byte(tt.yaml))
+			if err == nil {
+				t.Fatal("ParseSuite() expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseSuite() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSuite_ValidateJudge(t *testing.T) {
+	suite, err := ParseSuite([]byte(`
+cases:
+  - name: a
+    prompt: p
+    checks:
+      - rubric: is nice`))
+	if err != nil {
+		t.Fatalf("ParseSuite() of a rubric suite without judge unexpected error: %v", err)
+	}
+
+	if err := suite.ValidateJudge(nil); err == nil || !strings.Contains(err.Error(), "require a judge") {
+		t.Errorf("ValidateJudge(nil) error = %v, want it to require a judge", err)
+	}
+
+	// A judge supplied on the command line satisfies the suite
+	if err := suite.ValidateJudge(&ModelTarget{Provider: "ollama", Model: "llama3"}); err != nil {
+		t.Errorf("ValidateJudge() unexpected error: %v", err)
+	}
+}
+
+func TestParseModelTargets(t *testing.T) {
+	targets, err := ParseModelTargets("ollama:llama2, openai:gpt-4o")
+	if err != nil {
+		t.Fatalf("ParseModelTargets() unexpected error: %v", err)
+	}
+
+	if len(targets) != 2 || targets[1].Name() != "openai/gpt-4o" {
+		t.Errorf("ParseModelTargets() = %+v", targets)
+	}
+
+	if _, err := ParseModelTargets("ollama"); err == nil {
+		t.Error("ParseModelTargets() expected error for missing model, got nil")
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// AccountHandler handles the caller's data export and account deletion
+type AccountHandler struct {
+	kratos       auth.KratosService
+	admin        auth.AdminService
+	reauthWindow time.Duration
+	stores       map[string]auth.UserDataStore
+	now          func() time.Time
+}
+
+// NewAccountHandler creates a new account handler. Deleting an account
+// requires a session authenticated within reauthWindow; 0 disables deletion.
+func NewAccountHandler(kratos auth.KratosService, admin auth.AdminService, reauthWindow time.Duration) *AccountHandler {
+	return &AccountHandler{
+		kratos:       kratos,
+		admin:        admin,
+		reauthWindow: reauthWindow,
+		stores:       make(map[string]auth.UserDataStore),
+		now:          time.Now,
+	}
+}
+
+// WithUserData exports store's data under name and purges it when an
+// account is deleted
+func (h *AccountHandler) WithUserData(name string, store auth.UserDataStore) *AccountHandler {
+	h.stores[name] = store
+	return h
+}
+
+// ExportData handles GET /users/account/export - downloads everything held
+// about the caller as a JSON file
+func (h *AccountHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	sessionToken := middleware.ExtractSessionToken(r)
+	current, ok := middleware.GetSessionFromContext(r.Context())
+	if sessionToken == "" || !ok || current.Identity == nil {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	identity, err := h.admin.GetIdentity(r.Context(), current.Identity.Id)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	others, err := h.kratos.ListMySessions(r.Context(), sessionToken)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	data := make(map[string]interface{}, len(h.stores))
+	for name, store := range h.stores {
+		storeData, err := store.ExportUserData(r.Context(), identity.Id)
+		if err != nil {
+			apperrors.NewInternalError("failed to export "+name, err).WriteJSON(w)
+			return
+		}
+		if storeData != nil {
+			data[name] = storeData
+		}
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="account-export.json"`)
+	response.Success(w, response.AccountExportResponse{
+		ExportedAt: h.now().UTC().String(),
+		Identity:   accountIdentityResponse(identity),
+		Sessions:   sessionList(current, others),
+		Data:       data,
+	})
+}
+
+// DeleteAccount handles DELETE /users/account - permanently deletes the
+// caller's identity, which ends all their sessions, then purges their
+// application data
+func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	session, ok := middleware.GetSessionFromContext(r.Context())
+	if !ok || session.Identity == nil {
+		apperrors.NewUnauthorizedError("missing session").WriteJSON(w)
+		return
+	}
+
+	if h.reauthWindow <= 0 {
+		apperrors.NewForbiddenError("account deletion is disabled").WriteJSON(w)
+		return
+	}
+
+	if session.AuthenticatedAt == nil || h.now().Sub(*session.AuthenticatedAt) > h.reauthWindow {
+		apperrors.NewReauthenticationRequiredError(reauthRequiredMessage).WriteJSON(w)
+		return
+	}
+
+	identityID := session.Identity.Id
+	if err := h.admin.DeleteIdentity(r.Context(), identityID); err != nil && !errors.Is(err, auth.ErrIdentityNotFound) {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	var purgeErrs []error
+	for name, store := range h.stores {
+		if err := store.PurgeUserData(r.Context(), identityID); err != nil {
+			purgeErrs = append(purgeErrs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	if len(purgeErrs) > 0 {
+		apperrors.NewInternalError("account deleted but some data could not be purged", errors.Join(purgeErrs...)).WriteJSON(w)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// accountIdentityResponse exports an identity, listing only the types of its
+// credentials
+func accountIdentityResponse(identity *ory.Identity) response.AccountIdentityResponse {
+	resp := response.AccountIdentityResponse{
+		ID:                  identity.Id,
+		SchemaID:            identity.SchemaId,
+		State:               identity.GetState(),
+		Traits:              identity.Traits,
+		MetadataPublic:      identity.MetadataPublic,
+		VerifiableAddresses: identity.VerifiableAddresses,
+		RecoveryAddresses:   identity.RecoveryAddresses,
+		CredentialTypes:     make([]string, 0, len(identity.GetCredentials())),
+	}
+
+	if identity.CreatedAt != nil {
+		resp.CreatedAt = identity.CreatedAt.String()
+	}
+	if identity.UpdatedAt != nil {
+		resp.UpdatedAt = identity.UpdatedAt.String()
+	}
+
+	for credentialType := range identity.GetCredentials() {
+		resp.CredentialTypes = append(resp.CredentialTypes, credentialType)
+	}
+	sort.Strings(resp.CredentialTypes)
+
+	return resp
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// mockUserDataStore records purges and exports fixed data
+type mockUserDataStore struct {
+	data     interface{}
+	err      error
+	purged   []string
+	purgeErr error
+}
+
+func (m *mockUserDataStore) ExportUserData(ctx context.Context, identityID string) (interface{}, error) {
+	return m.data, m.err
+}
+
+func (m *mockUserDataStore) PurgeUserData(ctx context.Context, identityID string) error {
+	m.purged = append(m.purged, identityID)
+	return m.purgeErr
+}
+
+// withAuthenticatedSession is withTestSession with the session authenticated
+// at authenticatedAt
+func withAuthenticatedSession(req *http.Request, authenticatedAt *time.Time) *http.Request {
+	req = withTestSession(req)
+	session, _ := middleware.GetSessionFromContext(req.Context())
+	session.AuthenticatedAt = authenticatedAt
+	return req.WithContext(context.WithValue(req.Context(), middleware.SessionContextKey, session))
+}
+
+func TestAccountHandler_ExportData(t *testing.T) {
+	tests := []struct {
+		name        string
+		identityErr error
+		sessionsErr error
+		storeErr    error
+		wantStatus  int
+	}{
+		{name: "success", wantStatus: http.StatusOK},
+		{name: "identity error", identityErr: errors.New("kratos unavailable"), wantStatus: http.StatusServiceUnavailable},
+		{name: "sessions error", sessionsErr: errors.New("kratos unavailable"), wantStatus: http.StatusServiceUnavailable},
+		{name: "store error", storeErr: errors.New("store unavailable"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := auth.IdentityStateActive
+			admin := &MockAdminService{
+				GetIdentityFunc: func(ctx context.Context, id string) (*ory.Identity, error) {
+					if tt.identityErr != nil {
+						return nil, tt.identityErr
+					}
+					return &ory.Identity{
+						Id:       id,
+						SchemaId: "default",
+						State:    &state,
+						Traits:   map[string]interface{}{"email": "test@example.com"},
+						Credentials: &map[string]ory.IdentityCredentials{
+							"password": {},
+							"oidc":     {},
+						},
+						MetadataAdmin: map[string]interface{}{"api_keys": "secret"},
+					}, nil
+				},
+			}
+			kratos := &MockKratosService{
+				ListMySessionsFunc: func(ctx context.Context, sessionToken string) ([]ory.Session, error) {
+					return []ory.Session{{Id: "session-456"}}, tt.sessionsErr
+				},
+			}
+			store := &mockUserDataStore{data: []string{"conversation-1"}, err: tt.storeErr}
+
+			handler := NewAccountHandler(kratos, admin, 15*time.Minute).
+				WithUserData("conversations", store).
+				WithUserData("usage", &mockUserDataStore{})
+
+			req := withTestSession(httptest.NewRequest(http.MethodGet, "/users/account/export", nil))
+			w := httptest.NewRecorder()
+
+			handler.ExportData(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ExportData() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="account-export.json"` {
+				t.Errorf("ExportData() Content-Disposition = %q", got)
+			}
+
+			var got response.AccountExportResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if got.Identity.ID != "identity-123" || got.Identity.State != "active" {
+				t.Errorf("ExportData() identity = %+v", got.Identity)
+			}
+			if len(got.Identity.CredentialTypes) != 2 || got.Identity.CredentialTypes[0] != "oidc" {
+				t.Errorf("ExportData() credential types = %v, want [oidc password]", got.Identity.CredentialTypes)
+			}
+			if len(got.Sessions) != 2 || !got.Sessions[0].Current || got.Sessions[1].ID != "session-456" {
+				t.Errorf("ExportData() sessions = %+v", got.Sessions)
+			}
+			if _, ok := got.Data["conversations"]; !ok {
+				t.Errorf("ExportData() data = %v, want conversations", got.Data)
+			}
+			if _, ok := got.Data["usage"]; ok {
+				t.Errorf("ExportData() data = %v, want stores without data left out", got.Data)
+			}
+		})
+	}
+}
+
+func TestAccountHandler_DeleteAccount(t *testing.T) {
+	recent := time.Now().Add(-time.Minute)
+	stale := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name            string
+		reauthWindow    time.Duration
+		authenticatedAt *time.Time
+		deleteErr       error
+		purgeErr        error
+		wantStatus      int
+		wantCode        apperrors.ErrorCode
+		wantDeleted     bool
+	}{
+		{name: "success", reauthWindow: 15 * time.Minute, authenticatedAt: &recent, wantStatus: http.StatusNoContent, wantDeleted: true},
+		{
+			name:            "identity already gone",
+			reauthWindow:    15 * time.Minute,
+			authenticatedAt: &recent,
+			deleteErr:       auth.ErrIdentityNotFound,
+			wantStatus:      http.StatusNoContent,
+			wantDeleted:     true,
+		},
+		{
+			name:            "stale login",
+			reauthWindow:    15 * time.Minute,
+			authenticatedAt: &stale,
+			wantStatus:      http.StatusForbidden,
+			wantCode:        apperrors.ErrCodeReauthRequired,
+		},
+		{
+			name:         "unknown login time",
+			reauthWindow: 15 * time.Minute,
+			wantStatus:   http.StatusForbidden,
+			wantCode:     apperrors.ErrCodeReauthRequired,
+		},
+		{
+			name:            "deletion disabled",
+			authenticatedAt: &recent,
+			wantStatus:      http.StatusForbidden,
+			wantCode:        apperrors.ErrCodeForbidden,
+		},
+		{
+			name:            "kratos error",
+			reauthWindow:    15 * time.Minute,
+			authenticatedAt: &recent,
+			deleteErr:       errors.New("kratos unavailable"),
+			wantStatus:      http.StatusServiceUnavailable,
+			wantCode:        apperrors.ErrCodeServiceUnavail,
+		},
+		{
+			name:            "purge error",
+			reauthWindow:    15 * time.Minute,
+			authenticatedAt: &recent,
+			purgeErr:        errors.New("store unavailable"),
+			wantStatus:      http.StatusInternalServerError,
+			wantCode:        apperrors.ErrCodeInternal,
+			wantDeleted:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deleted string
+			admin := &MockAdminService{
+				DeleteIdentityFunc: func(ctx context.Context, id string) error {
+					deleted = id
+					return tt.deleteErr
+				},
+			}
+			store := &mockUserDataStore{purgeErr: tt.purgeErr}
+
+			handler := NewAccountHandler(&MockKratosService{}, admin, tt.reauthWindow).WithUserData("conversations", store)
+
+			req := withAuthenticatedSession(httptest.NewRequest(http.MethodDelete, "/users/account", nil), tt.authenticatedAt)
+			w := httptest.NewRecorder()
+
+			handler.DeleteAccount(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("DeleteAccount() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantCode != "" {
+				var got struct {
+					Error apperrors.AppError `json:"error"`
+				}
+				if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if got.Error.Code != tt.wantCode {
+					t.Errorf("DeleteAccount() code = %s, want %s", got.Error.Code, tt.wantCode)
+				}
+			}
+
+			if tt.wantDeleted {
+				if deleted != "identity-123" {
+					t.Errorf("DeleteAccount() deleted identity %q, want identity-123", deleted)
+				}
+				if len(store.purged) != 1 || store.purged[0] != "identity-123" {
+					t.Errorf("DeleteAccount() purged %v, want [identity-123]", store.purged)
+				}
+			} else if len(store.purged) != 0 {
+				t.Errorf("DeleteAccount() purged %v without deleting the identity", store.purged)
+			}
+		})
+	}
+}
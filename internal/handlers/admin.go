@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// AdminHandler handles identity administration requests
+type AdminHandler struct {
+	admin    auth.AdminService
+	schemaID string
+	schemas  *validation.IdentitySchemaLoader
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(admin auth.AdminService) *AdminHandler {
+	return &AdminHandler{admin: admin, schemaID: auth.DefaultIdentitySchemaID}
+}
+
+// WithIdentitySchema creates identities with the given Kratos schema, their
+// traits validated against the schema schemas loads. An empty schemaID keeps
+// auth.DefaultIdentitySchemaID.
+func (h *AdminHandler) WithIdentitySchema(schemaID string, schemas *validation.IdentitySchemaLoader) *AdminHandler {
+	if schemaID != "" {
+		h.schemaID = schemaID
+	}
+	h.schemas = schemas
+	return h
+}
+
+// ListIdentities handles GET /admin/identities - supports page_size, page_token
+// and identifier (exact credential identifier, e.g. an email address)
+func (h *AdminHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	pageSize, validationErr := validation.ValidatePageSize(query.Get("page_size"))
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	page, err := h.admin.ListIdentities(r.Context(), auth.IdentityListOptions{
+		PageSize:   pageSize,
+		PageToken:  strings.TrimSpace(query.Get("page_token")),
+		Identifier: strings.TrimSpace(query.Get("identifier")),
+	})
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	identities := make([]ory.Identity, len(page.Identities))
+	for i := range page.Identities {
+		identities[i] = *auth.WithoutAPIKeys(&page.Identities[i])
+	}
+
+	response.Success(w, response.IdentityListResponse{
+		Identities:    identities,
+		NextPageToken: page.NextPageToken,
+	})
+}
+
+// GetIdentity handles GET /admin/identities/{id}
+func (h *AdminHandler) GetIdentity(w http.ResponseWriter, r *http.Request) {
+	id, ok := identityID(w, r)
+	if !ok {
+		return
+	}
+
+	identity, err := h.admin.GetIdentity(r.Context(), id)
+	if err != nil {
+		adminError(err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, auth.WithoutAPIKeys(identity))
+}
+
+// CreateIdentity handles POST /admin/identities
+func (h *AdminHandler) CreateIdentity(w http.ResponseWriter, r *http.Request) {
+	input, validationErr := h.validateCreateIdentity(r)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	body := auth.BuildCreateIdentityBody(h.schemaID, input.Traits, input.Password)
+
+	identity, err := h.admin.CreateIdentity(r.Context(), body)
+	if err != nil {
+		adminError(err).WriteJSON(w)
+		return
+	}
+
+	response.Created(w, auth.WithoutAPIKeys(identity))
+}
+
+// validateCreateIdentity validates a creation body against the identity
+// schema when one is configured, or the built-in fields otherwise
+func (h *AdminHandler) validateCreateIdentity(r *http.Request) (*validation.CreateIdentityInput, *apperrors.AppError) {
+	if h.schemas == nil {
+		return validation.ValidateCreateIdentityInput(r.Body)
+	}
+
+	schema, err := h.schemas.Schema(r.Context())
+	if err != nil {
+		return nil, apperrors.NewServiceUnavailableError("identity schema", err)
+	}
+
+	return validation.ValidateSchemaCreateIdentityInput(r.Body, schema)
+}
+
+// UpdateIdentity handles PATCH /admin/identities/{id} - updates traits and/or state
+func (h *AdminHandler) UpdateIdentity(w http.ResponseWriter, r *http.Request) {
+	id, ok := identityID(w, r)
+	if !ok {
+		return
+	}
+
+	input, validationErr := validation.ValidateUpdateIdentityInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	var traits map[string]interface{}
+	nameChanged := input.FirstName != "" || input.LastName != ""
+	if input.Email != "" || nameChanged {
+		// Kratos replaces traits wholesale, so start from the current ones
+		identity, err := h.admin.GetIdentity(r.Context(), id)
+		if err != nil {
+			adminError(err).WriteJSON(w)
+			return
+		}
+
+		current := identityTraits(identity)
+		traits = make(map[string]interface{}, len(current))
+		for k, v := range current {
+			traits[k] = v
+		}
+		if nameChanged {
+			traits = auth.BuildNameTraits(traits, input.FirstName, input.LastName)
+		}
+		if input.Email != "" {
+			traits["email"] = input.Email
+		}
+	}
+
+	identity, err := h.admin.PatchIdentity(r.Context(), id, auth.BuildIdentityPatch(traits, input.State))
+	if err != nil {
+		adminError(err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, auth.WithoutAPIKeys(identity))
+}
+
+// DeleteIdentity handles DELETE /admin/identities/{id}
+func (h *AdminHandler) DeleteIdentity(w http.ResponseWriter, r *http.Request) {
+	id, ok := identityID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.admin.DeleteIdentity(r.Context(), id); err != nil {
+		adminError(err).WriteJSON(w)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// BlockIdentity handles POST /admin/identities/{id}/block - deactivates the
+// identity and signs it out everywhere
+func (h *AdminHandler) BlockIdentity(w http.ResponseWriter, r *http.Request) {
+	id, ok := identityID(w, r)
+	if !ok {
+		return
+	}
+
+	identity, err := h.admin.PatchIdentity(r.Context(), id, auth.BuildIdentityPatch(nil, auth.IdentityStateInactive))
+	if err != nil {
+		adminError(err).WriteJSON(w)
+		return
+	}
+
+	if err := h.admin.RevokeIdentitySessions(r.Context(), id); err != nil && !errors.Is(err, auth.ErrIdentityNotFound) {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, auth.WithoutAPIKeys(identity))
+}
+
+// UnblockIdentity handles POST /admin/identities/{id}/unblock
+func (h *AdminHandler) UnblockIdentity(w http.ResponseWriter, r *http.Request) {
+	id, ok := identityID(w, r)
+	if !ok {
+		return
+	}
+
+	identity, err := h.admin.PatchIdentity(r.Context(), id, auth.BuildIdentityPatch(nil, auth.IdentityStateActive))
+	if err != nil {
+		adminError(err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, auth.WithoutAPIKeys(identity))
+}
+
+// RevokeIdentitySessions handles DELETE /admin/identities/{id}/sessions - signs
+// the identity out of every device
+func (h *AdminHandler) RevokeIdentitySessions(w http.ResponseWriter, r *http.Request) {
+	id, ok := identityID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.admin.RevokeIdentitySessions(r.Context(), id); err != nil {
+		adminError(err).WriteJSON(w)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// identityID reads the {id} URL parameter, writing a validation error if it is missing
+func identityID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := strings.TrimSpace(chi.URLParam(r, "id"))
+	if id == "" {
+		apperrors.NewValidationError("identity id is required", "").WriteJSON(w)
+		return "", false
+	}
+	return id, true
+}
+
+// adminError maps admin API errors to API errors
+func adminError(err error) *apperrors.AppError {
+	if errors.Is(err, auth.ErrIdentityNotFound) {
+		return apperrors.NewNotFoundError("identity")
+	}
+	if errors.Is(err, auth.ErrIdentityRejected) {
+		return apperrors.NewValidationError("identity rejected by Kratos", err.Error())
+	}
+	return apperrors.NewServiceUnavailableError("Kratos", err)
+}
@@ -0,0 +1,534 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
+)
+
+// MockAdminService implements auth.AdminService for testing
+type MockAdminService struct {
+	ListIdentitiesFunc         func(ctx context.Context, opts auth.IdentityListOptions) (*auth.IdentityPage, error)
+	GetIdentityFunc            func(ctx context.Context, id string) (*ory.Identity, error)
+	CreateIdentityFunc         func(ctx context.Context, body ory.CreateIdentityBody) (*ory.Identity, error)
+	PatchIdentityFunc          func(ctx context.Context, id string, patches []ory.JsonPatch) (*ory.Identity, error)
+	DeleteIdentityFunc         func(ctx context.Context, id string) error
+	RevokeIdentitySessionsFunc func(ctx context.Context, id string) error
+}
+
+func (m *MockAdminService) ListIdentities(ctx context.Context, opts auth.IdentityListOptions) (*auth.IdentityPage, error) {
+	if m.ListIdentitiesFunc != nil {
+		return m.ListIdentitiesFunc(ctx, opts)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockAdminService) GetIdentity(ctx context.Context, id string) (*ory.Identity, error) {
+	if m.GetIdentityFunc != nil {
+		return m.GetIdentityFunc(ctx, id)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockAdminService) CreateIdentity(ctx context.Context, body ory.CreateIdentityBody) (*ory.Identity, error) {
+	if m.CreateIdentityFunc != nil {
+		return m.CreateIdentityFunc(ctx, body)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockAdminService) PatchIdentity(ctx context.Context, id string, patches []ory.JsonPatch) (*ory.Identity, error) {
+	if m.PatchIdentityFunc != nil {
+		return m.PatchIdentityFunc(ctx, id, patches)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockAdminService) DeleteIdentity(ctx context.Context, id string) error {
+	if m.DeleteIdentityFunc != nil {
+		return m.DeleteIdentityFunc(ctx, id)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockAdminService) RevokeIdentitySessions(ctx context.Context, id string) error {
+	if m.RevokeIdentitySessionsFunc != nil {
+		return m.RevokeIdentitySessionsFunc(ctx, id)
+	}
+	return errors.New("not implemented")
+}
+
+func TestAdminHandler_ListIdentities(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantOpts   auth.IdentityListOptions
+	}{
+		{
+			name:       "defaults",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "paginated and filtered",
+			query:      "?page_size=50&page_token=abc&identifier=test@example.com",
+			wantStatus: http.StatusOK,
+			wantOpts:   auth.IdentityListOptions{PageSize: 50, PageToken: "abc", Identifier: "test@example.com"},
+		},
+		{
+			name:       "invalid page size",
+			query:      "?page_size=0",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotOpts auth.IdentityListOptions
+			mock := &MockAdminService{
+				ListIdentitiesFunc: func(ctx context.Context, opts auth.IdentityListOptions) (*auth.IdentityPage, error) {
+					gotOpts = opts
+					return &auth.IdentityPage{
+						Identities: []ory.Identity{{
+							Id:        "identity-123",
+							SchemaId:  "default",
+							SchemaUrl: "http://localhost:4433/schemas/default",
+							Traits:    map[string]interface{}{"email": "test@example.com"},
+						}},
+						NextPageToken: "next",
+					}, nil
+				},
+			}
+
+			handler := NewAdminHandler(mock)
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/identities"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			handler.ListIdentities(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ListIdentities() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if gotOpts != tt.wantOpts {
+				t.Errorf("ListIdentities() opts = %+v, want %+v", gotOpts, tt.wantOpts)
+			}
+
+			var got response.IdentityListResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if len(got.Identities) != 1 || got.NextPageToken != "next" {
+				t.Errorf("ListIdentities() response = %+v", got)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_GetIdentity(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		wantStatus int
+	}{
+		{name: "success", wantStatus: http.StatusOK},
+		{
+			name:       "not found",
+			mockErr:    fmt.Errorf("failed to get identity: %w", auth.ErrIdentityNotFound),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "kratos error",
+			mockErr:    errors.New("connection refused"),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockAdminService{
+				GetIdentityFunc: func(ctx context.Context, id string) (*ory.Identity, error) {
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					return &ory.Identity{Id: id}, nil
+				},
+			}
+
+			handler := NewAdminHandler(mock)
+
+			req := withURLParam(httptest.NewRequest(http.MethodGet, "/admin/identities/identity-123", nil), "id", "identity-123")
+			w := httptest.NewRecorder()
+
+			handler.GetIdentity(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("GetIdentity() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_RedactsAPIKeys(t *testing.T) {
+	identity := func() *ory.Identity {
+		return &ory.Identity{Id: "identity-123", MetadataAdmin: map[string]interface{}{
+			"api_keys": []interface{}{map[string]interface{}{"id": "key-1", "hash": "secret-hash"}},
+			"note":     "keep me",
+		}}
+	}
+	mock := &MockAdminService{
+		GetIdentityFunc: func(ctx context.Context, id string) (*ory.Identity, error) {
+			return identity(), nil
+		},
+		ListIdentitiesFunc: func(ctx context.Context, opts auth.IdentityListOptions) (*auth.IdentityPage, error) {
+			return &auth.IdentityPage{Identities: []ory.Identity{*identity()}}, nil
+		},
+	}
+	handler := NewAdminHandler(mock)
+
+	tests := []struct {
+		name  string
+		serve func(w http.ResponseWriter)
+	}{
+		{name: "get", serve: func(w http.ResponseWriter) {
+			handler.GetIdentity(w, withURLParam(httptest.NewRequest(http.MethodGet, "/admin/identities/identity-123", nil), "id", "identity-123"))
+		}},
+		{name: "list", serve: func(w http.ResponseWriter) {
+			handler.ListIdentities(w, httptest.NewRequest(http.MethodGet, "/admin/identities", nil))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.serve(w)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			if body := w.Body.String(); strings.Contains(body, "secret-hash") || !strings.Contains(body, "keep me") {
+				t.Errorf("body = %s, want metadata_admin without api_keys", body)
+			}
+		})
+	}
+}
+
+func TestAdminHandler_CreateIdentity(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			body:       `{"email": "new@example.com", "first_name": "New", "last_name": "User", "password": "password123"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "missing email",
+			body:       `{"first_name": "New", "last_name": "User"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "email taken",
+			body:       `{"email": "new@example.com", "first_name": "New", "last_name": "User"}`,
+			mockErr:    fmt.Errorf("failed to create identity: %w", auth.ErrIdentityRejected),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody ory.CreateIdentityBody
+			mock := &MockAdminService{
+				CreateIdentityFunc: func(ctx context.Context, body ory.CreateIdentityBody) (*ory.Identity, error) {
+					gotBody = body
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					return &ory.Identity{Id: "identity-456", Traits: body.Traits}, nil
+				},
+			}
+
+			handler := NewAdminHandler(mock)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/identities", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.CreateIdentity(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("CreateIdentity() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusCreated {
+				if gotBody.Traits["email"] != "new@example.com" {
+					t.Errorf("CreateIdentity() traits = %v", gotBody.Traits)
+				}
+				if gotBody.Credentials == nil || gotBody.Credentials.Password == nil {
+					t.Error("CreateIdentity() expected password credentials")
+				}
+				if gotBody.SchemaId != auth.DefaultIdentitySchemaID {
+					t.Errorf("CreateIdentity() schema = %q, want %q", gotBody.SchemaId, auth.DefaultIdentitySchemaID)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminHandler_CreateIdentity_IdentitySchema(t *testing.T) {
+	const schema = `{
+		"type": "object",
+		"properties": {
+			"traits": {
+				"type": "object",
+				"properties": {
+					"email": {"type": "string", "format": "email"},
+					"phone": {"type": "string"}
+				},
+				"required": ["email", "phone"],
+				"additionalProperties": false
+			}
+		}
+	}`
+
+	tests := []struct {
+		name       string
+		body       string
+		loadErr    error
+		wantStatus int
+	}{
+		{
+			name:       "schema traits",
+			body:       `{"traits": {"email": "new@example.com", "phone": "+15555550100"}}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "missing schema trait",
+			body:       `{"email": "new@example.com", "first_name": "New", "last_name": "User"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "short password",
+			body:       `{"password": "short", "traits": {"email": "new@example.com", "phone": "+15555550100"}}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "schema unavailable",
+			body:       `{"traits": {"email": "new@example.com", "phone": "+15555550100"}}`,
+			loadErr:    errors.New("kratos unavailable"),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody ory.CreateIdentityBody
+			mock := &MockAdminService{
+				CreateIdentityFunc: func(ctx context.Context, body ory.CreateIdentityBody) (*ory.Identity, error) {
+					gotBody = body
+					return &ory.Identity{Id: "identity-456", Traits: body.Traits}, nil
+				},
+			}
+
+			schemas := validation.NewIdentitySchemaLoader(func(ctx context.Context) ([]byte, error) {
+				return []byte(schema), tt.loadErr
+			}, 0)
+			handler := NewAdminHandler(mock).WithIdentitySchema("customer", schemas)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/identities", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.CreateIdentity(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("CreateIdentity() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantStatus == http.StatusCreated {
+				if gotBody.SchemaId != "customer" {
+					t.Errorf("CreateIdentity() schema = %q, want customer", gotBody.SchemaId)
+				}
+				if gotBody.Traits["phone"] != "+15555550100" || gotBody.Credentials != nil {
+					t.Errorf("CreateIdentity() body = %+v, want the schema traits without credentials", gotBody)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminHandler_UpdateIdentity(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantStatus  int
+		wantPaths   []string
+		wantFetched bool
+	}{
+		{
+			name:        "update name keeps email",
+			body:        `{"first_name": "Jane", "last_name": "Doe"}`,
+			wantStatus:  http.StatusOK,
+			wantPaths:   []string{"/traits"},
+			wantFetched: true,
+		},
+		{
+			name:       "state only",
+			body:       `{"state": "inactive"}`,
+			wantStatus: http.StatusOK,
+			wantPaths:  []string{"/state"},
+		},
+		{
+			name:       "invalid state",
+			body:       `{"state": "banned"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "empty update",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fetched bool
+			var gotPatches []ory.JsonPatch
+			mock := &MockAdminService{
+				GetIdentityFunc: func(ctx context.Context, id string) (*ory.Identity, error) {
+					fetched = true
+					return &ory.Identity{
+						Id:     id,
+						Traits: map[string]interface{}{"email": "test@example.com"},
+					}, nil
+				},
+				PatchIdentityFunc: func(ctx context.Context, id string, patches []ory.JsonPatch) (*ory.Identity, error) {
+					gotPatches = patches
+					return &ory.Identity{Id: id}, nil
+				},
+			}
+
+			handler := NewAdminHandler(mock)
+
+			req := withURLParam(httptest.NewRequest(http.MethodPatch, "/admin/identities/identity-123", strings.NewReader(tt.body)), "id", "identity-123")
+			w := httptest.NewRecorder()
+
+			handler.UpdateIdentity(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("UpdateIdentity() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if fetched != tt.wantFetched {
+				t.Errorf("UpdateIdentity() fetched identity = %v, want %v", fetched, tt.wantFetched)
+			}
+
+			paths := make([]string, 0, len(gotPatches))
+			for _, patch := range gotPatches {
+				paths = append(paths, patch.Path)
+			}
+			if strings.Join(paths, ",") != strings.Join(tt.wantPaths, ",") {
+				t.Errorf("UpdateIdentity() patch paths = %v, want %v", paths, tt.wantPaths)
+			}
+
+			if tt.wantFetched {
+				traits := gotPatches[0].Value.(map[string]interface{})
+				if traits["email"] != "test@example.com" {
+					t.Errorf("UpdateIdentity() email trait = %v, want it preserved", traits["email"])
+				}
+			}
+		})
+	}
+}
+
+func TestAdminHandler_BlockIdentity(t *testing.T) {
+	var gotState interface{}
+	var revoked bool
+	mock := &MockAdminService{
+		PatchIdentityFunc: func(ctx context.Context, id string, patches []ory.JsonPatch) (*ory.Identity, error) {
+			gotState = patches[0].Value
+			return &ory.Identity{Id: id}, nil
+		},
+		RevokeIdentitySessionsFunc: func(ctx context.Context, id string) error {
+			revoked = true
+			return nil
+		},
+	}
+
+	handler := NewAdminHandler(mock)
+
+	req := withURLParam(httptest.NewRequest(http.MethodPost, "/admin/identities/identity-123/block", nil), "id", "identity-123")
+	w := httptest.NewRecorder()
+
+	handler.BlockIdentity(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("BlockIdentity() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if gotState != auth.IdentityStateInactive {
+		t.Errorf("BlockIdentity() state = %v, want %q", gotState, auth.IdentityStateInactive)
+	}
+
+	if !revoked {
+		t.Error("BlockIdentity() expected sessions to be revoked")
+	}
+}
+
+func TestAdminHandler_DeleteIdentity(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		wantStatus int
+	}{
+		{name: "success", wantStatus: http.StatusNoContent},
+		{
+			name:       "not found",
+			mockErr:    fmt.Errorf("failed to delete identity: %w", auth.ErrIdentityNotFound),
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockAdminService{
+				DeleteIdentityFunc: func(ctx context.Context, id string) error {
+					return tt.mockErr
+				},
+			}
+
+			handler := NewAdminHandler(mock)
+
+			req := withURLParam(httptest.NewRequest(http.MethodDelete, "/admin/identities/identity-123", nil), "id", "identity-123")
+			w := httptest.NewRecorder()
+
+			handler.DeleteIdentity(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("DeleteIdentity() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// APIKeyHandler handles the caller's API keys
+type APIKeyHandler struct {
+	keys auth.APIKeyManager
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(keys auth.APIKeyManager) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+// CreateAPIKey handles POST /users/api-keys - returns the key once
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	identityID, ok := sessionIdentityID(w, r)
+	if !ok {
+		return
+	}
+
+	input, validationErr := validation.ValidateAPIKeyInput(r.Body, auth.APIKeyScopes)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	key, plaintext, err := h.keys.CreateAPIKey(r.Context(), identityID, auth.APIKeyOptions{
+		Name:      input.Name,
+		Scopes:    input.Scopes,
+		ExpiresAt: input.ExpiresAt,
+	})
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	response.Created(w, response.APIKeyCreatedResponse{
+		APIKeyResponse: apiKeyResponse(key, time.Now()),
+		Key:            plaintext,
+	})
+}
+
+// ListAPIKeys handles GET /users/api-keys
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	identityID, ok := sessionIdentityID(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.keys.ListAPIKeys(r.Context(), identityID)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	now := time.Now()
+	resp := response.APIKeyListResponse{Keys: make([]response.APIKeyResponse, 0, len(keys))}
+	for i := range keys {
+		resp.Keys = append(resp.Keys, apiKeyResponse(&keys[i], now))
+	}
+
+	response.Success(w, resp)
+}
+
+// RevokeAPIKey handles DELETE /users/api-keys/{id}
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	identityID, ok := sessionIdentityID(w, r)
+	if !ok {
+		return
+	}
+
+	keyID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if keyID == "" {
+		apperrors.NewValidationError("API key id is required", "").WriteJSON(w)
+		return
+	}
+
+	if err := h.keys.RevokeAPIKey(r.Context(), identityID, keyID); err != nil {
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			apperrors.NewNotFoundError("API key").WriteJSON(w)
+			return
+		}
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// sessionIdentityID returns the identity ID of the signed-in caller
+func sessionIdentityID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	session, ok := middleware.GetSessionFromContext(r.Context())
+	if !ok || session.Identity == nil {
+		apperrors.NewUnauthorizedError("missing session").WriteJSON(w)
+		return "", false
+	}
+	return session.Identity.Id, true
+}
+
+// apiKeyResponse converts a stored key for clients, leaving out its hash
+func apiKeyResponse(key *auth.APIKey, now time.Time) response.APIKeyResponse {
+	resp := response.APIKeyResponse{
+		ID:        key.ID,
+		Name:      key.Name,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt.String(),
+		Expired:   key.Expired(now),
+	}
+	if key.ExpiresAt != nil {
+		resp.ExpiresAt = key.ExpiresAt.String()
+	}
+	return resp
+}
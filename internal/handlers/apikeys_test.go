@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+)
+
+// MockAPIKeyManager is a mock implementation of auth.APIKeyManager
+type MockAPIKeyManager struct {
+	CreateAPIKeyFunc func(ctx context.Context, identityID string, opts auth.APIKeyOptions) (*auth.APIKey, string, error)
+	ListAPIKeysFunc  func(ctx context.Context, identityID string) ([]auth.APIKey, error)
+	RevokeAPIKeyFunc func(ctx context.Context, identityID, keyID string) error
+}
+
+func (m *MockAPIKeyManager) CreateAPIKey(ctx context.Context, identityID string, opts auth.APIKeyOptions) (*auth.APIKey, string, error) {
+	if m.CreateAPIKeyFunc != nil {
+		return m.CreateAPIKeyFunc(ctx, identityID, opts)
+	}
+	return nil, "", errors.New("not implemented")
+}
+
+func (m *MockAPIKeyManager) ListAPIKeys(ctx context.Context, identityID string) ([]auth.APIKey, error) {
+	if m.ListAPIKeysFunc != nil {
+		return m.ListAPIKeysFunc(ctx, identityID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockAPIKeyManager) RevokeAPIKey(ctx context.Context, identityID, keyID string) error {
+	if m.RevokeAPIKeyFunc != nil {
+		return m.RevokeAPIKeyFunc(ctx, identityID, keyID)
+	}
+	return errors.New("not implemented")
+}
+
+func TestAPIKeyHandler_CreateAPIKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{name: "success", body: `{"name": "batch", "scopes": ["llm:chat"]}`, wantStatus: http.StatusCreated},
+		{name: "unknown scope", body: `{"name": "batch", "scopes": ["admin"]}`, wantStatus: http.StatusBadRequest},
+		{name: "kratos error", body: `{"name": "batch", "scopes": ["llm:chat"]}`, mockErr: errors.New("kratos unavailable"), wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotIdentity string
+			var gotOpts auth.APIKeyOptions
+			mock := &MockAPIKeyManager{
+				CreateAPIKeyFunc: func(ctx context.Context, identityID string, opts auth.APIKeyOptions) (*auth.APIKey, string, error) {
+					gotIdentity, gotOpts = identityID, opts
+					if tt.mockErr != nil {
+						return nil, "", tt.mockErr
+					}
+					return &auth.APIKey{
+						ID:        "key-1",
+						Name:      opts.Name,
+						Scopes:    opts.Scopes,
+						Hash:      "secret-hash",
+						CreatedAt: time.Now(),
+					}, "kco_identity-123_key-1_secret", nil
+				},
+			}
+
+			handler := NewAPIKeyHandler(mock)
+
+			req := withTestSession(httptest.NewRequest(http.MethodPost, "/users/api-keys", bytes.NewBufferString(tt.body)))
+			w := httptest.NewRecorder()
+
+			handler.CreateAPIKey(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("CreateAPIKey() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+
+			if gotIdentity != "identity-123" || gotOpts.Name != "batch" {
+				t.Errorf("CreateAPIKey() called with %q, %+v", gotIdentity, gotOpts)
+			}
+
+			if bytes.Contains(w.Body.Bytes(), []byte("secret-hash")) {
+				t.Error("CreateAPIKey() response leaks the key hash")
+			}
+
+			var got response.APIKeyCreatedResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if got.Key != "kco_identity-123_key-1_secret" || got.ID != "key-1" {
+				t.Errorf("CreateAPIKey() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestAPIKeyHandler_ListAPIKeys(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	mock := &MockAPIKeyManager{
+		ListAPIKeysFunc: func(ctx context.Context, identityID string) ([]auth.APIKey, error) {
+			return []auth.APIKey{
+				{ID: "key-1", Name: "batch", Scopes: []string{auth.ScopeLLMChat}, Hash: "secret-hash"},
+				{ID: "key-2", Name: "old", Scopes: []string{auth.ScopeLLMGenerate}, ExpiresAt: &past},
+			}, nil
+		},
+	}
+
+	handler := NewAPIKeyHandler(mock)
+
+	req := withTestSession(httptest.NewRequest(http.MethodGet, "/users/api-keys", nil))
+	w := httptest.NewRecorder()
+
+	handler.ListAPIKeys(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ListAPIKeys() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if bytes.Contains(w.Body.Bytes(), []byte("secret-hash")) {
+		t.Error("ListAPIKeys() response leaks the key hash")
+	}
+
+	var got response.APIKeyListResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(got.Keys) != 2 || got.Keys[0].Expired || !got.Keys[1].Expired {
+		t.Errorf("ListAPIKeys() = %+v", got.Keys)
+	}
+}
+
+func TestAPIKeyHandler_RevokeAPIKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		wantStatus int
+	}{
+		{name: "success", wantStatus: http.StatusNoContent},
+		{name: "unknown key", mockErr: fmt.Errorf("failed to revoke API key: %w", auth.ErrAPIKeyNotFound), wantStatus: http.StatusNotFound},
+		{name: "kratos error", mockErr: errors.New("kratos unavailable"), wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotKeyID string
+			mock := &MockAPIKeyManager{
+				RevokeAPIKeyFunc: func(ctx context.Context, identityID, keyID string) error {
+					gotKeyID = keyID
+					return tt.mockErr
+				},
+			}
+
+			handler := NewAPIKeyHandler(mock)
+
+			req := withTestSession(httptest.NewRequest(http.MethodDelete, "/users/api-keys/key-1", nil))
+			req = withURLParam(req, "id", "key-1")
+			w := httptest.NewRecorder()
+
+			handler.RevokeAPIKey(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("RevokeAPIKey() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if gotKeyID != "key-1" {
+				t.Errorf("RevokeAPIKey() key id = %q, want %q", gotKeyID, "key-1")
+			}
+		})
+	}
+}
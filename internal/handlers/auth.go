@@ -11,7 +11,9 @@ import (
 
 // AuthHandler handles authentication-related requests
 type AuthHandler struct {
-	kratos auth.KratosService
+	kratos   auth.KratosService
+	schemas  *validation.IdentitySchemaLoader
+	throttle *auth.LoginThrottle
 }
 
 // NewAuthHandler creates a new auth handler
@@ -19,6 +21,20 @@ func NewAuthHandler(kratos auth.KratosService) *AuthHandler {
 	return &AuthHandler{kratos: kratos}
 }
 
+// WithIdentitySchema validates registration traits against the identity
+// schema schemas loads instead of the built-in fields
+func (h *AuthHandler) WithIdentitySchema(schemas *validation.IdentitySchemaLoader) *AuthHandler {
+	h.schemas = schemas
+	return h
+}
+
+// WithLoginThrottle applies backoff and lockout to password logins,
+// re-authentication and second factor logins
+func (h *AuthHandler) WithLoginThrottle(throttle *auth.LoginThrottle) *AuthHandler {
+	h.throttle = throttle
+	return h
+}
+
 // CreateLoginFlow handles GET /auth/login
 func (h *AuthHandler) CreateLoginFlow(w http.ResponseWriter, r *http.Request) {
 	flow, err := h.kratos.CreateLoginFlow(r.Context())
@@ -44,11 +60,16 @@ func (h *AuthHandler) SubmitLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if loginThrottled(w, r, h.throttle, input.Email) {
+		return
+	}
+
 	loginBody := auth.BuildPasswordLoginBody(input.Email, input.Password)
 
-	result, err := h.kratos.UpdateLoginFlow(r.Context(), flowID, loginBody)
+	result, err := h.kratos.UpdateLoginFlow(r.Context(), flowID, loginBody, "")
+	recordLoginAttempt(r, h.throttle, input.Email, err)
 	if err != nil {
-		apperrors.NewUnauthorizedError("invalid credentials").WriteJSON(w)
+		kratosFlowError(err, apperrors.NewUnauthorizedError("invalid credentials")).WriteJSON(w)
 		return
 	}
 
@@ -87,28 +108,38 @@ func (h *AuthHandler) SubmitRegistration(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	input, validationErr := validation.ValidateRegistrationInput(r.Body)
+	input, validationErr := validateRegistration(r, h.schemas)
 	if validationErr != nil {
 		validationErr.WriteJSON(w)
 		return
 	}
 
-	regBody := auth.BuildPasswordRegistrationBody(
-		input.Email,
-		input.Password,
-		input.FirstName,
-		input.LastName,
-	)
+	regBody := auth.BuildTraitsRegistrationBody(input.Password, input.Traits)
 
 	result, err := h.kratos.UpdateRegistrationFlow(r.Context(), flowID, regBody)
 	if err != nil {
-		apperrors.NewInternalError("registration failed", err).WriteJSON(w)
+		kratosFlowError(err, apperrors.NewInternalError("registration failed", err)).WriteJSON(w)
 		return
 	}
 
 	response.Created(w, result)
 }
 
+// validateRegistration validates a registration body against the identity
+// schema when one is configured, or the built-in fields otherwise
+func validateRegistration(r *http.Request, schemas *validation.IdentitySchemaLoader) (*validation.RegistrationInput, *apperrors.AppError) {
+	if schemas == nil {
+		return validation.ValidateRegistrationInput(r.Body)
+	}
+
+	schema, err := schemas.Schema(r.Context())
+	if err != nil {
+		return nil, apperrors.NewServiceUnavailableError("identity schema", err)
+	}
+
+	return validation.ValidateSchemaRegistrationInput(r.Body, schema)
+}
+
 // CreateVerificationFlow handles GET /auth/verification
 func (h *AuthHandler) CreateVerificationFlow(w http.ResponseWriter, r *http.Request) {
 	flow, err := h.kratos.CreateVerificationFlow(r.Context())
@@ -177,6 +208,11 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
 		return
 	}
+	// Native logout only takes session tokens
+	if auth.IsSessionCookie(sessionToken) {
+		apperrors.NewBadRequestError("cookie sessions log out through POST /api/v1/users/browser/logout").WriteJSON(w)
+		return
+	}
 	sessionBody := auth.BuildNewPerformNativeLogoutBody	(sessionToken)
 
 	err := h.kratos.PerformNativeLogout(r.Context(), *sessionBody)
@@ -188,7 +224,8 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	response.Success(w, map[string]string{"message": "Successfully logged out"})
 }
 
-// WhoAmI handles GET /auth/whoami - returns current session
+// WhoAmI handles GET /auth/whoami - returns current session and whether its
+// email is verified
 func (h *AuthHandler) WhoAmI(w http.ResponseWriter, r *http.Request) {
 	sessionToken := middleware.ExtractSessionToken(r)
 	if sessionToken == "" {
@@ -202,7 +239,15 @@ func (h *AuthHandler) WhoAmI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response.Success(w, session)
+	// Report whether the current email is verified alongside the session
+	resp, err := session.ToMap()
+	if err != nil {
+		apperrors.NewInternalError("failed to encode session", err).WriteJSON(w)
+		return
+	}
+	resp["verification"] = verificationStatus(session.Identity)
+
+	response.Success(w, resp)
 }
 
 // CreateRecoveryFlow handles GET /auth/recovery
@@ -9,24 +9,41 @@ import (
 	"testing"
 	"time"
 
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
 	ory "github.com/ory/client-go"
 )
 
 // MockKratosService implements auth.KratosService for testing
 type MockKratosService struct {
-	ValidateSessionFunc        func(ctx context.Context, token string) (*ory.Session, error)
-	CreateLoginFlowFunc        func(ctx context.Context) (*ory.LoginFlow, error)
-	UpdateLoginFlowFunc        func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (*ory.SuccessfulNativeLogin, error)
-	CreateRegistrationFlowFunc func(ctx context.Context) (*ory.RegistrationFlow, error)
-	UpdateRegistrationFlowFunc func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.SuccessfulNativeRegistration, error)
-	CreateLogoutFlowFunc       func(ctx context.Context, cookie string) (*ory.LogoutFlow, error)
-	PerformNativeLogoutFunc    func(ctx context.Context, sessionToken string) error
-	CreateVerificationFlowFunc func(ctx context.Context) (*ory.VerificationFlow, error)
-	UpdateVerificationFlowFunc func(ctx context.Context, flowID string, body ory.UpdateVerificationFlowBody) (*ory.VerificationFlow, error)
-	CreateRecoveryFlowFunc     func(ctx context.Context) (*ory.RecoveryFlow, error)
-	UpdateRecoveryFlowFunc     func(ctx context.Context, flowID string, body ory.UpdateRecoveryFlowBody) (*ory.RecoveryFlow, error)
-	CreateSettingsFlowFunc     func(ctx context.Context) (*ory.SettingsFlow, error)
-	UpdateSettingsFlowFunc     func(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error)
+	ValidateSessionFunc               func(ctx context.Context, token string) (*ory.Session, error)
+	CreateLoginFlowFunc               func(ctx context.Context) (*ory.LoginFlow, error)
+	CreateRefreshLoginFlowFunc        func(ctx context.Context, sessionToken string) (*ory.LoginFlow, error)
+	CreateAAL2LoginFlowFunc           func(ctx context.Context, sessionToken string) (*ory.LoginFlow, error)
+	UpdateLoginFlowFunc               func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error)
+	SendLoginCodeFunc                 func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (*ory.LoginFlow, error)
+	CreateRegistrationFlowFunc        func(ctx context.Context) (*ory.RegistrationFlow, error)
+	UpdateRegistrationFlowFunc        func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.SuccessfulNativeRegistration, error)
+	SendRegistrationCodeFunc          func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.RegistrationFlow, error)
+	PreparePasskeyRegistrationFunc    func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.RegistrationFlow, error)
+	CreateLogoutFlowFunc              func(ctx context.Context, cookie string) (*ory.LogoutFlow, error)
+	PerformNativeLogoutFunc           func(ctx context.Context, body ory.PerformNativeLogoutBody) error
+	CreateBrowserLoginFlowFunc        func(ctx context.Context, cookies string) (*ory.LoginFlow, []string, error)
+	UpdateBrowserLoginFlowFunc        func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, cookies string) (*ory.SuccessfulNativeLogin, []string, error)
+	CreateBrowserRegistrationFlowFunc func(ctx context.Context, cookies string) (*ory.RegistrationFlow, []string, error)
+	UpdateBrowserRegistrationFlowFunc func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody, cookies string) (*ory.SuccessfulNativeRegistration, []string, error)
+	PerformBrowserLogoutFunc          func(ctx context.Context, logoutToken, cookie string) ([]string, error)
+	CreateVerificationFlowFunc        func(ctx context.Context) (*ory.VerificationFlow, error)
+	UpdateVerificationFlowFunc        func(ctx context.Context, flowID string, body ory.UpdateVerificationFlowBody) (*ory.VerificationFlow, error)
+	CreateRecoveryFlowFunc            func(ctx context.Context) (*ory.RecoveryFlow, error)
+	UpdateRecoveryFlowFunc            func(ctx context.Context, flowID string, body ory.UpdateRecoveryFlowBody) (*ory.RecoveryFlow, error)
+	CreateSettingsFlowFunc            func(ctx context.Context, sessionToken string) (*ory.SettingsFlow, error)
+	UpdateSettingsFlowFunc            func(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error)
+	CreateOIDCLoginFlowFunc           func(ctx context.Context, returnTo string) (*ory.LoginFlow, error)
+	StartOIDCLoginFunc                func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (string, error)
+	ExchangeSessionTokenFunc          func(ctx context.Context, initCode, returnToCode string) (*ory.SuccessfulNativeLogin, error)
+	ListMySessionsFunc                func(ctx context.Context, sessionToken string) ([]ory.Session, error)
+	RevokeMySessionFunc               func(ctx context.Context, sessionToken, sessionID string) error
+	RevokeMyOtherSessionsFunc         func(ctx context.Context, sessionToken string) (int64, error)
 }
 
 func (m *MockKratosService) ValidateSession(ctx context.Context, token string) (*ory.Session, error) {
@@ -43,9 +60,30 @@ func (m *MockKratosService) CreateLoginFlow(ctx context.Context) (*ory.LoginFlow
 	return nil, errors.New("not implemented")
 }
 
-func (m *MockKratosService) UpdateLoginFlow(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (*ory.SuccessfulNativeLogin, error) {
+func (m *MockKratosService) CreateRefreshLoginFlow(ctx context.Context, sessionToken string) (*ory.LoginFlow, error) {
+	if m.CreateRefreshLoginFlowFunc != nil {
+		return m.CreateRefreshLoginFlowFunc(ctx, sessionToken)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockKratosService) CreateAAL2LoginFlow(ctx context.Context, sessionToken string) (*ory.LoginFlow, error) {
+	if m.CreateAAL2LoginFlowFunc != nil {
+		return m.CreateAAL2LoginFlowFunc(ctx, sessionToken)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockKratosService) UpdateLoginFlow(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error) {
 	if m.UpdateLoginFlowFunc != nil {
-		return m.UpdateLoginFlowFunc(ctx, flowID, body)
+		return m.UpdateLoginFlowFunc(ctx, flowID, body, sessionToken)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockKratosService) SendLoginCode(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (*ory.LoginFlow, error) {
+	if m.SendLoginCodeFunc != nil {
+		return m.SendLoginCodeFunc(ctx, flowID, body)
 	}
 	return nil, errors.New("not implemented")
 }
@@ -64,6 +102,20 @@ func (m *MockKratosService) UpdateRegistrationFlow(ctx context.Context, flowID s
 	return nil, errors.New("not implemented")
 }
 
+func (m *MockKratosService) SendRegistrationCode(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.RegistrationFlow, error) {
+	if m.SendRegistrationCodeFunc != nil {
+		return m.SendRegistrationCodeFunc(ctx, flowID, body)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockKratosService) PreparePasskeyRegistration(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.RegistrationFlow, error) {
+	if m.PreparePasskeyRegistrationFunc != nil {
+		return m.PreparePasskeyRegistrationFunc(ctx, flowID, body)
+	}
+	return nil, errors.New("not implemented")
+}
+
 func (m *MockKratosService) CreateLogoutFlow(ctx context.Context, cookie string) (*ory.LogoutFlow, error) {
 	if m.CreateLogoutFlowFunc != nil {
 		return m.CreateLogoutFlowFunc(ctx, cookie)
@@ -71,13 +123,48 @@ func (m *MockKratosService) CreateLogoutFlow(ctx context.Context, cookie string)
 	return nil, errors.New("not implemented")
 }
 
-func (m *MockKratosService) PerformNativeLogout(ctx context.Context, sessionToken string) error {
+func (m *MockKratosService) PerformNativeLogout(ctx context.Context, body ory.PerformNativeLogoutBody) error {
 	if m.PerformNativeLogoutFunc != nil {
-		return m.PerformNativeLogoutFunc(ctx, sessionToken)
+		return m.PerformNativeLogoutFunc(ctx, body)
 	}
 	return errors.New("not implemented")
 }
 
+func (m *MockKratosService) CreateBrowserLoginFlow(ctx context.Context, cookies string) (*ory.LoginFlow, []string, error) {
+	if m.CreateBrowserLoginFlowFunc != nil {
+		return m.CreateBrowserLoginFlowFunc(ctx, cookies)
+	}
+	return nil, nil, errors.New("not implemented")
+}
+
+func (m *MockKratosService) UpdateBrowserLoginFlow(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, cookies string) (*ory.SuccessfulNativeLogin, []string, error) {
+	if m.UpdateBrowserLoginFlowFunc != nil {
+		return m.UpdateBrowserLoginFlowFunc(ctx, flowID, body, cookies)
+	}
+	return nil, nil, errors.New("not implemented")
+}
+
+func (m *MockKratosService) CreateBrowserRegistrationFlow(ctx context.Context, cookies string) (*ory.RegistrationFlow, []string, error) {
+	if m.CreateBrowserRegistrationFlowFunc != nil {
+		return m.CreateBrowserRegistrationFlowFunc(ctx, cookies)
+	}
+	return nil, nil, errors.New("not implemented")
+}
+
+func (m *MockKratosService) UpdateBrowserRegistrationFlow(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody, cookies string) (*ory.SuccessfulNativeRegistration, []string, error) {
+	if m.UpdateBrowserRegistrationFlowFunc != nil {
+		return m.UpdateBrowserRegistrationFlowFunc(ctx, flowID, body, cookies)
+	}
+	return nil, nil, errors.New("not implemented")
+}
+
+func (m *MockKratosService) PerformBrowserLogout(ctx context.Context, logoutToken, cookie string) ([]string, error) {
+	if m.PerformBrowserLogoutFunc != nil {
+		return m.PerformBrowserLogoutFunc(ctx, logoutToken, cookie)
+	}
+	return nil, errors.New("not implemented")
+}
+
 func (m *MockKratosService) CreateVerificationFlow(ctx context.Context) (*ory.VerificationFlow, error) {
 	if m.CreateVerificationFlowFunc != nil {
 		return m.CreateVerificationFlowFunc(ctx)
@@ -106,9 +193,9 @@ func (m *MockKratosService) UpdateRecoveryFlow(ctx context.Context, flowID strin
 	return nil, errors.New("not implemented")
 }
 
-func (m *MockKratosService) CreateSettingsFlow(ctx context.Context) (*ory.SettingsFlow, error) {
+func (m *MockKratosService) CreateSettingsFlow(ctx context.Context, sessionToken string) (*ory.SettingsFlow, error) {
 	if m.CreateSettingsFlowFunc != nil {
-		return m.CreateSettingsFlowFunc(ctx)
+		return m.CreateSettingsFlowFunc(ctx, sessionToken)
 	}
 	return nil, errors.New("not implemented")
 }
@@ -120,6 +207,48 @@ func (m *MockKratosService) UpdateSettingsFlow(ctx context.Context, flowID strin
 	return nil, errors.New("not implemented")
 }
 
+func (m *MockKratosService) CreateOIDCLoginFlow(ctx context.Context, returnTo string) (*ory.LoginFlow, error) {
+	if m.CreateOIDCLoginFlowFunc != nil {
+		return m.CreateOIDCLoginFlowFunc(ctx, returnTo)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockKratosService) StartOIDCLogin(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (string, error) {
+	if m.StartOIDCLoginFunc != nil {
+		return m.StartOIDCLoginFunc(ctx, flowID, body)
+	}
+	return "", errors.New("not implemented")
+}
+
+func (m *MockKratosService) ExchangeSessionToken(ctx context.Context, initCode, returnToCode string) (*ory.SuccessfulNativeLogin, error) {
+	if m.ExchangeSessionTokenFunc != nil {
+		return m.ExchangeSessionTokenFunc(ctx, initCode, returnToCode)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockKratosService) ListMySessions(ctx context.Context, sessionToken string) ([]ory.Session, error) {
+	if m.ListMySessionsFunc != nil {
+		return m.ListMySessionsFunc(ctx, sessionToken)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *MockKratosService) RevokeMySession(ctx context.Context, sessionToken, sessionID string) error {
+	if m.RevokeMySessionFunc != nil {
+		return m.RevokeMySessionFunc(ctx, sessionToken, sessionID)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *MockKratosService) RevokeMyOtherSessions(ctx context.Context, sessionToken string) (int64, error) {
+	if m.RevokeMyOtherSessionsFunc != nil {
+		return m.RevokeMyOtherSessionsFunc(ctx, sessionToken)
+	}
+	return 0, errors.New("not implemented")
+}
+
 func TestAuthHandler_CreateLoginFlow(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -214,7 +343,7 @@ func TestAuthHandler_SubmitLogin(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mock := &MockKratosService{
-				UpdateLoginFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (*ory.SuccessfulNativeLogin, error) {
+				UpdateLoginFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error) {
 					return tt.mockResult, tt.mockErr
 				},
 			}
@@ -347,6 +476,7 @@ func TestAuthHandler_Logout(t *testing.T) {
 	tests := []struct {
 		name         string
 		sessionToken string
+		cookie       string
 		mockErr      error
 		wantStatus   int
 	}{
@@ -356,6 +486,11 @@ func TestAuthHandler_Logout(t *testing.T) {
 			mockErr:      nil,
 			wantStatus:   http.StatusOK,
 		},
+		{
+			name:       "cookie session",
+			cookie:     "abc",
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:         "missing session token",
 			sessionToken: "",
@@ -372,7 +507,7 @@ func TestAuthHandler_Logout(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mock := &MockKratosService{
-				PerformNativeLogoutFunc: func(ctx context.Context, sessionToken string) error {
+				PerformNativeLogoutFunc: func(ctx context.Context, body ory.PerformNativeLogoutBody) error {
 					return tt.mockErr
 				},
 			}
@@ -382,6 +517,9 @@ func TestAuthHandler_Logout(t *testing.T) {
 			if tt.sessionToken != "" {
 				req.Header.Set("X-Session-Token", tt.sessionToken)
 			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "ory_kratos_session", Value: tt.cookie})
+			}
 			w := httptest.NewRecorder()
 
 			handler.Logout(w, req)
@@ -618,7 +756,7 @@ func TestAuthHandler_CreateRecoveryFlow(t *testing.T) {
 			name: "success",
 			mockFlow: &ory.RecoveryFlow{
 				Id:        "recovery-flow-123",
-				ExpiresAt: ptrTime(time.Now().Add(10 * time.Minute)),
+				ExpiresAt: time.Now().Add(10 * time.Minute),
 			},
 			mockErr:    nil,
 			wantStatus: http.StatusOK,
@@ -824,3 +962,75 @@ func ptrString(s string) *string {
 func ptrTime(t time.Time) *time.Time {
 	return &t
 }
+
+func TestAuthHandler_SubmitRegistration_IdentitySchema(t *testing.T) {
+	const schema = `{
+		"type": "object",
+		"properties": {
+			"traits": {
+				"type": "object",
+				"properties": {
+					"email": {"type": "string", "format": "email"},
+					"phone": {"type": "string"}
+				},
+				"required": ["email", "phone"],
+				"additionalProperties": false
+			}
+		}
+	}`
+
+	tests := []struct {
+		name       string
+		body       string
+		loadErr    error
+		wantStatus int
+	}{
+		{
+			name:       "schema traits",
+			body:       `{"pass": "password123", "traits": {"email": "test@example.com", "phone": "+15555550100"}}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "missing schema trait",
+			body:       `{"email": "test@example.com", "pass": "password123", "first_name": "Test", "last_name": "User"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "schema unavailable",
+			body:       `{"pass": "password123", "traits": {"email": "test@example.com", "phone": "+15555550100"}}`,
+			loadErr:    errors.New("kratos unavailable"),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotTraits map[string]interface{}
+			mock := &MockKratosService{
+				UpdateRegistrationFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.SuccessfulNativeRegistration, error) {
+					gotTraits = body.UpdateRegistrationFlowWithPasswordMethod.Traits
+					return &ory.SuccessfulNativeRegistration{}, nil
+				},
+			}
+
+			schemas := validation.NewIdentitySchemaLoader(func(ctx context.Context) ([]byte, error) {
+				return []byte(schema), tt.loadErr
+			}, 0)
+			handler := NewAuthHandler(mock).WithIdentitySchema(schemas)
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/registration/flow?flow=flow123", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.SubmitRegistration(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("SubmitRegistration() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusCreated && gotTraits["phone"] != "+15555550100" {
+				t.Errorf("SubmitRegistration() sent traits %v, want the phone trait", gotTraits)
+			}
+		})
+	}
+}
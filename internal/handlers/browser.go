@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// BrowserHandler handles browser flows for same-origin web apps. Kratos
+// cookies are relayed between the client and Kratos, so the session lives in
+// an HttpOnly cookie instead of a token the app has to store.
+type BrowserHandler struct {
+	kratos        auth.KratosService
+	secureCookies bool
+	schemas       *validation.IdentitySchemaLoader
+	throttle      *auth.LoginThrottle
+}
+
+// NewBrowserHandler creates a new browser flow handler. secureCookies marks
+// the CSRF cookie Secure; Kratos decides that for its own cookies.
+func NewBrowserHandler(kratos auth.KratosService, secureCookies bool) *BrowserHandler {
+	return &BrowserHandler{kratos: kratos, secureCookies: secureCookies}
+}
+
+// WithIdentitySchema validates registration traits against the identity
+// schema schemas loads instead of the built-in fields
+func (h *BrowserHandler) WithIdentitySchema(schemas *validation.IdentitySchemaLoader) *BrowserHandler {
+	h.schemas = schemas
+	return h
+}
+
+// WithLoginThrottle applies backoff and lockout to password logins
+func (h *BrowserHandler) WithLoginThrottle(throttle *auth.LoginThrottle) *BrowserHandler {
+	h.throttle = throttle
+	return h
+}
+
+// CreateLoginFlow handles GET /browser/login
+func (h *BrowserHandler) CreateLoginFlow(w http.ResponseWriter, r *http.Request) {
+	flow, cookies, err := h.kratos.CreateBrowserLoginFlow(r.Context(), r.Header.Get("Cookie"))
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	relayCookies(w, cookies)
+
+	resp := response.LoginFlowResponse{
+		FlowID:    flow.Id,
+		CSRFToken: auth.ExtractLoginCSRFToken(flow),
+		Action:    flow.Ui.Action,
+		Method:    flow.Ui.Method,
+		Fields:    auth.ExtractLoginFormFields(flow),
+	}
+
+	if !flow.ExpiresAt.IsZero() {
+		resp.ExpiresAt = flow.ExpiresAt.String()
+	}
+
+	response.Success(w, resp)
+}
+
+// SubmitLogin handles POST /browser/login/flow - sets the session cookie
+func (h *BrowserHandler) SubmitLogin(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidateLoginInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	if input.CSRFToken == "" {
+		apperrors.NewValidationError("csrf_token is required", "").WriteJSON(w)
+		return
+	}
+
+	if loginThrottled(w, r, h.throttle, input.Email) {
+		return
+	}
+
+	loginBody := auth.BuildBrowserPasswordLoginBody(input.Email, input.Password, input.CSRFToken)
+
+	result, cookies, err := h.kratos.UpdateBrowserLoginFlow(r.Context(), flowID, loginBody, r.Header.Get("Cookie"))
+	recordLoginAttempt(r, h.throttle, input.Email, err)
+	if err != nil {
+		kratosFlowError(err, apperrors.NewUnauthorizedError("invalid credentials")).WriteJSON(w)
+		return
+	}
+
+	relayCookies(w, cookies)
+	if err := middleware.SetCSRFCookie(w, h.secureCookies); err != nil {
+		apperrors.NewInternalError("failed to issue CSRF token", err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, result)
+}
+
+// CreateRegistrationFlow handles GET /browser/registration
+func (h *BrowserHandler) CreateRegistrationFlow(w http.ResponseWriter, r *http.Request) {
+	flow, cookies, err := h.kratos.CreateBrowserRegistrationFlow(r.Context(), r.Header.Get("Cookie"))
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	relayCookies(w, cookies)
+
+	resp := response.RegistrationFlowResponse{
+		FlowID:    flow.Id,
+		CSRFToken: auth.ExtractCSRFToken(flow),
+		Action:    flow.Ui.Action,
+		Method:    flow.Ui.Method,
+		Fields:    auth.ExtractFormFields(flow),
+	}
+
+	if !flow.ExpiresAt.IsZero() {
+		resp.ExpiresAt = flow.ExpiresAt.String()
+	}
+
+	response.Success(w, resp)
+}
+
+// SubmitRegistration handles POST /browser/registration/flow
+func (h *BrowserHandler) SubmitRegistration(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validateRegistration(r, h.schemas)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	if input.CSRFToken == "" {
+		apperrors.NewValidationError("csrf_token is required", "").WriteJSON(w)
+		return
+	}
+
+	regBody := auth.BuildBrowserTraitsRegistrationBody(input.Password, input.Traits, input.CSRFToken)
+
+	result, cookies, err := h.kratos.UpdateBrowserRegistrationFlow(r.Context(), flowID, regBody, r.Header.Get("Cookie"))
+	if err != nil {
+		kratosFlowError(err, apperrors.NewInternalError("registration failed", err)).WriteJSON(w)
+		return
+	}
+
+	relayCookies(w, cookies)
+	if err := middleware.SetCSRFCookie(w, h.secureCookies); err != nil {
+		apperrors.NewInternalError("failed to issue CSRF token", err).WriteJSON(w)
+		return
+	}
+
+	response.Created(w, result)
+}
+
+// Logout handles POST /browser/logout - ends the cookie session through a
+// Kratos logout flow and clears the cookies
+func (h *BrowserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	sessionCookie := middleware.ExtractSessionToken(r)
+	if !auth.IsSessionCookie(sessionCookie) {
+		apperrors.NewUnauthorizedError("no session cookie provided").WriteJSON(w)
+		return
+	}
+
+	flow, err := h.kratos.CreateLogoutFlow(r.Context(), sessionCookie)
+	if err != nil {
+		apperrors.NewInternalError("failed to logout", err).WriteJSON(w)
+		return
+	}
+
+	cookies, err := h.kratos.PerformBrowserLogout(r.Context(), flow.LogoutToken, sessionCookie)
+	if err != nil {
+		apperrors.NewInternalError("failed to logout", err).WriteJSON(w)
+		return
+	}
+
+	relayCookies(w, cookies)
+	middleware.ClearCSRFCookie(w, h.secureCookies)
+
+	response.Success(w, map[string]string{"message": "Successfully logged out"})
+}
+
+// relayCookies passes Kratos' Set-Cookie headers on to the client
+func relayCookies(w http.ResponseWriter, cookies []string) {
+	for _, cookie := range cookies {
+		w.Header().Add("Set-Cookie", cookie)
+	}
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
+	ory "github.com/ory/client-go"
+)
+
+const kratosCSRFCookie = "csrf_token_abc=kratos-csrf; Path=/; HttpOnly; SameSite=Lax"
+
+// setCookieNames returns the names of the cookies a response sets
+func setCookieNames(w *httptest.ResponseRecorder) map[string]bool {
+	names := make(map[string]bool)
+	for _, cookie := range w.Result().Cookies() {
+		names[cookie.Name] = true
+	}
+	return names
+}
+
+func TestBrowserHandler_CreateLoginFlow(t *testing.T) {
+	var gotCookies string
+	mock := &MockKratosService{
+		CreateBrowserLoginFlowFunc: func(ctx context.Context, cookies string) (*ory.LoginFlow, []string, error) {
+			gotCookies = cookies
+			return &ory.LoginFlow{Id: "flow-123"}, []string{kratosCSRFCookie}, nil
+		},
+	}
+
+	handler := NewBrowserHandler(mock, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/browser/login", nil)
+	req.Header.Set("Cookie", "csrf_token_abc=old")
+	w := httptest.NewRecorder()
+
+	handler.CreateLoginFlow(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("CreateLoginFlow() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if gotCookies != "csrf_token_abc=old" {
+		t.Errorf("CreateLoginFlow() forwarded cookies %q", gotCookies)
+	}
+
+	if !setCookieNames(w)["csrf_token_abc"] {
+		t.Error("CreateLoginFlow() did not relay the Kratos CSRF cookie")
+	}
+}
+
+func TestBrowserHandler_CreateRegistrationFlow(t *testing.T) {
+	var gotCookies string
+	mock := &MockKratosService{
+		CreateBrowserRegistrationFlowFunc: func(ctx context.Context, cookies string) (*ory.RegistrationFlow, []string, error) {
+			gotCookies = cookies
+			return &ory.RegistrationFlow{Id: "flow-123"}, []string{kratosCSRFCookie}, nil
+		},
+	}
+
+	handler := NewBrowserHandler(mock, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/browser/registration", nil)
+	req.Header.Set("Cookie", "csrf_token_abc=old")
+	w := httptest.NewRecorder()
+
+	handler.CreateRegistrationFlow(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("CreateRegistrationFlow() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if gotCookies != "csrf_token_abc=old" {
+		t.Errorf("CreateRegistrationFlow() forwarded cookies %q", gotCookies)
+	}
+
+	if !setCookieNames(w)["csrf_token_abc"] {
+		t.Error("CreateRegistrationFlow() did not relay the Kratos CSRF cookie")
+	}
+}
+
+func TestBrowserHandler_SubmitLogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{name: "success", body: `{"email": "test@example.com", "pass": "password123", "csrf_token": "kratos-csrf"}`, wantStatus: http.StatusOK},
+		{name: "missing csrf token", body: `{"email": "test@example.com", "pass": "password123"}`, wantStatus: http.StatusBadRequest},
+		{name: "rejected", body: `{"email": "test@example.com", "pass": "password123", "csrf_token": "kratos-csrf"}`, mockErr: errors.New("csrf violation"), wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotCSRF, gotCookies string
+			mock := &MockKratosService{
+				UpdateBrowserLoginFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, cookies string) (*ory.SuccessfulNativeLogin, []string, error) {
+					gotCSRF, gotCookies = body.UpdateLoginFlowWithPasswordMethod.GetCsrfToken(), cookies
+					if tt.mockErr != nil {
+						return nil, nil, tt.mockErr
+					}
+					return &ory.SuccessfulNativeLogin{Session: ory.Session{Id: "session-123"}},
+						[]string{"ory_kratos_session=new; Path=/; HttpOnly"}, nil
+				},
+			}
+
+			handler := NewBrowserHandler(mock, true)
+
+			req := httptest.NewRequest(http.MethodPost, "/users/browser/login/flow?flow=flow-123", bytes.NewBufferString(tt.body))
+			req.Header.Set("Cookie", "csrf_token_abc=kratos-csrf")
+			w := httptest.NewRecorder()
+
+			handler.SubmitLogin(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("SubmitLogin() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if gotCSRF != "kratos-csrf" || gotCookies != "csrf_token_abc=kratos-csrf" {
+				t.Errorf("SubmitLogin() sent csrf_token %q with cookies %q", gotCSRF, gotCookies)
+			}
+
+			names := setCookieNames(w)
+			if !names["ory_kratos_session"] || !names[middleware.CSRFCookieName] {
+				t.Errorf("SubmitLogin() set cookies %v, want the session and CSRF cookies", names)
+			}
+		})
+	}
+}
+
+func TestBrowserHandler_SubmitRegistration(t *testing.T) {
+	mock := &MockKratosService{
+		UpdateBrowserRegistrationFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody, cookies string) (*ory.SuccessfulNativeRegistration, []string, error) {
+			if body.UpdateRegistrationFlowWithPasswordMethod.GetCsrfToken() != "kratos-csrf" {
+				return nil, nil, errors.New("csrf violation")
+			}
+			return &ory.SuccessfulNativeRegistration{}, []string{"ory_kratos_session=new; Path=/; HttpOnly"}, nil
+		},
+	}
+
+	handler := NewBrowserHandler(mock, true)
+
+	body := `{"email": "test@example.com", "pass": "password123", "first_name": "Test", "last_name": "User", "csrf_token": "kratos-csrf"}`
+	req := httptest.NewRequest(http.MethodPost, "/users/browser/registration/flow?flow=flow-123", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	handler.SubmitRegistration(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("SubmitRegistration() status = %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	if !setCookieNames(w)["ory_kratos_session"] {
+		t.Error("SubmitRegistration() did not relay the session cookie")
+	}
+}
+
+func TestBrowserHandler_Logout(t *testing.T) {
+	tests := []struct {
+		name       string
+		cookie     string
+		logoutErr  error
+		wantStatus int
+	}{
+		{name: "success", cookie: "ory_kratos_session=abc", wantStatus: http.StatusOK},
+		{name: "no session cookie", wantStatus: http.StatusUnauthorized},
+		{name: "kratos error", cookie: "ory_kratos_session=abc", logoutErr: errors.New("kratos error"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotToken, gotCookie string
+			mock := &MockKratosService{
+				CreateLogoutFlowFunc: func(ctx context.Context, cookie string) (*ory.LogoutFlow, error) {
+					return &ory.LogoutFlow{LogoutToken: "logout-token"}, nil
+				},
+				PerformBrowserLogoutFunc: func(ctx context.Context, logoutToken, cookie string) ([]string, error) {
+					gotToken, gotCookie = logoutToken, cookie
+					if tt.logoutErr != nil {
+						return nil, tt.logoutErr
+					}
+					return []string{"ory_kratos_session=; Path=/; Max-Age=0"}, nil
+				},
+			}
+
+			handler := NewBrowserHandler(mock, true)
+
+			req := httptest.NewRequest(http.MethodPost, "/users/browser/logout", nil)
+			if tt.cookie != "" {
+				req.Header.Set("Cookie", tt.cookie)
+			}
+			w := httptest.NewRecorder()
+
+			handler.Logout(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Logout() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if gotToken != "logout-token" || gotCookie != "ory_kratos_session=abc" {
+				t.Errorf("Logout() performed logout with %q, %q", gotToken, gotCookie)
+			}
+
+			names := setCookieNames(w)
+			if !names["ory_kratos_session"] || !names[middleware.CSRFCookieName] {
+				t.Errorf("Logout() set cookies %v, want the session and CSRF cookies cleared", names)
+			}
+		})
+	}
+}
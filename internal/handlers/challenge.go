@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/challenge"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// ChallengeHandler hands out the challenges RequireChallenge demands
+type ChallengeHandler struct {
+	guard    *challenge.Guard
+	verifier challenge.Verifier
+}
+
+// NewChallengeHandler creates a new challenge handler
+func NewChallengeHandler(guard *challenge.Guard, verifier challenge.Verifier) *ChallengeHandler {
+	return &ChallengeHandler{guard: guard, verifier: verifier}
+}
+
+// GetChallenge handles GET /users/auth/challenge - reports whether the
+// caller's next registration, login or registration code, recovery or
+// verification request must carry a solved challenge, and returns one to solve
+func (h *ChallengeHandler) GetChallenge(w http.ResponseWriter, r *http.Request) {
+	c, err := h.verifier.Challenge(r.Context())
+	if err != nil {
+		apperrors.NewInternalError("failed to create challenge", err).WriteJSON(w)
+		return
+	}
+
+	resp := response.ChallengeResponse{
+		Required:   h.guard.Required(middleware.ClientIP(r)),
+		Type:       c.Type,
+		SiteKey:    c.SiteKey,
+		Token:      c.Token,
+		Difficulty: c.Difficulty,
+	}
+	if !c.ExpiresAt.IsZero() {
+		resp.ExpiresAt = c.ExpiresAt.String()
+	}
+
+	response.Success(w, resp)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/challenge"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+)
+
+func TestChallengeHandler_GetChallenge(t *testing.T) {
+	guard := challenge.NewGuard(challenge.GuardConfig{Threshold: 1, Window: time.Minute, MaxEntries: 10})
+	pow := challenge.NewProofOfWork([]byte("test-key"), 4, time.Minute)
+	handler := NewChallengeHandler(guard, pow)
+
+	get := func() response.ChallengeResponse {
+		req := httptest.NewRequest(http.MethodGet, "/users/auth/challenge", nil)
+		req.RemoteAddr = "203.0.113.7:51234"
+		w := httptest.NewRecorder()
+		handler.GetChallenge(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("GetChallenge() status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		var got response.ChallengeResponse
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return got
+	}
+
+	got := get()
+	if got.Required {
+		t.Error("GetChallenge() before any request Required = true, want false")
+	}
+	if got.Type != challenge.TypeProofOfWork || got.Token == "" || got.Difficulty != 4 || got.ExpiresAt == "" {
+		t.Errorf("GetChallenge() = %+v, want a pow puzzle", got)
+	}
+
+	guard.Hit("203.0.113.7")
+	got = get()
+	if !got.Required {
+		t.Error("GetChallenge() past the threshold Required = false, want true")
+	}
+
+	// The puzzle handed out is one the verifier accepts
+	solved := challenge.SolveProofOfWork(got.Token, got.Difficulty)
+	if err := pow.Verify(context.Background(), solved, "203.0.113.7"); err != nil {
+		t.Errorf("Verify() of the handed out puzzle error = %v", err)
+	}
+}
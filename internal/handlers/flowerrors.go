@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// kratosFieldNames maps Kratos node names to the request fields clients send
+var kratosFieldNames = map[string]string{
+	"identifier":        "email",
+	"password":          "pass",
+	"traits.email":      "email",
+	"traits.name.first": "first_name",
+	"traits.name.last":  "last_name",
+}
+
+// kratosFlowError translates a flow submission Kratos rejected into an
+// AppError: an expired flow is FLOW_EXPIRED, an existing identifier is
+// CONFLICT, and field messages become a VALIDATION_ERROR listing them.
+// Rejected credentials and errors Kratos did not explain return fallback.
+func kratosFlowError(err error, fallback *apperrors.AppError) *apperrors.AppError {
+	var flowErr *auth.FlowError
+	if !errors.As(err, &flowErr) {
+		return fallback
+	}
+
+	switch {
+	case flowErr.Expired():
+		return apperrors.NewFlowExpiredError("flow expired: start a new flow")
+	case flowErr.Conflict():
+		return apperrors.NewConflictError(flowErr.Message())
+	case flowErr.InvalidCredentials():
+		return fallback
+	}
+
+	fieldMessages := flowErr.FieldMessages()
+	if len(fieldMessages) == 0 {
+		if msg := flowErr.Message(); msg != "" && flowErr.Status < 500 {
+			return apperrors.NewValidationError(msg, "")
+		}
+		return fallback
+	}
+
+	fields := make([]apperrors.FieldError, 0, len(fieldMessages))
+	for _, msg := range fieldMessages {
+		field := msg.Field
+		if name, ok := kratosFieldNames[field]; ok {
+			field = name
+		}
+		fields = append(fields, apperrors.FieldError{Field: field, Message: msg.Text})
+	}
+
+	message := flowErr.Message()
+	if len(flowErr.Messages) == len(fieldMessages) {
+		message = "invalid input"
+	}
+	return apperrors.NewFieldValidationError(message, fields)
+}
+
+// kratosUnavailable reports whether err is Kratos failing, unreachable or
+// answering with a server error, rather than a rejection it explained
+func kratosUnavailable(err error) bool {
+	var flowErr *auth.FlowError
+	return !errors.As(err, &flowErr) || flowErr.Status >= http.StatusInternalServerError
+}
+
+// kratosUpdateError is kratosFlowError for flow submissions whose client
+// errors are all FlowErrors: anything else is SERVICE_UNAVAILABLE rather
+// than blamed on the request
+func kratosUpdateError(err error, fallback *apperrors.AppError) *apperrors.AppError {
+	if kratosUnavailable(err) {
+		return apperrors.NewServiceUnavailableError("Kratos", err)
+	}
+	return kratosFlowError(err, fallback)
+}
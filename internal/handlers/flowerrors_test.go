@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+	ory "github.com/ory/client-go"
+)
+
+func TestAuthHandler_SubmitRegistration_FlowErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		wantStatus int
+		wantCode   apperrors.ErrorCode
+		wantFields []apperrors.FieldError
+	}{
+		{
+			name: "identifier exists",
+			mockErr: &auth.FlowError{Status: http.StatusBadRequest, Messages: []auth.FlowMessage{
+				{ID: 4000007, Text: "An account with the same identifier exists already.", Type: "error"},
+			}},
+			wantStatus: http.StatusConflict,
+			wantCode:   apperrors.ErrCodeConflict,
+		},
+		{
+			name:       "flow expired",
+			mockErr:    &auth.FlowError{Status: http.StatusGone, ID: "self_service_flow_expired"},
+			wantStatus: http.StatusGone,
+			wantCode:   apperrors.ErrCodeFlowExpired,
+		},
+		{
+			name: "field messages",
+			mockErr: &auth.FlowError{Status: http.StatusBadRequest, Messages: []auth.FlowMessage{
+				{ID: 4000005, Field: "password", Text: "The password has been found in data breaches.", Type: "error"},
+				{ID: 4000001, Field: "traits.email", Text: "\"test\" is not valid \"email\"", Type: "error"},
+			}},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   apperrors.ErrCodeValidation,
+			wantFields: []apperrors.FieldError{
+				{Field: "pass", Message: "The password has been found in data breaches."},
+				{Field: "email", Message: "\"test\" is not valid \"email\""},
+			},
+		},
+		{
+			name:       "unexplained error",
+			mockErr:    errors.New("kratos unavailable"),
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   apperrors.ErrCodeInternal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockKratosService{
+				UpdateRegistrationFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.SuccessfulNativeRegistration, error) {
+					return nil, tt.mockErr
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			body := `{"email": "test@example.com", "pass": "password123", "first_name": "Test", "last_name": "User"}`
+			req := httptest.NewRequest(http.MethodPost, "/auth/registration/flow?flow=flow123", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			handler.SubmitRegistration(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("SubmitRegistration() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			var resp struct {
+				Error apperrors.AppError `json:"error"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			got := resp.Error
+
+			if got.Code != tt.wantCode {
+				t.Errorf("SubmitRegistration() code = %q, want %q", got.Code, tt.wantCode)
+			}
+
+			if len(got.Fields) != len(tt.wantFields) {
+				t.Fatalf("SubmitRegistration() fields = %+v, want %+v", got.Fields, tt.wantFields)
+			}
+			for i, field := range tt.wantFields {
+				if got.Fields[i] != field {
+					t.Errorf("SubmitRegistration() fields[%d] = %+v, want %+v", i, got.Fields[i], field)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthHandler_SubmitLogin_FlowExpired(t *testing.T) {
+	mock := &MockKratosService{
+		UpdateLoginFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error) {
+			return nil, &auth.FlowError{Status: http.StatusGone, ID: "self_service_flow_expired"}
+		},
+	}
+
+	handler := NewAuthHandler(mock)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/login/flow?flow=flow123", strings.NewReader(`{"email": "test@example.com", "pass": "password123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	handler.SubmitLogin(w, req)
+
+	if w.Code != http.StatusGone {
+		t.Errorf("SubmitLogin() status = %d, want %d", w.Code, http.StatusGone)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/davegermiquet/kratos-chi-ollama/internal/langchain"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
 	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
 	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
 	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
@@ -38,7 +39,13 @@ func (h *LLMHandler) Chat(w http.ResponseWriter, r *http.Request) {
 
 	llmMessages := langchain.ConvertMessages(messages)
 
-	content, chatErr := h.llm.Chat(r.Context(), llmMessages)
+	// Route by identity so experiment assignment is sticky per user
+	ctx := r.Context()
+	if session, ok := middleware.GetSessionFromContext(ctx); ok && session.Identity != nil {
+		ctx = langchain.WithRoutingKey(ctx, session.Identity.Id)
+	}
+
+	content, chatErr := h.llm.Chat(ctx, llmMessages)
 	if chatErr != nil {
 		apperrors.NewServiceUnavailableError("LLM", chatErr).WriteJSON(w)
 		return
@@ -8,7 +8,11 @@ import (
 	"strings"
 	"testing"
 
+	ory "github.com/ory/client-go"
 	"github.com/tmc/langchaingo/llms"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/langchain"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
 )
 
 // MockLLMService implements langchain.LLMService for testing
@@ -235,3 +239,29 @@ func TestLLMHandler_Chat_MessageRoleConversion(t *testing.T) {
 		})
 	}
 }
+
+func TestLLMHandler_Chat_RoutingKeyFromSession(t *testing.T) {
+	var gotKey string
+	mock := &MockLLMService{
+		ChatFunc: func(ctx context.Context, messages []llms.MessageContent, opts ...llms.CallOption) (string, error) {
+			gotKey, _ = langchain.RoutingKeyFromContext(ctx)
+			return "ok", nil
+		},
+	}
+
+	handler := NewLLMHandler(mock)
+	req := httptest.NewRequest(http.MethodPost, "/llm/chat", strings.NewReader(`{"messages": [{"role": "user", "content": "Hi"}]}`))
+	session := &ory.Session{Identity: &ory.Identity{Id: "identity-123"}}
+	req = req.WithContext(context.WithValue(req.Context(), middleware.SessionContextKey, session))
+	w := httptest.NewRecorder()
+
+	handler.Chat(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Chat() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if gotKey != "identity-123" {
+		t.Errorf("routing key = %q, want %q", gotKey, "identity-123")
+	}
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"net/http"
+
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// CreateTOTPSetup handles GET /users/settings/totp - starts TOTP enrollment
+func (h *AuthHandler) CreateTOTPSetup(w http.ResponseWriter, r *http.Request) {
+	sessionToken, ok := nativeSessionToken(w, r)
+	if !ok {
+		return
+	}
+
+	flow, err := h.kratos.CreateSettingsFlow(r.Context(), sessionToken)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	qrCode, secret, linked := auth.ExtractTOTPSetup(flow)
+	if !linked && qrCode == "" {
+		apperrors.NewBadRequestError("TOTP is not enabled on this Kratos instance").WriteJSON(w)
+		return
+	}
+
+	response.Success(w, response.TOTPSetupResponse{
+		FlowID: flow.Id,
+		QRCode: qrCode,
+		Secret: secret,
+		Linked: linked,
+	})
+}
+
+// EnrollTOTP handles POST /users/settings/totp - confirms enrollment with a code
+func (h *AuthHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	sessionToken := middleware.ExtractSessionToken(r)
+	if sessionToken == "" {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidateTOTPCodeInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	settingsBody := auth.BuildTOTPSettingsBody(input.Code)
+
+	flow, err := h.kratos.UpdateSettingsFlow(r.Context(), flowID, settingsBody, sessionToken)
+	if err != nil {
+		settingsError(err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, flow)
+}
+
+// UnlinkTOTP handles POST /users/settings/totp/unlink - removes the authenticator
+func (h *AuthHandler) UnlinkTOTP(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	sessionToken := middleware.ExtractSessionToken(r)
+	if sessionToken == "" {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	flow, err := h.kratos.UpdateSettingsFlow(r.Context(), flowID, auth.BuildTOTPUnlinkBody(), sessionToken)
+	if err != nil {
+		settingsError(err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, flow)
+}
+
+// CreateAAL2LoginFlow handles GET /auth/login/aal2 - starts the second factor
+// step for a session that logged in with a password
+func (h *AuthHandler) CreateAAL2LoginFlow(w http.ResponseWriter, r *http.Request) {
+	sessionToken, ok := nativeSessionToken(w, r)
+	if !ok {
+		return
+	}
+
+	flow, err := h.kratos.CreateAAL2LoginFlow(r.Context(), sessionToken)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, flow)
+}
+
+// SubmitTOTPLogin handles POST /auth/login/aal2/flow - completes aal2 with a TOTP code
+func (h *AuthHandler) SubmitTOTPLogin(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	sessionToken := middleware.ExtractSessionToken(r)
+	if sessionToken == "" {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidateTOTPCodeInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	throttleKey := sessionThrottleKey(r)
+	if loginThrottled(w, r, h.throttle, throttleKey) {
+		return
+	}
+
+	loginBody := auth.BuildTOTPLoginBody(input.Code)
+
+	result, err := h.kratos.UpdateLoginFlow(r.Context(), flowID, loginBody, sessionToken)
+	recordLoginAttempt(r, h.throttle, throttleKey, err)
+	if err != nil {
+		apperrors.NewUnauthorizedError("invalid authentication code").WriteJSON(w)
+		return
+	}
+
+	response.Success(w, result)
+}
+
+// CreateLookupSecretsFlow handles GET /users/settings/lookup - starts backup code management
+func (h *AuthHandler) CreateLookupSecretsFlow(w http.ResponseWriter, r *http.Request) {
+	sessionToken, ok := nativeSessionToken(w, r)
+	if !ok {
+		return
+	}
+
+	flow, err := h.kratos.CreateSettingsFlow(r.Context(), sessionToken)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, response.LookupSecretsResponse{
+		FlowID:  flow.Id,
+		Enabled: auth.HasLookupSecrets(flow),
+	})
+}
+
+// RegenerateLookupSecrets handles POST /users/settings/lookup/regenerate - generates
+// a new set of backup codes; they are not saved until confirmed
+func (h *AuthHandler) RegenerateLookupSecrets(w http.ResponseWriter, r *http.Request) {
+	h.updateLookupSecrets(w, r, auth.BuildLookupRegenerateBody())
+}
+
+// RevealLookupSecrets handles POST /users/settings/lookup/reveal - shows the saved backup codes
+func (h *AuthHandler) RevealLookupSecrets(w http.ResponseWriter, r *http.Request) {
+	h.updateLookupSecrets(w, r, auth.BuildLookupRevealBody())
+}
+
+// ConfirmLookupSecrets handles POST /users/settings/lookup/confirm - saves regenerated backup codes
+func (h *AuthHandler) ConfirmLookupSecrets(w http.ResponseWriter, r *http.Request) {
+	h.updateLookupSecrets(w, r, auth.BuildLookupConfirmBody())
+}
+
+func (h *AuthHandler) updateLookupSecrets(w http.ResponseWriter, r *http.Request, body ory.UpdateSettingsFlowBody) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	sessionToken := middleware.ExtractSessionToken(r)
+	if sessionToken == "" {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	flow, err := h.kratos.UpdateSettingsFlow(r.Context(), flowID, body, sessionToken)
+	if err != nil {
+		settingsError(err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, response.LookupSecretsResponse{
+		FlowID:  flow.Id,
+		Codes:   auth.ExtractLookupSecrets(flow),
+		Enabled: auth.HasLookupSecrets(flow),
+	})
+}
+
+// SubmitLookupSecretLogin handles POST /auth/login/aal2/lookup - completes aal2 with a backup code
+func (h *AuthHandler) SubmitLookupSecretLogin(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	sessionToken := middleware.ExtractSessionToken(r)
+	if sessionToken == "" {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidateLookupSecretInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	throttleKey := sessionThrottleKey(r)
+	if loginThrottled(w, r, h.throttle, throttleKey) {
+		return
+	}
+
+	loginBody := auth.BuildLookupSecretLoginBody(input.Code)
+
+	result, err := h.kratos.UpdateLoginFlow(r.Context(), flowID, loginBody, sessionToken)
+	recordLoginAttempt(r, h.throttle, throttleKey, err)
+	if err != nil {
+		apperrors.NewUnauthorizedError("invalid or used backup code").WriteJSON(w)
+		return
+	}
+
+	response.Success(w, result)
+}
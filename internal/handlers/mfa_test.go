@@ -0,0 +1,497 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+)
+
+func totpSetupFlow() *ory.SettingsFlow {
+	return &ory.SettingsFlow{
+		Id: "settings123",
+		Ui: ory.UiContainer{
+			Nodes: []ory.UiNode{
+				{
+					Group: "totp",
+					Attributes: ory.UiNodeAttributes{
+						UiNodeImageAttributes: &ory.UiNodeImageAttributes{Id: "totp_qr", Src: "data:image/png;base64,AAAA"},
+					},
+				},
+				{
+					Group: "totp",
+					Attributes: ory.UiNodeAttributes{
+						UiNodeTextAttributes: &ory.UiNodeTextAttributes{Id: "totp_secret_key", Text: ory.UiText{Text: "JBSWY3DPEHPK3PXP"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestAuthHandler_CreateTOTPSetup(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockFlow   *ory.SettingsFlow
+		mockErr    error
+		wantStatus int
+		wantSetup  response.TOTPSetupResponse
+	}{
+		{
+			name:       "not yet linked",
+			mockFlow:   totpSetupFlow(),
+			wantStatus: http.StatusOK,
+			wantSetup: response.TOTPSetupResponse{
+				FlowID: "settings123",
+				QRCode: "data:image/png;base64,AAAA",
+				Secret: "JBSWY3DPEHPK3PXP",
+			},
+		},
+		{
+			name: "already linked",
+			mockFlow: &ory.SettingsFlow{
+				Id: "settings123",
+				Ui: ory.UiContainer{
+					Nodes: []ory.UiNode{
+						{
+							Group: "totp",
+							Attributes: ory.UiNodeAttributes{
+								UiNodeInputAttributes: &ory.UiNodeInputAttributes{Name: "totp_unlink", Type: "submit"},
+							},
+						},
+					},
+				},
+			},
+			wantStatus: http.StatusOK,
+			wantSetup:  response.TOTPSetupResponse{FlowID: "settings123", Linked: true},
+		},
+		{
+			name:       "totp disabled",
+			mockFlow:   &ory.SettingsFlow{Id: "settings123"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "kratos error",
+			mockErr:    errors.New("kratos unavailable"),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockKratosService{
+				CreateSettingsFlowFunc: func(ctx context.Context, sessionToken string) (*ory.SettingsFlow, error) {
+					return tt.mockFlow, tt.mockErr
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+			req := withTestSession(httptest.NewRequest(http.MethodGet, "/users/settings/totp", nil))
+			w := httptest.NewRecorder()
+
+			handler.CreateTOTPSetup(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("CreateTOTPSetup() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got response.TOTPSetupResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if got != tt.wantSetup {
+				t.Errorf("CreateTOTPSetup() = %+v, want %+v", got, tt.wantSetup)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_EnrollTOTP(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			body:       `{"code": "123456"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid code format",
+			body:       `{"code": "12"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "kratos rejects code",
+			body: `{"code": "123456"}`,
+			mockErr: &auth.FlowError{Status: http.StatusBadRequest, Messages: []auth.FlowMessage{
+				{Field: "totp_code", Text: "The provided authentication code is invalid, please try again."},
+			}},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotCode string
+			mock := &MockKratosService{
+				UpdateSettingsFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error) {
+					gotCode = body.UpdateSettingsFlowWithTotpMethod.GetTotpCode()
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					return &ory.SettingsFlow{Id: flowID}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+			req := withTestSession(httptest.NewRequest(http.MethodPost, "/users/settings/totp?flow=settings123", strings.NewReader(tt.body)))
+			w := httptest.NewRecorder()
+
+			handler.EnrollTOTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("EnrollTOTP() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusOK && gotCode != "123456" {
+				t.Errorf("EnrollTOTP() sent code %q, want %q", gotCode, "123456")
+			}
+		})
+	}
+}
+
+func TestAuthHandler_UnlinkTOTP(t *testing.T) {
+	var gotUnlink bool
+	mock := &MockKratosService{
+		UpdateSettingsFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error) {
+			gotUnlink = body.UpdateSettingsFlowWithTotpMethod.GetTotpUnlink()
+			return &ory.SettingsFlow{Id: flowID}, nil
+		},
+	}
+
+	handler := NewAuthHandler(mock)
+	req := withTestSession(httptest.NewRequest(http.MethodPost, "/users/settings/totp/unlink?flow=settings123", nil))
+	w := httptest.NewRecorder()
+
+	handler.UnlinkTOTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("UnlinkTOTP() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if !gotUnlink {
+		t.Error("UnlinkTOTP() did not request totp_unlink")
+	}
+}
+
+func TestAuthHandler_CreateAAL2LoginFlow_CookieSession(t *testing.T) {
+	var called bool
+	mock := &MockKratosService{
+		CreateAAL2LoginFlowFunc: func(ctx context.Context, sessionToken string) (*ory.LoginFlow, error) {
+			called = true
+			return &ory.LoginFlow{Id: "login123"}, nil
+		},
+	}
+
+	handler := NewAuthHandler(mock)
+	req := httptest.NewRequest(http.MethodGet, "/users/auth/login/aal2", nil)
+	req.AddCookie(&http.Cookie{Name: auth.SessionCookieName, Value: "abc"})
+	w := httptest.NewRecorder()
+
+	handler.CreateAAL2LoginFlow(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("CreateAAL2LoginFlow() status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if called {
+		t.Error("CreateAAL2LoginFlow() passed a cookie session to Kratos")
+	}
+}
+
+func TestAuthHandler_SubmitTOTPLogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		flowID     string
+		token      bool
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			flowID:     "login123",
+			token:      true,
+			body:       `{"code": "123456"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing flow ID",
+			token:      true,
+			body:       `{"code": "123456"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "no session token",
+			flowID:     "login123",
+			body:       `{"code": "123456"}`,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong code",
+			flowID:     "login123",
+			token:      true,
+			body:       `{"code": "654321"}`,
+			mockErr:    errors.New("invalid code"),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotToken, gotCode string
+			mock := &MockKratosService{
+				UpdateLoginFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error) {
+					gotToken = sessionToken
+					gotCode = body.UpdateLoginFlowWithTotpMethod.TotpCode
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					return &ory.SuccessfulNativeLogin{}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			url := "/users/auth/login/aal2/flow"
+			if tt.flowID != "" {
+				url += "?flow=" + tt.flowID
+			}
+
+			req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(tt.body))
+			if tt.token {
+				req.Header.Set("X-Session-Token", "token123")
+			}
+			w := httptest.NewRecorder()
+
+			handler.SubmitTOTPLogin(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("SubmitTOTPLogin() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusOK && (gotToken != "token123" || gotCode != "123456") {
+				t.Errorf("SubmitTOTPLogin() token = %q code = %q", gotToken, gotCode)
+			}
+		})
+	}
+}
+
+func lookupCodesFlow() *ory.SettingsFlow {
+	return &ory.SettingsFlow{
+		Id: "settings123",
+		Ui: ory.UiContainer{
+			Nodes: []ory.UiNode{
+				{
+					Group: "lookup_secret",
+					Attributes: ory.UiNodeAttributes{
+						UiNodeTextAttributes: &ory.UiNodeTextAttributes{
+							Id: "lookup_secret_codes",
+							Text: ory.UiText{
+								Context: map[string]interface{}{
+									"secrets": []interface{}{
+										map[string]interface{}{"id": float64(1050009), "text": "aaaa1111"},
+										map[string]interface{}{"id": float64(1050014), "text": "Secret was used at 2026-01-01"},
+										map[string]interface{}{"id": float64(1050009), "text": "bbbb2222"},
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					Group: "lookup_secret",
+					Attributes: ory.UiNodeAttributes{
+						UiNodeInputAttributes: &ory.UiNodeInputAttributes{Name: "lookup_secret_confirm", Type: "submit"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestAuthHandler_RegenerateLookupSecrets(t *testing.T) {
+	tests := []struct {
+		name       string
+		flowID     string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			flowID:     "settings123",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing flow ID",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "flow expired",
+			flowID:     "settings123",
+			mockErr:    &auth.FlowError{Status: http.StatusGone, ID: "self_service_flow_expired"},
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:       "kratos error",
+			flowID:     "settings123",
+			mockErr:    errors.New("kratos unavailable"),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotRegenerate bool
+			mock := &MockKratosService{
+				UpdateSettingsFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error) {
+					gotRegenerate = body.UpdateSettingsFlowWithLookupMethod.GetLookupSecretRegenerate()
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					return lookupCodesFlow(), nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			url := "/users/settings/lookup/regenerate"
+			if tt.flowID != "" {
+				url += "?flow=" + tt.flowID
+			}
+
+			req := withTestSession(httptest.NewRequest(http.MethodPost, url, nil))
+			w := httptest.NewRecorder()
+
+			handler.RegenerateLookupSecrets(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("RegenerateLookupSecrets() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if !gotRegenerate {
+				t.Error("RegenerateLookupSecrets() did not request lookup_secret_regenerate")
+			}
+
+			var got response.LookupSecretsResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if strings.Join(got.Codes, ",") != "aaaa1111,bbbb2222" {
+				t.Errorf("RegenerateLookupSecrets() codes = %v, want unused codes only", got.Codes)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_ConfirmAndRevealLookupSecrets(t *testing.T) {
+	var confirmed, revealed bool
+	mock := &MockKratosService{
+		UpdateSettingsFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error) {
+			method := body.UpdateSettingsFlowWithLookupMethod
+			confirmed = confirmed || method.GetLookupSecretConfirm()
+			revealed = revealed || method.GetLookupSecretReveal()
+			return &ory.SettingsFlow{Id: flowID}, nil
+		},
+	}
+
+	handler := NewAuthHandler(mock)
+
+	w := httptest.NewRecorder()
+	handler.ConfirmLookupSecrets(w, withTestSession(httptest.NewRequest(http.MethodPost, "/users/settings/lookup/confirm?flow=settings123", nil)))
+	if w.Code != http.StatusOK || !confirmed {
+		t.Errorf("ConfirmLookupSecrets() status = %d, confirmed = %v", w.Code, confirmed)
+	}
+
+	w = httptest.NewRecorder()
+	handler.RevealLookupSecrets(w, withTestSession(httptest.NewRequest(http.MethodPost, "/users/settings/lookup/reveal?flow=settings123", nil)))
+	if w.Code != http.StatusOK || !revealed {
+		t.Errorf("RevealLookupSecrets() status = %d, revealed = %v", w.Code, revealed)
+	}
+}
+
+func TestAuthHandler_SubmitLookupSecretLogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			body:       `{"code": "aaaa1111"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing code",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "used code",
+			body:       `{"code": "aaaa1111"}`,
+			mockErr:    errors.New("code already used"),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotCode string
+			mock := &MockKratosService{
+				UpdateLoginFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error) {
+					gotCode = body.UpdateLoginFlowWithLookupSecretMethod.LookupSecret
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					return &ory.SuccessfulNativeLogin{}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+			req := httptest.NewRequest(http.MethodPost, "/users/auth/login/aal2/lookup?flow=login123", strings.NewReader(tt.body))
+			req.Header.Set("X-Session-Token", "token123")
+			w := httptest.NewRecorder()
+
+			handler.SubmitLookupSecretLogin(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("SubmitLookupSecretLogin() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusOK && gotCode != "aaaa1111" {
+				t.Errorf("SubmitLookupSecretLogin() sent code %q, want %q", gotCode, "aaaa1111")
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// CreateOIDCLoginFlow handles GET /auth/oidc - starts social sign-in and lists providers.
+// return_to is where the provider sends the browser afterwards; it must be
+// listed in Kratos' allowed_return_urls.
+func (h *AuthHandler) CreateOIDCLoginFlow(w http.ResponseWriter, r *http.Request) {
+	returnTo := strings.TrimSpace(r.URL.Query().Get("return_to"))
+	if returnTo == "" {
+		apperrors.NewValidationError("return_to parameter is required", "").WriteJSON(w)
+		return
+	}
+
+	flow, err := h.kratos.CreateOIDCLoginFlow(r.Context(), returnTo)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	resp := response.OIDCLoginFlowResponse{
+		FlowID:                   flow.Id,
+		Providers:                auth.ExtractOIDCProviders(flow),
+		SessionTokenExchangeCode: flow.GetSessionTokenExchangeCode(),
+	}
+
+	if !flow.ExpiresAt.IsZero() {
+		resp.ExpiresAt = flow.ExpiresAt.String()
+	}
+
+	response.Success(w, resp)
+}
+
+// StartOIDCLogin handles POST /auth/oidc/flow - returns the provider URL to open
+func (h *AuthHandler) StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidateOIDCProviderInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	loginBody := auth.BuildOIDCLoginBody(input.Provider)
+
+	redirectTo, err := h.kratos.StartOIDCLogin(r.Context(), flowID, loginBody)
+	if err != nil {
+		kratosUpdateError(err, apperrors.NewBadRequestError("failed to start social sign-in")).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, response.OIDCRedirectResponse{RedirectBrowserTo: redirectTo})
+}
+
+// ExchangeOIDCSession handles POST /auth/oidc/exchange - trades the exchange
+// codes for a session token once the provider has redirected back
+func (h *AuthHandler) ExchangeOIDCSession(w http.ResponseWriter, r *http.Request) {
+	input, validationErr := validation.ValidateSessionTokenExchangeInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	// Kratos rejects codes that are unknown, used or not yet completed alike
+	result, err := h.kratos.ExchangeSessionToken(r.Context(), input.InitCode, input.ReturnToCode)
+	if err != nil {
+		if kratosUnavailable(err) {
+			apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		} else {
+			apperrors.NewUnauthorizedError("social sign-in was not completed").WriteJSON(w)
+		}
+		return
+	}
+
+	response.Success(w, result)
+}
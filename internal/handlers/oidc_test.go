@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+)
+
+func TestAuthHandler_CreateOIDCLoginFlow(t *testing.T) {
+	exchangeCode := "init-code"
+	flow := &ory.LoginFlow{
+		Id:                       "flow123",
+		SessionTokenExchangeCode: &exchangeCode,
+		Ui: ory.UiContainer{
+			Nodes: []ory.UiNode{
+				{
+					Group: "oidc",
+					Attributes: ory.UiNodeAttributes{
+						UiNodeInputAttributes: &ory.UiNodeInputAttributes{Name: "provider", Value: "google"},
+					},
+				},
+				{
+					Group: "oidc",
+					Attributes: ory.UiNodeAttributes{
+						UiNodeInputAttributes: &ory.UiNodeInputAttributes{Name: "provider", Value: "github"},
+					},
+				},
+				{
+					Group: "password",
+					Attributes: ory.UiNodeAttributes{
+						UiNodeInputAttributes: &ory.UiNodeInputAttributes{Name: "identifier"},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		returnTo   string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			returnTo:   "myapp://callback",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing return_to",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "kratos error",
+			returnTo:   "myapp://callback",
+			mockErr:    errors.New("return_to not allowed"),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotReturnTo string
+			mock := &MockKratosService{
+				CreateOIDCLoginFlowFunc: func(ctx context.Context, returnTo string) (*ory.LoginFlow, error) {
+					gotReturnTo = returnTo
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					return flow, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			url := "/auth/oidc"
+			if tt.returnTo != "" {
+				url += "?return_to=" + tt.returnTo
+			}
+
+			req := httptest.NewRequest(http.MethodGet, url, nil)
+			w := httptest.NewRecorder()
+
+			handler.CreateOIDCLoginFlow(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("CreateOIDCLoginFlow() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if gotReturnTo != "myapp://callback" {
+				t.Errorf("CreateOIDCLoginFlow() return_to = %q", gotReturnTo)
+			}
+
+			var got response.OIDCLoginFlowResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if strings.Join(got.Providers, ",") != "google,github" {
+				t.Errorf("CreateOIDCLoginFlow() providers = %v", got.Providers)
+			}
+
+			if got.SessionTokenExchangeCode != "init-code" {
+				t.Errorf("CreateOIDCLoginFlow() exchange code = %q", got.SessionTokenExchangeCode)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_StartOIDCLogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		flowID     string
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			flowID:     "flow123",
+			body:       `{"provider": "google"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing flow ID",
+			body:       `{"provider": "google"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing provider",
+			flowID:     "flow123",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown provider",
+			flowID: "flow123",
+			body:   `{"provider": "myspace"}`,
+			mockErr: &auth.FlowError{Status: http.StatusBadRequest, Messages: []auth.FlowMessage{
+				{Text: "Unable to find OpenID Connect provider \"myspace\".", Type: "error"},
+			}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "expired flow",
+			flowID:     "flow123",
+			body:       `{"provider": "google"}`,
+			mockErr:    &auth.FlowError{Status: http.StatusGone, ID: "self_service_flow_expired"},
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:       "kratos unavailable",
+			flowID:     "flow123",
+			body:       `{"provider": "google"}`,
+			mockErr:    errors.New("connection refused"),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotProvider string
+			mock := &MockKratosService{
+				StartOIDCLoginFunc: func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (string, error) {
+					gotProvider = body.UpdateLoginFlowWithOidcMethod.Provider
+					if tt.mockErr != nil {
+						return "", tt.mockErr
+					}
+					return "https://accounts.google.com/o/oauth2/auth?state=abc", nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			url := "/auth/oidc/flow"
+			if tt.flowID != "" {
+				url += "?flow=" + tt.flowID
+			}
+
+			req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.StartOIDCLogin(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("StartOIDCLogin() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusOK {
+				if gotProvider != "google" {
+					t.Errorf("StartOIDCLogin() provider = %q, want %q", gotProvider, "google")
+				}
+				if !strings.Contains(w.Body.String(), "accounts.google.com") {
+					t.Errorf("StartOIDCLogin() body = %s, want redirect URL", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestAuthHandler_ExchangeOIDCSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			body:       `{"init_code": "init-code", "return_to_code": "return-code"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing return_to_code",
+			body:       `{"init_code": "init-code"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "not completed",
+			body:       `{"init_code": "init-code", "return_to_code": "return-code"}`,
+			mockErr:    &auth.FlowError{Status: http.StatusNotFound, Messages: []auth.FlowMessage{{Text: "The requested resource could not be found", Type: "error"}}},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "kratos unavailable",
+			body:       `{"init_code": "init-code", "return_to_code": "return-code"}`,
+			mockErr:    errors.New("connection refused"),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockKratosService{
+				ExchangeSessionTokenFunc: func(ctx context.Context, initCode, returnToCode string) (*ory.SuccessfulNativeLogin, error) {
+					if initCode != "init-code" || returnToCode != "return-code" {
+						t.Errorf("ExchangeSessionToken() codes = %q, %q", initCode, returnToCode)
+					}
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					token := "session-token"
+					return &ory.SuccessfulNativeLogin{SessionToken: &token}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+			req := httptest.NewRequest(http.MethodPost, "/auth/oidc/exchange", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.ExchangeOIDCSession(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ExchangeOIDCSession() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
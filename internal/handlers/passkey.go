@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// StartPasskeyRegistration handles POST /auth/registration/passkey - submits the
+// traits and returns the WebAuthn creation options for navigator.credentials.create
+func (h *AuthHandler) StartPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validatePasskeyRegistration(r, h.schemas, false)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	regBody := auth.BuildPasskeyRegistrationBody(input.Traits, "")
+
+	flow, err := h.kratos.PreparePasskeyRegistration(r.Context(), flowID, regBody)
+	if err != nil {
+		apperrors.NewBadRequestError("failed to start passkey registration").WriteJSON(w)
+		return
+	}
+
+	response.Success(w, response.PasskeyOptionsResponse{
+		FlowID:  flow.Id,
+		Options: auth.ExtractPasskeyCreationOptions(flow.Ui.Nodes),
+	})
+}
+
+// SubmitPasskeyRegistration handles POST /auth/registration/passkey/verify - completes
+// registration with the authenticator's attestation response
+func (h *AuthHandler) SubmitPasskeyRegistration(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validatePasskeyRegistration(r, h.schemas, true)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	regBody := auth.BuildPasskeyRegistrationBody(input.Traits, input.Credential)
+
+	result, err := h.kratos.UpdateRegistrationFlow(r.Context(), flowID, regBody)
+	if err != nil {
+		kratosFlowError(err, apperrors.NewBadRequestError("passkey registration failed")).WriteJSON(w)
+		return
+	}
+
+	response.Created(w, result)
+}
+
+// validatePasskeyRegistration validates a passkey registration body against
+// the identity schema when one is configured, like validateRegistration
+func validatePasskeyRegistration(r *http.Request, schemas *validation.IdentitySchemaLoader, requireCredential bool) (*validation.PasskeyRegistrationInput, *apperrors.AppError) {
+	if schemas == nil {
+		return validation.ValidatePasskeyRegistrationInput(r.Body, requireCredential)
+	}
+
+	schema, err := schemas.Schema(r.Context())
+	if err != nil {
+		return nil, apperrors.NewServiceUnavailableError("identity schema", err)
+	}
+
+	return validation.ValidateSchemaPasskeyRegistrationInput(r.Body, schema, requireCredential)
+}
+
+// CreatePasskeyLoginFlow handles GET /auth/login/passkey - returns the WebAuthn
+// request options for navigator.credentials.get
+func (h *AuthHandler) CreatePasskeyLoginFlow(w http.ResponseWriter, r *http.Request) {
+	flow, err := h.kratos.CreateLoginFlow(r.Context())
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	options := auth.ExtractPasskeyRequestOptions(flow.Ui.Nodes)
+	if options == nil {
+		apperrors.NewBadRequestError("passkeys are not enabled on this Kratos instance").WriteJSON(w)
+		return
+	}
+
+	response.Success(w, response.PasskeyOptionsResponse{
+		FlowID:  flow.Id,
+		Options: options,
+	})
+}
+
+// SubmitPasskeyLogin handles POST /auth/login/passkey - logs in with the
+// authenticator's assertion response
+func (h *AuthHandler) SubmitPasskeyLogin(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidatePasskeyCredentialInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	loginBody := auth.BuildPasskeyLoginBody(input.Credential)
+
+	result, err := h.kratos.UpdateLoginFlow(r.Context(), flowID, loginBody, "")
+	if err != nil {
+		apperrors.NewUnauthorizedError("passkey was not accepted").WriteJSON(w)
+		return
+	}
+
+	response.Success(w, result)
+}
+
+// CreatePasskeySettings handles GET /users/settings/passkey - returns creation
+// options for linking a passkey and the IDs of passkeys already linked
+func (h *AuthHandler) CreatePasskeySettings(w http.ResponseWriter, r *http.Request) {
+	sessionToken, ok := nativeSessionToken(w, r)
+	if !ok {
+		return
+	}
+
+	flow, err := h.kratos.CreateSettingsFlow(r.Context(), sessionToken)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, response.PasskeyOptionsResponse{
+		FlowID:        flow.Id,
+		Options:       auth.ExtractPasskeyCreationOptions(flow.Ui.Nodes),
+		CredentialIDs: auth.ExtractPasskeyCredentialIDs(flow),
+	})
+}
+
+// LinkPasskey handles POST /users/settings/passkey - links a new passkey
+func (h *AuthHandler) LinkPasskey(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	sessionToken := middleware.ExtractSessionToken(r)
+	if sessionToken == "" {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidatePasskeyCredentialInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	flow, err := h.kratos.UpdateSettingsFlow(r.Context(), flowID, auth.BuildPasskeySettingsBody(input.Credential), sessionToken)
+	if err != nil {
+		settingsError(err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, flow)
+}
+
+// RemovePasskey handles POST /users/settings/passkey/remove - unlinks a passkey
+func (h *AuthHandler) RemovePasskey(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	sessionToken := middleware.ExtractSessionToken(r)
+	if sessionToken == "" {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidatePasskeyRemoveInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	flow, err := h.kratos.UpdateSettingsFlow(r.Context(), flowID, auth.BuildPasskeyRemoveBody(input.ID), sessionToken)
+	if err != nil {
+		settingsError(err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, flow)
+}
@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
+)
+
+func passkeyNode(name, value string) ory.UiNode {
+	return ory.UiNode{
+		Group: "passkey",
+		Attributes: ory.UiNodeAttributes{
+			UiNodeInputAttributes: &ory.UiNodeInputAttributes{Name: name, Value: value},
+		},
+	}
+}
+
+func TestAuthHandler_StartPasskeyRegistration(t *testing.T) {
+	tests := []struct {
+		name       string
+		flowID     string
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			flowID:     "flow123",
+			body:       `{"email": "test@example.com", "first_name": "John", "last_name": "Doe"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing flow ID",
+			body:       `{"email": "test@example.com", "first_name": "John", "last_name": "Doe"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing traits",
+			flowID:     "flow123",
+			body:       `{"email": "test@example.com"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "kratos rejects",
+			flowID:     "flow123",
+			body:       `{"email": "test@example.com", "first_name": "John", "last_name": "Doe"}`,
+			mockErr:    errors.New("passkey not enabled"),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockKratosService{
+				PreparePasskeyRegistrationFunc: func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.RegistrationFlow, error) {
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					if body.UpdateRegistrationFlowWithPasskeyMethod == nil {
+						t.Fatal("expected passkey method body")
+					}
+					if body.UpdateRegistrationFlowWithPasskeyMethod.PasskeyRegister != nil {
+						t.Error("expected no credential on the first step")
+					}
+					return &ory.RegistrationFlow{
+						Id: flowID,
+						Ui: ory.UiContainer{Nodes: []ory.UiNode{
+							passkeyNode("passkey_create_data", `{"credentialOptions":{"publicKey":{"challenge":"abc"}}}`),
+						}},
+					}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			url := "/auth/registration/passkey"
+			if tt.flowID != "" {
+				url += "?flow=" + tt.flowID
+			}
+
+			req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.StartPasskeyRegistration(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("StartPasskeyRegistration() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got response.PasskeyOptionsResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if got.FlowID != "flow123" {
+				t.Errorf("StartPasskeyRegistration() flow_id = %q", got.FlowID)
+			}
+			if !strings.Contains(string(got.Options), `"challenge":"abc"`) {
+				t.Errorf("StartPasskeyRegistration() options = %s", got.Options)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_SubmitPasskeyRegistration(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			body:       `{"email": "test@example.com", "first_name": "John", "last_name": "Doe", "credential": {"id": "cred"}}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "missing credential",
+			body:       `{"email": "test@example.com", "first_name": "John", "last_name": "Doe"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockKratosService{
+				UpdateRegistrationFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.SuccessfulNativeRegistration, error) {
+					if got := body.UpdateRegistrationFlowWithPasskeyMethod.GetPasskeyRegister(); got != `{"id": "cred"}` {
+						t.Errorf("SubmitPasskeyRegistration() credential = %q", got)
+					}
+					return &ory.SuccessfulNativeRegistration{}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/registration/passkey/verify?flow=flow123", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.SubmitPasskeyRegistration(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("SubmitPasskeyRegistration() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_CreatePasskeyLoginFlow(t *testing.T) {
+	tests := []struct {
+		name       string
+		nodes      []ory.UiNode
+		wantStatus int
+	}{
+		{
+			name:       "passkey enabled",
+			nodes:      []ory.UiNode{passkeyNode("passkey_challenge", `{"publicKey":{"challenge":"xyz"}}`)},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "passkey not enabled",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockKratosService{
+				CreateLoginFlowFunc: func(ctx context.Context) (*ory.LoginFlow, error) {
+					return &ory.LoginFlow{Id: "flow123", Ui: ory.UiContainer{Nodes: tt.nodes}}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			req := httptest.NewRequest(http.MethodGet, "/auth/login/passkey", nil)
+			w := httptest.NewRecorder()
+
+			handler.CreatePasskeyLoginFlow(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("CreatePasskeyLoginFlow() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusOK && !strings.Contains(w.Body.String(), `"challenge":"xyz"`) {
+				t.Errorf("CreatePasskeyLoginFlow() body = %s, want request options", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthHandler_SubmitPasskeyLogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			body:       `{"credential": {"id": "cred"}}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing credential",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "assertion rejected",
+			body:       `{"credential": {"id": "cred"}}`,
+			mockErr:    errors.New("invalid signature"),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockKratosService{
+				UpdateLoginFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error) {
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					if body.UpdateLoginFlowWithPasskeyMethod.GetMethod() != "passkey" {
+						t.Errorf("SubmitPasskeyLogin() method = %q", body.UpdateLoginFlowWithPasskeyMethod.GetMethod())
+					}
+					return &ory.SuccessfulNativeLogin{}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/login/passkey?flow=flow123", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.SubmitPasskeyLogin(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("SubmitPasskeyLogin() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_CreatePasskeySettings(t *testing.T) {
+	mock := &MockKratosService{
+		CreateSettingsFlowFunc: func(ctx context.Context, sessionToken string) (*ory.SettingsFlow, error) {
+			return &ory.SettingsFlow{
+				Id: "settings123",
+				Ui: ory.UiContainer{Nodes: []ory.UiNode{
+					passkeyNode("passkey_create_data", `{"credentialOptions":{}}`),
+					passkeyNode("passkey_remove", "cred-1"),
+					passkeyNode("passkey_remove", "cred-2"),
+				}},
+			}, nil
+		},
+	}
+
+	handler := NewAuthHandler(mock)
+
+	req := withTestSession(httptest.NewRequest(http.MethodGet, "/users/settings/passkey", nil))
+	w := httptest.NewRecorder()
+
+	handler.CreatePasskeySettings(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("CreatePasskeySettings() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var got response.PasskeyOptionsResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if strings.Join(got.CredentialIDs, ",") != "cred-1,cred-2" {
+		t.Errorf("CreatePasskeySettings() credential_ids = %v", got.CredentialIDs)
+	}
+	if len(got.Options) == 0 {
+		t.Error("CreatePasskeySettings() expected creation options")
+	}
+}
+
+func TestAuthHandler_LinkPasskey(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		wantStatus int
+	}{
+		{name: "success", wantStatus: http.StatusOK},
+		{name: "reauthentication required", mockErr: auth.ErrPrivilegedSessionRequired, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockKratosService{
+				UpdateSettingsFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error) {
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					if body.UpdateSettingsFlowWithPasskeyMethod.GetPasskeySettingsRegister() == "" {
+						t.Error("LinkPasskey() expected credential")
+					}
+					return &ory.SettingsFlow{Id: flowID}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			req := withTestSession(httptest.NewRequest(http.MethodPost, "/users/settings/passkey?flow=settings123", strings.NewReader(`{"credential": {"id": "cred"}}`)))
+			w := httptest.NewRecorder()
+
+			handler.LinkPasskey(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("LinkPasskey() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_RemovePasskey(t *testing.T) {
+	var gotID string
+	mock := &MockKratosService{
+		UpdateSettingsFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error) {
+			gotID = body.UpdateSettingsFlowWithPasskeyMethod.GetPasskeyRemove()
+			return &ory.SettingsFlow{Id: flowID}, nil
+		},
+	}
+
+	handler := NewAuthHandler(mock)
+
+	req := withTestSession(httptest.NewRequest(http.MethodPost, "/users/settings/passkey/remove?flow=settings123", strings.NewReader(`{"id": "cred-1"}`)))
+	w := httptest.NewRecorder()
+
+	handler.RemovePasskey(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("RemovePasskey() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if gotID != "cred-1" {
+		t.Errorf("RemovePasskey() id = %q, want %q", gotID, "cred-1")
+	}
+}
+
+func TestAuthHandler_StartPasskeyRegistration_IdentitySchema(t *testing.T) {
+	schemas := validation.NewIdentitySchemaLoader(func(ctx context.Context) ([]byte, error) {
+		return []byte(`{"type": "object", "properties": {"traits": {"type": "object",
+			"properties": {"email": {"type": "string", "format": "email"}, "phone": {"type": "string"}},
+			"required": ["email", "phone"], "additionalProperties": false}}}`), nil
+	}, 0)
+
+	var gotTraits map[string]interface{}
+	mock := &MockKratosService{
+		PreparePasskeyRegistrationFunc: func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.RegistrationFlow, error) {
+			gotTraits = body.UpdateRegistrationFlowWithPasskeyMethod.Traits
+			return &ory.RegistrationFlow{Id: flowID}, nil
+		},
+	}
+	handler := NewAuthHandler(mock).WithIdentitySchema(schemas)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/registration/passkey?flow=flow123",
+		strings.NewReader(`{"traits": {"email": "test@example.com", "phone": "+15555550100"}}`))
+	w := httptest.NewRecorder()
+	handler.StartPasskeyRegistration(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("StartPasskeyRegistration() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if gotTraits["phone"] != "+15555550100" {
+		t.Errorf("StartPasskeyRegistration() sent traits %v, want the phone trait", gotTraits)
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// SendLoginCode handles POST /auth/login/code - emails a one-time login code
+func (h *AuthHandler) SendLoginCode(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidateCodeLoginInput(r.Body, false)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	loginBody := auth.BuildCodeLoginBody(input.Email, "")
+
+	flow, err := h.kratos.SendLoginCode(r.Context(), flowID, loginBody)
+	if err != nil {
+		apperrors.NewBadRequestError("failed to send login code").WriteJSON(w)
+		return
+	}
+
+	response.Success(w, flow)
+}
+
+// SubmitLoginCode handles POST /auth/login/code/verify - exchanges the code for a session
+func (h *AuthHandler) SubmitLoginCode(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidateCodeLoginInput(r.Body, true)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	loginBody := auth.BuildCodeLoginBody(input.Email, input.Code)
+
+	result, err := h.kratos.UpdateLoginFlow(r.Context(), flowID, loginBody, "")
+	if err != nil {
+		kratosUpdateError(err, apperrors.NewUnauthorizedError("invalid or expired login code")).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, result)
+}
+
+// SendRegistrationCode handles POST /auth/registration/code - emails a one-time registration code
+func (h *AuthHandler) SendRegistrationCode(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validateCodeRegistration(r, h.schemas, false)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	regBody := auth.BuildCodeRegistrationBody(input.Traits, "")
+
+	flow, err := h.kratos.SendRegistrationCode(r.Context(), flowID, regBody)
+	if err != nil {
+		apperrors.NewBadRequestError("failed to send registration code").WriteJSON(w)
+		return
+	}
+
+	response.Success(w, flow)
+}
+
+// SubmitRegistrationCode handles POST /auth/registration/code/verify - completes
+// registration with the code; the traits must match the ones the code was sent for
+func (h *AuthHandler) SubmitRegistrationCode(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validateCodeRegistration(r, h.schemas, true)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	regBody := auth.BuildCodeRegistrationBody(input.Traits, input.Code)
+
+	result, err := h.kratos.UpdateRegistrationFlow(r.Context(), flowID, regBody)
+	if err != nil {
+		kratosFlowError(err, apperrors.NewBadRequestError("invalid or expired registration code")).WriteJSON(w)
+		return
+	}
+
+	response.Created(w, result)
+}
+
+// validateCodeRegistration validates a passwordless registration body against
+// the identity schema when one is configured, like validateRegistration
+func validateCodeRegistration(r *http.Request, schemas *validation.IdentitySchemaLoader, requireCode bool) (*validation.CodeRegistrationInput, *apperrors.AppError) {
+	if schemas == nil {
+		return validation.ValidateCodeRegistrationInput(r.Body, requireCode)
+	}
+
+	schema, err := schemas.Schema(r.Context())
+	if err != nil {
+		return nil, apperrors.NewServiceUnavailableError("identity schema", err)
+	}
+
+	return validation.ValidateSchemaCodeRegistrationInput(r.Body, schema, requireCode)
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
+)
+
+func TestAuthHandler_SendLoginCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		flowID     string
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			flowID:     "flow123",
+			body:       `{"email": "test@example.com"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing flow ID",
+			body:       `{"email": "test@example.com"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid email",
+			flowID:     "flow123",
+			body:       `{"email": "nope"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "kratos error",
+			flowID:     "flow123",
+			body:       `{"email": "test@example.com"}`,
+			mockErr:    errors.New("flow expired"),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotIdentifier string
+			var gotCode *string
+			mock := &MockKratosService{
+				SendLoginCodeFunc: func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody) (*ory.LoginFlow, error) {
+					gotIdentifier = body.UpdateLoginFlowWithCodeMethod.GetIdentifier()
+					gotCode = body.UpdateLoginFlowWithCodeMethod.Code
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					return &ory.LoginFlow{Id: flowID, State: "sent_email"}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			url := "/auth/login/code"
+			if tt.flowID != "" {
+				url += "?flow=" + tt.flowID
+			}
+
+			req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.SendLoginCode(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("SendLoginCode() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusOK && (gotIdentifier != "test@example.com" || gotCode != nil) {
+				t.Errorf("SendLoginCode() identifier = %q, code = %v", gotIdentifier, gotCode)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_SubmitLoginCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			body:       `{"email": "test@example.com", "code": "123456"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing code",
+			body:       `{"email": "test@example.com"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "wrong code",
+			body: `{"email": "test@example.com", "code": "000000"}`,
+			mockErr: &auth.FlowError{Status: http.StatusBadRequest, Messages: []auth.FlowMessage{
+				{ID: 4010008, Text: "The login code is invalid or has already been used. Please try again.", Type: "error"},
+			}},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "expired flow",
+			body:       `{"email": "test@example.com", "code": "123456"}`,
+			mockErr:    &auth.FlowError{Status: http.StatusGone, ID: "self_service_flow_expired"},
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:       "kratos unavailable",
+			body:       `{"email": "test@example.com", "code": "123456"}`,
+			mockErr:    errors.New("connection refused"),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotCode string
+			mock := &MockKratosService{
+				UpdateLoginFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error) {
+					gotCode = body.UpdateLoginFlowWithCodeMethod.GetCode()
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					token := "session-token"
+					return &ory.SuccessfulNativeLogin{SessionToken: &token}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+			req := httptest.NewRequest(http.MethodPost, "/auth/login/code/verify?flow=flow123", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.SubmitLoginCode(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("SubmitLoginCode() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusOK {
+				if gotCode != "123456" {
+					t.Errorf("SubmitLoginCode() sent code %q, want %q", gotCode, "123456")
+				}
+				if !strings.Contains(w.Body.String(), "session-token") {
+					t.Errorf("SubmitLoginCode() body = %s, want session token", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestAuthHandler_SendRegistrationCode(t *testing.T) {
+	var gotTraits map[string]interface{}
+	mock := &MockKratosService{
+		SendRegistrationCodeFunc: func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.RegistrationFlow, error) {
+			gotTraits = body.UpdateRegistrationFlowWithCodeMethod.Traits
+			return &ory.RegistrationFlow{Id: flowID, State: "sent_email"}, nil
+		},
+	}
+
+	handler := NewAuthHandler(mock)
+	req := httptest.NewRequest(http.MethodPost, "/auth/registration/code?flow=flow123",
+		strings.NewReader(`{"email": "test@example.com", "first_name": "Jane", "last_name": "Doe"}`))
+	w := httptest.NewRecorder()
+
+	handler.SendRegistrationCode(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("SendRegistrationCode() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if gotTraits["email"] != "test@example.com" {
+		t.Errorf("SendRegistrationCode() traits = %v", gotTraits)
+	}
+}
+
+func TestAuthHandler_SubmitRegistrationCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			body:       `{"email": "test@example.com", "first_name": "Jane", "last_name": "Doe", "code": "123456"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "missing code",
+			body:       `{"email": "test@example.com", "first_name": "Jane", "last_name": "Doe"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "kratos rejects code",
+			body:       `{"email": "test@example.com", "first_name": "Jane", "last_name": "Doe", "code": "000000"}`,
+			mockErr:    errors.New("invalid code"),
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockKratosService{
+				UpdateRegistrationFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.SuccessfulNativeRegistration, error) {
+					if body.UpdateRegistrationFlowWithCodeMethod.GetCode() != "123456" {
+						return nil, errors.New("invalid code")
+					}
+					return &ory.SuccessfulNativeRegistration{}, tt.mockErr
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+			req := httptest.NewRequest(http.MethodPost, "/auth/registration/code/verify?flow=flow123", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			handler.SubmitRegistrationCode(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("SubmitRegistrationCode() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_SubmitRegistrationCode_IdentitySchema(t *testing.T) {
+	schemas := validation.NewIdentitySchemaLoader(func(ctx context.Context) ([]byte, error) {
+		return []byte(`{"type": "object", "properties": {"traits": {"type": "object",
+			"properties": {"email": {"type": "string", "format": "email"}, "phone": {"type": "string"}},
+			"required": ["email", "phone"], "additionalProperties": false}}}`), nil
+	}, 0)
+
+	var gotTraits map[string]interface{}
+	mock := &MockKratosService{
+		UpdateRegistrationFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateRegistrationFlowBody) (*ory.SuccessfulNativeRegistration, error) {
+			gotTraits = body.UpdateRegistrationFlowWithCodeMethod.Traits
+			return &ory.SuccessfulNativeRegistration{}, nil
+		},
+	}
+	handler := NewAuthHandler(mock).WithIdentitySchema(schemas)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/registration/code/verify?flow=flow123",
+		strings.NewReader(`{"code": "123456", "traits": {"email": "test@example.com", "phone": "+15555550100"}}`))
+	w := httptest.NewRecorder()
+	handler.SubmitRegistrationCode(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("SubmitRegistrationCode() status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+	if gotTraits["phone"] != "+15555550100" {
+		t.Errorf("SubmitRegistrationCode() sent traits %v, want the phone trait", gotTraits)
+	}
+
+	// The built-in fields do not satisfy a schema requiring phone
+	req = httptest.NewRequest(http.MethodPost, "/auth/registration/code?flow=flow123",
+		strings.NewReader(`{"email": "test@example.com", "first_name": "Test", "last_name": "User"}`))
+	w = httptest.NewRecorder()
+	handler.SendRegistrationCode(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("SendRegistrationCode() without the schema's phone status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
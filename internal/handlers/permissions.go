@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/authz"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// PermissionHandler handles relationship permission requests
+type PermissionHandler struct {
+	checker authz.PermissionChecker
+}
+
+// NewPermissionHandler creates a new permission handler
+func NewPermissionHandler(checker authz.PermissionChecker) *PermissionHandler {
+	return &PermissionHandler{checker: checker}
+}
+
+// Check handles GET /permissions/check - reports whether the caller has
+// relation on namespace:object, so clients can hide actions they cannot take
+func (h *PermissionHandler) Check(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	input, validationErr := validation.ValidatePermissionCheck(query.Get("namespace"), query.Get("object"), query.Get("relation"))
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	identityID, ok := sessionIdentityID(w, r)
+	if !ok {
+		return
+	}
+
+	tuple := authz.NewRelationTuple(input.Namespace, input.Object, input.Relation, identityID)
+	allowed, err := h.checker.Check(r.Context(), tuple)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Keto", err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, response.PermissionCheckResponse{Allowed: allowed})
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/authz"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+)
+
+func TestPermissionHandler_Check(t *testing.T) {
+	store := authz.NewMemoryStore(authz.NewRelationTuple("conversations", "conv-1", "viewer", "identity-123"))
+
+	tests := []struct {
+		name        string
+		query       string
+		wantStatus  int
+		wantAllowed bool
+	}{
+		{name: "allowed", query: "namespace=conversations&object=conv-1&relation=viewer", wantStatus: http.StatusOK, wantAllowed: true},
+		{name: "denied", query: "namespace=conversations&object=conv-1&relation=owner", wantStatus: http.StatusOK},
+		{name: "missing relation", query: "namespace=conversations&object=conv-1", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewPermissionHandler(store)
+
+			req := withTestSession(httptest.NewRequest(http.MethodGet, "/permissions/check?"+tt.query, nil))
+			w := httptest.NewRecorder()
+
+			handler.Check(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Check() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got response.PermissionCheckResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if got.Allowed != tt.wantAllowed {
+				t.Errorf("Check() allowed = %v, want %v", got.Allowed, tt.wantAllowed)
+			}
+		})
+	}
+}
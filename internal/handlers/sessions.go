@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// ListSessions handles GET /users/sessions - lists the caller's active sessions,
+// current one first
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	sessionToken := middleware.ExtractSessionToken(r)
+	current, ok := middleware.GetSessionFromContext(r.Context())
+	if sessionToken == "" || !ok {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	// Kratos only returns the other sessions here
+	others, err := h.kratos.ListMySessions(r.Context(), sessionToken)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, response.SessionListResponse{Sessions: sessionList(current, others)})
+}
+
+// RevokeSession handles DELETE /users/sessions/{id} - signs out another device
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	sessionToken := middleware.ExtractSessionToken(r)
+	current, ok := middleware.GetSessionFromContext(r.Context())
+	if sessionToken == "" || !ok {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	sessionID := strings.TrimSpace(chi.URLParam(r, "id"))
+	if sessionID == "" {
+		apperrors.NewValidationError("session id is required", "").WriteJSON(w)
+		return
+	}
+
+	if sessionID == current.Id {
+		apperrors.NewBadRequestError("use logout to end the current session").WriteJSON(w)
+		return
+	}
+
+	if err := h.kratos.RevokeMySession(r.Context(), sessionToken, sessionID); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			apperrors.NewNotFoundError("session").WriteJSON(w)
+			return
+		}
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// RevokeOtherSessions handles DELETE /users/sessions - signs out every other device
+func (h *AuthHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	sessionToken := middleware.ExtractSessionToken(r)
+	if sessionToken == "" {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	count, err := h.kratos.RevokeMyOtherSessions(r.Context(), sessionToken)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, response.RevokedSessionsResponse{Revoked: count})
+}
+
+// sessionList normalizes the current session and the caller's others, current first
+func sessionList(current *ory.Session, others []ory.Session) []response.SessionResponse {
+	sessions := make([]response.SessionResponse, 0, len(others)+1)
+	sessions = append(sessions, sessionResponse(current, true))
+	for i := range others {
+		if others[i].Id == current.Id {
+			continue
+		}
+		sessions = append(sessions, sessionResponse(&others[i], false))
+	}
+	return sessions
+}
+
+// sessionResponse normalizes a Kratos session for clients
+func sessionResponse(session *ory.Session, current bool) response.SessionResponse {
+	resp := response.SessionResponse{
+		ID:      session.Id,
+		Current: current,
+		Active:  session.GetActive(),
+		Methods: make([]string, 0, len(session.AuthenticationMethods)),
+		Devices: make([]response.SessionDeviceResponse, 0, len(session.Devices)),
+	}
+
+	if session.AuthenticatorAssuranceLevel != nil {
+		resp.AAL = string(*session.AuthenticatorAssuranceLevel)
+	}
+	if session.AuthenticatedAt != nil {
+		resp.AuthenticatedAt = session.AuthenticatedAt.String()
+	}
+	if session.ExpiresAt != nil {
+		resp.ExpiresAt = session.ExpiresAt.String()
+	}
+
+	for _, method := range session.AuthenticationMethods {
+		if m := method.GetMethod(); m != "" {
+			resp.Methods = append(resp.Methods, m)
+		}
+	}
+
+	for _, device := range session.Devices {
+		resp.Devices = append(resp.Devices, response.SessionDeviceResponse{
+			IPAddress: device.GetIpAddress(),
+			UserAgent: device.GetUserAgent(),
+			Location:  device.GetLocation(),
+		})
+	}
+
+	return resp
+}
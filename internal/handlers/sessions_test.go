@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+)
+
+func withURLParam(req *http.Request, key, value string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestAuthHandler_ListSessions(t *testing.T) {
+	active := true
+	aal := ory.AUTHENTICATORASSURANCELEVEL_AAL1
+	ip := "203.0.113.7"
+	agent := "Mozilla/5.0"
+	expires := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		mockErr    error
+		wantStatus int
+	}{
+		{name: "success", wantStatus: http.StatusOK},
+		{name: "kratos error", mockErr: errors.New("kratos unavailable"), wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotToken string
+			mock := &MockKratosService{
+				ListMySessionsFunc: func(ctx context.Context, sessionToken string) ([]ory.Session, error) {
+					gotToken = sessionToken
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					return []ory.Session{{
+						Id:                          "session-456",
+						Active:                      &active,
+						AuthenticatorAssuranceLevel: &aal,
+						ExpiresAt:                   &expires,
+						AuthenticationMethods:       []ory.SessionAuthenticationMethod{{Method: ory.PtrString("password")}},
+						Devices:                     []ory.SessionDevice{{Id: "device-1", IpAddress: &ip, UserAgent: &agent}},
+					}}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			req := withTestSession(httptest.NewRequest(http.MethodGet, "/users/sessions", nil))
+			w := httptest.NewRecorder()
+
+			handler.ListSessions(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ListSessions() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if gotToken != "token123" {
+				t.Errorf("ListSessions() session token = %q, want %q", gotToken, "token123")
+			}
+
+			var got response.SessionListResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if len(got.Sessions) != 2 {
+				t.Fatalf("ListSessions() returned %d sessions, want 2", len(got.Sessions))
+			}
+
+			if got.Sessions[0].ID != "session-123" || !got.Sessions[0].Current {
+				t.Errorf("ListSessions() first session = %+v, want current session", got.Sessions[0])
+			}
+
+			other := got.Sessions[1]
+			if other.Current || !other.Active || other.AAL != "aal1" {
+				t.Errorf("ListSessions() other session = %+v", other)
+			}
+			if len(other.Methods) != 1 || other.Methods[0] != "password" {
+				t.Errorf("ListSessions() methods = %v, want [password]", other.Methods)
+			}
+			if len(other.Devices) != 1 || other.Devices[0].IPAddress != ip || other.Devices[0].UserAgent != agent {
+				t.Errorf("ListSessions() devices = %+v", other.Devices)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_RevokeSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		sessionID  string
+		mockErr    error
+		wantStatus int
+	}{
+		{name: "success", sessionID: "session-456", wantStatus: http.StatusNoContent},
+		{name: "current session", sessionID: "session-123", wantStatus: http.StatusBadRequest},
+		{
+			name:       "unknown session",
+			sessionID:  "session-789",
+			mockErr:    fmt.Errorf("failed to revoke session: %w", auth.ErrSessionNotFound),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "kratos error",
+			sessionID:  "session-456",
+			mockErr:    errors.New("kratos unavailable"),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotID string
+			mock := &MockKratosService{
+				RevokeMySessionFunc: func(ctx context.Context, sessionToken, sessionID string) error {
+					gotID = sessionID
+					return tt.mockErr
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			req := withTestSession(httptest.NewRequest(http.MethodDelete, "/users/sessions/"+tt.sessionID, nil))
+			req = withURLParam(req, "id", tt.sessionID)
+			w := httptest.NewRecorder()
+
+			handler.RevokeSession(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("RevokeSession() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusNoContent && gotID != tt.sessionID {
+				t.Errorf("RevokeSession() id = %q, want %q", gotID, tt.sessionID)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_RevokeOtherSessions(t *testing.T) {
+	mock := &MockKratosService{
+		RevokeMyOtherSessionsFunc: func(ctx context.Context, sessionToken string) (int64, error) {
+			return 3, nil
+		},
+	}
+
+	handler := NewAuthHandler(mock)
+
+	req := withTestSession(httptest.NewRequest(http.MethodDelete, "/users/sessions", nil))
+	w := httptest.NewRecorder()
+
+	handler.RevokeOtherSessions(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("RevokeOtherSessions() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var got response.RevokedSessionsResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if got.Revoked != 3 {
+		t.Errorf("RevokeOtherSessions() revoked = %d, want 3", got.Revoked)
+	}
+}
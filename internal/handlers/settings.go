@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/validation"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// CreateSettingsFlow handles GET /users/settings
+func (h *AuthHandler) CreateSettingsFlow(w http.ResponseWriter, r *http.Request) {
+	sessionToken, ok := nativeSessionToken(w, r)
+	if !ok {
+		return
+	}
+
+	flow, err := h.kratos.CreateSettingsFlow(r.Context(), sessionToken)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	resp := response.SettingsFlowResponse{
+		FlowID: flow.Id,
+		Fields: auth.ExtractSettingsFormFields(flow),
+	}
+
+	if state, ok := flow.State.(string); ok {
+		resp.State = state
+	}
+
+	if !flow.ExpiresAt.IsZero() {
+		resp.ExpiresAt = flow.ExpiresAt.String()
+	}
+
+	response.Success(w, resp)
+}
+
+// UpdatePassword handles POST /users/settings/password - changes the password
+func (h *AuthHandler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	sessionToken := middleware.ExtractSessionToken(r)
+	if sessionToken == "" {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidatePasswordSettingsInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	settingsBody := auth.BuildPasswordSettingsBody(input.Password)
+
+	flow, err := h.kratos.UpdateSettingsFlow(r.Context(), flowID, settingsBody, sessionToken)
+	if err != nil {
+		settingsError(err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, flow)
+}
+
+// UpdateProfile handles POST /users/settings/profile - changes name traits
+func (h *AuthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	sessionToken := middleware.ExtractSessionToken(r)
+	session, ok := middleware.GetSessionFromContext(r.Context())
+	if sessionToken == "" || !ok || session.Identity == nil {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidateProfileSettingsInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	// Kratos replaces traits wholesale, so keep the ones we are not changing
+	traits := auth.BuildNameTraits(identityTraits(session.Identity), input.FirstName, input.LastName)
+	settingsBody := auth.BuildProfileSettingsBody(traits)
+
+	flow, err := h.kratos.UpdateSettingsFlow(r.Context(), flowID, settingsBody, sessionToken)
+	if err != nil {
+		settingsError(err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, flow)
+}
+
+// UpdateEmail handles POST /users/settings/email - changes the email trait and
+// sends a verification code to the new address. The new address stays
+// unverified, and is reported as such, until the code is submitted to
+// /auth/verification/code.
+func (h *AuthHandler) UpdateEmail(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	sessionToken := middleware.ExtractSessionToken(r)
+	session, ok := middleware.GetSessionFromContext(r.Context())
+	if sessionToken == "" || !ok || session.Identity == nil {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidateEmailSettingsInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	if current, _ := auth.IdentityEmail(session.Identity); strings.EqualFold(current, input.Email) {
+		apperrors.NewBadRequestError("email is unchanged").WriteJSON(w)
+		return
+	}
+
+	// Kratos replaces traits wholesale, so keep the ones we are not changing
+	traits := auth.BuildEmailTraits(identityTraits(session.Identity), input.Email)
+	settingsBody := auth.BuildProfileSettingsBody(traits)
+
+	flow, err := h.kratos.UpdateSettingsFlow(r.Context(), flowID, settingsBody, sessionToken)
+	if err != nil {
+		settingsError(err).WriteJSON(w)
+		return
+	}
+
+	resp := response.EmailChangeResponse{Email: input.Email}
+	if email, verified := auth.IdentityEmail(&flow.Identity); email != "" {
+		resp.Email, resp.EmailVerified = email, verified
+	}
+
+	// The new address replaces the old trait before it is verified, so it
+	// works as a login identifier at once. Kratos normally sends it a
+	// verification message itself; only send one when it has not. The change
+	// has been saved, so a failure to send the code is logged and reported
+	// rather than returned as an error; the client can resend it through
+	// /users/verification.
+	if !resp.EmailVerified {
+		if verificationFlowID, pending := auth.PendingVerification(flow, resp.Email); pending {
+			resp.VerificationFlowID = verificationFlowID
+			resp.VerificationSent = true
+		} else if verificationFlow, err := h.kratos.CreateVerificationFlow(r.Context()); err != nil {
+			log.Printf("settings: failed to start verification for changed email of %s: %v", session.Identity.Id, err)
+		} else {
+			verificationBody := auth.BuildCodeVerificationBody(resp.Email)
+			if _, err := h.kratos.UpdateVerificationFlow(r.Context(), verificationFlow.Id, verificationBody); err != nil {
+				log.Printf("settings: failed to send verification for changed email of %s: %v", session.Identity.Id, err)
+			} else {
+				resp.VerificationFlowID = verificationFlow.Id
+				resp.VerificationSent = true
+			}
+		}
+	}
+
+	response.Success(w, resp)
+}
+
+// CreateReauthFlow handles GET /users/settings/reauth - starts a refresh login
+// flow for sessions too old to change privileged settings
+func (h *AuthHandler) CreateReauthFlow(w http.ResponseWriter, r *http.Request) {
+	sessionToken, ok := nativeSessionToken(w, r)
+	if !ok {
+		return
+	}
+
+	flow, err := h.kratos.CreateRefreshLoginFlow(r.Context(), sessionToken)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, flow)
+}
+
+// SubmitReauth handles POST /users/settings/reauth/flow - re-authenticates the
+// current session with its password
+func (h *AuthHandler) SubmitReauth(w http.ResponseWriter, r *http.Request) {
+	flowID := r.URL.Query().Get("flow")
+	if err := validation.ValidateFlowID(flowID); err != nil {
+		err.WriteJSON(w)
+		return
+	}
+
+	sessionToken := middleware.ExtractSessionToken(r)
+	session, ok := middleware.GetSessionFromContext(r.Context())
+	if sessionToken == "" || !ok || session.Identity == nil {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return
+	}
+
+	input, validationErr := validation.ValidateReauthInput(r.Body)
+	if validationErr != nil {
+		validationErr.WriteJSON(w)
+		return
+	}
+
+	email, _ := identityTraits(session.Identity)["email"].(string)
+	if email == "" {
+		apperrors.NewBadRequestError("session identity has no email").WriteJSON(w)
+		return
+	}
+
+	throttleKey := sessionThrottleKey(r)
+	if loginThrottled(w, r, h.throttle, throttleKey) {
+		return
+	}
+
+	loginBody := auth.BuildPasswordLoginBody(email, input.Password)
+
+	result, err := h.kratos.UpdateLoginFlow(r.Context(), flowID, loginBody, sessionToken)
+	recordLoginAttempt(r, h.throttle, throttleKey, err)
+	if err != nil {
+		apperrors.NewUnauthorizedError("invalid credentials").WriteJSON(w)
+		return
+	}
+
+	response.Success(w, result)
+}
+
+// reauthRequiredMessage tells clients how to refresh a session that is too
+// old for a privileged action
+const reauthRequiredMessage = "recent login required: re-authenticate via /users/settings/reauth and retry"
+
+// settingsError maps a settings flow failure to an API error. Rejections
+// Kratos explained go through kratosFlowError; anything else, including
+// Kratos being unreachable or failing, is SERVICE_UNAVAILABLE.
+func settingsError(err error) *apperrors.AppError {
+	if errors.Is(err, auth.ErrPrivilegedSessionRequired) {
+		return apperrors.NewReauthenticationRequiredError(reauthRequiredMessage)
+	}
+
+	if kratosUnavailable(err) {
+		return apperrors.NewServiceUnavailableError("Kratos", err)
+	}
+	var flowErr *auth.FlowError
+	errors.As(err, &flowErr)
+	switch flowErr.Status {
+	case http.StatusUnauthorized:
+		return apperrors.NewUnauthorizedError("session is no longer valid")
+	case http.StatusNotFound:
+		return apperrors.NewNotFoundError("settings flow")
+	}
+	return kratosFlowError(err, apperrors.NewValidationError("settings update rejected", ""))
+}
+
+// nativeSessionToken returns the session token for starting a native Kratos
+// flow. Kratos starts those only for session tokens, so a cookie session is
+// a 400; it writes the error and returns false when there is no usable token.
+func nativeSessionToken(w http.ResponseWriter, r *http.Request) (string, bool) {
+	sessionToken := middleware.ExtractSessionToken(r)
+	if sessionToken == "" {
+		apperrors.NewUnauthorizedError("no session token provided").WriteJSON(w)
+		return "", false
+	}
+	if auth.IsSessionCookie(sessionToken) {
+		apperrors.NewBadRequestError("this endpoint requires a session token; cookie sessions are not supported").WriteJSON(w)
+		return "", false
+	}
+	return sessionToken, true
+}
+
+// verificationStatus summarises an identity's email and verifiable addresses
+func verificationStatus(identity *ory.Identity) response.VerificationStatusResponse {
+	resp := response.VerificationStatusResponse{
+		Addresses: make([]response.VerifiableAddressResponse, 0),
+	}
+	if identity == nil {
+		return resp
+	}
+
+	resp.Email, resp.EmailVerified = auth.IdentityEmail(identity)
+
+	for _, address := range identity.VerifiableAddresses {
+		addressResp := response.VerifiableAddressResponse{
+			Value:    address.Value,
+			Via:      address.Via,
+			Status:   address.Status,
+			Verified: address.Verified,
+		}
+		if address.VerifiedAt != nil {
+			addressResp.VerifiedAt = address.VerifiedAt.String()
+		}
+		resp.Addresses = append(resp.Addresses, addressResp)
+	}
+
+	return resp
+}
+
+func identityTraits(identity *ory.Identity) map[string]interface{} {
+	traits, _ := identity.Traits.(map[string]interface{})
+	return traits
+}
@@ -0,0 +1,580 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+)
+
+func withTestSession(req *http.Request) *http.Request {
+	req.Header.Set("Authorization", "Bearer token123")
+	session := &ory.Session{
+		Id: "session-123",
+		Identity: &ory.Identity{
+			Id: "identity-123",
+			Traits: map[string]interface{}{
+				"email": "test@example.com",
+				"name":  map[string]interface{}{"first": "Old", "last": "Name"},
+			},
+		},
+	}
+	return req.WithContext(context.WithValue(req.Context(), middleware.SessionContextKey, session))
+}
+
+func TestAuthHandler_CreateSettingsFlow(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      bool
+		mockFlow   *ory.SettingsFlow
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			token:      true,
+			mockFlow:   &ory.SettingsFlow{Id: "settings123", State: "show_form"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no session token",
+			token:      false,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "kratos error",
+			token:      true,
+			mockErr:    errors.New("kratos unavailable"),
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotToken string
+			mock := &MockKratosService{
+				CreateSettingsFlowFunc: func(ctx context.Context, sessionToken string) (*ory.SettingsFlow, error) {
+					gotToken = sessionToken
+					return tt.mockFlow, tt.mockErr
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+			req := httptest.NewRequest(http.MethodGet, "/users/settings", nil)
+			if tt.token {
+				req = withTestSession(req)
+			}
+			w := httptest.NewRecorder()
+
+			handler.CreateSettingsFlow(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("CreateSettingsFlow() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusOK {
+				if gotToken != "token123" {
+					t.Errorf("CreateSettingsFlow() session token = %q, want %q", gotToken, "token123")
+				}
+				if !strings.Contains(w.Body.String(), `"flow_id":"settings123"`) {
+					t.Errorf("CreateSettingsFlow() body = %s, want flow_id", w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+// newRecordingKratos returns a client whose Kratos answers every request with
+// flow and stores the request's headers in got
+func newRecordingKratos(t *testing.T, flow interface{}, got *http.Header) *auth.KratosClient {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(flow)
+	}))
+	t.Cleanup(server.Close)
+
+	return auth.NewKratosClient(server.URL, server.URL)
+}
+
+func TestAuthHandler_CookieSessionSettings(t *testing.T) {
+	now := time.Now()
+	identity := ory.NewIdentity("identity-123", "default", "", map[string]interface{}{})
+	flow := ory.NewSettingsFlow(now.Add(time.Hour), "settings123", *identity, now, "", "success", "api", *ory.NewUiContainer("", "POST", nil))
+
+	var got http.Header
+	handler := NewAuthHandler(newRecordingKratos(t, flow, &got))
+	cookie := &http.Cookie{Name: auth.SessionCookieName, Value: "abc"}
+
+	// Native settings flows cannot be started with a cookie
+	req := httptest.NewRequest(http.MethodGet, "/users/settings", nil)
+	req.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	handler.CreateSettingsFlow(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("CreateSettingsFlow() status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if got != nil {
+		t.Errorf("CreateSettingsFlow() reached Kratos with %v", got)
+	}
+
+	// Submitting a flow sends the cookie as a cookie, not as a session token
+	req = httptest.NewRequest(http.MethodPost, "/users/settings/password?flow=settings123", strings.NewReader(`{"password": "newpassword123"}`))
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	handler.UpdatePassword(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("UpdatePassword() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if got.Get("Cookie") != "ory_kratos_session=abc" || got.Get("X-Session-Token") != "" {
+		t.Errorf("Kratos got Cookie = %q, X-Session-Token = %q, want the cookie only", got.Get("Cookie"), got.Get("X-Session-Token"))
+	}
+}
+
+func TestAuthHandler_UpdatePassword(t *testing.T) {
+	tests := []struct {
+		name       string
+		flowID     string
+		body       string
+		mockErr    error
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "success",
+			flowID:     "settings123",
+			body:       `{"password": "newpassword123"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing flow ID",
+			body:       `{"password": "newpassword123"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "short password",
+			flowID:     "settings123",
+			body:       `{"password": "short"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "privileged session required",
+			flowID:     "settings123",
+			body:       `{"password": "newpassword123"}`,
+			mockErr:    fmt.Errorf("failed to update settings flow: %w", auth.ErrPrivilegedSessionRequired),
+			wantStatus: http.StatusForbidden,
+			wantCode:   "REAUTHENTICATION_REQUIRED",
+		},
+		{
+			name:   "kratos rejects password",
+			flowID: "settings123",
+			body:   `{"password": "newpassword123"}`,
+			mockErr: &auth.FlowError{Status: http.StatusBadRequest, Messages: []auth.FlowMessage{
+				{Field: "password", Text: "The password is too similar to the user identifier."},
+			}},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "VALIDATION_ERROR",
+		},
+		{
+			name:       "flow expired",
+			flowID:     "settings123",
+			body:       `{"password": "newpassword123"}`,
+			mockErr:    &auth.FlowError{Status: http.StatusGone, ID: "self_service_flow_expired"},
+			wantStatus: http.StatusGone,
+			wantCode:   "FLOW_EXPIRED",
+		},
+		{
+			name:       "unknown flow",
+			flowID:     "settings123",
+			body:       `{"password": "newpassword123"}`,
+			mockErr:    &auth.FlowError{Status: http.StatusNotFound, ID: "not_found"},
+			wantStatus: http.StatusNotFound,
+			wantCode:   "NOT_FOUND",
+		},
+		{
+			name:       "kratos unavailable",
+			flowID:     "settings123",
+			body:       `{"password": "newpassword123"}`,
+			mockErr:    errors.New("dial tcp: connection refused"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "SERVICE_UNAVAILABLE",
+		},
+		{
+			name:       "kratos server error",
+			flowID:     "settings123",
+			body:       `{"password": "newpassword123"}`,
+			mockErr:    &auth.FlowError{Status: http.StatusInternalServerError, ID: "internal_server_error"},
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   "SERVICE_UNAVAILABLE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotBody ory.UpdateSettingsFlowBody
+			mock := &MockKratosService{
+				UpdateSettingsFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error) {
+					gotBody = body
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					return &ory.SettingsFlow{Id: flowID}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+
+			url := "/users/settings/password"
+			if tt.flowID != "" {
+				url += "?flow=" + tt.flowID
+			}
+
+			req := withTestSession(httptest.NewRequest(http.MethodPost, url, strings.NewReader(tt.body)))
+			w := httptest.NewRecorder()
+
+			handler.UpdatePassword(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("UpdatePassword() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantCode != "" && !strings.Contains(w.Body.String(), tt.wantCode) {
+				t.Errorf("UpdatePassword() body = %s, want code %s", w.Body.String(), tt.wantCode)
+			}
+
+			if tt.wantStatus == http.StatusOK {
+				method := gotBody.UpdateSettingsFlowWithPasswordMethod
+				if method == nil || method.Password != "newpassword123" {
+					t.Errorf("UpdatePassword() sent unexpected settings body: %+v", gotBody)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthHandler_UpdateProfile(t *testing.T) {
+	var gotTraits map[string]interface{}
+	mock := &MockKratosService{
+		UpdateSettingsFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error) {
+			gotTraits = body.UpdateSettingsFlowWithProfileMethod.Traits
+			return &ory.SettingsFlow{Id: flowID}, nil
+		},
+	}
+
+	handler := NewAuthHandler(mock)
+	req := withTestSession(httptest.NewRequest(http.MethodPost, "/users/settings/profile?flow=settings123",
+		strings.NewReader(`{"first_name": "Jane", "last_name": "Doe"}`)))
+	w := httptest.NewRecorder()
+
+	handler.UpdateProfile(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("UpdateProfile() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if gotTraits["email"] != "test@example.com" {
+		t.Errorf("UpdateProfile() email trait = %v, want it preserved", gotTraits["email"])
+	}
+
+	data, _ := json.Marshal(gotTraits["name"])
+	if string(data) != `{"first":"Jane","last":"Doe"}` {
+		t.Errorf("UpdateProfile() name trait = %s", data)
+	}
+}
+
+func TestAuthHandler_UpdateProfile_Validation(t *testing.T) {
+	handler := NewAuthHandler(&MockKratosService{})
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+	}{
+		{
+			name: "missing last name",
+			req: withTestSession(httptest.NewRequest(http.MethodPost, "/users/settings/profile?flow=settings123",
+				strings.NewReader(`{"first_name": "Jane"}`))),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "no session",
+			req: httptest.NewRequest(http.MethodPost, "/users/settings/profile?flow=settings123",
+				strings.NewReader(`{"first_name": "Jane", "last_name": "Doe"}`)),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			handler.UpdateProfile(w, tt.req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("UpdateProfile() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_UpdateEmail(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		settingsErr      error
+		addressStatus    string
+		continueWith     []ory.ContinueWith
+		verificationErr  error
+		wantStatus       int
+		wantVerification bool
+		wantFlowID       string
+		wantSentByUs     bool
+	}{
+		{
+			name:             "success",
+			body:             `{"email": "new@example.com"}`,
+			wantStatus:       http.StatusOK,
+			wantVerification: true,
+			wantFlowID:       "verification-123",
+			wantSentByUs:     true,
+		},
+		{
+			name: "kratos started verification",
+			body: `{"email": "new@example.com"}`,
+			continueWith: []ory.ContinueWith{{ContinueWithVerificationUi: &ory.ContinueWithVerificationUi{
+				Action: "show_verification_ui",
+				Flow:   ory.ContinueWithVerificationUiFlow{Id: "kratos-verification", VerifiableAddress: "new@example.com"},
+			}}},
+			wantStatus:       http.StatusOK,
+			wantVerification: true,
+			wantFlowID:       "kratos-verification",
+		},
+		{
+			name:             "kratos already sent verification",
+			body:             `{"email": "new@example.com"}`,
+			addressStatus:    "sent",
+			wantStatus:       http.StatusOK,
+			wantVerification: true,
+		},
+		{
+			name:            "verification email fails",
+			body:            `{"email": "new@example.com"}`,
+			verificationErr: errors.New("courier unavailable"),
+			wantStatus:      http.StatusOK,
+			wantSentByUs:    true,
+		},
+		{
+			name:       "unchanged email",
+			body:       `{"email": "TEST@example.com"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid email",
+			body:       `{"email": "not-an-email"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "stale session",
+			body:        `{"email": "new@example.com"}`,
+			settingsErr: auth.ErrPrivilegedSessionRequired,
+			wantStatus:  http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotTraits map[string]interface{}
+			var gotVerificationEmail string
+			mock := &MockKratosService{
+				UpdateSettingsFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateSettingsFlowBody, sessionToken string) (*ory.SettingsFlow, error) {
+					if tt.settingsErr != nil {
+						return nil, tt.settingsErr
+					}
+					gotTraits = body.UpdateSettingsFlowWithProfileMethod.Traits
+					status := tt.addressStatus
+					if status == "" {
+						status = "pending"
+					}
+					return &ory.SettingsFlow{
+						Id:           flowID,
+						ContinueWith: tt.continueWith,
+						Identity: ory.Identity{
+							Id:     "identity-123",
+							Traits: gotTraits,
+							VerifiableAddresses: []ory.VerifiableIdentityAddress{
+								{Value: "new@example.com", Via: "email", Status: status},
+							},
+						},
+					}, nil
+				},
+				CreateVerificationFlowFunc: func(ctx context.Context) (*ory.VerificationFlow, error) {
+					return &ory.VerificationFlow{Id: "verification-123"}, nil
+				},
+				UpdateVerificationFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateVerificationFlowBody) (*ory.VerificationFlow, error) {
+					gotVerificationEmail = body.UpdateVerificationFlowWithCodeMethod.GetEmail()
+					return &ory.VerificationFlow{Id: flowID}, tt.verificationErr
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+			req := withTestSession(httptest.NewRequest(http.MethodPost, "/users/settings/email?flow=settings123",
+				strings.NewReader(tt.body)))
+			w := httptest.NewRecorder()
+
+			handler.UpdateEmail(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("UpdateEmail() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if gotTraits["email"] != "new@example.com" || gotTraits["name"] == nil {
+				t.Errorf("UpdateEmail() traits = %v, want the new email with other traits kept", gotTraits)
+			}
+
+			if tt.wantSentByUs && gotVerificationEmail != "new@example.com" {
+				t.Errorf("UpdateEmail() verification sent to %q, want new@example.com", gotVerificationEmail)
+			}
+			if !tt.wantSentByUs && gotVerificationEmail != "" {
+				t.Errorf("UpdateEmail() sent a second verification to %q after Kratos sent one", gotVerificationEmail)
+			}
+
+			var got response.EmailChangeResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if got.Email != "new@example.com" || got.EmailVerified {
+				t.Errorf("UpdateEmail() = %+v, want the new email unverified", got)
+			}
+			if got.VerificationSent != tt.wantVerification || got.VerificationFlowID != tt.wantFlowID {
+				t.Errorf("UpdateEmail() verification = %+v, want sent = %v", got, tt.wantVerification)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_WhoAmI_Verification(t *testing.T) {
+	verifiedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock := &MockKratosService{
+		ValidateSessionFunc: func(ctx context.Context, token string) (*ory.Session, error) {
+			return &ory.Session{
+				Id: "session-123",
+				Identity: &ory.Identity{
+					Id:     "identity-123",
+					Traits: map[string]interface{}{"email": "new@example.com"},
+					VerifiableAddresses: []ory.VerifiableIdentityAddress{
+						{Value: "old@example.com", Via: "email", Status: "completed", Verified: true, VerifiedAt: &verifiedAt},
+						{Value: "new@example.com", Via: "email", Status: "sent"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	handler := NewAuthHandler(mock)
+	req := httptest.NewRequest(http.MethodGet, "/auth/whoami", nil)
+	req.Header.Set("X-Session-Token", "token123")
+	w := httptest.NewRecorder()
+
+	handler.WhoAmI(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("WhoAmI() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var got struct {
+		ID           string                              `json:"id"`
+		Verification response.VerificationStatusResponse `json:"verification"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if got.ID != "session-123" {
+		t.Errorf("WhoAmI() id = %q, want the session fields kept", got.ID)
+	}
+	if got.Verification.Email != "new@example.com" || got.Verification.EmailVerified {
+		t.Errorf("WhoAmI() verification = %+v, want new@example.com unverified", got.Verification)
+	}
+	if len(got.Verification.Addresses) != 2 || got.Verification.Addresses[0].VerifiedAt == "" {
+		t.Errorf("WhoAmI() addresses = %+v", got.Verification.Addresses)
+	}
+}
+
+func TestAuthHandler_SubmitReauth(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mockErr    error
+		wantStatus int
+	}{
+		{
+			name:       "success",
+			body:       `{"pass": "password123"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing password",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "wrong password",
+			body:       `{"pass": "wrongpassword"}`,
+			mockErr:    errors.New("invalid credentials"),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotToken, gotIdentifier string
+			mock := &MockKratosService{
+				UpdateLoginFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error) {
+					gotToken = sessionToken
+					gotIdentifier = body.UpdateLoginFlowWithPasswordMethod.Identifier
+					if tt.mockErr != nil {
+						return nil, tt.mockErr
+					}
+					return &ory.SuccessfulNativeLogin{}, nil
+				},
+			}
+
+			handler := NewAuthHandler(mock)
+			req := withTestSession(httptest.NewRequest(http.MethodPost, "/users/settings/reauth/flow?flow=login123",
+				strings.NewReader(tt.body)))
+			w := httptest.NewRecorder()
+
+			handler.SubmitReauth(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("SubmitReauth() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus == http.StatusOK {
+				if gotToken != "token123" || gotIdentifier != "test@example.com" {
+					t.Errorf("SubmitReauth() token = %q identifier = %q", gotToken, gotIdentifier)
+				}
+			}
+		})
+	}
+}
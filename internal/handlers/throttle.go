@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// loginThrottled writes TOO_MANY_ATTEMPTS and reports true when a login for
+// identifier from the request's client must wait first. Otherwise the attempt
+// is reserved, and recordLoginAttempt must be called with its outcome.
+func loginThrottled(w http.ResponseWriter, r *http.Request, throttle *auth.LoginThrottle, identifier string) bool {
+	if throttle == nil {
+		return false
+	}
+
+	wait := throttle.Check(identifier, middleware.ClientIP(r))
+	if wait <= 0 {
+		return false
+	}
+
+	apperrors.NewTooManyAttemptsError(
+		fmt.Sprintf("too many failed login attempts: retry in %s", wait.Round(time.Second)),
+		wait,
+	).WriteJSON(w)
+	return true
+}
+
+// recordLoginAttempt counts a login's outcome and ends its reservation. Only
+// credentials Kratos rejected are failures; expired flows and Kratos errors
+// are not.
+func recordLoginAttempt(r *http.Request, throttle *auth.LoginThrottle, identifier string, err error) {
+	if throttle == nil {
+		return
+	}
+
+	if err == nil {
+		throttle.RecordSuccess(identifier, middleware.ClientIP(r))
+		return
+	}
+
+	var flowErr *auth.FlowError
+	if errors.As(err, &flowErr) && flowErr.InvalidCredentials() {
+		throttle.RecordFailure(r.Context(), identifier, middleware.ClientIP(r))
+		return
+	}
+	throttle.Release(identifier, middleware.ClientIP(r))
+}
+
+// sessionThrottleKey is the throttle identifier for re-authentication and
+// second factor attempts, which guess a password or code for the caller's
+// own session: its identity when AuthMiddleware loaded it, otherwise the
+// session token, hashed so it is not kept in memory. The prefixes keep them
+// apart from login emails.
+func sessionThrottleKey(r *http.Request) string {
+	if session, ok := middleware.GetSessionFromContext(r.Context()); ok && session.Identity != nil {
+		return "identity:" + session.Identity.Id
+	}
+	sum := sha256.Sum256([]byte(middleware.ExtractSessionToken(r)))
+	return "session:" + hex.EncodeToString(sum[:])
+}
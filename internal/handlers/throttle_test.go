@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	ory "github.com/ory/client-go"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+func TestAuthHandler_SubmitLogin_Throttle(t *testing.T) {
+	invalidCredentials := &auth.FlowError{Status: http.StatusBadRequest, Messages: []auth.FlowMessage{
+		{ID: 4000006, Text: "The provided credentials are invalid.", Type: "error"},
+	}}
+
+	var mockErr error
+	calls := 0
+	mock := &MockKratosService{
+		UpdateLoginFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error) {
+			calls++
+			if mockErr != nil {
+				return nil, mockErr
+			}
+			return &ory.SuccessfulNativeLogin{}, nil
+		},
+	}
+
+	throttle := auth.NewLoginThrottle(auth.LoginThrottleConfig{
+		MaxFailures:     2,
+		IPMaxFailures:   10,
+		BackoffBase:     time.Minute,
+		BackoffMax:      time.Minute,
+		LockoutDuration: time.Hour,
+		FailureWindow:   time.Hour,
+		MaxEntries:      10,
+	}, nil)
+	handler := NewAuthHandler(mock).WithLoginThrottle(throttle)
+
+	submit := func(email string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login/flow?flow=flow-123",
+			strings.NewReader(`{"email": "`+email+`", "pass": "password123"}`))
+		req.RemoteAddr = "203.0.113.7:51234"
+		w := httptest.NewRecorder()
+		handler.SubmitLogin(w, req)
+		return w
+	}
+
+	// Kratos being unavailable is not a failed login
+	mockErr = errors.New("kratos unavailable")
+	if w := submit("user@example.com"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("SubmitLogin() status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	mockErr = invalidCredentials
+	if w := submit("user@example.com"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("SubmitLogin() status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	w := submit("user@example.com")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("SubmitLogin() during backoff status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if calls != 2 {
+		t.Errorf("SubmitLogin() during backoff reached Kratos: %d calls, want 2", calls)
+	}
+	if w.Header().Get("Retry-After") != "60" {
+		t.Errorf("SubmitLogin() Retry-After = %q, want %q", w.Header().Get("Retry-After"), "60")
+	}
+
+	var got struct {
+		Error apperrors.AppError `json:"error"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.Error.Code != apperrors.ErrCodeTooManyAttempts || got.Error.RetryAfter != 60 {
+		t.Errorf("SubmitLogin() error = %+v, want TOO_MANY_ATTEMPTS after 60s", got.Error)
+	}
+}
+
+func TestAuthHandler_SessionAttempts_Throttle(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		body        string
+		messageID   int64
+		withSession bool
+		submit      func(h *AuthHandler, w http.ResponseWriter, r *http.Request)
+	}{
+		{
+			name:        "re-authentication",
+			path:        "/users/settings/reauth/flow?flow=flow-123",
+			body:        `{"pass": "password123"}`,
+			messageID:   4000006,
+			withSession: true,
+			submit:      (*AuthHandler).SubmitReauth,
+		},
+		{
+			name:        "TOTP login",
+			path:        "/auth/login/aal2/flow?flow=flow-123",
+			body:        `{"code": "123456"}`,
+			messageID:   4000008,
+			withSession: true,
+			submit:      (*AuthHandler).SubmitTOTPLogin,
+		},
+		{
+			name:      "backup code login without a loaded session",
+			path:      "/auth/login/aal2/lookup?flow=flow-123",
+			body:      `{"code": "abcd1234"}`,
+			messageID: 4000016,
+			submit:    (*AuthHandler).SubmitLookupSecretLogin,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			mock := &MockKratosService{
+				UpdateLoginFlowFunc: func(ctx context.Context, flowID string, body ory.UpdateLoginFlowBody, sessionToken string) (*ory.SuccessfulNativeLogin, error) {
+					calls++
+					return nil, &auth.FlowError{Status: http.StatusBadRequest, Messages: []auth.FlowMessage{
+						{ID: tt.messageID, Text: "rejected", Type: "error"},
+					}}
+				},
+			}
+
+			throttle := auth.NewLoginThrottle(auth.LoginThrottleConfig{
+				MaxFailures:     5,
+				IPMaxFailures:   50,
+				BackoffBase:     time.Minute,
+				BackoffMax:      time.Minute,
+				LockoutDuration: time.Hour,
+				FailureWindow:   time.Hour,
+				MaxEntries:      10,
+			}, nil)
+			handler := NewAuthHandler(mock).WithLoginThrottle(throttle)
+
+			submit := func(remoteAddr string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+				req.RemoteAddr = remoteAddr
+				if tt.withSession {
+					req = withTestSession(req)
+				} else {
+					req.Header.Set("Authorization", "Bearer token123")
+				}
+				w := httptest.NewRecorder()
+				tt.submit(handler, w, req)
+				return w
+			}
+
+			if w := submit("203.0.113.7:51234"); w.Code != http.StatusUnauthorized {
+				t.Fatalf("first attempt status = %d, want %d: %s", w.Code, http.StatusUnauthorized, w.Body.String())
+			}
+
+			// A new IP does not escape the session's backoff
+			if w := submit("198.51.100.9:40000"); w.Code != http.StatusTooManyRequests {
+				t.Fatalf("attempt during backoff status = %d, want %d", w.Code, http.StatusTooManyRequests)
+			}
+			if calls != 1 {
+				t.Errorf("attempt during backoff reached Kratos: %d calls, want 1", calls)
+			}
+		})
+	}
+}
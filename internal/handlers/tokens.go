@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/middleware"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// TokenHandler exchanges sessions for JWTs and publishes the keys that
+// verify them
+type TokenHandler struct {
+	tokenizer auth.SessionTokenizer
+	keys      *auth.JWTKeySet
+}
+
+// NewTokenHandler creates a new token handler
+func NewTokenHandler(tokenizer auth.SessionTokenizer, keys *auth.JWTKeySet) *TokenHandler {
+	return &TokenHandler{tokenizer: tokenizer, keys: keys}
+}
+
+// CreateToken handles POST /users/token - signs a short-lived JWT for the
+// caller's session that downstream services can verify without Kratos
+func (h *TokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	session, ok := middleware.GetSessionFromContext(r.Context())
+	if !ok {
+		apperrors.NewUnauthorizedError("missing session").WriteJSON(w)
+		return
+	}
+
+	token, expiresAt, err := h.tokenizer.TokenizeSession(r.Context(), middleware.ExtractSessionToken(r), session)
+	if errors.Is(err, auth.ErrInvalidSession) {
+		apperrors.NewUnauthorizedError("invalid or expired session").WriteJSON(w)
+		return
+	}
+	if err != nil {
+		apperrors.NewInternalError("failed to issue token", err).WriteJSON(w)
+		return
+	}
+
+	response.Success(w, response.TokenResponse{
+		Token:     token,
+		TokenType: "Bearer",
+		ExpiresAt: expiresAt.String(),
+	})
+}
+
+// JWKS handles GET /.well-known/jwks.json - the public keys for verifying
+// tokens from CreateToken
+func (h *TokenHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	response.JSON(w, http.StatusOK, h.keys.PublicJWKS())
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
+	"github.com/davegermiquet/kratos-chi-ollama/internal/response"
+	ory "github.com/ory/client-go"
+)
+
+// MockSessionTokenizer is a mock implementation of auth.SessionTokenizer
+type MockSessionTokenizer struct {
+	TokenizeSessionFunc func(ctx context.Context, sessionToken string, session *ory.Session) (string, time.Time, error)
+}
+
+func (m *MockSessionTokenizer) TokenizeSession(ctx context.Context, sessionToken string, session *ory.Session) (string, time.Time, error) {
+	if m.TokenizeSessionFunc != nil {
+		return m.TokenizeSessionFunc(ctx, sessionToken, session)
+	}
+	return "", time.Time{}, errors.New("not implemented")
+}
+
+func TestTokenHandler_CreateToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		mockErr    error
+		wantStatus int
+	}{
+		{name: "success", wantStatus: http.StatusOK},
+		{name: "session revoked", mockErr: fmt.Errorf("failed to tokenize session: %w", auth.ErrInvalidSession), wantStatus: http.StatusUnauthorized},
+		{name: "tokenizer error", mockErr: errors.New("signing failed"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotToken, gotSessionID string
+			mock := &MockSessionTokenizer{
+				TokenizeSessionFunc: func(ctx context.Context, sessionToken string, session *ory.Session) (string, time.Time, error) {
+					gotToken, gotSessionID = sessionToken, session.Id
+					if tt.mockErr != nil {
+						return "", time.Time{}, tt.mockErr
+					}
+					return "header.claims.signature", time.Now().Add(15 * time.Minute), nil
+				},
+			}
+
+			handler := NewTokenHandler(mock, nil)
+
+			req := withTestSession(httptest.NewRequest(http.MethodPost, "/users/token", nil))
+			w := httptest.NewRecorder()
+
+			handler.CreateToken(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("CreateToken() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if gotToken != "token123" || gotSessionID != "session-123" {
+				t.Errorf("CreateToken() tokenized %q for session %q", gotToken, gotSessionID)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got response.TokenResponse
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if got.Token != "header.claims.signature" || got.TokenType != "Bearer" || got.ExpiresAt == "" {
+				t.Errorf("CreateToken() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestTokenHandler_JWKS(t *testing.T) {
+	keys, err := auth.GenerateJWTKeySet()
+	if err != nil {
+		t.Fatalf("GenerateJWTKeySet() error = %v", err)
+	}
+
+	handler := NewTokenHandler(&MockSessionTokenizer{}, keys)
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+
+	handler.JWKS(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("JWKS() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var got auth.JWKS
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if len(got.Keys) != 1 || got.Keys[0].N == "" || got.Keys[0].D != "" {
+		t.Errorf("JWKS() = %+v", got)
+	}
+}
@@ -0,0 +1,341 @@
+package langchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// CassetteMode selects how LLM traffic is recorded or replayed
+type CassetteMode string
+
+const (
+	CassetteModeOff    CassetteMode = ""
+	CassetteModeRecord CassetteMode = "record"
+	CassetteModeReplay CassetteMode = "replay"
+)
+
+// Request kinds stored in a cassette
+const (
+	cassetteKindGenerate = "generate"
+	cassetteKindChat     = "chat"
+)
+
+// ErrCassetteMiss is returned in replay mode when no recorded interaction matches a request
+var ErrCassetteMiss = errors.New("no recorded interaction matches request")
+
+// Cassette is a recorded sequence of LLM interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and its response
+type Interaction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteRequest captures everything that influences an LLM response
+type CassetteRequest struct {
+	Kind     string            `json:"kind"`
+	Model    string            `json:"model"`
+	Prompt   string            `json:"prompt,omitempty"`
+	Messages []CassetteMessage `json:"messages,omitempty"`
+	Options  json.RawMessage   `json:"options,omitempty"`
+}
+
+// CassetteMessage is the text form of a chat message
+type CassetteMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// CassetteResponse is the recorded outcome of a request
+type CassetteResponse struct {
+	Content string `json:"content"`
+	Error   string `json:"error,omitempty"`
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette: %w", err)
+	}
+
+	return &cassette, nil
+}
+
+// Save writes the cassette to path, replacing any existing file. It writes a
+// temporary file and renames it over path, so a crash or failed write never
+// leaves a truncated cassette.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+
+	return nil
+}
+
+// key returns a canonical representation used to match requests
+func (r CassetteRequest) key() string {
+	data, _ := json.Marshal(r)
+	return string(data)
+}
+
+func newGenerateRequest(model, prompt string, opts []llms.CallOption) CassetteRequest {
+	return CassetteRequest{
+		Kind:    cassetteKindGenerate,
+		Model:   model,
+		Prompt:  prompt,
+		Options: encodeCallOptions(opts),
+	}
+}
+
+func newChatRequest(model string, messages []llms.MessageContent, opts []llms.CallOption) CassetteRequest {
+	converted := make([]CassetteMessage, 0, len(messages))
+	for _, msg := range messages {
+		var sb strings.Builder
+		for _, part := range msg.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				sb.WriteString(text.Text)
+			}
+		}
+		converted = append(converted, CassetteMessage{
+			Role:    string(msg.Role),
+			Content: sb.String(),
+		})
+	}
+
+	return CassetteRequest{
+		Kind:     cassetteKindChat,
+		Model:    model,
+		Messages: converted,
+		Options:  encodeCallOptions(opts),
+	}
+}
+
+// encodeCallOptions serializes call options, omitting them when none are set
+func encodeCallOptions(opts []llms.CallOption) json.RawMessage {
+	if len(opts) == 0 {
+		return nil
+	}
+
+	var callOpts llms.CallOptions
+	for _, opt := range opts {
+		opt(&callOpts)
+	}
+
+	data, err := json.Marshal(callOpts)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// RecordingService wraps an LLMService and appends every interaction to a cassette file
+type RecordingService struct {
+	next  LLMService
+	model string
+	path  string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// Ensure RecordingService implements LLMService
+var _ LLMService = (*RecordingService)(nil)
+
+// NewRecordingService creates a recorder writing to path. An existing cassette
+// at path is extended rather than overwritten.
+func NewRecordingService(next LLMService, model, path string) (*RecordingService, error) {
+	if path == "" {
+		return nil, fmt.Errorf("cassette path is required")
+	}
+
+	r := &RecordingService{next: next, model: model, path: path}
+
+	if _, err := os.Stat(path); err == nil {
+		existing, err := LoadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = *existing
+	}
+
+	return r, nil
+}
+
+// GenerateContent calls the wrapped service and records the result
+func (r *RecordingService) GenerateContent(ctx context.Context, prompt string, opts ...llms.CallOption) (string, error) {
+	content, err := r.next.GenerateContent(ctx, prompt, opts...)
+	r.record(newGenerateRequest(r.model, prompt, opts), content, err)
+	return content, err
+}
+
+// Chat calls the wrapped service and records the result
+func (r *RecordingService) Chat(ctx context.Context, messages []llms.MessageContent, opts ...llms.CallOption) (string, error) {
+	content, err := r.next.Chat(ctx, messages, opts...)
+	r.record(newChatRequest(r.model, messages, opts), content, err)
+	return content, err
+}
+
+// record appends an interaction and saves the cassette. A failed save is
+// logged rather than returned, so the caller still gets the model's response;
+// the interaction stays in memory and is written by the next save.
+func (r *RecordingService) record(req CassetteRequest, content string, callErr error) {
+	resp := CassetteResponse{Content: content}
+	if callErr != nil {
+		resp.Error = callErr.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request:  req,
+		Response: resp,
+	})
+
+	if err := r.cassette.Save(r.path); err != nil {
+		log.Printf("llm cassette: %v", err)
+	}
+}
+
+// ReplayService serves responses from a cassette without calling a model.
+// Identical requests recorded several times are replayed in recorded order;
+// once exhausted the last response is repeated.
+type ReplayService struct {
+	model string
+
+	mu        sync.Mutex
+	responses map[string][]CassetteResponse
+	served    map[string]int
+}
+
+// Ensure ReplayService implements LLMService
+var _ LLMService = (*ReplayService)(nil)
+
+// NewReplayService creates a replayer from a cassette
+func NewReplayService(cassette *Cassette, model string) *ReplayService {
+	s := &ReplayService{
+		model:     model,
+		responses: make(map[string][]CassetteResponse),
+		served:    make(map[string]int),
+	}
+
+	if cassette != nil {
+		for _, interaction := range cassette.Interactions {
+			key := interaction.Request.key()
+			s.responses[key] = append(s.responses[key], interaction.Response)
+		}
+	}
+
+	return s
+}
+
+// GenerateContent returns the recorded response for the prompt
+func (s *ReplayService) GenerateContent(ctx context.Context, prompt string, opts ...llms.CallOption) (string, error) {
+	return s.replay(newGenerateRequest(s.model, prompt, opts))
+}
+
+// Chat returns the recorded response for the messages
+func (s *ReplayService) Chat(ctx context.Context, messages []llms.MessageContent, opts ...llms.CallOption) (string, error) {
+	return s.replay(newChatRequest(s.model, messages, opts))
+}
+
+func (s *ReplayService) replay(req CassetteRequest) (string, error) {
+	key := req.key()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recorded := s.responses[key]
+	if len(recorded) == 0 {
+		return "", fmt.Errorf("%w: %s %s", ErrCassetteMiss, req.Kind, summarizeRequest(req))
+	}
+
+	i := s.served[key]
+	if i >= len(recorded) {
+		i = len(recorded) - 1
+	}
+	s.served[key]++
+
+	resp := recorded[i]
+	if resp.Error != "" {
+		return "", errors.New(resp.Error)
+	}
+	return resp.Content, nil
+}
+
+// summarizeRequest returns a short description of a request for error messages
+func summarizeRequest(req CassetteRequest) string {
+	text := req.Prompt
+	if len(req.Messages) > 0 {
+		text = req.Messages[len(req.Messages)-1].Content
+	}
+
+	const maxLen = 60
+	if len(text) > maxLen {
+		text = text[:maxLen] + "..."
+	}
+	return fmt.Sprintf("%q", text)
+}
+
+// WrapWithCassette applies record or replay mode to an LLMService. In replay
+// mode the wrapped service is never called and may be nil.
+func WrapWithCassette(next LLMService, mode CassetteMode, path, model string) (LLMService, error) {
+	switch mode {
+	case CassetteModeOff:
+		return next, nil
+	case CassetteModeRecord:
+		return NewRecordingService(next, model, path)
+	case CassetteModeReplay:
+		cassette, err := LoadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewReplayService(cassette, model), nil
+	default:
+		return nil, fmt.Errorf("unsupported cassette mode %q", mode)
+	}
+}
@@ -0,0 +1,185 @@
+package langchain
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestCassette_RecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "chat.json")
+
+	model, err := NewFakeModel(&FakeFixture{
+		Responses: []FakeResponse{
+			{Match: "hello", Content: "Hi!"},
+			{Match: "boom", Error: "model overloaded"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFakeModel() unexpected error: %v", err)
+	}
+	live := &Client{llm: model, config: Config{Provider: ProviderFake, Model: "fake"}}
+
+	recorder, err := NewRecordingService(live, "fake", path)
+	if err != nil {
+		t.Fatalf("NewRecordingService() unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	chat := ConvertMessages([]ChatMessage{{Role: RoleUser, Content: "hello"}})
+
+	if got, err := recorder.Chat(ctx, chat, llms.WithTemperature(0.2)); err != nil || got != "Hi!" {
+		t.Fatalf("recorder.Chat() = %q, %v; want %q, nil", got, err, "Hi!")
+	}
+	if got, err := recorder.GenerateContent(ctx, "echo me"); err != nil || got != "echo me" {
+		t.Fatalf("recorder.GenerateContent() = %q, %v; want %q, nil", got, err, "echo me")
+	}
+	if _, err := recorder.GenerateContent(ctx, "boom"); err == nil {
+		t.Fatal("recorder.GenerateContent() expected error, got nil")
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("LoadCassette() unexpected error: %v", err)
+	}
+	if len(cassette.Interactions) != 3 {
+		t.Fatalf("recorded interactions = %d, want 3", len(cassette.Interactions))
+	}
+
+	replay := NewReplayService(cassette, "fake")
+
+	if got, err := replay.Chat(ctx, chat, llms.WithTemperature(0.2)); err != nil || got != "Hi!" {
+		t.Errorf("replay.Chat() = %q, %v; want %q, nil", got, err, "Hi!")
+	}
+	if got, err := replay.GenerateContent(ctx, "echo me"); err != nil || got != "echo me" {
+		t.Errorf("replay.GenerateContent() = %q, %v; want %q, nil", got, err, "echo me")
+	}
+	if _, err := replay.GenerateContent(ctx, "boom"); err == nil || err.Error() != "failed to generate content: model overloaded" {
+		t.Errorf("replay.GenerateContent() error = %v, want recorded error", err)
+	}
+}
+
+func TestRecordingService_SaveFailureKeepsResponse(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cassettes")
+	path := filepath.Join(dir, "chat.json")
+
+	model, err := NewFakeModel(&FakeFixture{})
+	if err != nil {
+		t.Fatalf("NewFakeModel() unexpected error: %v", err)
+	}
+	live := &Client{llm: model, config: Config{Provider: ProviderFake, Model: "fake"}}
+
+	recorder, err := NewRecordingService(live, "fake", path)
+	if err != nil {
+		t.Fatalf("NewRecordingService() unexpected error: %v", err)
+	}
+
+	// A file where the cassette directory should be makes every save fail
+	if err := os.WriteFile(dir, nil, 0o644); err != nil {
+		t.Fatalf("WriteFile() unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+	if got, err := recorder.GenerateContent(ctx, "echo me"); err != nil || got != "echo me" {
+		t.Fatalf("recorder.GenerateContent() = %q, %v; want %q, nil", got, err, "echo me")
+	}
+
+	// Once saving works again, the interaction that failed to save is written too
+	if err := os.Remove(dir); err != nil {
+		t.Fatalf("Remove() unexpected error: %v", err)
+	}
+	if _, err := recorder.GenerateContent(ctx, "again"); err != nil {
+		t.Fatalf("recorder.GenerateContent() unexpected error: %v", err)
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("LoadCassette() unexpected error: %v", err)
+	}
+	if len(cassette.Interactions) != 2 {
+		t.Errorf("recorded interactions = %d, want 2", len(cassette.Interactions))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() unexpected error: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("cassette directory holds %d files, want only the cassette", len(entries))
+	}
+}
+
+func TestReplayService_Miss(t *testing.T) {
+	cassette := &Cassette{
+		Interactions: []Interaction{
+			{
+				Request:  newGenerateRequest("llama2", "hello", nil),
+				Response: CassetteResponse{Content: "Hi!"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		model  string
+		prompt string
+		opts   []llms.CallOption
+	}{
+		{name: "different prompt", model: "llama2", prompt: "goodbye"},
+		{name: "different model", model: "mistral", prompt: "hello"},
+		{name: "different options", model: "llama2", prompt: "hello", opts: []llms.CallOption{llms.WithMaxTokens(10)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replay := NewReplayService(cassette, tt.model)
+
+			_, err := replay.GenerateContent(context.Background(), tt.prompt, tt.opts...)
+			if !errors.Is(err, ErrCassetteMiss) {
+				t.Errorf("GenerateContent() error = %v, want ErrCassetteMiss", err)
+			}
+		})
+	}
+}
+
+func TestReplayService_RepeatedRequestsInOrder(t *testing.T) {
+	req := newGenerateRequest("llama2", "roll a die", nil)
+	replay := NewReplayService(&Cassette{
+		Interactions: []Interaction{
+			{Request: req, Response: CassetteResponse{Content: "3"}},
+			{Request: req, Response: CassetteResponse{Content: "5"}},
+		},
+	}, "llama2")
+
+	want := []string{"3", "5", "5"}
+	for i, w := range want {
+		got, err := replay.GenerateContent(context.Background(), "roll a die")
+		if err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i, err)
+		}
+		if got != w {
+			t.Errorf("call %d = %q, want %q", i, got, w)
+		}
+	}
+}
+
+func TestWrapWithCassette(t *testing.T) {
+	live := &Client{config: Config{Model: "llama2"}}
+
+	svc, err := WrapWithCassette(live, CassetteModeOff, "", "llama2")
+	if err != nil || svc != LLMService(live) {
+		t.Errorf("WrapWithCassette(off) = %v, %v; want the wrapped service", svc, err)
+	}
+
+	if _, err := WrapWithCassette(live, CassetteMode("rewind"), "x.json", "llama2"); err == nil {
+		t.Error("WrapWithCassette(invalid mode) expected error, got nil")
+	}
+
+	if _, err := WrapWithCassette(live, CassetteModeReplay, filepath.Join(t.TempDir(), "missing.json"), "llama2"); err == nil {
+		t.Error("WrapWithCassette(replay, missing file) expected error, got nil")
+	}
+}
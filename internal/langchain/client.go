@@ -3,8 +3,13 @@ package langchain
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/anthropic"
+	"github.com/tmc/langchaingo/llms/googleai"
+	"github.com/tmc/langchaingo/llms/huggingface"
+	"github.com/tmc/langchaingo/llms/mistral"
 	"github.com/tmc/langchaingo/llms/ollama"
 	"github.com/tmc/langchaingo/llms/openai"
 )
@@ -13,16 +18,72 @@ import (
 type Provider string
 
 const (
-	ProviderOllama Provider = "ollama"
-	ProviderOpenAI Provider = "openai"
+	ProviderOllama           Provider = "ollama"
+	ProviderOpenAI           Provider = "openai"
+	ProviderOpenAICompatible Provider = "openai-compatible"
+	ProviderAnthropic        Provider = "anthropic"
+	ProviderMistral          Provider = "mistral"
+	ProviderGoogle           Provider = "google"
+	ProviderHuggingFace      Provider = "huggingface"
+	ProviderLlamaCpp         Provider = "llamacpp"
+	// ProviderFake is a deterministic in-process model for tests and local development
+	ProviderFake Provider = "fake"
 )
 
+// placeholderAPIKey is sent to self-hosted servers that speak an
+// authenticated protocol but don't check the key (TGI, llama.cpp)
+const placeholderAPIKey = "no-key"
+
+// defaultLlamaCppURL is the OpenAI-compatible endpoint of a local llama.cpp server
+const defaultLlamaCppURL = "http://localhost:8080/v1"
+
+// SupportedProviders returns all providers accepted by NewClient
+func SupportedProviders() []Provider {
+	return []Provider{
+		ProviderOllama,
+		ProviderOpenAI,
+		ProviderOpenAICompatible,
+		ProviderAnthropic,
+		ProviderMistral,
+		ProviderGoogle,
+		ProviderHuggingFace,
+		ProviderLlamaCpp,
+		ProviderFake,
+	}
+}
+
+// IsValid reports whether the provider is supported
+func (p Provider) IsValid() bool {
+	for _, supported := range SupportedProviders() {
+		if p == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseProvider converts a configuration string into a Provider
+func ParseProvider(name string) (Provider, error) {
+	p := Provider(strings.ToLower(strings.TrimSpace(name)))
+	if !p.IsValid() {
+		return "", fmt.Errorf("unsupported LLM provider %q", name)
+	}
+	return p, nil
+}
+
 // Config holds the configuration for LLM client
 type Config struct {
 	Provider Provider
 	Model    string
 	BaseURL  string
 	APIKey   string
+
+	// Organization is the OpenAI organization ID (openai only)
+	Organization string
+	// CredentialsFile is a service account JSON file (google only, alternative to APIKey)
+	CredentialsFile string
+	// FixtureFile is a JSON file of scripted responses (fake only, echoes when empty)
+	FixtureFile string
 }
 
 // Client wraps langchaingo LLM functionality
@@ -48,9 +109,22 @@ func NewClient(cfg Config) (*Client, error) {
 		llm, err = createOllamaClient(cfg)
 	case ProviderOpenAI:
 		llm, err = createOpenAIClient(cfg)
+	case ProviderOpenAICompatible:
+		llm, err = createOpenAICompatibleClient(cfg)
+	case ProviderAnthropic:
+		llm, err = createAnthropicClient(cfg)
+	case ProviderMistral:
+		llm, err = createMistralClient(cfg)
+	case ProviderGoogle:
+		llm, err = createGoogleClient(cfg)
+	case ProviderHuggingFace:
+		llm, err = createHuggingFaceClient(cfg)
+	case ProviderLlamaCpp:
+		llm, err = createLlamaCppClient(cfg)
+	case ProviderFake:
+		llm, err = createFakeClient(cfg)
 	default:
-		// Default to Ollama
-		llm, err = createOllamaClient(cfg)
+		return nil, fmt.Errorf("unsupported LLM provider %q", cfg.Provider)
 	}
 
 	if err != nil {
@@ -85,9 +159,116 @@ func createOpenAIClient(cfg Config) (llms.Model, error) {
 		opts = append(opts, openai.WithBaseURL(cfg.BaseURL))
 	}
 
+	if cfg.Organization != "" {
+		opts = append(opts, openai.WithOrganization(cfg.Organization))
+	}
+
 	return openai.New(opts...)
 }
 
+// createOpenAICompatibleClient targets any server implementing the OpenAI
+// chat completions API (vLLM, LocalAI, LM Studio, ...)
+func createOpenAICompatibleClient(cfg Config) (llms.Model, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("base URL is required for provider %q", cfg.Provider)
+	}
+
+	apiKey := cfg.APIKey
+	if apiKey == "" {
+		apiKey = placeholderAPIKey
+	}
+
+	return openai.New(
+		openai.WithModel(cfg.Model),
+		openai.WithBaseURL(cfg.BaseURL),
+		openai.WithToken(apiKey),
+	)
+}
+
+func createAnthropicClient(cfg Config) (llms.Model, error) {
+	opts := []anthropic.Option{
+		anthropic.WithModel(cfg.Model),
+	}
+
+	if cfg.APIKey != "" {
+		opts = append(opts, anthropic.WithToken(cfg.APIKey))
+	}
+
+	if cfg.BaseURL != "" {
+		opts = append(opts, anthropic.WithBaseURL(cfg.BaseURL))
+	}
+
+	return anthropic.New(opts...)
+}
+
+func createMistralClient(cfg Config) (llms.Model, error) {
+	opts := []mistral.Option{
+		mistral.WithModel(cfg.Model),
+	}
+
+	if cfg.APIKey != "" {
+		opts = append(opts, mistral.WithAPIKey(cfg.APIKey))
+	}
+
+	if cfg.BaseURL != "" {
+		opts = append(opts, mistral.WithEndpoint(cfg.BaseURL))
+	}
+
+	return mistral.New(opts...)
+}
+
+func createGoogleClient(cfg Config) (llms.Model, error) {
+	if cfg.APIKey == "" && cfg.CredentialsFile == "" {
+		return nil, fmt.Errorf("API key or credentials file is required for provider %q", cfg.Provider)
+	}
+
+	opts := []googleai.Option{
+		googleai.WithDefaultModel(cfg.Model),
+	}
+
+	if cfg.APIKey != "" {
+		opts = append(opts, googleai.WithAPIKey(cfg.APIKey))
+	}
+
+	if cfg.CredentialsFile != "" {
+		opts = append(opts, googleai.WithCredentialsFile(cfg.CredentialsFile))
+	}
+
+	return googleai.New(context.Background(), opts...)
+}
+
+// createHuggingFaceClient supports both the hosted inference API and a
+// self-hosted Text Generation Inference server (set BaseURL)
+func createHuggingFaceClient(cfg Config) (llms.Model, error) {
+	opts := []huggingface.Option{
+		huggingface.WithModel(cfg.Model),
+	}
+
+	apiKey := cfg.APIKey
+	if apiKey == "" && cfg.BaseURL != "" {
+		apiKey = placeholderAPIKey
+	}
+
+	if apiKey != "" {
+		opts = append(opts, huggingface.WithToken(apiKey))
+	}
+
+	if cfg.BaseURL != "" {
+		opts = append(opts, huggingface.WithURL(cfg.BaseURL))
+	}
+
+	return huggingface.New(opts...)
+}
+
+// createLlamaCppClient talks to llama.cpp's server through its
+// OpenAI-compatible endpoint
+func createLlamaCppClient(cfg Config) (llms.Model, error) {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = defaultLlamaCppURL
+	}
+	return createOpenAICompatibleClient(cfg)
+}
+
 // GenerateContent generates text from a prompt
 func (c *Client) GenerateContent(ctx context.Context, prompt string, opts ...llms.CallOption) (string, error) {
 	if prompt == "" {
@@ -0,0 +1,282 @@
+package langchain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// defaultFakeChunkSize is the number of characters per streamed chunk
+const defaultFakeChunkSize = 4
+
+// FakeFixture scripts the responses of a FakeModel
+type FakeFixture struct {
+	// Responses are matched in order against the last user message.
+	// The first matching rule wins.
+	Responses []FakeResponse `json:"responses"`
+	// Default is used when no rule matches. When nil the prompt is echoed back.
+	Default *FakeResponse `json:"default,omitempty"`
+}
+
+// FakeResponse describes a single scripted reply
+type FakeResponse struct {
+	// Match is a case-insensitive substring of the last user message
+	Match string `json:"match,omitempty"`
+	// Regex is a regular expression matched against the last user message
+	Regex string `json:"regex,omitempty"`
+
+	// Content is returned as the reply. Empty with Echo set returns the prompt.
+	Content string `json:"content,omitempty"`
+	// Echo returns the last user message as the reply
+	Echo bool `json:"echo,omitempty"`
+	// Error makes the call fail with this message
+	Error string `json:"error,omitempty"`
+	// ToolCalls are returned alongside the content
+	ToolCalls []FakeToolCall `json:"tool_calls,omitempty"`
+
+	// LatencyMS delays the response (before the first token when streaming)
+	LatencyMS int `json:"latency_ms,omitempty"`
+	// TokenDelayMS delays each streamed chunk
+	TokenDelayMS int `json:"token_delay_ms,omitempty"`
+	// ChunkSize is the number of characters per streamed chunk
+	ChunkSize int `json:"chunk_size,omitempty"`
+
+	regex *regexp.Regexp
+}
+
+// FakeToolCall is a scripted tool invocation
+type FakeToolCall struct {
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// FakeModel implements llms.Model without calling a real LLM
+type FakeModel struct {
+	fixture FakeFixture
+
+	mu    sync.Mutex
+	calls int
+}
+
+// Ensure FakeModel implements llms.Model
+var _ llms.Model = (*FakeModel)(nil)
+
+// NewFakeModel creates a fake model from a fixture. A nil fixture echoes every prompt.
+func NewFakeModel(fixture *FakeFixture) (*FakeModel, error) {
+	m := &FakeModel{}
+	if fixture == nil {
+		return m, nil
+	}
+
+	m.fixture = *fixture
+	m.fixture.Responses = make([]FakeResponse, len(fixture.Responses))
+	copy(m.fixture.Responses, fixture.Responses)
+
+	for i := range m.fixture.Responses {
+		resp := &m.fixture.Responses[i]
+		if resp.Regex == "" {
+			continue
+		}
+		re, err := regexp.Compile(resp.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex in fake response %d: %w", i, err)
+		}
+		resp.regex = re
+	}
+
+	return m, nil
+}
+
+// LoadFakeFixture reads a JSON fixture file
+func LoadFakeFixture(path string) (*FakeFixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake fixture: %w", err)
+	}
+
+	var fixture FakeFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("failed to parse fake fixture: %w", err)
+	}
+
+	return &fixture, nil
+}
+
+func createFakeClient(cfg Config) (llms.Model, error) {
+	if cfg.FixtureFile == "" {
+		return NewFakeModel(nil)
+	}
+
+	fixture, err := LoadFakeFixture(cfg.FixtureFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFakeModel(fixture)
+}
+
+// Calls returns the number of GenerateContent calls served
+func (m *FakeModel) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+// Call implements the deprecated llms.Model text interface
+func (m *FakeModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// GenerateContent returns the scripted response for the last user message
+func (m *FakeModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.mu.Lock()
+	m.calls++
+	m.mu.Unlock()
+
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	prompt := lastUserText(messages)
+	rule := m.match(prompt)
+
+	if err := sleepContext(ctx, time.Duration(rule.LatencyMS)*time.Millisecond); err != nil {
+		return nil, err
+	}
+
+	if rule.Error != "" {
+		return nil, errors.New(rule.Error)
+	}
+
+	content := rule.Content
+	if rule.Echo || (content == "" && len(rule.ToolCalls) == 0) {
+		content = prompt
+	}
+
+	if opts.StreamingFunc != nil {
+		if err := streamContent(ctx, content, rule, opts.StreamingFunc); err != nil {
+			return nil, err
+		}
+	}
+
+	choice := &llms.ContentChoice{
+		Content:    content,
+		StopReason: "stop",
+	}
+
+	for i, tc := range rule.ToolCalls {
+		id := tc.ID
+		if id == "" {
+			id = fmt.Sprintf("call_%d", i+1)
+		}
+		args := string(tc.Arguments)
+		if args == "" {
+			args = "{}"
+		}
+		choice.ToolCalls = append(choice.ToolCalls, llms.ToolCall{
+			ID:   id,
+			Type: "function",
+			FunctionCall: &llms.FunctionCall{
+				Name:      tc.Name,
+				Arguments: args,
+			},
+		})
+	}
+
+	if len(choice.ToolCalls) > 0 {
+		choice.FuncCall = choice.ToolCalls[0].FunctionCall
+		choice.StopReason = "tool_calls"
+	}
+
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{choice}}, nil
+}
+
+func (m *FakeModel) match(prompt string) FakeResponse {
+	lower := strings.ToLower(prompt)
+
+	for _, resp := range m.fixture.Responses {
+		if resp.regex != nil && resp.regex.MatchString(prompt) {
+			return resp
+		}
+		if resp.Match != "" && strings.Contains(lower, strings.ToLower(resp.Match)) {
+			return resp
+		}
+	}
+
+	if m.fixture.Default != nil {
+		return *m.fixture.Default
+	}
+
+	return FakeResponse{Echo: true}
+}
+
+func streamContent(ctx context.Context, content string, rule FakeResponse, fn func(ctx context.Context, chunk []byte) error) error {
+	size := rule.ChunkSize
+	if size <= 0 {
+		size = defaultFakeChunkSize
+	}
+
+	runes := []rune(content)
+	for start := 0; start < len(runes); start += size {
+		if start > 0 {
+			if err := sleepContext(ctx, time.Duration(rule.TokenDelayMS)*time.Millisecond); err != nil {
+				return err
+			}
+		}
+
+		end := start + size
+		if end > len(runes) {
+			end = len(runes)
+		}
+
+		if err := fn(ctx, []byte(string(runes[start:end]))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lastUserText returns the text of the last human message
+func lastUserText(messages []llms.MessageContent) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != llms.ChatMessageTypeHuman {
+			continue
+		}
+
+		var sb strings.Builder
+		for _, part := range messages[i].Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				sb.WriteString(text.Text)
+			}
+		}
+		return sb.String()
+	}
+	return ""
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
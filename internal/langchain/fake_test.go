@@ -0,0 +1,165 @@
+package langchain
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestFakeModel_Echo(t *testing.T) {
+	client, err := NewClient(Config{Provider: ProviderFake, Model: "fake"})
+	if err != nil {
+		t.Fatalf("NewClient() unexpected error: %v", err)
+	}
+
+	got, err := client.GenerateContent(context.Background(), "ping")
+	if err != nil {
+		t.Fatalf("GenerateContent() unexpected error: %v", err)
+	}
+
+	if got != "ping" {
+		t.Errorf("GenerateContent() = %q, want %q", got, "ping")
+	}
+}
+
+func TestFakeModel_Fixture(t *testing.T) {
+	client, err := NewClient(Config{
+		Provider:    ProviderFake,
+		Model:       "fake",
+		FixtureFile: "testdata/fake_fixture.json",
+	})
+	if err != nil {
+		t.Fatalf("NewClient() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		prompt  string
+		want    string
+		wantErr bool
+	}{
+		{name: "substring match", prompt: "Hello model", want: "Hi there! How can I help?"},
+		{name: "error rule", prompt: "please fail", wantErr: true},
+		{name: "default echo", prompt: "something else", want: "something else"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages := ConvertMessages([]ChatMessage{
+				{Role: RoleSystem, Content: "Be helpful"},
+				{Role: RoleUser, Content: tt.prompt},
+			})
+
+			got, err := client.Chat(context.Background(), messages)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Chat() expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Chat() unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Chat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFakeModel_ToolCalls(t *testing.T) {
+	fixture, err := LoadFakeFixture("testdata/fake_fixture.json")
+	if err != nil {
+		t.Fatalf("LoadFakeFixture() unexpected error: %v", err)
+	}
+
+	model, err := NewFakeModel(fixture)
+	if err != nil {
+		t.Fatalf("NewFakeModel() unexpected error: %v", err)
+	}
+
+	resp, err := model.GenerateContent(context.Background(), ConvertMessages([]ChatMessage{
+		{Role: RoleUser, Content: "What is the weather in Toronto?"},
+	}))
+	if err != nil {
+		t.Fatalf("GenerateContent() unexpected error: %v", err)
+	}
+
+	choice := resp.Choices[0]
+	if len(choice.ToolCalls) != 1 {
+		t.Fatalf("ToolCalls count = %d, want 1", len(choice.ToolCalls))
+	}
+
+	call := choice.ToolCalls[0]
+	if call.ID != "call_weather" || call.FunctionCall.Name != "get_weather" {
+		t.Errorf("ToolCall = %+v, want get_weather/call_weather", call)
+	}
+
+	if !strings.Contains(call.FunctionCall.Arguments, "Toronto") {
+		t.Errorf("ToolCall arguments = %s, want city Toronto", call.FunctionCall.Arguments)
+	}
+
+	if choice.StopReason != "tool_calls" {
+		t.Errorf("StopReason = %q, want %q", choice.StopReason, "tool_calls")
+	}
+}
+
+func TestFakeModel_Streaming(t *testing.T) {
+	fixture, err := LoadFakeFixture("testdata/fake_fixture.json")
+	if err != nil {
+		t.Fatalf("LoadFakeFixture() unexpected error: %v", err)
+	}
+
+	model, err := NewFakeModel(fixture)
+	if err != nil {
+		t.Fatalf("NewFakeModel() unexpected error: %v", err)
+	}
+
+	var chunks []string
+	_, err = llms.GenerateFromSinglePrompt(context.Background(), model, "stream please",
+		llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+			chunks = append(chunks, string(chunk))
+			return nil
+		}),
+	)
+	if err != nil {
+		t.Fatalf("GenerateFromSinglePrompt() unexpected error: %v", err)
+	}
+
+	want := []string{"str", "eam", "ed ", "rep", "ly"}
+	if strings.Join(chunks, "|") != strings.Join(want, "|") {
+		t.Errorf("chunks = %q, want %q", chunks, want)
+	}
+}
+
+func TestFakeModel_LatencyRespectsContext(t *testing.T) {
+	model, err := NewFakeModel(&FakeFixture{
+		Default: &FakeResponse{Content: "slow", LatencyMS: 1000},
+	})
+	if err != nil {
+		t.Fatalf("NewFakeModel() unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = llms.GenerateFromSinglePrompt(ctx, model, "anything")
+	if err == nil {
+		t.Error("GenerateFromSinglePrompt() expected context error, got nil")
+	}
+}
+
+func TestNewFakeModel_InvalidRegex(t *testing.T) {
+	_, err := NewFakeModel(&FakeFixture{
+		Responses: []FakeResponse{{Regex: "("}},
+	})
+	if err == nil {
+		t.Error("NewFakeModel() expected error for invalid regex, got nil")
+	}
+}
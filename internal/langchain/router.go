@@ -0,0 +1,256 @@
+package langchain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// ExperimentMode selects how a candidate model receives chat traffic
+type ExperimentMode string
+
+const (
+	// ExperimentModeOff sends all traffic to the primary model
+	ExperimentModeOff ExperimentMode = ""
+	// ExperimentModeSplit serves a share of users from the candidate model
+	ExperimentModeSplit ExperimentMode = "split"
+	// ExperimentModeShadow serves everyone from the primary model and sends
+	// a copy of a share of requests to the candidate, discarding its result
+	ExperimentModeShadow ExperimentMode = "shadow"
+)
+
+// Variant names reported in comparisons
+const (
+	VariantPrimary   = "primary"
+	VariantCandidate = "candidate"
+)
+
+// shadowTimeout bounds shadow calls, which outlive the originating request
+const shadowTimeout = 2 * time.Minute
+
+// DefaultMaxShadows is the number of concurrent shadow calls allowed when
+// ExperimentConfig.MaxShadows is not set
+const DefaultMaxShadows = 10
+
+type routingKeyContextKey struct{}
+
+// WithRoutingKey returns a context carrying the key used to assign a request
+// to an experiment variant. The same key is always assigned the same variant.
+func WithRoutingKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, routingKeyContextKey{}, key)
+}
+
+// RoutingKeyFromContext returns the routing key, if any
+func RoutingKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(routingKeyContextKey{}).(string)
+	return key, ok && key != ""
+}
+
+// Comparison records the outputs of one request for offline analysis
+type Comparison struct {
+	Mode       ExperimentMode `json:"mode"`
+	RoutingKey string         `json:"routing_key"`
+	Served     string         `json:"served"`
+
+	PrimaryModel     string        `json:"primary_model"`
+	PrimaryOutput    string        `json:"primary_output,omitempty"`
+	PrimaryError     string        `json:"primary_error,omitempty"`
+	PrimaryLatency   time.Duration `json:"primary_latency_ns,omitempty"`
+	CandidateModel   string        `json:"candidate_model"`
+	CandidateOutput  string        `json:"candidate_output,omitempty"`
+	CandidateError   string        `json:"candidate_error,omitempty"`
+	CandidateLatency time.Duration `json:"candidate_latency_ns,omitempty"`
+}
+
+// ExperimentConfig configures an ExperimentRouter
+type ExperimentConfig struct {
+	Mode ExperimentMode
+	// Percent is the share (0-100) of routing keys assigned to the candidate
+	Percent int
+	// PrimaryModel and CandidateModel label the variants in comparisons
+	PrimaryModel   string
+	CandidateModel string
+	// MaxShadows bounds the shadow calls in flight; requests beyond it are
+	// served without a shadow. Defaults to DefaultMaxShadows.
+	MaxShadows int
+	// OnComparison receives every comparison; defaults to logging a JSON line
+	OnComparison func(Comparison)
+}
+
+// ExperimentRouter routes chat traffic between a primary and a candidate model.
+// GenerateContent always uses the primary model.
+type ExperimentRouter struct {
+	primary   LLMService
+	candidate LLMService
+	cfg       ExperimentConfig
+	shadows   chan struct{}
+}
+
+// Ensure ExperimentRouter implements LLMService
+var _ LLMService = (*ExperimentRouter)(nil)
+
+// NewExperimentRouter creates a router between primary and candidate
+func NewExperimentRouter(primary, candidate LLMService, cfg ExperimentConfig) (*ExperimentRouter, error) {
+	switch cfg.Mode {
+	case ExperimentModeSplit, ExperimentModeShadow:
+	default:
+		return nil, fmt.Errorf("unsupported experiment mode %q", cfg.Mode)
+	}
+
+	if cfg.Percent < 0 || cfg.Percent > 100 {
+		return nil, fmt.Errorf("experiment percent must be between 0 and 100, got %d", cfg.Percent)
+	}
+
+	if primary == nil || candidate == nil {
+		return nil, fmt.Errorf("primary and candidate models are required")
+	}
+
+	if cfg.MaxShadows < 0 {
+		return nil, fmt.Errorf("max shadows must not be negative, got %d", cfg.MaxShadows)
+	}
+	if cfg.MaxShadows == 0 {
+		cfg.MaxShadows = DefaultMaxShadows
+	}
+
+	if cfg.OnComparison == nil {
+		cfg.OnComparison = logComparison
+	}
+
+	return &ExperimentRouter{
+		primary:   primary,
+		candidate: candidate,
+		cfg:       cfg,
+		shadows:   make(chan struct{}, cfg.MaxShadows),
+	}, nil
+}
+
+// GenerateContent delegates to the primary model
+func (r *ExperimentRouter) GenerateContent(ctx context.Context, prompt string, opts ...llms.CallOption) (string, error) {
+	return r.primary.GenerateContent(ctx, prompt, opts...)
+}
+
+// Chat serves the request from the variant assigned to the routing key
+func (r *ExperimentRouter) Chat(ctx context.Context, messages []llms.MessageContent, opts ...llms.CallOption) (string, error) {
+	key, ok := RoutingKeyFromContext(ctx)
+	if !ok {
+		return r.primary.Chat(ctx, messages, opts...)
+	}
+
+	if r.cfg.Mode == ExperimentModeSplit {
+		return r.split(ctx, key, messages, opts...)
+	}
+
+	if !r.inCandidateBucket(key) {
+		return r.primary.Chat(ctx, messages, opts...)
+	}
+	return r.shadow(ctx, key, messages, opts...)
+}
+
+// split serves the request from the key's variant and reports it, so both
+// arms of the split can be compared
+func (r *ExperimentRouter) split(ctx context.Context, key string, messages []llms.MessageContent, opts ...llms.CallOption) (string, error) {
+	served, svc := VariantPrimary, r.primary
+	if r.inCandidateBucket(key) {
+		served, svc = VariantCandidate, r.candidate
+	}
+
+	start := time.Now()
+	content, err := svc.Chat(ctx, messages, opts...)
+	latency := time.Since(start)
+
+	cmp := r.newComparison(key, served)
+	if served == VariantCandidate {
+		cmp.CandidateOutput, cmp.CandidateError, cmp.CandidateLatency = content, errString(err), latency
+	} else {
+		cmp.PrimaryOutput, cmp.PrimaryError, cmp.PrimaryLatency = content, errString(err), latency
+	}
+	r.cfg.OnComparison(cmp)
+
+	return content, err
+}
+
+// shadow serves the request from the primary model and copies it to the
+// candidate. When MaxShadows calls are already in flight the copy is dropped.
+func (r *ExperimentRouter) shadow(ctx context.Context, key string, messages []llms.MessageContent, opts ...llms.CallOption) (string, error) {
+	select {
+	case r.shadows <- struct{}{}:
+	default:
+		return r.primary.Chat(ctx, messages, opts...)
+	}
+
+	// The candidate call must not delay or fail the user's request
+	shadowDone := make(chan Comparison, 1)
+	go func() {
+		shadowCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shadowTimeout)
+		start := time.Now()
+		content, err := r.candidate.Chat(shadowCtx, messages, opts...)
+		cancel()
+		<-r.shadows
+
+		cmp := Comparison{
+			CandidateOutput:  content,
+			CandidateError:   errString(err),
+			CandidateLatency: time.Since(start),
+		}
+		shadowDone <- cmp
+	}()
+
+	start := time.Now()
+	content, err := r.primary.Chat(ctx, messages, opts...)
+	primaryLatency := time.Since(start)
+
+	go func() {
+		shadow := <-shadowDone
+
+		cmp := r.newComparison(key, VariantPrimary)
+		cmp.PrimaryOutput, cmp.PrimaryError = content, errString(err)
+		cmp.PrimaryLatency = primaryLatency
+		cmp.CandidateOutput = shadow.CandidateOutput
+		cmp.CandidateError = shadow.CandidateError
+		cmp.CandidateLatency = shadow.CandidateLatency
+		r.cfg.OnComparison(cmp)
+	}()
+
+	return content, err
+}
+
+func (r *ExperimentRouter) newComparison(key, served string) Comparison {
+	return Comparison{
+		Mode:           r.cfg.Mode,
+		RoutingKey:     key,
+		Served:         served,
+		PrimaryModel:   r.cfg.PrimaryModel,
+		CandidateModel: r.cfg.CandidateModel,
+	}
+}
+
+// inCandidateBucket deterministically assigns a key to a bucket in [0, 100).
+// The candidate model name salts the hash so each experiment gets a fresh split.
+func (r *ExperimentRouter) inCandidateBucket(key string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(r.cfg.CandidateModel))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return int(h.Sum32()%100) < r.cfg.Percent
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func logComparison(cmp Comparison) {
+	data, err := json.Marshal(cmp)
+	if err != nil {
+		log.Printf("llm experiment: failed to encode comparison: %v", err)
+		return
+	}
+	log.Printf("llm experiment: %s", data)
+}
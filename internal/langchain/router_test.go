@@ -0,0 +1,269 @@
+package langchain
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// staticService answers every request with a fixed reply
+type staticService struct {
+	reply string
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (s *staticService) GenerateContent(ctx context.Context, prompt string, opts ...llms.CallOption) (string, error) {
+	return s.Chat(ctx, nil, opts...)
+}
+
+func (s *staticService) Chat(ctx context.Context, messages []llms.MessageContent, opts ...llms.CallOption) (string, error) {
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+	return s.reply, nil
+}
+
+func (s *staticService) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func newTestRouter(t *testing.T, mode ExperimentMode, percent int, onCmp func(Comparison)) (*ExperimentRouter, *staticService, *staticService) {
+	t.Helper()
+
+	primary := &staticService{reply: "primary"}
+	candidate := &staticService{reply: "candidate"}
+
+	router, err := NewExperimentRouter(primary, candidate, ExperimentConfig{
+		Mode:           mode,
+		Percent:        percent,
+		PrimaryModel:   "llama2",
+		CandidateModel: "llama3",
+		OnComparison:   onCmp,
+	})
+	if err != nil {
+		t.Fatalf("NewExperimentRouter() unexpected error: %v", err)
+	}
+
+	return router, primary, candidate
+}
+
+func TestNewExperimentRouter_Validation(t *testing.T) {
+	svc := &staticService{}
+
+	tests := []struct {
+		name string
+		cfg  ExperimentConfig
+	}{
+		{name: "no mode", cfg: ExperimentConfig{Percent: 10}},
+		{name: "unknown mode", cfg: ExperimentConfig{Mode: "canary", Percent: 10}},
+		{name: "percent too high", cfg: ExperimentConfig{Mode: ExperimentModeSplit, Percent: 101}},
+		{name: "negative percent", cfg: ExperimentConfig{Mode: ExperimentModeShadow, Percent: -1}},
+		{name: "negative max shadows", cfg: ExperimentConfig{Mode: ExperimentModeShadow, Percent: 10, MaxShadows: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewExperimentRouter(svc, svc, tt.cfg); err == nil {
+				t.Error("NewExperimentRouter() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestExperimentRouter_SplitIsStickyPerKey(t *testing.T) {
+	router, _, _ := newTestRouter(t, ExperimentModeSplit, 50, func(Comparison) {})
+
+	candidates := 0
+	for i := 0; i < 200; i++ {
+		ctx := WithRoutingKey(context.Background(), fmt.Sprintf("identity-%d", i))
+
+		first, _ := router.Chat(ctx, nil)
+		for j := 0; j < 3; j++ {
+			again, _ := router.Chat(ctx, nil)
+			if again != first {
+				t.Fatalf("identity-%d switched variant from %q to %q", i, first, again)
+			}
+		}
+
+		if first == "candidate" {
+			candidates++
+		}
+	}
+
+	if candidates < 60 || candidates > 140 {
+		t.Errorf("candidate share = %d/200, want roughly half", candidates)
+	}
+}
+
+func TestExperimentRouter_SplitBounds(t *testing.T) {
+	tests := []struct {
+		name    string
+		percent int
+		key     string
+		want    string
+	}{
+		{name: "0 percent", percent: 0, key: "user-1", want: "primary"},
+		{name: "100 percent", percent: 100, key: "user-1", want: "candidate"},
+		{name: "no routing key", percent: 100, key: "", want: "primary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, _, _ := newTestRouter(t, ExperimentModeSplit, tt.percent, func(Comparison) {})
+
+			ctx := context.Background()
+			if tt.key != "" {
+				ctx = WithRoutingKey(ctx, tt.key)
+			}
+
+			got, err := router.Chat(ctx, nil)
+			if err != nil {
+				t.Fatalf("Chat() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Chat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExperimentRouter_Shadow(t *testing.T) {
+	comparisons := make(chan Comparison, 1)
+	router, primary, candidate := newTestRouter(t, ExperimentModeShadow, 100, func(cmp Comparison) {
+		comparisons <- cmp
+	})
+
+	got, err := router.Chat(WithRoutingKey(context.Background(), "user-1"), nil)
+	if err != nil {
+		t.Fatalf("Chat() unexpected error: %v", err)
+	}
+	if got != "primary" {
+		t.Errorf("Chat() = %q, want the primary reply", got)
+	}
+
+	select {
+	case cmp := <-comparisons:
+		if cmp.PrimaryOutput != "primary" || cmp.CandidateOutput != "candidate" {
+			t.Errorf("comparison = %+v, want both outputs", cmp)
+		}
+		if cmp.Served != VariantPrimary || cmp.RoutingKey != "user-1" {
+			t.Errorf("comparison served %q for %q, want primary for user-1", cmp.Served, cmp.RoutingKey)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no comparison logged for shadow request")
+	}
+
+	if primary.Calls() != 1 || candidate.Calls() != 1 {
+		t.Errorf("calls = primary %d candidate %d, want 1 each", primary.Calls(), candidate.Calls())
+	}
+}
+
+func TestExperimentRouter_SplitComparesBothArms(t *testing.T) {
+	var comparisons []Comparison
+	router, _, _ := newTestRouter(t, ExperimentModeSplit, 50, func(cmp Comparison) {
+		comparisons = append(comparisons, cmp)
+	})
+
+	served := map[string]int{}
+	for i := 0; i < 50; i++ {
+		ctx := WithRoutingKey(context.Background(), fmt.Sprintf("identity-%d", i))
+		got, _ := router.Chat(ctx, nil)
+
+		cmp := comparisons[len(comparisons)-1]
+		served[cmp.Served]++
+
+		output := cmp.PrimaryOutput
+		if cmp.Served == VariantCandidate {
+			output = cmp.CandidateOutput
+		}
+		if output != got {
+			t.Fatalf("comparison %+v does not record the %q reply", cmp, got)
+		}
+	}
+
+	if len(comparisons) != 50 || served[VariantPrimary] == 0 || served[VariantCandidate] == 0 {
+		t.Errorf("comparisons = %d served %v, want one per request from both arms", len(comparisons), served)
+	}
+}
+
+// blockingService blocks chat calls until release is closed
+type blockingService struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingService) GenerateContent(ctx context.Context, prompt string, opts ...llms.CallOption) (string, error) {
+	return b.Chat(ctx, nil, opts...)
+}
+
+func (b *blockingService) Chat(ctx context.Context, messages []llms.MessageContent, opts ...llms.CallOption) (string, error) {
+	b.started <- struct{}{}
+	<-b.release
+	return "candidate", nil
+}
+
+func TestExperimentRouter_ShadowLimit(t *testing.T) {
+	primary := &staticService{reply: "primary"}
+	candidate := &blockingService{started: make(chan struct{}, 10), release: make(chan struct{})}
+
+	comparisons := make(chan Comparison, 10)
+	router, err := NewExperimentRouter(primary, candidate, ExperimentConfig{
+		Mode:           ExperimentModeShadow,
+		Percent:        100,
+		CandidateModel: "llama3",
+		MaxShadows:     2,
+		OnComparison:   func(cmp Comparison) { comparisons <- cmp },
+	})
+	if err != nil {
+		t.Fatalf("NewExperimentRouter() unexpected error: %v", err)
+	}
+
+	ctx := WithRoutingKey(context.Background(), "user-1")
+	for i := 0; i < 5; i++ {
+		if got, _ := router.Chat(ctx, nil); got != "primary" {
+			t.Fatalf("Chat() = %q, want the primary reply", got)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		<-candidate.started
+	}
+	select {
+	case <-candidate.started:
+		t.Fatal("more shadow calls started than MaxShadows")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if primary.Calls() != 5 {
+		t.Errorf("primary calls = %d, want every request served", primary.Calls())
+	}
+
+	// Finished shadows free their slots
+	close(candidate.release)
+	for i := 0; i < 2; i++ {
+		<-comparisons
+	}
+	router.Chat(ctx, nil)
+	select {
+	case <-candidate.started:
+	case <-time.After(time.Second):
+		t.Error("no shadow call after slots were freed")
+	}
+}
+
+func TestExperimentRouter_GenerateUsesPrimary(t *testing.T) {
+	router, _, candidate := newTestRouter(t, ExperimentModeSplit, 100, func(Comparison) {})
+
+	got, _ := router.GenerateContent(WithRoutingKey(context.Background(), "user-1"), "hi")
+	if got != "primary" || candidate.Calls() != 0 {
+		t.Errorf("GenerateContent() = %q with %d candidate calls, want primary only", got, candidate.Calls())
+	}
+}
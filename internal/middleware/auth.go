@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -16,9 +17,12 @@ type ContextKey string
 const (
 	// SessionContextKey is the key for storing session in context
 	SessionContextKey ContextKey = "session"
+	// APIKeyContextKey is the key for storing the API key a request authenticated with
+	APIKeyContextKey ContextKey = "api_key"
 )
 
-// ExtractSessionToken extracts session token from request
+// ExtractSessionToken extracts session token from request. A session cookie
+// is returned as a credential from auth.SessionCookieCredential.
 func ExtractSessionToken(r *http.Request) string {
 	// 1. Try X-Session-Token header (recommended for APIs)
 	if token := r.Header.Get("X-Session-Token"); token != "" {
@@ -31,6 +35,16 @@ func ExtractSessionToken(r *http.Request) string {
 		return strings.TrimPrefix(authHeader, "Bearer ")
 	}
 
+	// 3. Try X-API-Key header (machine clients)
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	// 4. Try the Kratos session cookie (browser flows)
+	if cookie, err := r.Cookie(auth.SessionCookieName); err == nil && cookie.Value != "" {
+		return auth.SessionCookieCredential(cookie.Value)
+	}
+
 	return ""
 }
 
@@ -40,8 +54,23 @@ func GetSessionFromContext(ctx context.Context) (*ory.Session, bool) {
 	return session, ok
 }
 
-// AuthMiddleware validates the session and rejects unauthenticated requests
+// GetAPIKeyFromContext retrieves the API key the request authenticated with.
+// It is absent for requests made with a session token.
+func GetAPIKeyFromContext(ctx context.Context) (*auth.APIKey, bool) {
+	key, ok := ctx.Value(APIKeyContextKey).(*auth.APIKey)
+	return key, ok
+}
+
+// AuthMiddleware validates the session and rejects unauthenticated requests.
+// API keys are rejected; use AuthMiddlewareWithAPIKeys where they are allowed.
 func AuthMiddleware(validator auth.SessionValidator) func(http.Handler) http.Handler {
+	return AuthMiddlewareWithAPIKeys(validator, nil)
+}
+
+// AuthMiddlewareWithAPIKeys is AuthMiddleware that also accepts API keys.
+// A key resolves to a synthetic session for the identity that owns it, and
+// the key itself is stored in the context for RequireScope.
+func AuthMiddlewareWithAPIKeys(validator auth.SessionValidator, keys auth.APIKeyValidator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			sessionToken := ExtractSessionToken(r)
@@ -51,6 +80,28 @@ func AuthMiddleware(validator auth.SessionValidator) func(http.Handler) http.Han
 				return
 			}
 
+			if auth.IsAPIKey(sessionToken) {
+				if keys == nil {
+					apperrors.NewUnauthorizedError("API keys are not accepted for this endpoint").WriteJSON(w)
+					return
+				}
+
+				key, session, err := keys.ValidateAPIKey(r.Context(), sessionToken)
+				if errors.Is(err, auth.ErrInvalidAPIKey) {
+					apperrors.NewUnauthorizedError("invalid or expired API key").WriteJSON(w)
+					return
+				}
+				if err != nil {
+					apperrors.NewServiceUnavailableError("Kratos", err).WriteJSON(w)
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), SessionContextKey, session)
+				ctx = context.WithValue(ctx, APIKeyContextKey, key)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
 			session, err := validator.ValidateSession(r.Context(), sessionToken)
 			if err != nil {
 				apperrors.NewUnauthorizedError("invalid or expired session").WriteJSON(w)
@@ -83,6 +134,41 @@ func OptionalAuthMiddleware(validator auth.SessionValidator) func(http.Handler)
 	}
 }
 
+// RequireAAL2 rejects sessions that have not completed a second factor.
+// It must run after AuthMiddleware.
+func RequireAAL2(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, ok := GetSessionFromContext(r.Context())
+		if !ok {
+			apperrors.NewUnauthorizedError("missing session").WriteJSON(w)
+			return
+		}
+
+		switch session.GetAuthenticatorAssuranceLevel() {
+		case ory.AUTHENTICATORASSURANCELEVEL_AAL2, ory.AUTHENTICATORASSURANCELEVEL_AAL3:
+			next.ServeHTTP(w, r)
+		default:
+			apperrors.NewMFARequiredError("second factor required: complete an aal2 login").WriteJSON(w)
+		}
+	})
+}
+
+// RequireScope rejects API keys that were not granted scope. Requests made
+// with a session token are not scoped and pass through. It must run after
+// AuthMiddlewareWithAPIKeys.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if key, ok := GetAPIKeyFromContext(r.Context()); ok && !key.HasScope(scope) {
+				apperrors.NewForbiddenError("API key lacks scope: " + scope).WriteJSON(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // RequestLogger logs incoming requests
 func RequestLogger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
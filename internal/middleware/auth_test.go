@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/davegermiquet/kratos-chi-ollama/internal/auth"
 	ory "github.com/ory/client-go"
 )
 
@@ -22,6 +23,18 @@ func (m *MockSessionValidator) ValidateSession(ctx context.Context, token string
 	return nil, errors.New("not implemented")
 }
 
+// MockAPIKeyValidator is a mock implementation of APIKeyValidator
+type MockAPIKeyValidator struct {
+	ValidateFunc func(ctx context.Context, key string) (*auth.APIKey, *ory.Session, error)
+}
+
+func (m *MockAPIKeyValidator) ValidateAPIKey(ctx context.Context, key string) (*auth.APIKey, *ory.Session, error) {
+	if m.ValidateFunc != nil {
+		return m.ValidateFunc(ctx, key)
+	}
+	return nil, nil, errors.New("not implemented")
+}
+
 func TestExtractSessionToken(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -53,11 +66,31 @@ func TestExtractSessionToken(t *testing.T) {
 			headers:   map[string]string{"Authorization": "Basic abc"},
 			wantToken: "",
 		},
+		{
+			name:      "X-API-Key header",
+			headers:   map[string]string{"X-API-Key": "kco_identity_key_secret"},
+			wantToken: "kco_identity_key_secret",
+		},
+		{
+			name:      "session token takes precedence over API key",
+			headers:   map[string]string{"X-API-Key": "kco_identity_key_secret", "Authorization": "Bearer bearertoken456"},
+			wantToken: "bearertoken456",
+		},
 		{
 			name:      "empty X-Session-Token",
 			headers:   map[string]string{"X-Session-Token": ""},
 			wantToken: "",
 		},
+		{
+			name:      "session cookie",
+			headers:   map[string]string{"Cookie": "ory_kratos_session=cookie789; kco_csrf=abc"},
+			wantToken: "ory_kratos_session=cookie789",
+		},
+		{
+			name:      "session token takes precedence over cookie",
+			headers:   map[string]string{"Cookie": "ory_kratos_session=cookie789", "X-Session-Token": "token123"},
+			wantToken: "token123",
+		},
 	}
 
 	for _, tt := range tests {
@@ -156,6 +189,14 @@ func TestAuthMiddleware(t *testing.T) {
 			wantStatus:     http.StatusUnauthorized,
 			wantNextCalled: false,
 		},
+		{
+			name:           "API key not accepted",
+			token:          "kco_identity-123_key_secret",
+			validateResult: &ory.Session{Id: "session123"},
+			validateErr:    nil,
+			wantStatus:     http.StatusUnauthorized,
+			wantNextCalled: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -194,6 +235,74 @@ func TestAuthMiddleware(t *testing.T) {
 	}
 }
 
+func TestAuthMiddlewareWithAPIKeys(t *testing.T) {
+	apiKey := &auth.APIKey{ID: "key-1", Scopes: []string{auth.ScopeLLMChat}}
+	keySession := &ory.Session{Id: "api_key:key-1", Identity: &ory.Identity{Id: "identity-123"}}
+
+	tests := []struct {
+		name       string
+		token      string
+		keyErr     error
+		wantStatus int
+		wantAPIKey bool
+	}{
+		{name: "valid API key", token: "kco_identity-123_key-1_secret", wantStatus: http.StatusOK, wantAPIKey: true},
+		{name: "invalid API key", token: "kco_identity-123_key-1_wrong", keyErr: auth.ErrInvalidAPIKey, wantStatus: http.StatusUnauthorized},
+		{name: "kratos unavailable", token: "kco_identity-123_key-1_secret", keyErr: errors.New("connection refused"), wantStatus: http.StatusServiceUnavailable},
+		{name: "session token", token: "valid-token", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotKey *auth.APIKey
+			var gotSession *ory.Session
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotKey, _ = GetAPIKeyFromContext(r.Context())
+				gotSession, _ = GetSessionFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			})
+
+			sessions := &MockSessionValidator{
+				ValidateFunc: func(ctx context.Context, token string) (*ory.Session, error) {
+					return &ory.Session{Id: "session123"}, nil
+				},
+			}
+			keys := &MockAPIKeyValidator{
+				ValidateFunc: func(ctx context.Context, key string) (*auth.APIKey, *ory.Session, error) {
+					if tt.keyErr != nil {
+						return nil, nil, tt.keyErr
+					}
+					return apiKey, keySession, nil
+				},
+			}
+
+			handler := AuthMiddlewareWithAPIKeys(sessions, keys)(next)
+
+			req := httptest.NewRequest(http.MethodPost, "/llm/chat", nil)
+			req.Header.Set("X-API-Key", tt.token)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("AuthMiddlewareWithAPIKeys() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if (gotKey != nil) != tt.wantAPIKey {
+				t.Errorf("AuthMiddlewareWithAPIKeys() API key in context = %v, want %v", gotKey != nil, tt.wantAPIKey)
+			}
+
+			if tt.wantAPIKey && gotSession != keySession {
+				t.Errorf("AuthMiddlewareWithAPIKeys() session = %+v, want API key session", gotSession)
+			}
+		})
+	}
+}
+
 func TestOptionalAuthMiddleware(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -268,6 +377,108 @@ func TestOptionalAuthMiddleware(t *testing.T) {
 	}
 }
 
+func TestRequireAAL2(t *testing.T) {
+	aal := func(level ory.AuthenticatorAssuranceLevel) *ory.Session {
+		return &ory.Session{Id: "session123", AuthenticatorAssuranceLevel: &level}
+	}
+
+	tests := []struct {
+		name           string
+		session        *ory.Session
+		wantStatus     int
+		wantNextCalled bool
+	}{
+		{
+			name:           "aal2 session",
+			session:        aal(ory.AUTHENTICATORASSURANCELEVEL_AAL2),
+			wantStatus:     http.StatusOK,
+			wantNextCalled: true,
+		},
+		{
+			name:           "aal3 session",
+			session:        aal(ory.AUTHENTICATORASSURANCELEVEL_AAL3),
+			wantStatus:     http.StatusOK,
+			wantNextCalled: true,
+		},
+		{
+			name:           "aal1 session",
+			session:        aal(ory.AUTHENTICATORASSURANCELEVEL_AAL1),
+			wantStatus:     http.StatusForbidden,
+			wantNextCalled: false,
+		},
+		{
+			name:           "session without level",
+			session:        &ory.Session{Id: "session123"},
+			wantStatus:     http.StatusForbidden,
+			wantNextCalled: false,
+		},
+		{
+			name:           "no session",
+			session:        nil,
+			wantStatus:     http.StatusUnauthorized,
+			wantNextCalled: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nextCalled := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				nextCalled = true
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tt.session != nil {
+				req = req.WithContext(context.WithValue(req.Context(), SessionContextKey, tt.session))
+			}
+
+			w := httptest.NewRecorder()
+			RequireAAL2(next).ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("RequireAAL2() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if nextCalled != tt.wantNextCalled {
+				t.Errorf("RequireAAL2() next called = %v, want %v", nextCalled, tt.wantNextCalled)
+			}
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        *auth.APIKey
+		wantStatus int
+	}{
+		{name: "session token", wantStatus: http.StatusOK},
+		{name: "API key with scope", key: &auth.APIKey{Scopes: []string{auth.ScopeLLMChat}}, wantStatus: http.StatusOK},
+		{name: "API key without scope", key: &auth.APIKey{Scopes: []string{auth.ScopeLLMGenerate}}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireScope(auth.ScopeLLMChat)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/llm/chat", nil)
+			if tt.key != nil {
+				req = req.WithContext(context.WithValue(req.Context(), APIKeyContextKey, tt.key))
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("RequireScope() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
 func TestRecoverer(t *testing.T) {
 	tests := []struct {
 		name       string
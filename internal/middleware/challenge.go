@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/challenge"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// ChallengeHeaderName is the header clients send a solved challenge in
+const ChallengeHeaderName = "X-Challenge-Response"
+
+// RequireChallenge demands a solved challenge in the X-Challenge-Response
+// header once guard considers the client IP risky. Requests are counted
+// whether or not they carry a response, so solving one challenge does not
+// reset the client's count.
+func RequireChallenge(guard *challenge.Guard, verifier challenge.Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := ClientIP(r)
+			if !guard.Hit(ip) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			response := r.Header.Get(ChallengeHeaderName)
+			if response == "" {
+				apperrors.NewChallengeRequiredError("challenge required").WriteJSON(w)
+				return
+			}
+
+			if err := verifier.Verify(r.Context(), response, ip); err != nil {
+				if errors.Is(err, challenge.ErrChallengeFailed) {
+					apperrors.NewChallengeRequiredError("challenge failed").WriteJSON(w)
+					return
+				}
+				apperrors.NewServiceUnavailableError("challenge verifier", err).WriteJSON(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP is the request's remote address without its port. With
+// TRUST_PROXY_HEADERS set, RealIP has already replaced it with the forwarded
+// client address; otherwise forwarding headers are ignored, so clients cannot
+// pick the IP that throttling and challenges are keyed on.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
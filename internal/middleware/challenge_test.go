@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/challenge"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// stubVerifier accepts the response "solved"
+type stubVerifier struct {
+	err error
+}
+
+func (s *stubVerifier) Challenge(ctx context.Context) (*challenge.Challenge, error) {
+	return &challenge.Challenge{Type: "stub"}, nil
+}
+
+func (s *stubVerifier) Verify(ctx context.Context, response, remoteIP string) error {
+	if s.err != nil {
+		return s.err
+	}
+	if response != "solved" {
+		return fmt.Errorf("%w: wrong answer", challenge.ErrChallengeFailed)
+	}
+	return nil
+}
+
+func TestRequireChallenge(t *testing.T) {
+	tests := []struct {
+		name        string
+		threshold   int
+		response    string
+		verifierErr error
+		wantStatus  int
+		wantCode    apperrors.ErrorCode
+	}{
+		{
+			name:       "below threshold",
+			threshold:  5,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing response",
+			threshold:  0,
+			wantStatus: http.StatusForbidden,
+			wantCode:   apperrors.ErrCodeChallengeRequired,
+		},
+		{
+			name:       "wrong response",
+			threshold:  0,
+			response:   "guess",
+			wantStatus: http.StatusForbidden,
+			wantCode:   apperrors.ErrCodeChallengeRequired,
+		},
+		{
+			name:       "solved",
+			threshold:  0,
+			response:   "solved",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "verifier unavailable",
+			threshold:   0,
+			response:    "solved",
+			verifierErr: errors.New("connection refused"),
+			wantStatus:  http.StatusServiceUnavailable,
+			wantCode:    apperrors.ErrCodeServiceUnavail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := challenge.NewGuard(challenge.GuardConfig{Threshold: tt.threshold, Window: time.Minute, MaxEntries: 10})
+			handler := RequireChallenge(guard, &stubVerifier{err: tt.verifierErr})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/registration/flow", nil)
+			req.RemoteAddr = "203.0.113.7:51234"
+			if tt.response != "" {
+				req.Header.Set(ChallengeHeaderName, tt.response)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantCode == "" {
+				return
+			}
+
+			var got struct {
+				Error apperrors.AppError `json:"error"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got.Error.Code != tt.wantCode {
+				t.Errorf("error code = %s, want %s", got.Error.Code, tt.wantCode)
+			}
+		})
+	}
+}
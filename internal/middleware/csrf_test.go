@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRFProtection(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		headers       map[string]string
+		wantStatus    int
+		wantNewCookie bool
+	}{
+		{
+			name:       "session token request",
+			method:     http.MethodPost,
+			headers:    map[string]string{"X-Session-Token": "token123"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "cookie session with matching token",
+			method:     http.MethodPost,
+			headers:    map[string]string{"Cookie": "ory_kratos_session=abc; kco_csrf=csrf123", "X-CSRF-Token": "csrf123"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "cookie session without token",
+			method:     http.MethodPost,
+			headers:    map[string]string{"Cookie": "ory_kratos_session=abc; kco_csrf=csrf123"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "cookie session with wrong token",
+			method:     http.MethodDelete,
+			headers:    map[string]string{"Cookie": "ory_kratos_session=abc; kco_csrf=csrf123", "X-CSRF-Token": "other"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:          "cookie session without CSRF cookie",
+			method:        http.MethodPost,
+			headers:       map[string]string{"Cookie": "ory_kratos_session=abc", "X-CSRF-Token": "guess"},
+			wantStatus:    http.StatusForbidden,
+			wantNewCookie: true,
+		},
+		{
+			name:          "safe method issues CSRF cookie",
+			method:        http.MethodGet,
+			headers:       map[string]string{"Cookie": "ory_kratos_session=abc"},
+			wantStatus:    http.StatusOK,
+			wantNewCookie: true,
+		},
+		{
+			name:       "session token wins over cookie",
+			method:     http.MethodPost,
+			headers:    map[string]string{"Cookie": "ory_kratos_session=abc", "Authorization": "Bearer token123"},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CSRFProtection(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(tt.method, "/test", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("CSRFProtection() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			var issued *http.Cookie
+			for _, cookie := range w.Result().Cookies() {
+				if cookie.Name == CSRFCookieName {
+					issued = cookie
+				}
+			}
+
+			if (issued != nil) != tt.wantNewCookie {
+				t.Fatalf("CSRFProtection() issued cookie = %v, want %v", issued != nil, tt.wantNewCookie)
+			}
+			if issued != nil && (issued.Value == "" || !issued.Secure || issued.HttpOnly) {
+				t.Errorf("CSRFProtection() cookie = %+v, want a Secure script-readable token", issued)
+			}
+		})
+	}
+}
+
+func TestIgnoreSessionCookie(t *testing.T) {
+	var gotToken, gotCookie string
+	handler := IgnoreSessionCookie(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotToken = ExtractSessionToken(r)
+		if cookie, err := r.Cookie("other"); err == nil {
+			gotCookie = cookie.Value
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/test", nil)
+	req.Header.Set("Cookie", "ory_kratos_session=abc; other=keep")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if gotToken != "" {
+		t.Errorf("IgnoreSessionCookie() credential = %q, want none", gotToken)
+	}
+	if gotCookie != "keep" {
+		t.Errorf("IgnoreSessionCookie() other cookie = %q, want keep", gotCookie)
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/authz"
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// AssertRelation checks that the signed-in identity has relation on
+// namespace:object. When it does not, the error response is written and
+// false is returned, so handlers can return early. It must run after
+// AuthMiddleware.
+func AssertRelation(w http.ResponseWriter, r *http.Request, checker authz.PermissionChecker, namespace, object, relation string) bool {
+	session, ok := GetSessionFromContext(r.Context())
+	if !ok || session.Identity == nil {
+		apperrors.NewUnauthorizedError("missing session").WriteJSON(w)
+		return false
+	}
+
+	tuple := authz.NewRelationTuple(namespace, object, relation, session.Identity.Id)
+	allowed, err := checker.Check(r.Context(), tuple)
+	if err != nil {
+		apperrors.NewServiceUnavailableError("Keto", err).WriteJSON(w)
+		return false
+	}
+
+	if !allowed {
+		apperrors.NewForbiddenError("requires " + relation + " on " + namespace + ":" + object).WriteJSON(w)
+		return false
+	}
+
+	return true
+}
+
+// RequireRelation rejects callers without relation on the object named by
+// the URL parameter param, e.g. RequireRelation(checker, "conversations",
+// "viewer", "id") on /conversations/{id}. Mount it with chi's With so the
+// URL parameters are routed before it runs.
+func RequireRelation(checker authz.PermissionChecker, namespace, relation, param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			object := chi.URLParam(r, param)
+			if object == "" {
+				apperrors.NewBadRequestError(param + " is required").WriteJSON(w)
+				return
+			}
+
+			if !AssertRelation(w, r, checker, namespace, object, relation) {
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
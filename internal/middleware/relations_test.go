@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/davegermiquet/kratos-chi-ollama/internal/authz"
+)
+
+type failingChecker struct{}
+
+func (failingChecker) Check(ctx context.Context, tuple authz.RelationTuple) (bool, error) {
+	return false, errors.New("keto unavailable")
+}
+
+func TestRequireRelation(t *testing.T) {
+	store := authz.NewMemoryStore(authz.NewRelationTuple("conversations", "conv-1", "viewer", "identity-123"))
+
+	tests := []struct {
+		name       string
+		checker    authz.PermissionChecker
+		object     string
+		noSession  bool
+		wantStatus int
+	}{
+		{name: "allowed", checker: store, object: "conv-1", wantStatus: http.StatusOK},
+		{name: "denied", checker: store, object: "conv-2", wantStatus: http.StatusForbidden},
+		{name: "no session", checker: store, object: "conv-1", noSession: true, wantStatus: http.StatusUnauthorized},
+		{name: "checker error", checker: failingChecker{}, object: "conv-1", wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := chi.NewRouter()
+			r.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if !tt.noSession {
+						session := sessionWithIdentity(nil, nil)
+						r = r.WithContext(context.WithValue(r.Context(), SessionContextKey, session))
+					}
+					next.ServeHTTP(w, r)
+				})
+			})
+			r.With(RequireRelation(tt.checker, "conversations", "viewer", "id")).Get("/conversations/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/conversations/"+tt.object, nil)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("RequireRelation() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
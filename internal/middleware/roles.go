@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+	ory "github.com/ory/client-go"
+)
+
+// DefaultRolesPath is where roles are read from when no path is configured
+const DefaultRolesPath = "metadata_public.roles"
+
+// Authorizer checks the caller's roles, read from the identity attached to
+// the session by AuthMiddleware. Its middlewares must run after AuthMiddleware.
+type Authorizer struct {
+	rolesPath       []string
+	routeRoles      []routeRule
+	rolePermissions map[string][]string
+}
+
+type routeRule struct {
+	prefix string
+	roles  []string
+}
+
+// NewAuthorizer creates an authorizer.
+//
+// rolesPath is a dotted path into the identity starting at metadata_public,
+// e.g. "metadata_public.roles". Traits are never read: identities write their
+// own through registration and settings, so any role there is self-granted. routeRoles maps path prefixes to the
+// roles allowed under them; the longest matching prefix wins. rolePermissions
+// maps each role to the permissions it grants.
+func NewAuthorizer(rolesPath string, routeRoles, rolePermissions map[string][]string) *Authorizer {
+	if rolesPath == "" {
+		rolesPath = DefaultRolesPath
+	}
+
+	rules := make([]routeRule, 0, len(routeRoles))
+	for prefix, roles := range routeRoles {
+		rules = append(rules, routeRule{prefix: prefix, roles: roles})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return len(rules[i].prefix) > len(rules[j].prefix)
+	})
+
+	return &Authorizer{
+		rolesPath:       strings.Split(rolesPath, "."),
+		routeRoles:      rules,
+		rolePermissions: rolePermissions,
+	}
+}
+
+// Roles returns the roles of the session's identity
+func (a *Authorizer) Roles(session *ory.Session) []string {
+	if session == nil || session.Identity == nil || len(a.rolesPath) < 2 {
+		return nil
+	}
+
+	if a.rolesPath[0] != "metadata_public" {
+		return nil
+	}
+
+	var value interface{} = session.Identity.GetMetadataPublic()
+
+	for _, key := range a.rolesPath[1:] {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = obj[key]
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		roles := make([]string, 0, len(v))
+		for _, item := range v {
+			if role, ok := item.(string); ok {
+				roles = append(roles, role)
+			}
+		}
+		return roles
+	}
+	return nil
+}
+
+// HasRole reports whether the session has any of the given roles
+func (a *Authorizer) HasRole(session *ory.Session, roles ...string) bool {
+	for _, have := range a.Roles(session) {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasPermission reports whether any of the session's roles grants the permission
+func (a *Authorizer) HasPermission(session *ory.Session, permission string) bool {
+	for _, role := range a.Roles(session) {
+		for _, granted := range a.rolePermissions[role] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RequireRole rejects callers that have none of the given roles
+func (a *Authorizer) RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session, ok := GetSessionFromContext(r.Context())
+			if !ok {
+				apperrors.NewUnauthorizedError("missing session").WriteJSON(w)
+				return
+			}
+
+			if !a.HasRole(session, roles...) {
+				apperrors.NewForbiddenError("requires role: " + strings.Join(roles, " or ")).WriteJSON(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermission rejects callers whose roles do not grant the permission
+func (a *Authorizer) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session, ok := GetSessionFromContext(r.Context())
+			if !ok {
+				apperrors.NewUnauthorizedError("missing session").WriteJSON(w)
+				return
+			}
+
+			if !a.HasPermission(session, permission) {
+				apperrors.NewForbiddenError("requires permission: " + permission).WriteJSON(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRouteRoles applies the configured route roles to the request path.
+// Paths without a matching rule are allowed through.
+func (a *Authorizer) RequireRouteRoles(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, rule := range a.routeRoles {
+			if !matchesPrefix(r.URL.Path, rule.prefix) {
+				continue
+			}
+			a.RequireRole(rule.roles...)(next).ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// matchesPrefix reports whether path is prefix or lies below it
+func matchesPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
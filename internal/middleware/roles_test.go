@@ -0,0 +1,229 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ory "github.com/ory/client-go"
+)
+
+func sessionWithIdentity(metadata map[string]interface{}, traits interface{}) *ory.Session {
+	return &ory.Session{
+		Identity: &ory.Identity{
+			Id:             "identity-123",
+			MetadataPublic: metadata,
+			Traits:         traits,
+		},
+	}
+}
+
+func TestAuthorizer_Roles(t *testing.T) {
+	tests := []struct {
+		name      string
+		rolesPath string
+		session   *ory.Session
+		want      []string
+	}{
+		{
+			name:    "metadata_public list",
+			session: sessionWithIdentity(map[string]interface{}{"roles": []interface{}{"admin", "user"}}, nil),
+			want:    []string{"admin", "user"},
+		},
+		{
+			name:    "metadata_public single role",
+			session: sessionWithIdentity(map[string]interface{}{"roles": "admin"}, nil),
+			want:    []string{"admin"},
+		},
+		{
+			name:      "nested metadata path",
+			rolesPath: "metadata_public.access.roles",
+			session: sessionWithIdentity(map[string]interface{}{
+				"access": map[string]interface{}{"roles": []interface{}{"editor"}},
+			}, nil),
+			want: []string{"editor"},
+		},
+		{
+			name:      "trait path is never read",
+			rolesPath: "traits.roles",
+			session:   sessionWithIdentity(nil, map[string]interface{}{"roles": []interface{}{"admin"}}),
+		},
+		{
+			name:    "no metadata",
+			session: sessionWithIdentity(nil, nil),
+		},
+		{
+			name:    "no identity",
+			session: &ory.Session{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authorizer := NewAuthorizer(tt.rolesPath, nil, nil)
+
+			got := authorizer.Roles(tt.session)
+
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Roles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_RequireRole(t *testing.T) {
+	authorizer := NewAuthorizer("", nil, nil)
+
+	tests := []struct {
+		name       string
+		session    *ory.Session
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "has role",
+			session:    sessionWithIdentity(map[string]interface{}{"roles": []interface{}{"admin"}}, nil),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "missing role",
+			session:    sessionWithIdentity(map[string]interface{}{"roles": []interface{}{"user"}}, nil),
+			wantStatus: http.StatusForbidden,
+			wantCode:   "FORBIDDEN",
+		},
+		{
+			name:       "no session",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := authorizer.RequireRole("admin", "owner")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.session != nil {
+				req = req.WithContext(context.WithValue(req.Context(), SessionContextKey, tt.session))
+			}
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("RequireRole() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantCode != "" && !strings.Contains(w.Body.String(), tt.wantCode) {
+				t.Errorf("RequireRole() body = %s, want code %s", w.Body.String(), tt.wantCode)
+			}
+		})
+	}
+}
+
+// Traits are written by the identity itself through registration and
+// /users/settings/profile, so a role placed there must never authorize
+func TestAuthorizer_TraitsCannotGrantRoles(t *testing.T) {
+	session := sessionWithIdentity(nil, map[string]interface{}{
+		"email": "user@example.com",
+		"roles": []interface{}{"admin"},
+	})
+
+	for _, rolesPath := range []string{"", "traits.roles"} {
+		t.Run("path "+rolesPath, func(t *testing.T) {
+			authorizer := NewAuthorizer(rolesPath, nil, nil)
+			handler := authorizer.RequireRole("admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/identities", nil)
+			req = req.WithContext(context.WithValue(req.Context(), SessionContextKey, session))
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusForbidden {
+				t.Errorf("RequireRole() status = %d, want %d", w.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_RequirePermission(t *testing.T) {
+	authorizer := NewAuthorizer("", nil, map[string][]string{
+		"admin": {"identities:write", "llm:chat"},
+		"user":  {"llm:chat"},
+	})
+
+	tests := []struct {
+		name       string
+		roles      []interface{}
+		permission string
+		wantStatus int
+	}{
+		{name: "granted by role", roles: []interface{}{"user"}, permission: "llm:chat", wantStatus: http.StatusOK},
+		{name: "not granted", roles: []interface{}{"user"}, permission: "identities:write", wantStatus: http.StatusForbidden},
+		{name: "unknown role", roles: []interface{}{"guest"}, permission: "llm:chat", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := authorizer.RequirePermission(tt.permission)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			session := sessionWithIdentity(map[string]interface{}{"roles": tt.roles}, nil)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req = req.WithContext(context.WithValue(req.Context(), SessionContextKey, session))
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("RequirePermission() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_RequireRouteRoles(t *testing.T) {
+	authorizer := NewAuthorizer("", map[string][]string{
+		"/api/v1/admin":         {"admin"},
+		"/api/v1/admin/reports": {"admin", "auditor"},
+	}, nil)
+
+	tests := []struct {
+		name       string
+		path       string
+		role       string
+		wantStatus int
+	}{
+		{name: "unrestricted path", path: "/api/v1/app/llm/chat", role: "user", wantStatus: http.StatusOK},
+		{name: "admin path as admin", path: "/api/v1/admin/identities", role: "admin", wantStatus: http.StatusOK},
+		{name: "admin path as user", path: "/api/v1/admin/identities", role: "user", wantStatus: http.StatusForbidden},
+		{name: "longest prefix wins", path: "/api/v1/admin/reports/daily", role: "auditor", wantStatus: http.StatusOK},
+		{name: "prefix matches whole segments", path: "/api/v1/administrator", role: "user", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := authorizer.RequireRouteRoles(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			session := sessionWithIdentity(map[string]interface{}{"roles": []interface{}{tt.role}}, nil)
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req = req.WithContext(context.WithValue(req.Context(), SessionContextKey, session))
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("RequireRouteRoles(%s) status = %d, want %d", tt.path, w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
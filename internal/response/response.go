@@ -3,6 +3,8 @@ package response
 import (
 	"encoding/json"
 	"net/http"
+
+	ory "github.com/ory/client-go"
 )
 
 // JSON writes a JSON response
@@ -49,6 +51,188 @@ type RegistrationFlowResponse struct {
 	Fields    []map[string]interface{} `json:"fields"`
 }
 
+// LoginFlowResponse represents a clean browser login flow response
+type LoginFlowResponse struct {
+	FlowID    string                   `json:"flow_id"`
+	CSRFToken string                   `json:"csrf_token"`
+	ExpiresAt string                   `json:"expires_at,omitempty"`
+	Action    string                   `json:"action"`
+	Method    string                   `json:"method"`
+	Fields    []map[string]interface{} `json:"fields"`
+}
+
+// SettingsFlowResponse represents a clean settings flow response
+type SettingsFlowResponse struct {
+	FlowID    string                   `json:"flow_id"`
+	ExpiresAt string                   `json:"expires_at,omitempty"`
+	State     string                   `json:"state"`
+	Fields    []map[string]interface{} `json:"fields"`
+}
+
+// EmailChangeResponse represents an email change awaiting verification.
+// VerificationFlowID is set when a code was sent to the new address.
+type EmailChangeResponse struct {
+	Email              string `json:"email"`
+	EmailVerified      bool   `json:"email_verified"`
+	VerificationFlowID string `json:"verification_flow_id,omitempty"`
+	VerificationSent   bool   `json:"verification_sent"`
+}
+
+// VerificationStatusResponse represents the verification state of an
+// identity's email and verifiable addresses
+type VerificationStatusResponse struct {
+	Email         string                      `json:"email,omitempty"`
+	EmailVerified bool                        `json:"email_verified"`
+	Addresses     []VerifiableAddressResponse `json:"addresses"`
+}
+
+// VerifiableAddressResponse represents one verifiable address
+type VerifiableAddressResponse struct {
+	Value      string `json:"value"`
+	Via        string `json:"via"`
+	Status     string `json:"status"`
+	Verified   bool   `json:"verified"`
+	VerifiedAt string `json:"verified_at,omitempty"`
+}
+
+// TOTPSetupResponse represents a TOTP enrollment flow
+type TOTPSetupResponse struct {
+	FlowID string `json:"flow_id"`
+	QRCode string `json:"qr_code,omitempty"`
+	Secret string `json:"secret,omitempty"`
+	Linked bool   `json:"linked"`
+}
+
+// LookupSecretsResponse represents backup codes in a settings flow
+type LookupSecretsResponse struct {
+	FlowID  string   `json:"flow_id"`
+	Codes   []string `json:"codes,omitempty"`
+	Enabled bool     `json:"enabled"`
+}
+
+// OIDCLoginFlowResponse represents a social sign-in login flow
+type OIDCLoginFlowResponse struct {
+	FlowID                   string   `json:"flow_id"`
+	ExpiresAt                string   `json:"expires_at,omitempty"`
+	Providers                []string `json:"providers"`
+	SessionTokenExchangeCode string   `json:"session_token_exchange_code"`
+}
+
+// OIDCRedirectResponse tells the client where to send the browser
+type OIDCRedirectResponse struct {
+	RedirectBrowserTo string `json:"redirect_browser_to"`
+}
+
+// PasskeyOptionsResponse carries WebAuthn options for the client's authenticator
+type PasskeyOptionsResponse struct {
+	FlowID        string          `json:"flow_id"`
+	Options       json.RawMessage `json:"options"`
+	CredentialIDs []string        `json:"credential_ids,omitempty"`
+}
+
+// SessionResponse represents one of the caller's sessions
+type SessionResponse struct {
+	ID              string                  `json:"id"`
+	Current         bool                    `json:"current"`
+	Active          bool                    `json:"active"`
+	AAL             string                  `json:"aal,omitempty"`
+	Methods         []string                `json:"methods"`
+	AuthenticatedAt string                  `json:"authenticated_at,omitempty"`
+	ExpiresAt       string                  `json:"expires_at,omitempty"`
+	Devices         []SessionDeviceResponse `json:"devices"`
+}
+
+// SessionDeviceResponse represents a device a session was used from
+type SessionDeviceResponse struct {
+	IPAddress string `json:"ip_address,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	Location  string `json:"location,omitempty"`
+}
+
+// SessionListResponse represents the caller's active sessions
+type SessionListResponse struct {
+	Sessions []SessionResponse `json:"sessions"`
+}
+
+// RevokedSessionsResponse reports how many sessions were revoked
+type RevokedSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// IdentityListResponse represents one page of identities from the admin API
+type IdentityListResponse struct {
+	Identities    []ory.Identity `json:"identities"`
+	NextPageToken string         `json:"next_page_token,omitempty"`
+}
+
+// APIKeyResponse represents an API key without its secret
+type APIKeyResponse struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt string   `json:"created_at"`
+	ExpiresAt string   `json:"expires_at,omitempty"`
+	Expired   bool     `json:"expired"`
+}
+
+// APIKeyCreatedResponse represents a new API key. Key is only shown once.
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// APIKeyListResponse represents the caller's API keys
+type APIKeyListResponse struct {
+	Keys []APIKeyResponse `json:"keys"`
+}
+
+// PermissionCheckResponse reports whether the caller holds a relation
+type PermissionCheckResponse struct {
+	Allowed bool `json:"allowed"`
+}
+
+// TokenResponse represents a session exchanged for a JWT
+type TokenResponse struct {
+	Token     string `json:"token"`
+	TokenType string `json:"token_type"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// AccountExportResponse is the archive of all data held about the caller.
+// Data holds application data outside Kratos, keyed by store.
+type AccountExportResponse struct {
+	ExportedAt string                  `json:"exported_at"`
+	Identity   AccountIdentityResponse `json:"identity"`
+	Sessions   []SessionResponse       `json:"sessions"`
+	Data       map[string]interface{}  `json:"data"`
+}
+
+// AccountIdentityResponse represents the caller's Kratos identity without
+// credential secrets or admin metadata
+type AccountIdentityResponse struct {
+	ID                  string                          `json:"id"`
+	SchemaID            string                          `json:"schema_id"`
+	State               string                          `json:"state,omitempty"`
+	Traits              interface{}                     `json:"traits"`
+	MetadataPublic      map[string]interface{}          `json:"metadata_public,omitempty"`
+	VerifiableAddresses []ory.VerifiableIdentityAddress `json:"verifiable_addresses"`
+	RecoveryAddresses   []ory.RecoveryIdentityAddress   `json:"recovery_addresses"`
+	CredentialTypes     []string                        `json:"credential_types"`
+	CreatedAt           string                          `json:"created_at,omitempty"`
+	UpdatedAt           string                          `json:"updated_at,omitempty"`
+}
+
+// ChallengeResponse tells the client whether its next request to a guarded
+// endpoint needs a solved challenge, and how to produce one
+type ChallengeResponse struct {
+	Required   bool   `json:"required"`
+	Type       string `json:"type"`
+	SiteKey    string `json:"site_key,omitempty"`
+	Token      string `json:"token,omitempty"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	ExpiresAt  string `json:"expires_at,omitempty"`
+}
+
 // HealthResponse represents health check response
 type HealthResponse struct {
 	Status  string `json:"status"`
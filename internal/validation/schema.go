@@ -0,0 +1,307 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
+)
+
+// IdentitySchema validates identity traits against a Kratos identity schema,
+// so traits added to the schema need no Go changes
+type IdentitySchema struct {
+	schema *jsonschema.Schema
+}
+
+// ParseIdentitySchema compiles a Kratos identity schema: the JSON schema of
+// an identity, whose properties.traits describes the traits
+func ParseIdentitySchema(raw []byte) (*IdentitySchema, error) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("identity.schema.json", bytes.NewReader(raw)); err != nil {
+		return nil, fmt.Errorf("invalid identity schema: %w", err)
+	}
+
+	compiled, err := compiler.Compile("identity.schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity schema: %w", err)
+	}
+
+	return &IdentitySchema{schema: compiled}, nil
+}
+
+// ValidateTraits checks traits against the schema. Each violation is listed
+// in the error's fields under the trait's path, e.g. "traits.name.first".
+func (s *IdentitySchema) ValidateTraits(traits map[string]interface{}) *apperrors.AppError {
+	err := s.schema.Validate(map[string]interface{}{"traits": traits})
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return apperrors.NewValidationError("invalid traits", err.Error())
+	}
+
+	return apperrors.NewFieldValidationError("invalid traits", schemaFieldErrors(validationErr))
+}
+
+// schemaFieldErrors flattens a schema validation error into one field error
+// per failing keyword
+func schemaFieldErrors(err *jsonschema.ValidationError) []apperrors.FieldError {
+	if len(err.Causes) > 0 {
+		var fields []apperrors.FieldError
+		for _, cause := range err.Causes {
+			fields = append(fields, schemaFieldErrors(cause)...)
+		}
+		return fields
+	}
+
+	field := schemaFieldName(err.InstanceLocation)
+
+	// required is reported on the parent object; report it on each missing trait
+	if strings.HasSuffix(err.KeywordLocation, "/required") {
+		var fields []apperrors.FieldError
+		for _, name := range strings.Split(strings.TrimPrefix(err.Message, "missing properties: "), ", ") {
+			name = strings.Trim(name, "'")
+			fields = append(fields, apperrors.FieldError{
+				Field:   field + "." + name,
+				Message: name + " is required",
+			})
+		}
+		return fields
+	}
+
+	return []apperrors.FieldError{{Field: field, Message: err.Message}}
+}
+
+// schemaFieldName turns a JSON pointer such as /traits/name/first into a
+// dotted field name
+func schemaFieldName(pointer string) string {
+	if pointer == "" {
+		return "traits"
+	}
+
+	parts := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	for i, part := range parts {
+		parts[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// ValidateSchemaRegistrationInput validates a registration request whose
+// traits are checked against schema rather than the built-in fields. Traits
+// are read from a "traits" object; requests without one have their email,
+// first_name and last_name fields mapped onto the default schema's traits.
+func ValidateSchemaRegistrationInput(body io.Reader, schema *IdentitySchema) (*RegistrationInput, *apperrors.AppError) {
+	data, appErr := decodeSchemaRegistrationBody(body)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	password, ok := data["pass"].(string)
+	if !ok || password == "" {
+		return nil, apperrors.NewValidationError("password is required", "")
+	}
+
+	if appErr := validatePasswordLength(password); appErr != nil {
+		return nil, appErr
+	}
+
+	traits, appErr := schemaRegistrationTraits(data, schema)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	csrfToken, _ := data["csrf_token"].(string)
+
+	return &RegistrationInput{
+		Password:  password,
+		Traits:    traits,
+		CSRFToken: csrfToken,
+	}, nil
+}
+
+// ValidateSchemaCodeRegistrationInput is ValidateCodeRegistrationInput with
+// traits read and checked like ValidateSchemaRegistrationInput
+func ValidateSchemaCodeRegistrationInput(body io.Reader, schema *IdentitySchema, requireCode bool) (*CodeRegistrationInput, *apperrors.AppError) {
+	data, appErr := decodeSchemaRegistrationBody(body)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	traits, appErr := schemaRegistrationTraits(data, schema)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	code, _ := data["code"].(string)
+	code, appErr = validateOneTimeCode(code, requireCode)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return &CodeRegistrationInput{Traits: traits, Code: code}, nil
+}
+
+// ValidateSchemaPasskeyRegistrationInput is ValidatePasskeyRegistrationInput
+// with traits read and checked like ValidateSchemaRegistrationInput
+func ValidateSchemaPasskeyRegistrationInput(body io.Reader, schema *IdentitySchema, requireCredential bool) (*PasskeyRegistrationInput, *apperrors.AppError) {
+	data, appErr := decodeSchemaRegistrationBody(body)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	traits, appErr := schemaRegistrationTraits(data, schema)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	input := &PasskeyRegistrationInput{Traits: traits}
+
+	if requireCredential {
+		var raw json.RawMessage
+		if credential, ok := data["credential"]; ok {
+			raw, _ = json.Marshal(credential)
+		}
+
+		credential, appErr := validateCredential(raw)
+		if appErr != nil {
+			return nil, appErr
+		}
+		input.Credential = credential
+	}
+
+	return input, nil
+}
+
+// ValidateSchemaCreateIdentityInput is ValidateCreateIdentityInput with traits
+// read and checked like ValidateSchemaRegistrationInput
+func ValidateSchemaCreateIdentityInput(body io.Reader, schema *IdentitySchema) (*CreateIdentityInput, *apperrors.AppError) {
+	data, appErr := decodeSchemaRegistrationBody(body)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	password, _ := data["password"].(string)
+	if password != "" {
+		if appErr := validatePasswordLength(password); appErr != nil {
+			return nil, appErr
+		}
+	}
+
+	traits, appErr := schemaRegistrationTraits(data, schema)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return &CreateIdentityInput{Traits: traits, Password: password}, nil
+}
+
+// decodeSchemaRegistrationBody decodes a registration body, keeping numbers
+// as json.Number so integer traits validate
+func decodeSchemaRegistrationBody(body io.Reader) (map[string]interface{}, *apperrors.AppError) {
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+
+	var data map[string]interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+	return data, nil
+}
+
+// schemaRegistrationTraits reads the traits of a registration body, or maps
+// its legacy fields onto them, and checks them against schema
+func schemaRegistrationTraits(data map[string]interface{}, schema *IdentitySchema) (map[string]interface{}, *apperrors.AppError) {
+	var traits map[string]interface{}
+	if raw, ok := data["traits"]; ok {
+		traits, ok = raw.(map[string]interface{})
+		if !ok {
+			return nil, apperrors.NewValidationError("traits must be an object", "")
+		}
+	} else {
+		traits = legacyRegistrationTraits(data)
+	}
+
+	if err := schema.ValidateTraits(traits); err != nil {
+		return nil, err
+	}
+	return traits, nil
+}
+
+// legacyRegistrationTraits maps the email, first_name and last_name request
+// fields that are present onto the default schema's traits
+func legacyRegistrationTraits(data map[string]interface{}) map[string]interface{} {
+	traits := make(map[string]interface{})
+	if email, ok := data["email"].(string); ok {
+		traits["email"] = email
+	}
+
+	name := make(map[string]interface{})
+	if firstName, ok := data["first_name"].(string); ok {
+		name["first"] = firstName
+	}
+	if lastName, ok := data["last_name"].(string); ok {
+		name["last"] = lastName
+	}
+	if len(name) > 0 {
+		traits["name"] = name
+	}
+
+	return traits
+}
+
+// IdentitySchemaLoader loads the identity schema on first use and reloads it
+// once it is older than the refresh interval. A failed reload keeps the last
+// schema that loaded.
+type IdentitySchemaLoader struct {
+	load    func(ctx context.Context) ([]byte, error)
+	refresh time.Duration
+
+	mu       sync.Mutex
+	schema   *IdentitySchema
+	loadedAt time.Time
+}
+
+// NewIdentitySchemaLoader creates a loader reading the schema with load. A
+// refresh of 0 loads the schema once.
+func NewIdentitySchemaLoader(load func(ctx context.Context) ([]byte, error), refresh time.Duration) *IdentitySchemaLoader {
+	return &IdentitySchemaLoader{load: load, refresh: refresh}
+}
+
+// Schema returns the current identity schema
+func (l *IdentitySchemaLoader) Schema(ctx context.Context) (*IdentitySchema, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.schema != nil && (l.refresh <= 0 || time.Since(l.loadedAt) < l.refresh) {
+		return l.schema, nil
+	}
+
+	raw, err := l.load(ctx)
+	var schema *IdentitySchema
+	if err == nil {
+		schema, err = ParseIdentitySchema(raw)
+	}
+
+	if err != nil {
+		if l.schema != nil {
+			// Retry after another interval rather than on every request
+			l.loadedAt = time.Now()
+			return l.schema, nil
+		}
+		return nil, err
+	}
+
+	l.schema, l.loadedAt = schema, time.Now()
+	return schema, nil
+}
@@ -0,0 +1,264 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testIdentitySchema is the default identity schema from values.yaml with an
+// optional phone trait added
+const testIdentitySchema = `{
+  "$id": "https://schemas.ory.sh/presets/kratos/quickstart/email-password/identity.schema.json",
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "Person",
+  "type": "object",
+  "properties": {
+    "traits": {
+      "type": "object",
+      "properties": {
+        "email": {
+          "type": "string",
+          "format": "email",
+          "minLength": 3,
+          "ory.sh/kratos": {"credentials": {"password": {"identifier": true}}}
+        },
+        "phone": {"type": "string", "pattern": "^\\+[0-9]{7,15}$"},
+        "name": {
+          "type": "object",
+          "properties": {
+            "first": {"type": "string"},
+            "last": {"type": "string"}
+          }
+        }
+      },
+      "required": ["email"],
+      "additionalProperties": false
+    }
+  }
+}`
+
+func mustParseIdentitySchema(t *testing.T) *IdentitySchema {
+	t.Helper()
+
+	schema, err := ParseIdentitySchema([]byte(testIdentitySchema))
+	if err != nil {
+		t.Fatalf("ParseIdentitySchema() error = %v", err)
+	}
+	return schema
+}
+
+func TestParseIdentitySchema_Invalid(t *testing.T) {
+	if _, err := ParseIdentitySchema([]byte(`{"type": 42}`)); err == nil {
+		t.Error("ParseIdentitySchema() expected error for an invalid schema, got nil")
+	}
+}
+
+func TestValidateSchemaRegistrationInput(t *testing.T) {
+	schema := mustParseIdentitySchema(t)
+
+	tests := []struct {
+		name        string
+		body        string
+		wantErr     bool
+		errContains string
+		wantFields  []string
+		check       func(*RegistrationInput) bool
+	}{
+		{
+			name: "traits with phone",
+			body: `{"pass": "password123", "csrf_token": "kratos-csrf", "traits": {"email": "test@example.com", "phone": "+15555550100"}}`,
+			check: func(in *RegistrationInput) bool {
+				return in.Password == "password123" &&
+					in.CSRFToken == "kratos-csrf" &&
+					in.Traits["phone"] == "+15555550100"
+			},
+		},
+		{
+			name: "legacy fields",
+			body: `{"email": "test@example.com", "pass": "password123", "first_name": "Test"}`,
+			check: func(in *RegistrationInput) bool {
+				name, _ := in.Traits["name"].(map[string]interface{})
+				return in.Traits["email"] == "test@example.com" && name["first"] == "Test" && name["last"] == nil
+			},
+		},
+		{
+			name:       "missing required trait",
+			body:       `{"pass": "password123", "traits": {"phone": "+15555550100"}}`,
+			wantErr:    true,
+			wantFields: []string{"traits.email"},
+		},
+		{
+			name:       "invalid traits",
+			body:       `{"pass": "password123", "traits": {"email": "not-an-email", "phone": "555"}}`,
+			wantErr:    true,
+			wantFields: []string{"traits.email", "traits.phone"},
+		},
+		{
+			name:       "nested trait of the wrong type",
+			body:       `{"pass": "password123", "traits": {"email": "test@example.com", "name": {"first": 7}}}`,
+			wantErr:    true,
+			wantFields: []string{"traits.name.first"},
+		},
+		{
+			name:       "unknown trait",
+			body:       `{"pass": "password123", "traits": {"email": "test@example.com", "nickname": "tester"}}`,
+			wantErr:    true,
+			wantFields: []string{"traits"},
+		},
+		{
+			name:        "traits not an object",
+			body:        `{"pass": "password123", "traits": "test@example.com"}`,
+			wantErr:     true,
+			errContains: "traits must be an object",
+		},
+		{
+			name:        "short password",
+			body:        `{"pass": "short", "traits": {"email": "test@example.com"}}`,
+			wantErr:     true,
+			errContains: "at least 8 characters",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidateSchemaRegistrationInput(strings.NewReader(tt.body), schema)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ValidateSchemaRegistrationInput() expected error, got nil")
+				}
+				if tt.errContains != "" && !strings.Contains(err.Message, tt.errContains) {
+					t.Errorf("ValidateSchemaRegistrationInput() error = %q, want error containing %q", err.Message, tt.errContains)
+				}
+
+				got := make([]string, 0, len(err.Fields))
+				for _, field := range err.Fields {
+					got = append(got, field.Field)
+				}
+				for _, want := range tt.wantFields {
+					if !containsString(got, want) {
+						t.Errorf("ValidateSchemaRegistrationInput() fields = %v, want %q", got, want)
+					}
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ValidateSchemaRegistrationInput() unexpected error: %v", err)
+			}
+
+			if !tt.check(result) {
+				t.Errorf("ValidateSchemaRegistrationInput() = %+v", result)
+			}
+		})
+	}
+}
+
+func TestValidateSchemaCodeRegistrationInput(t *testing.T) {
+	schema := mustParseIdentitySchema(t)
+
+	input, err := ValidateSchemaCodeRegistrationInput(strings.NewReader(`{"code": "123456", "traits": {"email": "test@example.com", "phone": "+15555550100"}}`), schema, true)
+	if err != nil {
+		t.Fatalf("ValidateSchemaCodeRegistrationInput() unexpected error: %v", err)
+	}
+	if input.Code != "123456" || input.Traits["phone"] != "+15555550100" {
+		t.Errorf("ValidateSchemaCodeRegistrationInput() = %+v", input)
+	}
+
+	if _, err := ValidateSchemaCodeRegistrationInput(strings.NewReader(`{"traits": {"phone": "+15555550100"}}`), schema, false); err == nil {
+		t.Error("ValidateSchemaCodeRegistrationInput() without a required trait expected error, got nil")
+	}
+	if _, err := ValidateSchemaCodeRegistrationInput(strings.NewReader(`{"traits": {"email": "test@example.com"}}`), schema, true); err == nil {
+		t.Error("ValidateSchemaCodeRegistrationInput() without a code expected error, got nil")
+	}
+}
+
+func TestValidateSchemaPasskeyRegistrationInput(t *testing.T) {
+	schema := mustParseIdentitySchema(t)
+
+	input, err := ValidateSchemaPasskeyRegistrationInput(strings.NewReader(`{"credential": {"id": "cred-1"}, "traits": {"email": "test@example.com", "phone": "+15555550100"}}`), schema, true)
+	if err != nil {
+		t.Fatalf("ValidateSchemaPasskeyRegistrationInput() unexpected error: %v", err)
+	}
+	if input.Credential != `{"id":"cred-1"}` || input.Traits["phone"] != "+15555550100" {
+		t.Errorf("ValidateSchemaPasskeyRegistrationInput() = %+v", input)
+	}
+
+	if _, err := ValidateSchemaPasskeyRegistrationInput(strings.NewReader(`{"traits": {"email": "not-an-email"}}`), schema, false); err == nil {
+		t.Error("ValidateSchemaPasskeyRegistrationInput() with invalid traits expected error, got nil")
+	}
+	if _, err := ValidateSchemaPasskeyRegistrationInput(strings.NewReader(`{"traits": {"email": "test@example.com"}}`), schema, true); err == nil {
+		t.Error("ValidateSchemaPasskeyRegistrationInput() without a credential expected error, got nil")
+	}
+}
+
+func TestValidateSchemaCreateIdentityInput(t *testing.T) {
+	schema := mustParseIdentitySchema(t)
+
+	input, err := ValidateSchemaCreateIdentityInput(strings.NewReader(`{"password": "password123", "traits": {"email": "test@example.com", "phone": "+15555550100"}}`), schema)
+	if err != nil {
+		t.Fatalf("ValidateSchemaCreateIdentityInput() unexpected error: %v", err)
+	}
+	if input.Password != "password123" || input.Traits["phone"] != "+15555550100" {
+		t.Errorf("ValidateSchemaCreateIdentityInput() = %+v", input)
+	}
+
+	input, err = ValidateSchemaCreateIdentityInput(strings.NewReader(`{"email": "test@example.com", "first_name": "Test", "last_name": "User"}`), schema)
+	if err != nil {
+		t.Fatalf("ValidateSchemaCreateIdentityInput() with legacy fields unexpected error: %v", err)
+	}
+	if input.Password != "" || input.Traits["email"] != "test@example.com" {
+		t.Errorf("ValidateSchemaCreateIdentityInput() = %+v", input)
+	}
+
+	if _, err := ValidateSchemaCreateIdentityInput(strings.NewReader(`{"traits": {"email": "test@example.com", "role": "admin"}}`), schema); err == nil {
+		t.Error("ValidateSchemaCreateIdentityInput() with an unknown trait expected error, got nil")
+	}
+	if _, err := ValidateSchemaCreateIdentityInput(strings.NewReader(`{"password": "short", "traits": {"email": "test@example.com"}}`), schema); err == nil {
+		t.Error("ValidateSchemaCreateIdentityInput() with a short password expected error, got nil")
+	}
+}
+
+func TestIdentitySchemaLoader(t *testing.T) {
+	loads := 0
+	var loadErr error
+	loader := NewIdentitySchemaLoader(func(ctx context.Context) ([]byte, error) {
+		loads++
+		if loadErr != nil {
+			return nil, loadErr
+		}
+		return []byte(testIdentitySchema), nil
+	}, time.Minute)
+
+	first, err := loader.Schema(context.Background())
+	if err != nil {
+		t.Fatalf("Schema() error = %v", err)
+	}
+
+	second, err := loader.Schema(context.Background())
+	if err != nil || second != first || loads != 1 {
+		t.Fatalf("Schema() reloaded a fresh schema: loads = %d, err = %v", loads, err)
+	}
+
+	// Once stale, a failed reload keeps the last schema
+	loader.loadedAt = time.Now().Add(-2 * time.Minute)
+	loadErr = errors.New("kratos unavailable")
+
+	stale, err := loader.Schema(context.Background())
+	if err != nil || stale != first || loads != 2 {
+		t.Errorf("Schema() after failed reload = %p, %v (loads = %d), want the last schema", stale, err, loads)
+	}
+}
+
+func TestIdentitySchemaLoader_Error(t *testing.T) {
+	loader := NewIdentitySchemaLoader(func(ctx context.Context) ([]byte, error) {
+		return []byte(`not json`), nil
+	}, 0)
+
+	if _, err := loader.Schema(context.Background()); err == nil {
+		t.Error("Schema() expected error for an unparsable schema, got nil")
+	}
+}
@@ -5,23 +5,36 @@ import (
 	"fmt"
 	"io"
 	"net/mail"
+	"strconv"
 	"strings"
+	"time"
 
 	apperrors "github.com/davegermiquet/kratos-chi-ollama/pkg/errors"
 )
 
+// MinPasswordLength is the shortest password accepted anywhere a password
+// is sent: login, registration, recovery, settings and admin creation
+const MinPasswordLength = 8
+
 // LoginInput represents validated login input
 type LoginInput struct {
 	Email    string
 	Password string
+	// CSRFToken is the flow's csrf_token, sent only by browser flows
+	CSRFToken string
 }
 
-// RegistrationInput represents validated registration input
+// RegistrationInput represents validated registration input.
+// Traits holds the identity traits to register, shaped by the identity schema;
+// Email, FirstName and LastName are set only without a schema.
 type RegistrationInput struct {
 	Email     string
 	Password  string
 	FirstName string
 	LastName  string
+	Traits    map[string]interface{}
+	// CSRFToken is the flow's csrf_token, sent only by browser flows
+	CSRFToken string
 }
 
 // ChatInput represents validated chat input
@@ -62,6 +75,119 @@ type RecoveryCodeInput struct {
 	Password string
 }
 
+// PasswordSettingsInput represents validated password change request
+type PasswordSettingsInput struct {
+	Password string
+}
+
+// ProfileSettingsInput represents validated profile traits update
+type ProfileSettingsInput struct {
+	FirstName string
+	LastName  string
+}
+
+// EmailSettingsInput represents validated email change request
+type EmailSettingsInput struct {
+	Email string
+}
+
+// ReauthInput represents validated re-authentication request
+type ReauthInput struct {
+	Password string
+}
+
+// TOTPCodeInput represents a validated authenticator app code
+type TOTPCodeInput struct {
+	Code string
+}
+
+// LookupSecretInput represents a validated backup code submission
+type LookupSecretInput struct {
+	Code string
+}
+
+// CodeLoginInput represents a validated passwordless login request.
+// Code is empty when requesting that a code be sent.
+type CodeLoginInput struct {
+	Email string
+	Code  string
+}
+
+// CodeRegistrationInput represents a validated passwordless registration request.
+// Code is empty when requesting that a code be sent. Traits holds the identity
+// traits to register; Email, FirstName and LastName are set only without a schema.
+type CodeRegistrationInput struct {
+	Email     string
+	FirstName string
+	LastName  string
+	Traits    map[string]interface{}
+	Code      string
+}
+
+// OIDCProviderInput represents a validated social sign-in provider choice
+type OIDCProviderInput struct {
+	Provider string
+}
+
+// SessionTokenExchangeInput represents validated session token exchange codes
+type SessionTokenExchangeInput struct {
+	InitCode     string
+	ReturnToCode string
+}
+
+// PasskeyRegistrationInput represents a validated passkey registration request.
+// Credential is empty when requesting the WebAuthn creation options. Traits
+// holds the identity traits to register; Email, FirstName and LastName are set
+// only without a schema.
+type PasskeyRegistrationInput struct {
+	Email      string
+	FirstName  string
+	LastName   string
+	Traits     map[string]interface{}
+	Credential string
+}
+
+// PasskeyCredentialInput represents a validated WebAuthn authenticator response
+type PasskeyCredentialInput struct {
+	Credential string
+}
+
+// PasskeyRemoveInput represents a validated passkey removal request
+type PasskeyRemoveInput struct {
+	ID string
+}
+
+// CreateIdentityInput represents a validated admin identity creation request.
+// Password is optional.
+type CreateIdentityInput struct {
+	Traits   map[string]interface{}
+	Password string
+}
+
+// UpdateIdentityInput represents a validated admin identity update.
+// Empty fields are left unchanged.
+type UpdateIdentityInput struct {
+	Email     string
+	FirstName string
+	LastName  string
+	State     string
+}
+
+// APIKeyInput represents a validated API key creation request.
+// A nil ExpiresAt never expires.
+type APIKeyInput struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+// PermissionCheckInput represents a validated relation check for the caller
+type PermissionCheckInput struct {
+	Namespace string
+	Object    string
+	Relation  string
+}
+
 // ValidateLoginInput validates and parses login request body
 func ValidateLoginInput(body io.Reader) (*LoginInput, *apperrors.AppError) {
 	var data map[string]interface{}
@@ -83,13 +209,16 @@ func ValidateLoginInput(body io.Reader) (*LoginInput, *apperrors.AppError) {
 		return nil, apperrors.NewValidationError("password is required", "")
 	}
 
-	if len(password) < 6 {
-		return nil, apperrors.NewValidationError("password must be at least 6 characters", "")
+	if appErr := validatePasswordLength(password); appErr != nil {
+		return nil, appErr
 	}
 
+	csrfToken, _ := data["csrf_token"].(string)
+
 	return &LoginInput{
-		Email:    email,
-		Password: password,
+		Email:     email,
+		Password:  password,
+		CSRFToken: csrfToken,
 	}, nil
 }
 
@@ -114,8 +243,8 @@ func ValidateRegistrationInput(body io.Reader) (*RegistrationInput, *apperrors.A
 		return nil, apperrors.NewValidationError("password is required", "")
 	}
 
-	if len(password) < 8 {
-		return nil, apperrors.NewValidationError("password must be at least 8 characters", "")
+	if appErr := validatePasswordLength(password); appErr != nil {
+		return nil, appErr
 	}
 
 	firstName, ok := data["first_name"].(string)
@@ -128,11 +257,15 @@ func ValidateRegistrationInput(body io.Reader) (*RegistrationInput, *apperrors.A
 		return nil, apperrors.NewValidationError("last_name is required", "")
 	}
 
+	csrfToken, _ := data["csrf_token"].(string)
+
 	return &RegistrationInput{
 		Email:     email,
 		Password:  password,
 		FirstName: firstName,
 		LastName:  lastName,
+		Traits:    legacyRegistrationTraits(data),
+		CSRFToken: csrfToken,
 	}, nil
 }
 
@@ -302,14 +435,554 @@ func ValidateRecoveryCodeInput(body io.Reader) (*RecoveryCodeInput, *apperrors.A
 		return nil, apperrors.NewValidationError("password is required", "")
 	}
 
-	if len(password) < 8 {
-		return nil, apperrors.NewValidationError("password must be at least 8 characters", "")
+	if appErr := validatePasswordLength(password); appErr != nil {
+		return nil, appErr
 	}
 
 	return &RecoveryCodeInput{Code: code, Password: password}, nil
 }
 
+// ValidatePasswordSettingsInput validates password change request
+func ValidatePasswordSettingsInput(body io.Reader) (*PasswordSettingsInput, *apperrors.AppError) {
+	var req struct {
+		Password string `json:"password"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	if req.Password == "" {
+		return nil, apperrors.NewValidationError("password is required", "")
+	}
+
+	if appErr := validatePasswordLength(req.Password); appErr != nil {
+		return nil, appErr
+	}
+
+	return &PasswordSettingsInput{Password: req.Password}, nil
+}
+
+// ValidateProfileSettingsInput validates profile traits update
+func ValidateProfileSettingsInput(body io.Reader) (*ProfileSettingsInput, *apperrors.AppError) {
+	var req struct {
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	firstName := strings.TrimSpace(req.FirstName)
+	if firstName == "" {
+		return nil, apperrors.NewValidationError("first_name is required", "")
+	}
+
+	lastName := strings.TrimSpace(req.LastName)
+	if lastName == "" {
+		return nil, apperrors.NewValidationError("last_name is required", "")
+	}
+
+	return &ProfileSettingsInput{FirstName: firstName, LastName: lastName}, nil
+}
+
+// ValidateEmailSettingsInput validates email change request
+func ValidateEmailSettingsInput(body io.Reader) (*EmailSettingsInput, *apperrors.AppError) {
+	var req struct {
+		Email string `json:"email"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		return nil, apperrors.NewValidationError("email is required", "")
+	}
+
+	if !isValidEmail(email) {
+		return nil, apperrors.NewValidationError("invalid email format", "")
+	}
+
+	return &EmailSettingsInput{Email: email}, nil
+}
+
+// ValidateReauthInput validates re-authentication request
+func ValidateReauthInput(body io.Reader) (*ReauthInput, *apperrors.AppError) {
+	var req struct {
+		Password string `json:"pass"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	if req.Password == "" {
+		return nil, apperrors.NewValidationError("password is required", "")
+	}
+
+	return &ReauthInput{Password: req.Password}, nil
+}
+
+// ValidateTOTPCodeInput validates an authenticator app code submission
+func ValidateTOTPCodeInput(body io.Reader) (*TOTPCodeInput, *apperrors.AppError) {
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	code := strings.TrimSpace(req.Code)
+	if code == "" {
+		return nil, apperrors.NewValidationError("code is required", "")
+	}
+
+	if len(code) != 6 || strings.Trim(code, "0123456789") != "" {
+		return nil, apperrors.NewValidationError("code must be 6 digits", "")
+	}
+
+	return &TOTPCodeInput{Code: code}, nil
+}
+
+// ValidateLookupSecretInput validates a backup code submission
+func ValidateLookupSecretInput(body io.Reader) (*LookupSecretInput, *apperrors.AppError) {
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	code := strings.TrimSpace(req.Code)
+	if code == "" {
+		return nil, apperrors.NewValidationError("code is required", "")
+	}
+
+	return &LookupSecretInput{Code: code}, nil
+}
+
+// ValidateCodeLoginInput validates a passwordless login request. The code is
+// required only when requireCode is set (the exchange step).
+func ValidateCodeLoginInput(body io.Reader, requireCode bool) (*CodeLoginInput, *apperrors.AppError) {
+	var req struct {
+		Email string `json:"email"`
+		Code  string `json:"code"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if email == "" {
+		return nil, apperrors.NewValidationError("email is required", "")
+	}
+
+	if !isValidEmail(email) {
+		return nil, apperrors.NewValidationError("invalid email format", "")
+	}
+
+	code, appErr := validateOneTimeCode(req.Code, requireCode)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return &CodeLoginInput{Email: email, Code: code}, nil
+}
+
+// ValidateCodeRegistrationInput validates a passwordless registration request.
+// The code is required only when requireCode is set (the exchange step).
+func ValidateCodeRegistrationInput(body io.Reader, requireCode bool) (*CodeRegistrationInput, *apperrors.AppError) {
+	var req struct {
+		Email     string `json:"email"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+		Code      string `json:"code"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	email, firstName, lastName, appErr := validateRegistrationTraits(req.Email, req.FirstName, req.LastName)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	code, appErr := validateOneTimeCode(req.Code, requireCode)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return &CodeRegistrationInput{
+		Email:     email,
+		FirstName: firstName,
+		LastName:  lastName,
+		Traits:    defaultTraits(email, firstName, lastName),
+		Code:      code,
+	}, nil
+}
+
+// ValidatePasskeyRegistrationInput validates a passkey registration request.
+// The credential is required only when requireCredential is set (the final step).
+func ValidatePasskeyRegistrationInput(body io.Reader, requireCredential bool) (*PasskeyRegistrationInput, *apperrors.AppError) {
+	var req struct {
+		Email      string          `json:"email"`
+		FirstName  string          `json:"first_name"`
+		LastName   string          `json:"last_name"`
+		Credential json.RawMessage `json:"credential"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	email, firstName, lastName, appErr := validateRegistrationTraits(req.Email, req.FirstName, req.LastName)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	input := &PasskeyRegistrationInput{
+		Email:     email,
+		FirstName: firstName,
+		LastName:  lastName,
+		Traits:    defaultTraits(email, firstName, lastName),
+	}
+
+	if requireCredential {
+		credential, appErr := validateCredential(req.Credential)
+		if appErr != nil {
+			return nil, appErr
+		}
+		input.Credential = credential
+	}
+
+	return input, nil
+}
+
+// ValidatePasskeyCredentialInput validates an authenticator response submission
+func ValidatePasskeyCredentialInput(body io.Reader) (*PasskeyCredentialInput, *apperrors.AppError) {
+	var req struct {
+		Credential json.RawMessage `json:"credential"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	credential, appErr := validateCredential(req.Credential)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return &PasskeyCredentialInput{Credential: credential}, nil
+}
+
+// ValidatePasskeyRemoveInput validates a passkey removal request
+func ValidatePasskeyRemoveInput(body io.Reader) (*PasskeyRemoveInput, *apperrors.AppError) {
+	var req struct {
+		ID string `json:"id"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	id := strings.TrimSpace(req.ID)
+	if id == "" {
+		return nil, apperrors.NewValidationError("id is required", "")
+	}
+
+	return &PasskeyRemoveInput{ID: id}, nil
+}
+
+func validateRegistrationTraits(email, firstName, lastName string) (string, string, string, *apperrors.AppError) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", "", "", apperrors.NewValidationError("email is required", "")
+	}
+
+	if !isValidEmail(email) {
+		return "", "", "", apperrors.NewValidationError("invalid email format", "")
+	}
+
+	firstName = strings.TrimSpace(firstName)
+	if firstName == "" {
+		return "", "", "", apperrors.NewValidationError("first_name is required", "")
+	}
+
+	lastName = strings.TrimSpace(lastName)
+	if lastName == "" {
+		return "", "", "", apperrors.NewValidationError("last_name is required", "")
+	}
+
+	return email, firstName, lastName, nil
+}
+
+// defaultTraits returns the default schema's traits for validated fields
+func defaultTraits(email, firstName, lastName string) map[string]interface{} {
+	return map[string]interface{}{
+		"email": email,
+		"name":  map[string]interface{}{"first": firstName, "last": lastName},
+	}
+}
+
+// validateCredential checks that a WebAuthn authenticator response is a JSON
+// object and returns it as the string Kratos expects
+func validateCredential(credential json.RawMessage) (string, *apperrors.AppError) {
+	var obj map[string]interface{}
+	if len(credential) == 0 || string(credential) == "null" {
+		return "", apperrors.NewValidationError("credential is required", "")
+	}
+
+	if err := json.Unmarshal(credential, &obj); err != nil {
+		return "", apperrors.NewValidationError("credential must be a JSON object", err.Error())
+	}
+
+	return string(credential), nil
+}
+
+// ValidateOIDCProviderInput validates a social sign-in provider choice
+func ValidateOIDCProviderInput(body io.Reader) (*OIDCProviderInput, *apperrors.AppError) {
+	var req struct {
+		Provider string `json:"provider"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	provider := strings.TrimSpace(req.Provider)
+	if provider == "" {
+		return nil, apperrors.NewValidationError("provider is required", "")
+	}
+
+	return &OIDCProviderInput{Provider: provider}, nil
+}
+
+// ValidateSessionTokenExchangeInput validates a session token exchange request
+func ValidateSessionTokenExchangeInput(body io.Reader) (*SessionTokenExchangeInput, *apperrors.AppError) {
+	var req struct {
+		InitCode     string `json:"init_code"`
+		ReturnToCode string `json:"return_to_code"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	initCode := strings.TrimSpace(req.InitCode)
+	if initCode == "" {
+		return nil, apperrors.NewValidationError("init_code is required", "")
+	}
+
+	returnToCode := strings.TrimSpace(req.ReturnToCode)
+	if returnToCode == "" {
+		return nil, apperrors.NewValidationError("return_to_code is required", "")
+	}
+
+	return &SessionTokenExchangeInput{InitCode: initCode, ReturnToCode: returnToCode}, nil
+}
+
+// ValidateCreateIdentityInput validates an admin identity creation request
+func ValidateCreateIdentityInput(body io.Reader) (*CreateIdentityInput, *apperrors.AppError) {
+	var req struct {
+		Email     string `json:"email"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+		Password  string `json:"password"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	email, firstName, lastName, appErr := validateRegistrationTraits(req.Email, req.FirstName, req.LastName)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if req.Password != "" {
+		if appErr := validatePasswordLength(req.Password); appErr != nil {
+			return nil, appErr
+		}
+	}
+
+	return &CreateIdentityInput{
+		Traits:   defaultTraits(email, firstName, lastName),
+		Password: req.Password,
+	}, nil
+}
+
+// ValidateUpdateIdentityInput validates an admin identity update. first_name and
+// last_name must be sent together; state must be active or inactive.
+func ValidateUpdateIdentityInput(body io.Reader) (*UpdateIdentityInput, *apperrors.AppError) {
+	var req struct {
+		Email     string `json:"email"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+		State     string `json:"state"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	input := &UpdateIdentityInput{
+		Email:     strings.TrimSpace(req.Email),
+		FirstName: strings.TrimSpace(req.FirstName),
+		LastName:  strings.TrimSpace(req.LastName),
+		State:     strings.TrimSpace(req.State),
+	}
+
+	if input.Email == "" && input.FirstName == "" && input.LastName == "" && input.State == "" {
+		return nil, apperrors.NewValidationError("nothing to update", "")
+	}
+
+	if input.Email != "" && !isValidEmail(input.Email) {
+		return nil, apperrors.NewValidationError("invalid email format", "")
+	}
+
+	if (input.FirstName == "") != (input.LastName == "") {
+		return nil, apperrors.NewValidationError("first_name and last_name must be updated together", "")
+	}
+
+	if input.State != "" && input.State != "active" && input.State != "inactive" {
+		return nil, apperrors.NewValidationError("state must be active or inactive", "")
+	}
+
+	return input, nil
+}
+
+// ValidatePageSize validates an optional page_size query parameter.
+// An empty value returns 0, meaning the server default.
+func ValidatePageSize(raw string) (int64, *apperrors.AppError) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+
+	size, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || size < 1 || size > 1000 {
+		return 0, apperrors.NewValidationError("page_size must be between 1 and 1000", "")
+	}
+
+	return size, nil
+}
+
+// ValidateAPIKeyInput validates an API key creation request. Every scope
+// must be in allowedScopes and expires_at, when set, must be in the future.
+func ValidateAPIKeyInput(body io.Reader, allowedScopes []string) (*APIKeyInput, *apperrors.AppError) {
+	var req struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		return nil, apperrors.NewValidationError("Invalid JSON body", err.Error())
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, apperrors.NewValidationError("name is required", "")
+	}
+
+	if len(name) > 100 {
+		return nil, apperrors.NewValidationError("name must be at most 100 characters", "")
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, apperrors.NewValidationError("at least one scope is required", "allowed: "+strings.Join(allowedScopes, ", "))
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	seen := make(map[string]bool, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		scope = strings.TrimSpace(scope)
+		if !containsString(allowedScopes, scope) {
+			return nil, apperrors.NewValidationError(fmt.Sprintf("unknown scope: %q", scope), "allowed: "+strings.Join(allowedScopes, ", "))
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, apperrors.NewValidationError("expires_at must be in the future", "")
+	}
+
+	return &APIKeyInput{
+		Name:      name,
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}, nil
+}
+
+// ValidatePermissionCheck validates the namespace, object and relation query parameters
+func ValidatePermissionCheck(namespace, object, relation string) (*PermissionCheckInput, *apperrors.AppError) {
+	input := &PermissionCheckInput{
+		Namespace: strings.TrimSpace(namespace),
+		Object:    strings.TrimSpace(object),
+		Relation:  strings.TrimSpace(relation),
+	}
+
+	if input.Namespace == "" {
+		return nil, apperrors.NewValidationError("namespace is required", "")
+	}
+
+	if input.Object == "" {
+		return nil, apperrors.NewValidationError("object is required", "")
+	}
+
+	if input.Relation == "" {
+		return nil, apperrors.NewValidationError("relation is required", "")
+	}
+
+	return input, nil
+}
+
+func validateOneTimeCode(code string, required bool) (string, *apperrors.AppError) {
+	code = strings.TrimSpace(code)
+	if !required {
+		return "", nil
+	}
+
+	if code == "" {
+		return "", apperrors.NewValidationError("code is required", "")
+	}
+
+	if len(code) < 6 {
+		return "", apperrors.NewValidationError("code must be at least 6 characters", "")
+	}
+
+	return code, nil
+}
+
+// validatePasswordLength rejects passwords shorter than MinPasswordLength
+func validatePasswordLength(password string) *apperrors.AppError {
+	if len(password) < MinPasswordLength {
+		return apperrors.NewValidationError(fmt.Sprintf("password must be at least %d characters", MinPasswordLength), "")
+	}
+	return nil
+}
+
 func isValidEmail(email string) bool {
 	_, err := mail.ParseAddress(email)
 	return err == nil
 }
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -47,9 +47,9 @@ func TestValidateLoginInput(t *testing.T) {
 		},
 		{
 			name:        "password too short",
-			body:        `{"email": "test@example.com", "pass": "12345"}`,
+			body:        `{"email": "test@example.com", "pass": "1234567"}`,
 			wantErr:     true,
-			errContains: "at least 6 characters",
+			errContains: "at least 8 characters",
 		},
 		{
 			name:        "invalid JSON",
@@ -533,3 +533,899 @@ func TestValidateVerificationCodeInput(t *testing.T) {
 		})
 	}
 }
+
+func TestValidatePasswordSettingsInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name:    "valid password",
+			body:    `{"password": "newpassword123"}`,
+			wantErr: false,
+		},
+		{
+			name:        "missing password",
+			body:        `{}`,
+			wantErr:     true,
+			errContains: "password is required",
+		},
+		{
+			name:        "short password",
+			body:        `{"password": "short"}`,
+			wantErr:     true,
+			errContains: "at least 8 characters",
+		},
+		{
+			name:        "invalid JSON",
+			body:        `{bad json}`,
+			wantErr:     true,
+			errContains: "Invalid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidatePasswordSettingsInput(strings.NewReader(tt.body))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidatePasswordSettingsInput() expected error containing %q, got nil", tt.errContains)
+					return
+				}
+				if !strings.Contains(err.Message, tt.errContains) {
+					t.Errorf("ValidatePasswordSettingsInput() error = %q, want error containing %q", err.Message, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ValidatePasswordSettingsInput() unexpected error: %v", err)
+				return
+			}
+
+			if result.Password != "newpassword123" {
+				t.Errorf("ValidatePasswordSettingsInput() Password = %q, want %q", result.Password, "newpassword123")
+			}
+		})
+	}
+}
+
+func TestValidateProfileSettingsInput(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		wantFirstName string
+		wantLastName  string
+		wantErr       bool
+		errContains   string
+	}{
+		{
+			name:          "valid names",
+			body:          `{"first_name": " Jane ", "last_name": "Doe"}`,
+			wantFirstName: "Jane",
+			wantLastName:  "Doe",
+			wantErr:       false,
+		},
+		{
+			name:        "missing first name",
+			body:        `{"last_name": "Doe"}`,
+			wantErr:     true,
+			errContains: "first_name is required",
+		},
+		{
+			name:        "whitespace last name",
+			body:        `{"first_name": "Jane", "last_name": "  "}`,
+			wantErr:     true,
+			errContains: "last_name is required",
+		},
+		{
+			name:        "invalid JSON",
+			body:        `{bad json}`,
+			wantErr:     true,
+			errContains: "Invalid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidateProfileSettingsInput(strings.NewReader(tt.body))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidateProfileSettingsInput() expected error containing %q, got nil", tt.errContains)
+					return
+				}
+				if !strings.Contains(err.Message, tt.errContains) {
+					t.Errorf("ValidateProfileSettingsInput() error = %q, want error containing %q", err.Message, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ValidateProfileSettingsInput() unexpected error: %v", err)
+				return
+			}
+
+			if result.FirstName != tt.wantFirstName || result.LastName != tt.wantLastName {
+				t.Errorf("ValidateProfileSettingsInput() = %q %q, want %q %q",
+					result.FirstName, result.LastName, tt.wantFirstName, tt.wantLastName)
+			}
+		})
+	}
+}
+
+func TestValidateEmailSettingsInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantEmail   string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name:      "valid email",
+			body:      `{"email": " new@example.com "}`,
+			wantEmail: "new@example.com",
+		},
+		{
+			name:        "missing email",
+			body:        `{}`,
+			wantErr:     true,
+			errContains: "email is required",
+		},
+		{
+			name:        "invalid email",
+			body:        `{"email": "not-an-email"}`,
+			wantErr:     true,
+			errContains: "invalid email format",
+		},
+		{
+			name:        "invalid JSON",
+			body:        `{bad json}`,
+			wantErr:     true,
+			errContains: "Invalid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidateEmailSettingsInput(strings.NewReader(tt.body))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidateEmailSettingsInput() expected error containing %q, got nil", tt.errContains)
+					return
+				}
+				if !strings.Contains(err.Message, tt.errContains) {
+					t.Errorf("ValidateEmailSettingsInput() error = %q, want error containing %q", err.Message, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ValidateEmailSettingsInput() unexpected error: %v", err)
+				return
+			}
+
+			if result.Email != tt.wantEmail {
+				t.Errorf("ValidateEmailSettingsInput() email = %q, want %q", result.Email, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestValidateReauthInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name:    "valid password",
+			body:    `{"pass": "password123"}`,
+			wantErr: false,
+		},
+		{
+			name:        "missing password",
+			body:        `{}`,
+			wantErr:     true,
+			errContains: "password is required",
+		},
+		{
+			name:        "invalid JSON",
+			body:        `{bad json}`,
+			wantErr:     true,
+			errContains: "Invalid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateReauthInput(strings.NewReader(tt.body))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidateReauthInput() expected error containing %q, got nil", tt.errContains)
+					return
+				}
+				if !strings.Contains(err.Message, tt.errContains) {
+					t.Errorf("ValidateReauthInput() error = %q, want error containing %q", err.Message, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ValidateReauthInput() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPCodeInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantCode    string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name:     "valid code",
+			body:     `{"code": " 123456 "}`,
+			wantCode: "123456",
+			wantErr:  false,
+		},
+		{
+			name:        "missing code",
+			body:        `{}`,
+			wantErr:     true,
+			errContains: "code is required",
+		},
+		{
+			name:        "short code",
+			body:        `{"code": "12345"}`,
+			wantErr:     true,
+			errContains: "6 digits",
+		},
+		{
+			name:        "non-numeric code",
+			body:        `{"code": "12a456"}`,
+			wantErr:     true,
+			errContains: "6 digits",
+		},
+		{
+			name:        "invalid JSON",
+			body:        `{bad json}`,
+			wantErr:     true,
+			errContains: "Invalid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidateTOTPCodeInput(strings.NewReader(tt.body))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidateTOTPCodeInput() expected error containing %q, got nil", tt.errContains)
+					return
+				}
+				if !strings.Contains(err.Message, tt.errContains) {
+					t.Errorf("ValidateTOTPCodeInput() error = %q, want error containing %q", err.Message, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ValidateTOTPCodeInput() unexpected error: %v", err)
+				return
+			}
+
+			if result.Code != tt.wantCode {
+				t.Errorf("ValidateTOTPCodeInput() Code = %q, want %q", result.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestValidateLookupSecretInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantCode    string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name:     "valid code",
+			body:     `{"code": " abcd1234 "}`,
+			wantCode: "abcd1234",
+			wantErr:  false,
+		},
+		{
+			name:        "missing code",
+			body:        `{"code": "  "}`,
+			wantErr:     true,
+			errContains: "code is required",
+		},
+		{
+			name:        "invalid JSON",
+			body:        `{bad json}`,
+			wantErr:     true,
+			errContains: "Invalid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidateLookupSecretInput(strings.NewReader(tt.body))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidateLookupSecretInput() expected error containing %q, got nil", tt.errContains)
+					return
+				}
+				if !strings.Contains(err.Message, tt.errContains) {
+					t.Errorf("ValidateLookupSecretInput() error = %q, want error containing %q", err.Message, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ValidateLookupSecretInput() unexpected error: %v", err)
+				return
+			}
+
+			if result.Code != tt.wantCode {
+				t.Errorf("ValidateLookupSecretInput() Code = %q, want %q", result.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestValidateCodeLoginInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		requireCode bool
+		wantCode    string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name:        "send step with email only",
+			body:        `{"email": "test@example.com"}`,
+			requireCode: false,
+			wantErr:     false,
+		},
+		{
+			name:        "send step ignores code",
+			body:        `{"email": "test@example.com", "code": "123456"}`,
+			requireCode: false,
+			wantCode:    "",
+			wantErr:     false,
+		},
+		{
+			name:        "exchange step with code",
+			body:        `{"email": "test@example.com", "code": " 123456 "}`,
+			requireCode: true,
+			wantCode:    "123456",
+			wantErr:     false,
+		},
+		{
+			name:        "exchange step without code",
+			body:        `{"email": "test@example.com"}`,
+			requireCode: true,
+			wantErr:     true,
+			errContains: "code is required",
+		},
+		{
+			name:        "short code",
+			body:        `{"email": "test@example.com", "code": "123"}`,
+			requireCode: true,
+			wantErr:     true,
+			errContains: "at least 6 characters",
+		},
+		{
+			name:        "invalid email",
+			body:        `{"email": "not-an-email"}`,
+			wantErr:     true,
+			errContains: "invalid email format",
+		},
+		{
+			name:        "invalid JSON",
+			body:        `{bad json}`,
+			wantErr:     true,
+			errContains: "Invalid JSON",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidateCodeLoginInput(strings.NewReader(tt.body), tt.requireCode)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidateCodeLoginInput() expected error containing %q, got nil", tt.errContains)
+					return
+				}
+				if !strings.Contains(err.Message, tt.errContains) {
+					t.Errorf("ValidateCodeLoginInput() error = %q, want error containing %q", err.Message, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ValidateCodeLoginInput() unexpected error: %v", err)
+				return
+			}
+
+			if result.Email != "test@example.com" || result.Code != tt.wantCode {
+				t.Errorf("ValidateCodeLoginInput() = %+v, want code %q", result, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestValidateCodeRegistrationInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		requireCode bool
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name:        "send step",
+			body:        `{"email": "test@example.com", "first_name": "Jane", "last_name": "Doe"}`,
+			requireCode: false,
+			wantErr:     false,
+		},
+		{
+			name:        "exchange step",
+			body:        `{"email": "test@example.com", "first_name": "Jane", "last_name": "Doe", "code": "123456"}`,
+			requireCode: true,
+			wantErr:     false,
+		},
+		{
+			name:        "exchange step without code",
+			body:        `{"email": "test@example.com", "first_name": "Jane", "last_name": "Doe"}`,
+			requireCode: true,
+			wantErr:     true,
+			errContains: "code is required",
+		},
+		{
+			name:        "missing first name",
+			body:        `{"email": "test@example.com", "last_name": "Doe"}`,
+			wantErr:     true,
+			errContains: "first_name is required",
+		},
+		{
+			name:        "missing email",
+			body:        `{"first_name": "Jane", "last_name": "Doe"}`,
+			wantErr:     true,
+			errContains: "email is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateCodeRegistrationInput(strings.NewReader(tt.body), tt.requireCode)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidateCodeRegistrationInput() expected error containing %q, got nil", tt.errContains)
+					return
+				}
+				if !strings.Contains(err.Message, tt.errContains) {
+					t.Errorf("ValidateCodeRegistrationInput() error = %q, want error containing %q", err.Message, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ValidateCodeRegistrationInput() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateOIDCProviderInput(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantProvider string
+		wantErr      bool
+	}{
+		{name: "valid provider", body: `{"provider": " google "}`, wantProvider: "google"},
+		{name: "missing provider", body: `{}`, wantErr: true},
+		{name: "invalid JSON", body: `{bad json}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidateOIDCProviderInput(strings.NewReader(tt.body))
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateOIDCProviderInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && result.Provider != tt.wantProvider {
+				t.Errorf("ValidateOIDCProviderInput() Provider = %q, want %q", result.Provider, tt.wantProvider)
+			}
+		})
+	}
+}
+
+func TestValidateSessionTokenExchangeInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name:    "valid codes",
+			body:    `{"init_code": "init", "return_to_code": "ret"}`,
+			wantErr: false,
+		},
+		{
+			name:        "missing init code",
+			body:        `{"return_to_code": "ret"}`,
+			wantErr:     true,
+			errContains: "init_code is required",
+		},
+		{
+			name:        "missing return_to code",
+			body:        `{"init_code": "init"}`,
+			wantErr:     true,
+			errContains: "return_to_code is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateSessionTokenExchangeInput(strings.NewReader(tt.body))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidateSessionTokenExchangeInput() expected error containing %q, got nil", tt.errContains)
+					return
+				}
+				if !strings.Contains(err.Message, tt.errContains) {
+					t.Errorf("ValidateSessionTokenExchangeInput() error = %q, want error containing %q", err.Message, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ValidateSessionTokenExchangeInput() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidatePasskeyRegistrationInput(t *testing.T) {
+	tests := []struct {
+		name              string
+		body              string
+		requireCredential bool
+		wantErr           bool
+		errContains       string
+	}{
+		{
+			name: "traits only",
+			body: `{"email": "test@example.com", "first_name": "John", "last_name": "Doe"}`,
+		},
+		{
+			name:              "traits and credential",
+			body:              `{"email": "test@example.com", "first_name": "John", "last_name": "Doe", "credential": {"id": "abc"}}`,
+			requireCredential: true,
+		},
+		{
+			name:              "missing credential",
+			body:              `{"email": "test@example.com", "first_name": "John", "last_name": "Doe"}`,
+			requireCredential: true,
+			wantErr:           true,
+			errContains:       "credential is required",
+		},
+		{
+			name:              "credential not an object",
+			body:              `{"email": "test@example.com", "first_name": "John", "last_name": "Doe", "credential": "abc"}`,
+			requireCredential: true,
+			wantErr:           true,
+			errContains:       "credential must be a JSON object",
+		},
+		{
+			name:        "invalid email",
+			body:        `{"email": "invalid", "first_name": "John", "last_name": "Doe"}`,
+			wantErr:     true,
+			errContains: "invalid email format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidatePasskeyRegistrationInput(strings.NewReader(tt.body), tt.requireCredential)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidatePasskeyRegistrationInput() expected error containing %q, got nil", tt.errContains)
+					return
+				}
+				if !strings.Contains(err.Message, tt.errContains) {
+					t.Errorf("ValidatePasskeyRegistrationInput() error = %q, want error containing %q", err.Message, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ValidatePasskeyRegistrationInput() unexpected error: %v", err)
+			}
+
+			if tt.requireCredential && result.Credential != `{"id": "abc"}` {
+				t.Errorf("ValidatePasskeyRegistrationInput() Credential = %q", result.Credential)
+			}
+		})
+	}
+}
+
+func TestValidatePasskeyCredentialInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{name: "valid credential", body: `{"credential": {"id": "abc", "type": "public-key"}}`},
+		{name: "missing credential", body: `{}`, wantErr: true},
+		{name: "null credential", body: `{"credential": null}`, wantErr: true},
+		{name: "invalid JSON", body: `{bad json}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidatePasskeyCredentialInput(strings.NewReader(tt.body))
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePasskeyCredentialInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePasskeyRemoveInput(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantID  string
+		wantErr bool
+	}{
+		{name: "valid id", body: `{"id": " cred-1 "}`, wantID: "cred-1"},
+		{name: "missing id", body: `{}`, wantErr: true},
+		{name: "invalid JSON", body: `{bad json}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidatePasskeyRemoveInput(strings.NewReader(tt.body))
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidatePasskeyRemoveInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && result.ID != tt.wantID {
+				t.Errorf("ValidatePasskeyRemoveInput() ID = %q, want %q", result.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestValidateCreateIdentityInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name: "without password",
+			body: `{"email": "new@example.com", "first_name": "New", "last_name": "User"}`,
+		},
+		{
+			name: "with password",
+			body: `{"email": "new@example.com", "first_name": "New", "last_name": "User", "password": "password123"}`,
+		},
+		{
+			name:        "short password",
+			body:        `{"email": "new@example.com", "first_name": "New", "last_name": "User", "password": "short"}`,
+			wantErr:     true,
+			errContains: "password must be at least 8 characters",
+		},
+		{
+			name:        "missing last name",
+			body:        `{"email": "new@example.com", "first_name": "New"}`,
+			wantErr:     true,
+			errContains: "last_name is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateCreateIdentityInput(strings.NewReader(tt.body))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidateCreateIdentityInput() expected error containing %q, got nil", tt.errContains)
+					return
+				}
+				if !strings.Contains(err.Message, tt.errContains) {
+					t.Errorf("ValidateCreateIdentityInput() error = %q, want error containing %q", err.Message, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ValidateCreateIdentityInput() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateUpdateIdentityInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantErr     bool
+		errContains string
+	}{
+		{name: "email only", body: `{"email": "new@example.com"}`},
+		{name: "name", body: `{"first_name": "Jane", "last_name": "Doe"}`},
+		{name: "state", body: `{"state": "inactive"}`},
+		{
+			name:        "nothing to update",
+			body:        `{}`,
+			wantErr:     true,
+			errContains: "nothing to update",
+		},
+		{
+			name:        "first name alone",
+			body:        `{"first_name": "Jane"}`,
+			wantErr:     true,
+			errContains: "must be updated together",
+		},
+		{
+			name:        "last name alone",
+			body:        `{"last_name": "Doe"}`,
+			wantErr:     true,
+			errContains: "must be updated together",
+		},
+		{
+			name:        "invalid state",
+			body:        `{"state": "deleted"}`,
+			wantErr:     true,
+			errContains: "state must be active or inactive",
+		},
+		{
+			name:        "invalid email",
+			body:        `{"email": "invalid"}`,
+			wantErr:     true,
+			errContains: "invalid email format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateUpdateIdentityInput(strings.NewReader(tt.body))
+
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ValidateUpdateIdentityInput() expected error containing %q, got nil", tt.errContains)
+					return
+				}
+				if !strings.Contains(err.Message, tt.errContains) {
+					t.Errorf("ValidateUpdateIdentityInput() error = %q, want error containing %q", err.Message, tt.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Errorf("ValidateUpdateIdentityInput() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidatePageSize(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int64
+		wantErr bool
+	}{
+		{raw: "", want: 0},
+		{raw: "25", want: 25},
+		{raw: "0", wantErr: true},
+		{raw: "5000", wantErr: true},
+		{raw: "ten", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ValidatePageSize(tt.raw)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidatePageSize(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ValidatePageSize(%q) = %d, want %d", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePermissionCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		object    string
+		relation  string
+		wantErr   bool
+	}{
+		{name: "valid", namespace: "conversations", object: " conv-1 ", relation: "viewer"},
+		{name: "missing namespace", object: "conv-1", relation: "viewer", wantErr: true},
+		{name: "missing object", namespace: "conversations", relation: "viewer", wantErr: true},
+		{name: "blank relation", namespace: "conversations", object: "conv-1", relation: "  ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidatePermissionCheck(tt.namespace, tt.object, tt.relation)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidatePermissionCheck() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && got.Object != "conv-1" {
+				t.Errorf("ValidatePermissionCheck() object = %q, want %q", got.Object, "conv-1")
+			}
+		})
+	}
+}
+
+func TestValidateAPIKeyInput(t *testing.T) {
+	allowed := []string{"llm:chat", "llm:generate"}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantScopes int
+		wantErr    bool
+	}{
+		{name: "valid", body: `{"name": "batch", "scopes": ["llm:chat", "llm:generate"]}`, wantScopes: 2},
+		{name: "duplicate scopes", body: `{"name": "batch", "scopes": ["llm:chat", "llm:chat"]}`, wantScopes: 1},
+		{name: "future expiry", body: `{"name": "batch", "scopes": ["llm:chat"], "expires_at": "2999-01-01T00:00:00Z"}`, wantScopes: 1},
+		{name: "past expiry", body: `{"name": "batch", "scopes": ["llm:chat"], "expires_at": "2000-01-01T00:00:00Z"}`, wantErr: true},
+		{name: "missing name", body: `{"scopes": ["llm:chat"]}`, wantErr: true},
+		{name: "no scopes", body: `{"name": "batch", "scopes": []}`, wantErr: true},
+		{name: "unknown scope", body: `{"name": "batch", "scopes": ["identities:write"]}`, wantErr: true},
+		{name: "invalid JSON", body: `{`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateAPIKeyInput(strings.NewReader(tt.body), allowed)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateAPIKeyInput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && len(got.Scopes) != tt.wantScopes {
+				t.Errorf("ValidateAPIKeyInput() scopes = %v, want %d", got.Scopes, tt.wantScopes)
+			}
+		})
+	}
+}
@@ -4,26 +4,43 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // ErrorCode represents application error codes
 type ErrorCode string
 
 const (
-	ErrCodeValidation     ErrorCode = "VALIDATION_ERROR"
-	ErrCodeUnauthorized   ErrorCode = "UNAUTHORIZED"
-	ErrCodeNotFound       ErrorCode = "NOT_FOUND"
-	ErrCodeInternal       ErrorCode = "INTERNAL_ERROR"
-	ErrCodeBadRequest     ErrorCode = "BAD_REQUEST"
-	ErrCodeServiceUnavail ErrorCode = "SERVICE_UNAVAILABLE"
+	ErrCodeValidation        ErrorCode = "VALIDATION_ERROR"
+	ErrCodeUnauthorized      ErrorCode = "UNAUTHORIZED"
+	ErrCodeNotFound          ErrorCode = "NOT_FOUND"
+	ErrCodeInternal          ErrorCode = "INTERNAL_ERROR"
+	ErrCodeBadRequest        ErrorCode = "BAD_REQUEST"
+	ErrCodeServiceUnavail    ErrorCode = "SERVICE_UNAVAILABLE"
+	ErrCodeReauthRequired    ErrorCode = "REAUTHENTICATION_REQUIRED"
+	ErrCodeMFARequired       ErrorCode = "MFA_REQUIRED"
+	ErrCodeForbidden         ErrorCode = "FORBIDDEN"
+	ErrCodeConflict          ErrorCode = "CONFLICT"
+	ErrCodeFlowExpired       ErrorCode = "FLOW_EXPIRED"
+	ErrCodeTooManyAttempts   ErrorCode = "TOO_MANY_ATTEMPTS"
+	ErrCodeChallengeRequired ErrorCode = "CHALLENGE_REQUIRED"
 )
 
 // AppError represents a structured application error
 type AppError struct {
-	Code       ErrorCode `json:"code"`
-	Message    string    `json:"message"`
-	Details    string    `json:"details,omitempty"`
-	HTTPStatus int       `json:"-"`
+	Code       ErrorCode    `json:"code"`
+	Message    string       `json:"message"`
+	Details    string       `json:"details,omitempty"`
+	Fields     []FieldError `json:"fields,omitempty"`
+	RetryAfter int          `json:"retry_after,omitempty"` // seconds, also sent as Retry-After
+	HTTPStatus int          `json:"-"`
+}
+
+// FieldError is a validation message for one request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 func (e *AppError) Error() string {
@@ -36,6 +53,9 @@ func (e *AppError) Error() string {
 // WriteJSON writes the error as JSON response
 func (e *AppError) WriteJSON(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
+	if e.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(e.RetryAfter))
+	}
 	w.WriteHeader(e.HTTPStatus)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"error": e,
@@ -98,3 +118,73 @@ func NewServiceUnavailableError(service string, err error) *AppError {
 		HTTPStatus: http.StatusServiceUnavailable,
 	}
 }
+
+func NewReauthenticationRequiredError(message string) *AppError {
+	return &AppError{
+		Code:       ErrCodeReauthRequired,
+		Message:    message,
+		HTTPStatus: http.StatusForbidden,
+	}
+}
+
+func NewMFARequiredError(message string) *AppError {
+	return &AppError{
+		Code:       ErrCodeMFARequired,
+		Message:    message,
+		HTTPStatus: http.StatusForbidden,
+	}
+}
+
+func NewForbiddenError(message string) *AppError {
+	return &AppError{
+		Code:       ErrCodeForbidden,
+		Message:    message,
+		HTTPStatus: http.StatusForbidden,
+	}
+}
+
+func NewConflictError(message string) *AppError {
+	return &AppError{
+		Code:       ErrCodeConflict,
+		Message:    message,
+		HTTPStatus: http.StatusConflict,
+	}
+}
+
+func NewFlowExpiredError(message string) *AppError {
+	return &AppError{
+		Code:       ErrCodeFlowExpired,
+		Message:    message,
+		HTTPStatus: http.StatusGone,
+	}
+}
+
+func NewFieldValidationError(message string, fields []FieldError) *AppError {
+	return &AppError{
+		Code:       ErrCodeValidation,
+		Message:    message,
+		Fields:     fields,
+		HTTPStatus: http.StatusBadRequest,
+	}
+}
+
+// NewTooManyAttemptsError reports that the caller must wait retryAfter,
+// rounded up to whole seconds, before trying again
+func NewTooManyAttemptsError(message string, retryAfter time.Duration) *AppError {
+	return &AppError{
+		Code:       ErrCodeTooManyAttempts,
+		Message:    message,
+		RetryAfter: int((retryAfter + time.Second - 1) / time.Second),
+		HTTPStatus: http.StatusTooManyRequests,
+	}
+}
+
+// NewChallengeRequiredError reports that the request must carry a solved
+// challenge
+func NewChallengeRequiredError(message string) *AppError {
+	return &AppError{
+		Code:       ErrCodeChallengeRequired,
+		Message:    message,
+		HTTPStatus: http.StatusForbidden,
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestAppError_Error(t *testing.T) {
@@ -89,6 +90,48 @@ func TestAppError_WriteJSON(t *testing.T) {
 			wantStatus: http.StatusServiceUnavailable,
 			wantCode:   ErrCodeServiceUnavail,
 		},
+		{
+			name:       "reauthentication required error",
+			appErr:     NewReauthenticationRequiredError("please log in again"),
+			wantStatus: http.StatusForbidden,
+			wantCode:   ErrCodeReauthRequired,
+		},
+		{
+			name:       "mfa required error",
+			appErr:     NewMFARequiredError("second factor required"),
+			wantStatus: http.StatusForbidden,
+			wantCode:   ErrCodeMFARequired,
+		},
+		{
+			name:       "forbidden error",
+			appErr:     NewForbiddenError("admin role required"),
+			wantStatus: http.StatusForbidden,
+			wantCode:   ErrCodeForbidden,
+		},
+		{
+			name:       "conflict error",
+			appErr:     NewConflictError("email already exists"),
+			wantStatus: http.StatusConflict,
+			wantCode:   ErrCodeConflict,
+		},
+		{
+			name:       "flow expired error",
+			appErr:     NewFlowExpiredError("start a new flow"),
+			wantStatus: http.StatusGone,
+			wantCode:   ErrCodeFlowExpired,
+		},
+		{
+			name:       "too many attempts error",
+			appErr:     NewTooManyAttemptsError("too many failed login attempts", time.Second),
+			wantStatus: http.StatusTooManyRequests,
+			wantCode:   ErrCodeTooManyAttempts,
+		},
+		{
+			name:       "challenge required error",
+			appErr:     NewChallengeRequiredError("challenge required"),
+			wantStatus: http.StatusForbidden,
+			wantCode:   ErrCodeChallengeRequired,
+		},
 	}
 
 	for _, tt := range tests {
@@ -143,6 +186,55 @@ func TestNewValidationError(t *testing.T) {
 	}
 }
 
+func TestNewFieldValidationError(t *testing.T) {
+	err := NewFieldValidationError("registration rejected", []FieldError{
+		{Field: "pass", Message: "password is too similar to the email"},
+	})
+
+	w := httptest.NewRecorder()
+	err.WriteJSON(w)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("WriteJSON() status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	var response struct {
+		Error struct {
+			Code   ErrorCode    `json:"code"`
+			Fields []FieldError `json:"fields"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Error.Code != ErrCodeValidation || len(response.Error.Fields) != 1 || response.Error.Fields[0].Field != "pass" {
+		t.Errorf("WriteJSON() error = %+v", response.Error)
+	}
+}
+
+func TestNewTooManyAttemptsError(t *testing.T) {
+	err := NewTooManyAttemptsError("too many failed login attempts", 1500*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	err.WriteJSON(w)
+
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("WriteJSON() Retry-After = %q, want %q", got, "2")
+	}
+
+	var response struct {
+		Error AppError `json:"error"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response.Error.RetryAfter != 2 {
+		t.Errorf("WriteJSON() retry_after = %d, want 2", response.Error.RetryAfter)
+	}
+}
+
 func TestNewInternalError_NilError(t *testing.T) {
 	err := NewInternalError("something failed", nil)
 